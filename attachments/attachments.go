@@ -0,0 +1,347 @@
+package attachments
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// MAX_ATTACHMENT_BYTES is the largest attachment that can be added.
+	MAX_ATTACHMENT_BYTES = 8 << 20
+
+	// MAX_ATTACHMENTS is how many attachments one note can have.
+	MAX_ATTACHMENTS = 4
+
+	// THUMBNAIL_SIZE is the longest side of an image's thumbnail, in
+	// pixels. Thumbnails are always PNGs.
+	THUMBNAIL_SIZE = 256
+	THUMBNAIL_CONTENT_TYPE = "image/png"
+
+	// MAX_IMAGE_PIXELS bounds the images that are decoded for a
+	// thumbnail, since a small file can claim to be a huge image.
+	MAX_IMAGE_PIXELS = 40 * 1000 * 1000
+
+	// thumbnailSuffix is added to an attachment's id to get the key of
+	// its thumbnail's blob.
+	thumbnailSuffix = ".thumb"
+)
+
+// ALLOWED_CONTENT_TYPES maps each content type an attachment can have to
+// what http.DetectContentType may make of its first bytes. Images have to
+// be recognised, since they're decoded for thumbnails. Many audio files
+// aren't recognised at all, so for those "application/octet-stream" is
+// fine too; it's only content that's plainly something else, e.g. HTML,
+// that's refused.
+var ALLOWED_CONTENT_TYPES = map[string][]string{
+	"image/jpeg": {"image/jpeg"},
+	"image/png": {"image/png"},
+	"image/gif": {"image/gif"},
+	"audio/mpeg": {"audio/mpeg", "application/octet-stream"},
+	"audio/mp4": {"video/mp4", "application/octet-stream"},
+	"audio/ogg": {"application/ogg", "application/octet-stream"},
+	"audio/wav": {"audio/wave", "application/octet-stream"},
+}
+
+var (
+	ErrNoAttachment = errors.New("No such attachment.")
+	ErrTooLarge = errors.New("Attachments can be at most 8 MiB.")
+	ErrTooManyAttachments = errors.New("Too many attachments on this note.")
+	ErrContentType = errors.New("Attachments must be JPEG, PNG or GIF images, or MP3, MP4, Ogg or WAV audio.")
+	ErrContentMismatch = errors.New("Attachment content doesn't match its content type.")
+	ErrBadImage = errors.New("Image couldn't be read.")
+)
+
+// Attachment is a row in the attachments table. The content itself is
+// kept in a BlobStore under the attachment's id, along with a thumbnail
+// if it's an image.
+type Attachment struct {
+	Id uuid.UUID
+	NoteId uuid.UUID
+	ContentType string
+	Size int64
+	Thumbnail bool
+	CreatedAt time.Time
+}
+
+type AttachmentConnection interface {
+	InsertAttachment(attachment *Attachment) error
+
+	// GetAttachment returns the attachment, or nil if there is none.
+	GetAttachment(id uuid.UUID) (*Attachment, error)
+
+	// ListAttachments returns noteId's attachments, oldest first.
+	ListAttachments(noteId uuid.UUID) ([]*Attachment, error)
+
+	// DeleteNoteAttachments deletes noteId's attachments and returns how
+	// many there were.
+	DeleteNoteAttachments(noteId uuid.UUID) (int, error)
+}
+
+// Attachments manages the photos and audio added to notes. It doesn't
+// check who is adding or fetching them; that's up to the caller, who
+// knows who can see the note.
+type Attachments struct {
+	store AttachmentConnection
+	blobs BlobStore
+	now func() time.Time
+}
+
+func NewAttachments(store AttachmentConnection, blobs BlobStore) *Attachments {
+	return &Attachments{store: store, blobs: blobs, now: time.Now}
+}
+
+// Add reads an attachment of the given content type from body and adds
+// it to the note noteId. An image's thumbnail is made straight away, so
+// an image that can't be decoded is refused.
+func (a *Attachments) Add(noteId uuid.UUID, contentType string, body io.Reader) (*Attachment, error) {
+	contentType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrContentType
+	}
+	sniffs, ok := ALLOWED_CONTENT_TYPES[contentType]
+	if !ok {
+		return nil, ErrContentType
+	}
+
+	existing, err := a.store.ListAttachments(noteId)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= MAX_ATTACHMENTS {
+		return nil, ErrTooManyAttachments
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(body, MAX_ATTACHMENT_BYTES + 1))
+	if err != nil {
+		log.Printf("Failed to read attachment for note %v. Err: %v", noteId, err)
+		return nil, err
+	}
+	if len(data) > MAX_ATTACHMENT_BYTES {
+		return nil, ErrTooLarge
+	}
+	if !contains(sniffs, http.DetectContentType(data)) {
+		return nil, ErrContentMismatch
+	}
+
+	attachment := &Attachment{
+		Id: uuid.NewV4(),
+		NoteId: noteId,
+		ContentType: contentType,
+		Size: int64(len(data)),
+		CreatedAt: a.now().UTC().Truncate(time.Second),
+	}
+
+	var thumbnail []byte
+	if isImage(contentType) {
+		if thumbnail, err = makeThumbnail(data); err != nil {
+			return nil, err
+		}
+		attachment.Thumbnail = true
+	}
+
+	if err = a.blobs.Put(attachment.Id.String(), bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if thumbnail != nil {
+		if err = a.blobs.Put(thumbnailKey(attachment.Id), bytes.NewReader(thumbnail)); err != nil {
+			a.deleteBlobs(attachment)
+			return nil, err
+		}
+	}
+	if err = a.store.InsertAttachment(attachment); err != nil {
+		a.deleteBlobs(attachment)
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// Get returns the attachment, or ErrNoAttachment if there is none.
+func (a *Attachments) Get(id uuid.UUID) (*Attachment, error) {
+	attachment, err := a.store.GetAttachment(id)
+	if err != nil {
+		return nil, err
+	}
+	if attachment == nil {
+		return nil, ErrNoAttachment
+	}
+	return attachment, nil
+}
+
+func (a *Attachments) List(noteId uuid.UUID) ([]*Attachment, error) {
+	return a.store.ListAttachments(noteId)
+}
+
+// Open returns the attachment's content, which the caller must close.
+func (a *Attachments) Open(attachment *Attachment) (io.ReadCloser, error) {
+	return a.blobs.Open(attachment.Id.String())
+}
+
+// OpenThumbnail returns the attachment's thumbnail, which the caller
+// must close, or ErrNoAttachment if it isn't an image.
+func (a *Attachments) OpenThumbnail(attachment *Attachment) (io.ReadCloser, error) {
+	if !attachment.Thumbnail {
+		return nil, ErrNoAttachment
+	}
+	return a.blobs.Open(thumbnailKey(attachment.Id))
+}
+
+// PurgeNote deletes every attachment of noteId's, content and all, and
+// returns how many there were. The blobs go first, so if it fails part
+// way the rows are still there to try again with.
+func (a *Attachments) PurgeNote(noteId uuid.UUID) (int, error) {
+	attachments, err := a.store.ListAttachments(noteId)
+	if err != nil {
+		return 0, err
+	}
+	for _, attachment := range attachments {
+		if err = a.blobs.Delete(attachment.Id.String()); err != nil {
+			return 0, err
+		}
+		if attachment.Thumbnail {
+			if err = a.blobs.Delete(thumbnailKey(attachment.Id)); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return a.store.DeleteNoteAttachments(noteId)
+}
+
+// deleteBlobs cleans up after an Add that failed part way.
+func (a *Attachments) deleteBlobs(attachment *Attachment) {
+	for _, key := range []string{attachment.Id.String(), thumbnailKey(attachment.Id)} {
+		if err := a.blobs.Delete(key); err != nil {
+			log.Printf("Failed to delete blob %v of failed attachment. Err: %v", key, err)
+		}
+	}
+}
+
+func thumbnailKey(id uuid.UUID) string {
+	return id.String() + thumbnailSuffix
+}
+
+func isImage(contentType string) bool {
+	return len(contentType) > 6 && contentType[:6] == "image/"
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type MysqlAttachments struct {
+	conn *sql.DB
+}
+
+type DbCredentials struct {
+	User string
+	Password string
+	Host string
+	Port string
+}
+
+func NewMysqlAttachments(credentials *DbCredentials) (*MysqlAttachments, error) {
+	dsn := credentials.User + ":" + credentials.Password + "@tcp(" +
+		credentials.Host + ":" + credentials.Port + ")/geonote?parseTime=true"
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Print("Failed to open db:", err)
+		return nil, err
+	}
+
+	return &MysqlAttachments{conn: db}, nil
+}
+
+func (db MysqlAttachments) InsertAttachment(attachment *Attachment) error {
+	_, err := db.conn.Exec("INSERT INTO attachments " +
+		" (id, note_id, content_type, size, has_thumbnail, created_at) VALUES (?, ?, ?, ?, ?, ?)",
+		attachment.Id.String(),
+		attachment.NoteId.String(),
+		attachment.ContentType,
+		attachment.Size,
+		attachment.Thumbnail,
+		attachment.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to insert attachment %v. Err: %v", attachment.Id, err)
+	}
+	return err
+}
+
+// GetAttachment returns the attachment, or nil if there is none.
+func (db MysqlAttachments) GetAttachment(id uuid.UUID) (*Attachment, error) {
+	attachments, err := db.query("SELECT id, note_id, content_type, size, has_thumbnail, created_at " +
+		" FROM attachments WHERE id = ?", id.String())
+	if err != nil || len(attachments) == 0 {
+		return nil, err
+	}
+	return attachments[0], nil
+}
+
+func (db MysqlAttachments) ListAttachments(noteId uuid.UUID) ([]*Attachment, error) {
+	return db.query("SELECT id, note_id, content_type, size, has_thumbnail, created_at " +
+		" FROM attachments WHERE note_id = ? ORDER BY created_at, id", noteId.String())
+}
+
+func (db MysqlAttachments) DeleteNoteAttachments(noteId uuid.UUID) (int, error) {
+	result, err := db.conn.Exec("DELETE FROM attachments WHERE note_id = ?", noteId.String())
+	if err != nil {
+		log.Printf("Failed to delete attachments of note %v. Err: %v", noteId, err)
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Failed to count deleted attachments of note %v. Err: %v", noteId, err)
+		return 0, err
+	}
+	return int(deleted), nil
+}
+
+func (db MysqlAttachments) query(sql string, args ...interface{}) ([]*Attachment, error) {
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(args...)
+	if err != nil {
+		log.Printf("Failed to query attachments. Err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*Attachment
+	for rows.Next() {
+		var attachment Attachment
+		err = rows.Scan(
+			&attachment.Id,
+			&attachment.NoteId,
+			&attachment.ContentType,
+			&attachment.Size,
+			&attachment.Thumbnail,
+			&attachment.CreatedAt,
+		)
+		if err != nil {
+			log.Printf("Failed to scan row while fetching attachments. Err: %v", err)
+			return nil, err
+		}
+		attachments = append(attachments, &attachment)
+	}
+
+	return attachments, rows.Err()
+}
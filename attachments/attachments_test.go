@@ -0,0 +1,242 @@
+package attachments
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
+)
+
+func TestAddImage(t *testing.T) {
+	a, blobs, _ := getTestAttachments()
+	noteId := uuid.NewV4()
+	data := getTestPng(t, 1024, 512)
+
+	attachment, err := a.Add(noteId, "image/png", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Failed to add image. Err: ", err)
+	}
+	if attachment.NoteId != noteId || attachment.ContentType != "image/png" ||
+		attachment.Size != int64(len(data)) || !attachment.Thumbnail {
+		t.Fatal("Unexpected attachment: ", attachment)
+	}
+	if blobs.Len() != 2 {
+		t.Fatal("Expected the image and its thumbnail to be stored, got: ", blobs.Len())
+	}
+
+	got, err := a.Get(attachment.Id)
+	if err != nil || *got != *attachment {
+		t.Fatal("Expected to get the attachment back, got: ", got, " ", err)
+	}
+	if content := readAll(t)(a.Open(attachment)); !bytes.Equal(content, data) {
+		t.Fatal("Expected the image back unchanged.")
+	}
+
+	thumbnail, err := png.Decode(bytes.NewReader(readAll(t)(a.OpenThumbnail(attachment))))
+	if err != nil {
+		t.Fatal("Failed to decode thumbnail. Err: ", err)
+	}
+	if size := thumbnail.Bounds().Size(); size.X != THUMBNAIL_SIZE || size.Y != THUMBNAIL_SIZE / 2 {
+		t.Fatal("Unexpected thumbnail size: ", size)
+	}
+	if r, _, _, _ := thumbnail.At(0, 0).RGBA(); r >> 8 != 200 {
+		t.Fatal("Expected the thumbnail to keep the image's colour, got red: ", r >> 8)
+	}
+}
+
+func TestAddAudio(t *testing.T) {
+	a, blobs, _ := getTestAttachments()
+	data := append([]byte("ID3"), make([]byte, 100)...)
+
+	attachment, err := a.Add(uuid.NewV4(), "audio/mpeg; foo=bar", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal("Failed to add audio. Err: ", err)
+	}
+	if attachment.ContentType != "audio/mpeg" || attachment.Thumbnail {
+		t.Fatal("Unexpected attachment: ", attachment)
+	}
+	if blobs.Len() != 1 {
+		t.Fatal("Expected only the audio to be stored, got: ", blobs.Len())
+	}
+	if _, err = a.OpenThumbnail(attachment); err != ErrNoAttachment {
+		t.Fatal("Expected ErrNoAttachment for audio's thumbnail, got: ", err)
+	}
+}
+
+func TestAddValidation(t *testing.T) {
+	image := getTestPng(t, 10, 10)
+	cases := []struct {
+		name string
+		contentType string
+		data []byte
+		expected error
+	}{
+		{"unsupported type", "application/pdf", []byte("%PDF-1.4"), ErrContentType},
+		{"bad content type", "image/", image, ErrContentType},
+		{"image that isn't", "image/png", []byte("<html><body>hi</body></html>"), ErrContentMismatch},
+		{"wrong image type", "image/jpeg", image, ErrContentMismatch},
+		{"audio that's html", "audio/mpeg", []byte("<html><body>hi</body></html>"), ErrContentMismatch},
+		{"truncated image", "image/png", image[:len(image) / 2], ErrBadImage},
+		{"too large", "audio/wav", make([]byte, MAX_ATTACHMENT_BYTES + 1), ErrTooLarge},
+	}
+
+	for _, c := range cases {
+		a, blobs, _ := getTestAttachments()
+		noteId := uuid.NewV4()
+		if _, err := a.Add(noteId, c.contentType, bytes.NewReader(c.data)); err != c.expected {
+			t.Fatal(c.name, ": expected ", c.expected, ", got: ", err)
+		}
+		if attachments, _ := a.List(noteId); len(attachments) != 0 || blobs.Len() != 0 {
+			t.Fatal(c.name, ": expected nothing to be stored, got: ", attachments, " ", blobs.Len())
+		}
+	}
+}
+
+func TestAddTooMany(t *testing.T) {
+	a, _, clock := getTestAttachments()
+	noteId := uuid.NewV4()
+	for i := 0; i < MAX_ATTACHMENTS; i++ {
+		*clock = clock.Add(time.Second)
+		if _, err := a.Add(noteId, "audio/ogg", strings.NewReader("OggS\x00")); err != nil {
+			t.Fatal("Failed to add attachment. Err: ", err)
+		}
+	}
+	if _, err := a.Add(noteId, "audio/ogg", strings.NewReader("OggS\x00")); err != ErrTooManyAttachments {
+		t.Fatal("Expected ErrTooManyAttachments, got: ", err)
+	}
+
+	attachments, err := a.List(noteId)
+	if err != nil || len(attachments) != MAX_ATTACHMENTS {
+		t.Fatal("Expected every attachment, got: ", attachments, " ", err)
+	}
+	for i := 1; i < len(attachments); i++ {
+		if !attachments[i - 1].CreatedAt.Before(attachments[i].CreatedAt) {
+			t.Fatal("Expected attachments oldest first, got: ", attachments)
+		}
+	}
+}
+
+func TestPurgeNote(t *testing.T) {
+	a, blobs, _ := getTestAttachments()
+	notes := notesdb.NewMemoryNotesdb()
+	db := NewNotesdb(notes, a)
+
+	note := notesdb.NewNote(uuid.NewV4(), uuid.NewV4(), "look", 40.7, -74.0, time.Now().UTC())
+	other := notesdb.NewNote(uuid.NewV4(), uuid.NewV4(), "listen", 40.7, -74.0, time.Now().UTC())
+	for _, n := range []*notesdb.Note{note, other} {
+		if err := db.InsertNote(n); err != nil {
+			t.Fatal("Failed to insert note. Err: ", err)
+		}
+	}
+	if _, err := a.Add(note.Id(), "image/png", bytes.NewReader(getTestPng(t, 10, 10))); err != nil {
+		t.Fatal("Failed to add image. Err: ", err)
+	}
+	kept, err := a.Add(other.Id(), "audio/mpeg", strings.NewReader("ID3\x00"))
+	if err != nil {
+		t.Fatal("Failed to add audio. Err: ", err)
+	}
+
+	if err = db.PurgeNote(note.Id()); err != nil {
+		t.Fatal("Failed to purge note. Err: ", err)
+	}
+	if found, _ := notes.GetNotesByIds([]uuid.UUID{note.Id()}); len(found) != 1 || found[0] != nil {
+		t.Fatal("Expected the note to be purged, got: ", found)
+	}
+	if attachments, _ := a.List(note.Id()); len(attachments) != 0 {
+		t.Fatal("Expected the note's attachments to be purged, got: ", attachments)
+	}
+	if blobs.Len() != 1 {
+		t.Fatal("Expected only the other note's blob to be left, got: ", blobs.Len())
+	}
+	if _, err = a.Get(kept.Id); err != nil {
+		t.Fatal("Expected the other note's attachment to be kept. Err: ", err)
+	}
+}
+
+func TestLocalBlobStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geonote-blobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := NewLocalBlobStore(dir + "/attachments")
+	if err != nil {
+		t.Fatal("Failed to make blob store. Err: ", err)
+	}
+
+	key := uuid.NewV4().String()
+	for _, content := range []string{"first", "second"} {
+		if err = s.Put(key, strings.NewReader(content)); err != nil {
+			t.Fatal("Failed to put blob. Err: ", err)
+		}
+		if got := string(readAll(t)(s.Open(key))); got != content {
+			t.Fatal("Expected ", content, ", got: ", got)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = s.Delete(key); err != nil {
+			t.Fatal("Failed to delete blob. Err: ", err)
+		}
+	}
+	if _, err = s.Open(key); err != ErrNoBlob {
+		t.Fatal("Expected ErrNoBlob, got: ", err)
+	}
+
+	for _, key := range []string{"", ".tmp-1", "../escape", "a/b", "a\\b", "é"} {
+		if err = s.Put(key, strings.NewReader("x")); err != ErrBlobKey {
+			t.Fatal("Expected ErrBlobKey for ", key, ", got: ", err)
+		}
+	}
+}
+
+func getTestAttachments() (*Attachments, *MemoryBlobStore, *time.Time) {
+	blobs := NewMemoryBlobStore()
+	a := NewAttachments(NewMemoryAttachments(), blobs)
+	clock := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	a.now = func() time.Time {
+		return clock
+	}
+	return a, blobs, &clock
+}
+
+func getTestPng(t *testing.T, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{200, 100, 50, 255})
+		}
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+// readAll returns a function that reads and closes whatever it's given,
+// so that it can be called with an Open's results.
+func readAll(t *testing.T) func(io.ReadCloser, error) []byte {
+	return func(reader io.ReadCloser, err error) []byte {
+		if err != nil {
+			t.Fatal("Failed to open blob. Err: ", err)
+		}
+		defer reader.Close()
+		data, err := ioutil.ReadAll(reader)
+		if err != nil {
+			t.Fatal("Failed to read blob. Err: ", err)
+		}
+		return data
+	}
+}
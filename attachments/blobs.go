@@ -0,0 +1,139 @@
+package attachments
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrNoBlob = errors.New("No such blob.")
+	ErrBlobKey = errors.New("Blob keys must be letters, digits, '-' and '.', and not start with '.'.")
+)
+
+// BlobStore holds attachment content by key. Keys are made of letters,
+// digits, '-' and '.', and don't start with '.', so a store can use them
+// as file names.
+type BlobStore interface {
+	// Put stores everything read from body under key, replacing whatever
+	// was there.
+	Put(key string, body io.Reader) error
+
+	// Open returns the blob for reading, or ErrNoBlob if there is none.
+	// The caller must close it.
+	Open(key string) (io.ReadCloser, error)
+
+	// Delete is idempotent; deleting a missing blob isn't an error.
+	Delete(key string) error
+}
+
+// LocalBlobStore keeps blobs as files under a directory on the local
+// disk, spread across subdirectories named for the first two characters
+// of their keys.
+type LocalBlobStore struct {
+	root string
+}
+
+// NewLocalBlobStore makes the directory root if it doesn't exist yet.
+func NewLocalBlobStore(root string) (*LocalBlobStore, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		log.Printf("Failed to make blob directory %v. Err: %v", root, err)
+		return nil, err
+	}
+	return &LocalBlobStore{root: root}, nil
+}
+
+// Put writes to a temporary file first and renames it into place, so a
+// failed write never leaves half a blob behind.
+func (s *LocalBlobStore) Put(key string, body io.Reader) error {
+	filename, err := s.filename(key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(filename)
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		log.Printf("Failed to make blob directory %v. Err: %v", dir, err)
+		return err
+	}
+
+	file, err := ioutil.TempFile(dir, ".tmp-")
+	if err != nil {
+		log.Printf("Failed to create temporary file for blob %v. Err: %v", key, err)
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err = io.Copy(file, body); err != nil {
+		file.Close()
+		log.Printf("Failed to write blob %v. Err: %v", key, err)
+		return err
+	}
+	if err = file.Close(); err != nil {
+		log.Printf("Failed to write blob %v. Err: %v", key, err)
+		return err
+	}
+	if err = os.Rename(file.Name(), filename); err != nil {
+		log.Printf("Failed to move blob %v into place. Err: %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) Open(key string) (io.ReadCloser, error) {
+	filename, err := s.filename(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, ErrNoBlob
+	}
+	if err != nil {
+		log.Printf("Failed to open blob %v. Err: %v", key, err)
+		return nil, err
+	}
+	return file, nil
+}
+
+func (s *LocalBlobStore) Delete(key string) error {
+	filename, err := s.filename(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(filename); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to delete blob %v. Err: %v", key, err)
+		return err
+	}
+	return nil
+}
+
+func (s *LocalBlobStore) filename(key string) (string, error) {
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+	prefix := key
+	if len(prefix) > 2 {
+		prefix = prefix[:2]
+	}
+	return filepath.Join(s.root, prefix, key), nil
+}
+
+// validateKey refuses keys that could name a file outside the store, or
+// one of its temporary files.
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, ".") {
+		return ErrBlobKey
+	}
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+		default:
+			return ErrBlobKey
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package attachments
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/satori/go.uuid"
+)
+
+// MemoryAttachments is an in-memory AttachmentConnection for tests in
+// packages that sit on top of attachments and shouldn't need a live
+// MySQL.
+type MemoryAttachments struct {
+	mutex sync.Mutex
+	attachments []Attachment
+}
+
+func NewMemoryAttachments() *MemoryAttachments {
+	return &MemoryAttachments{}
+}
+
+func (db *MemoryAttachments) InsertAttachment(attachment *Attachment) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.attachments = append(db.attachments, *attachment)
+	return nil
+}
+
+func (db *MemoryAttachments) GetAttachment(id uuid.UUID) (*Attachment, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, attachment := range db.attachments {
+		if attachment.Id == id {
+			return &attachment, nil
+		}
+	}
+	return nil, nil
+}
+
+func (db *MemoryAttachments) ListAttachments(noteId uuid.UUID) ([]*Attachment, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var attachments []*Attachment
+	for _, attachment := range db.attachments {
+		if attachment.NoteId == noteId {
+			attachment := attachment
+			attachments = append(attachments, &attachment)
+		}
+	}
+	sort.SliceStable(attachments, func(i, j int) bool {
+		return attachments[i].CreatedAt.Before(attachments[j].CreatedAt)
+	})
+	return attachments, nil
+}
+
+func (db *MemoryAttachments) DeleteNoteAttachments(noteId uuid.UUID) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	kept := db.attachments[:0]
+	for _, attachment := range db.attachments {
+		if attachment.NoteId != noteId {
+			kept = append(kept, attachment)
+		}
+	}
+	deleted := len(db.attachments) - len(kept)
+	db.attachments = kept
+	return deleted, nil
+}
+
+// MemoryBlobStore is an in-memory BlobStore for tests.
+type MemoryBlobStore struct {
+	mutex sync.Mutex
+	blobs map[string][]byte
+}
+
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: map[string][]byte{}}
+}
+
+func (s *MemoryBlobStore) Put(key string, body io.Reader) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.blobs[key] = data
+	return nil
+}
+
+func (s *MemoryBlobStore) Open(key string) (io.ReadCloser, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, ErrNoBlob
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *MemoryBlobStore) Delete(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.blobs, key)
+	return nil
+}
+
+// Len is how many blobs the store holds.
+func (s *MemoryBlobStore) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.blobs)
+}
@@ -0,0 +1,14 @@
+-- Photos and audio added to notes. The content is kept in a blob store
+-- under the attachment's id, and an image's thumbnail under its id
+-- followed by ".thumb".
+
+CREATE TABLE attachments (
+	id CHAR(36) NOT NULL,
+	note_id CHAR(36) NOT NULL,
+	content_type VARCHAR(64) NOT NULL,
+	size INT NOT NULL,
+	has_thumbnail BOOLEAN NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (id),
+	KEY attachments_note_id (note_id, created_at)
+);
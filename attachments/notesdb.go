@@ -0,0 +1,28 @@
+package attachments
+
+import (
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
+)
+
+// Notesdb is a NotesdbConnection that also purges a note's attachments
+// when the note is purged. Everything else is passed straight through to
+// the wrapped connection, so it can stand in for it anywhere.
+type Notesdb struct {
+	notesdb.NotesdbConnection
+	attachments *Attachments
+}
+
+func NewNotesdb(notes notesdb.NotesdbConnection, attachments *Attachments) *Notesdb {
+	return &Notesdb{NotesdbConnection: notes, attachments: attachments}
+}
+
+// PurgeNote purges the attachments first, so that they're cleaned up
+// even if the note itself is already gone.
+func (db *Notesdb) PurgeNote(id uuid.UUID) error {
+	if _, err := db.attachments.PurgeNote(id); err != nil {
+		return err
+	}
+	return db.NotesdbConnection.PurgeNote(id)
+}
@@ -0,0 +1,89 @@
+package attachments
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"log"
+
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+// makeThumbnail decodes an image and scales it down, keeping its shape,
+// until neither side is longer than THUMBNAIL_SIZE. Images that are
+// already small enough are only re-encoded. Only the first frame of an
+// animated GIF is used.
+func makeThumbnail(data []byte) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrBadImage
+	}
+	if config.Width <= 0 || config.Height <= 0 ||
+		config.Width * config.Height > MAX_IMAGE_PIXELS {
+		return nil, ErrBadImage
+	}
+
+	source, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrBadImage
+	}
+
+	width, height := thumbnailBounds(config.Width, config.Height)
+	var out bytes.Buffer
+	if err = png.Encode(&out, shrink(source, width, height)); err != nil {
+		log.Printf("Failed to encode thumbnail. Err: %v", err)
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// thumbnailBounds is the size of the thumbnail of a width by height
+// image; neither side is ever rounded down to nothing.
+func thumbnailBounds(width int, height int) (int, int) {
+	if width <= THUMBNAIL_SIZE && height <= THUMBNAIL_SIZE {
+		return width, height
+	}
+	if width >= height {
+		return THUMBNAIL_SIZE, max(1, height * THUMBNAIL_SIZE / width)
+	}
+	return max(1, width * THUMBNAIL_SIZE / height), THUMBNAIL_SIZE
+}
+
+// shrink scales source down to width by height, averaging the source
+// pixels that fall in each thumbnail pixel.
+func shrink(source image.Image, width int, height int) *image.RGBA {
+	bounds := source.Bounds()
+	thumbnail := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y * bounds.Dy() / height
+		y1 := max(y0 + 1, bounds.Min.Y + (y + 1) * bounds.Dy() / height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x * bounds.Dx() / width
+			x1 := max(x0 + 1, bounds.Min.X + (x + 1) * bounds.Dx() / width)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := source.At(sx, sy).RGBA()
+					r, g, b, a = r + uint64(pr), g + uint64(pg), b + uint64(pb), a + uint64(pa)
+					n++
+				}
+			}
+
+			i := thumbnail.PixOffset(x, y)
+			thumbnail.Pix[i] = uint8(r / n >> 8)
+			thumbnail.Pix[i + 1] = uint8(g / n >> 8)
+			thumbnail.Pix[i + 2] = uint8(b / n >> 8)
+			thumbnail.Pix[i + 3] = uint8(a / n >> 8)
+		}
+	}
+	return thumbnail
+}
+
+func max(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
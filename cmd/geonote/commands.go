@@ -0,0 +1,652 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/attachments"
+	"github.com/dbenny42/geonote/config"
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/erasure"
+	"github.com/dbenny42/geonote/export"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/reconcile"
+	"github.com/dbenny42/geonote/reindex"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/solrnotes"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+// env is everything a command needs, so tests can swap in memory stores.
+type env struct {
+	users userdb.UserdbConnection
+	twoFactor *userdb.TwoFactor
+	notes notesdb.NotesdbConnection
+	index solrnotes.SolrConnection
+	events lockout.LoginEventConnection
+	sessions *sessions.Manager
+	contacts *contacts.Contacts
+
+	// openCore connects to a Solr core other than the configured one,
+	// e.g. a fresh core being filled by reindex.
+	openCore func(core string) (solrnotes.SolrConnection, error)
+
+	in io.Reader
+	out io.Writer
+	now func() time.Time
+}
+
+func newEnv(conf *config.Config, in io.Reader, out io.Writer) (*env, error) {
+	users, err := conf.OpenUserdb()
+	if err != nil {
+		return nil, err
+	}
+	notes, err := conf.OpenNotesdb()
+	if err != nil {
+		return nil, err
+	}
+	index, err := conf.OpenSolr()
+	if err != nil {
+		return nil, err
+	}
+	events, err := conf.OpenLoginEvents()
+	if err != nil {
+		return nil, err
+	}
+	manager, err := conf.OpenSessions()
+	if err != nil {
+		return nil, err
+	}
+	contactStore, err := conf.OpenContacts()
+	if err != nil {
+		return nil, err
+	}
+	files, err := conf.OpenAttachments()
+	if err != nil {
+		return nil, err
+	}
+
+	return &env{
+		users: users,
+		twoFactor: userdb.NewTwoFactor(users, users),
+		// So that purging notes, or users, purges their attachments too.
+		notes: attachments.NewNotesdb(notes, files),
+		index: index,
+		events: events,
+		sessions: manager,
+		contacts: contacts.NewContacts(contactStore),
+		openCore: func(core string) (solrnotes.SolrConnection, error) {
+			return solrnotes.NewSolrNoteConnectionToCore(conf.Solr.Host, conf.Solr.Port, core)
+		},
+		in: in,
+		out: out,
+		now: time.Now,
+	}, nil
+}
+
+type command struct {
+	name string
+	summary string
+	run func(e *env, args []string) error
+}
+
+var commands = []command{
+	{"user-create", "register a user; the password is read from stdin", userCreate},
+	{"user-delete", "erase a user along with their notes, contacts and sessions", userDelete},
+	{"user-export", "write everything held about a user to a zip archive", userExport},
+	{"user-password", "set a user's password; it is read from stdin", userPassword},
+	{"totp-reset", "turn off a user's two-factor authentication", totpReset},
+	{"logins", "show a user's recent login attempts", logins},
+	{"send", "send a note at coordinates", send},
+	{"list", "list notes by -sender or -recipient, or a note's -thread", list},
+	{"nearby", "list a recipient's notes near coordinates", nearby},
+	{"purge", "permanently remove notes, and their attachments, from MySQL and Solr", purge},
+	{"reindex", "rebuild a Solr core from MySQL", runReindex},
+	{"check", "compare MySQL and Solr, optionally repairing Solr", check},
+}
+
+func findCommand(name string) *command {
+	for i, _ := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+func newFlagSet(e *env, name string, argsUsage string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.out)
+	flags.Usage = func() {
+		fmt.Fprintf(e.out, "usage: geonote %v [flags] %v\n", name, argsUsage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func userCreate(e *env, args []string) error {
+	flags := newFlagSet(e, "user-create", "<username>")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one username")
+	}
+
+	password, err := readPassword(e)
+	if err != nil {
+		return err
+	}
+
+	id, err := e.users.RegisterUser(flags.Arg(0), password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.out, "Registered %v as %v\n", flags.Arg(0), id)
+	return nil
+}
+
+// userPassword sets a password without the old one, for users who can't
+// get a reset token. It doesn't end their sessions.
+func userPassword(e *env, args []string) error {
+	flags := newFlagSet(e, "user-password", "<username>")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one username")
+	}
+
+	user, err := e.users.GetUserByName(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("no user named %v", flags.Arg(0))
+	}
+
+	password, err := readPassword(e)
+	if err != nil {
+		return err
+	}
+
+	if err = e.users.SetPassword(user.Id, password); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.out, "Set password for %v\n", user.Name)
+	return nil
+}
+
+// readPassword reads a password from the first line of stdin.
+func readPassword(e *env) (string, error) {
+	password, err := bufio.NewReader(e.in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return "", errors.New("no password given on stdin")
+	}
+	return password, nil
+}
+
+// userDelete erases everything about a user, not just their account; see
+// erasure.Eraser. An interrupted run can be finished by running it again.
+func userDelete(e *env, args []string) error {
+	flags := newFlagSet(e, "user-delete", "<username>")
+	batchSize := flags.Int("batch", erasure.DEFAULT_BATCH_SIZE, "notes per batch")
+	checkpoint := flags.String("checkpoint", "", "checkpoint file, for resuming an interrupted run")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one username")
+	}
+
+	eraser := erasure.NewEraser(e.users, e.notes, e.index, e.sessions, e.contacts, e.events, erasure.Options{
+		BatchSize: *batchSize,
+		CheckpointFile: *checkpoint,
+		Progress: func(r erasure.Report) {
+			if r.Step != erasure.STEP_DONE {
+				fmt.Fprintf(e.out, "Erasing %v: %v sent and %v received notes purged, now at %v\n",
+					r.Username, r.SentPurged, r.ReceivedPurged, r.Step)
+			}
+		},
+	})
+
+	report, err := eraser.Erase(flags.Arg(0))
+	if err == userdb.ErrNotFound {
+		return fmt.Errorf("no user named %v", flags.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Deleted %v\n", report.Username)
+	fmt.Fprintf(w, "user id\t%v\n", report.UserId)
+	fmt.Fprintf(w, "sent notes purged\t%v\n", report.SentPurged)
+	fmt.Fprintf(w, "received notes purged\t%v\n", report.ReceivedPurged)
+	fmt.Fprintf(w, "group notes left for others\t%v\n", report.RemovedFromNotes)
+	fmt.Fprintf(w, "shared note reads removed\t%v\n", report.ReadsRemoved)
+	fmt.Fprintf(w, "contact rows deleted\t%v\n", report.ContactsDeleted)
+	fmt.Fprintf(w, "login events deleted\t%v\n", report.LoginEventsDeleted)
+	fmt.Fprintf(w, "started\t%v\n", report.StartedAt.Format(time.RFC3339))
+	fmt.Fprintf(w, "completed\t%v\n", report.CompletedAt.Format(time.RFC3339))
+	return w.Flush()
+}
+
+func userExport(e *env, args []string) error {
+	flags := newFlagSet(e, "user-export", "<username>")
+	output := flags.String("o", "", "archive file to write (required)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 || *output == "" {
+		flags.Usage()
+		return errors.New("expected -o and exactly one username")
+	}
+
+	user, err := e.users.GetUserByName(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("no user named %v", flags.Arg(0))
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	summary, err := export.NewExporter(e.users, e.notes, export.Options{}).Export(user.Id, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*output)
+		return err
+	}
+
+	fmt.Fprintf(e.out, "Exported %v sent and %v received notes of %v to %v\n",
+		summary.SentNotes, summary.ReceivedNotes, summary.Username, *output)
+	return nil
+}
+
+// totpReset is for users who have lost both their authenticator and their
+// recovery codes. Check who's asking before running it.
+func totpReset(e *env, args []string) error {
+	flags := newFlagSet(e, "totp-reset", "<username>")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one username")
+	}
+
+	user, err := e.users.GetUserByName(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("no user named %v", flags.Arg(0))
+	}
+
+	if err = e.twoFactor.Reset(user.Id); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.out, "Turned off two-factor for %v\n", user.Name)
+	return nil
+}
+
+func logins(e *env, args []string) error {
+	flags := newFlagSet(e, "logins", "<username>")
+	count := flags.Int("count", 20, "maximum number of attempts")
+	offset := flags.Int("offset", 0, "number of attempts to skip")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one username")
+	}
+
+	events, err := e.events.GetLoginEvents(userdb.NormalizeUsername(flags.Arg(0)), *count, *offset)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "AT\tOUTCOME\tSOURCE")
+	for _, event := range events {
+		fmt.Fprintf(w, "%v\t%v\t%v\n", event.At.Format(time.RFC3339), event.Outcome, event.Source)
+	}
+	w.Flush()
+	return nil
+}
+
+func send(e *env, args []string) error {
+	flags := newFlagSet(e, "send", "<text>")
+	senderFlag := flags.String("sender", "", "sender uuid")
+	recipientFlag := flags.String("recipient", "", "recipient uuid, or several separated by commas")
+	visibility := flags.String("visibility", notesdb.VISIBILITY_PRIVATE,
+		"private, or contacts or public for a note without recipients")
+	latitude := flags.Float64("lat", 0, "latitude")
+	longitude := flags.Float64("lon", 0, "longitude")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return errors.New("no note text given")
+	}
+	if !notesdb.ValidVisibility(*visibility) {
+		return errors.New("-visibility must be private, contacts or public")
+	}
+	if (*visibility == notesdb.VISIBILITY_PRIVATE) == (*recipientFlag == "") {
+		return errors.New("give -recipient for a private note, and only for a private note")
+	}
+
+	sender, err := parseId("-sender", *senderFlag)
+	if err != nil {
+		return err
+	}
+	var recipients []uuid.UUID
+	if *recipientFlag != "" {
+		for _, value := range strings.Split(*recipientFlag, ",") {
+			recipient, err := parseId("-recipient", strings.TrimSpace(value))
+			if err != nil {
+				return err
+			}
+			recipients = append(recipients, recipient)
+		}
+	}
+
+	text := strings.Join(flags.Args(), " ")
+	timeSent := e.now().UTC().Truncate(time.Second)
+	note := notesdb.NewGroupNote(sender, recipients, text, *latitude, *longitude, timeSent)
+	if *visibility != notesdb.VISIBILITY_PRIVATE {
+		note = notesdb.NewSharedNote(sender, *visibility, text, *latitude, *longitude, timeSent)
+	}
+
+	if err = e.notes.InsertNote(note); err != nil {
+		return err
+	}
+	if err = e.index.AddDoc(solrnotes.DocumentFromNote(note)); err != nil {
+		return fmt.Errorf("note %v was stored but not indexed; run check -repair: %v", note.Id(), err)
+	}
+
+	fmt.Fprintf(e.out, "Sent %v\n", note.Id())
+	return nil
+}
+
+func list(e *env, args []string) error {
+	flags := newFlagSet(e, "list", "")
+	senderFlag := flags.String("sender", "", "list notes sent by this uuid")
+	recipientFlag := flags.String("recipient", "", "list notes sent to this uuid")
+	threadFlag := flags.String("thread", "", "list the conversation this note uuid belongs to, oldest first")
+	count := flags.Int("count", 20, "maximum number of notes")
+	offset := flags.Int("offset", 0, "number of notes to skip")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	given := 0
+	for _, value := range []string{*senderFlag, *recipientFlag, *threadFlag} {
+		if value != "" {
+			given++
+		}
+	}
+	if given != 1 {
+		flags.Usage()
+		return errors.New("give exactly one of -sender, -recipient or -thread")
+	}
+
+	var notes []*notesdb.Note
+	if *threadFlag != "" {
+		id, err := parseId("-thread", *threadFlag)
+		if err != nil {
+			return err
+		}
+		found, err := e.notes.GetNotesByIds([]uuid.UUID{id})
+		if err != nil {
+			return err
+		}
+		if len(found) != 1 || found[0] == nil {
+			return fmt.Errorf("no note %v", id)
+		}
+		notes, err = e.notes.GetThread(found[0].ThreadId(), *count, *offset)
+		if err != nil {
+			return err
+		}
+	} else if *senderFlag != "" {
+		sender, err := parseId("-sender", *senderFlag)
+		if err != nil {
+			return err
+		}
+		notes, err = e.notes.GetNotesBySender(sender, *count, *offset)
+		if err != nil {
+			return err
+		}
+	} else {
+		recipient, err := parseId("-recipient", *recipientFlag)
+		if err != nil {
+			return err
+		}
+		notes, err = e.notes.GetNotesByRecipient(recipient, nil, *count, *offset)
+		if err != nil {
+			return err
+		}
+	}
+
+	printNotes(e.out, notes)
+	return nil
+}
+
+func nearby(e *env, args []string) error {
+	flags := newFlagSet(e, "nearby", "")
+	recipientFlag := flags.String("recipient", "", "recipient uuid")
+	latitude := flags.Float64("lat", 0, "latitude")
+	longitude := flags.Float64("lon", 0, "longitude")
+	radiusKm := flags.Float64("radius", 0.1, "search radius in km")
+	count := flags.Int("count", 20, "maximum number of notes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	recipient, err := parseId("-recipient", *recipientFlag)
+	if err != nil {
+		return err
+	}
+
+	docs, err := e.index.FindDocsNearby(recipient, nil, nil, *latitude, *longitude, *radiusKm, *count)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.Id()
+	}
+	notes, err := e.notes.GetNotesByIds(ids)
+	if err != nil {
+		return err
+	}
+
+	printNotes(e.out, notes)
+	return nil
+}
+
+// purge removes notes outright, unlike the API's delete which only flags
+// them. Notes already missing from MySQL are still purged from Solr.
+func purge(e *env, args []string) error {
+	flags := newFlagSet(e, "purge", "<note id>...")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return errors.New("no note ids given")
+	}
+
+	var ids []uuid.UUID
+	for _, arg := range flags.Args() {
+		id, err := parseId("note id", arg)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+
+	for _, id := range ids {
+		if err := e.notes.PurgeNote(id); err != nil {
+			fmt.Fprintf(e.out, "Could not purge %v from MySQL: %v\n", id, err)
+		}
+	}
+	if err := e.index.PurgeDocs(ids); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.out, "Purged %v notes\n", len(ids))
+	return nil
+}
+
+func runReindex(e *env, args []string) error {
+	flags := newFlagSet(e, "reindex", "<target core>")
+	batchSize := flags.Int("batch", reindex.DEFAULT_BATCH_SIZE, "notes per batch")
+	checkpoint := flags.String("checkpoint", "", "checkpoint file, for resuming an interrupted run")
+	alias := flags.String("alias", "", "alias to point at the target core when done")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("expected exactly one target core")
+	}
+
+	target, err := e.openCore(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	reindexer := reindex.NewReindexer(e.notes, target, reindex.Options{
+		BatchSize: *batchSize,
+		CheckpointFile: *checkpoint,
+		Alias: *alias,
+		Progress: func(p reindex.Progress) {
+			fmt.Fprintf(e.out, "Indexed %v notes in %v (last id %v)\n",
+				p.Indexed, p.Elapsed.Truncate(time.Millisecond), p.LastId)
+		},
+	})
+
+	progress, err := reindexer.Run()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.out, "Done: indexed %v notes into %v\n", progress.Indexed, flags.Arg(0))
+	return nil
+}
+
+func check(e *env, args []string) error {
+	flags := newFlagSet(e, "check", "")
+	after := flags.String("after", "", "only check note ids after this one")
+	through := flags.String("through", "", "only check note ids up to and including this one")
+	batchSize := flags.Int("batch", reconcile.DEFAULT_BATCH_SIZE, "notes per batch")
+	repair := flags.Bool("repair", false, "rewrite Solr from MySQL where they differ")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	options := reconcile.Options{BatchSize: *batchSize, Repair: *repair}
+	var err error
+	if *after != "" {
+		if options.AfterId, err = parseId("-after", *after); err != nil {
+			return err
+		}
+	}
+	if *through != "" {
+		if options.ThroughId, err = parseId("-through", *through); err != nil {
+			return err
+		}
+	}
+
+	report, err := reconcile.NewChecker(e.notes, e.index, options).Run()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range report.Missing {
+		fmt.Fprintf(e.out, "missing\t%v\n", id)
+	}
+	for _, id := range report.Orphaned {
+		fmt.Fprintf(e.out, "orphaned\t%v\n", id)
+	}
+	for _, mismatch := range report.Mismatched {
+		fmt.Fprintf(e.out, "mismatched\t%v\t%v\n", mismatch.Id, strings.Join(mismatch.Fields, ","))
+	}
+	fmt.Fprintf(e.out, "Checked %v notes: %v missing, %v orphaned, %v mismatched, %v repaired\n",
+		report.Checked, len(report.Missing), len(report.Orphaned), len(report.Mismatched),
+		report.Repaired)
+
+	if !report.Consistent() && !*repair {
+		return errors.New("stores are inconsistent")
+	}
+	return nil
+}
+
+func parseId(name string, value string) (uuid.UUID, error) {
+	if value == "" {
+		return uuid.Nil, errors.New(name + " is required")
+	}
+	id, err := uuid.FromString(value)
+	if err != nil {
+		return uuid.Nil, errors.New(name + " is not a valid uuid")
+	}
+	return id, nil
+}
+
+func printNotes(out io.Writer, notes []*notesdb.Note) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSENDER\tRECIPIENTS\tSENT\tLAT,LON\tREAD\tDELETED\tTEXT")
+	for _, note := range notes {
+		if note == nil {
+			continue
+		}
+		var recipients []string
+		for _, recipient := range note.RecipientIds() {
+			recipients = append(recipients, recipient.String())
+		}
+		if note.Shared() {
+			recipients = []string{"(" + note.Visibility() + ")"}
+		}
+		fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v,%v\t%v\t%v\t%q\n",
+			note.Id(),
+			note.Sender(),
+			strings.Join(recipients, ","),
+			note.TimeSent().Format(time.RFC3339),
+			note.Latitude(),
+			note.Longitude(),
+			note.Read(),
+			note.Deleted(),
+			note.Text(),
+		)
+	}
+	w.Flush()
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"testing"
+	"bytes"
+	"strings"
+	"time"
+	"os"
+	"io/ioutil"
+	"path/filepath"
+	"archive/zip"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/solrnotes"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+func TestUserCommands(t *testing.T) {
+	e, out := getTestEnv("password\n")
+	users := e.users.(*userdb.MemoryUserdb)
+
+	if err := userCreate(e, []string{"myusername"}); err != nil {
+		t.Fatal("user-create failed. Err: ", err)
+	}
+	if _, valid, _ := users.CheckCredentials("myusername", "password"); !valid {
+		t.Fatal("User was not registered with the password from stdin.")
+	}
+	user, _ := users.GetUserByName("myusername")
+	note := notesdb.NewNote(user.Id, user.Id, "hi", 1, 1, e.now())
+	e.notes.InsertNote(note)
+
+	if err := userDelete(e, []string{"myusername"}); err != nil {
+		t.Fatal("user-delete failed. Err: ", err)
+	}
+	if _, valid, _ := users.CheckCredentials("myusername", "password"); valid {
+		t.Fatal("User was not deleted.")
+	}
+	if notes, _ := e.notes.GetNotesByIds([]uuid.UUID{note.Id()}); notes[0] != nil {
+		t.Fatal("User's notes were not purged.")
+	}
+	if err := userDelete(e, []string{"myusername"}); err == nil {
+		t.Fatal("user-delete should fail for an unknown user.")
+	}
+
+	words := strings.Join(strings.Fields(out.String()), " ")
+	if !strings.Contains(words, "Deleted myusername") || !strings.Contains(words, "sent notes purged 1") {
+		t.Fatal("Unexpected output: ", out.String())
+	}
+}
+
+func TestUserPassword(t *testing.T) {
+	e, _ := getTestEnv("newpassword\n")
+	users := e.users.(*userdb.MemoryUserdb)
+	if _, err := users.RegisterUser("myusername", "password"); err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+
+	if err := userPassword(e, []string{"myusername"}); err != nil {
+		t.Fatal("user-password failed. Err: ", err)
+	}
+	if _, valid, _ := users.CheckCredentials("myusername", "newpassword"); !valid {
+		t.Fatal("Password was not set from stdin.")
+	}
+
+	e, _ = getTestEnv("newpassword\n")
+	if err := userPassword(e, []string{"nosuchuser"}); err == nil {
+		t.Fatal("user-password should fail for an unknown user.")
+	}
+}
+
+func TestUserExport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "export.zip")
+
+	e, out := getTestEnv("")
+	id, err := e.users.RegisterUser("myusername", "password")
+	if err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+	e.notes.InsertNote(notesdb.NewNote(id, uuid.NewV4(), "hi", 1, 1, e.now()))
+
+	if err = userExport(e, []string{"myusername"}); err == nil {
+		t.Fatal("user-export should fail without -o.")
+	}
+	if err = userExport(e, []string{"-o", output, "myusername"}); err != nil {
+		t.Fatal("user-export failed. Err: ", err)
+	}
+	if archive, err := zip.OpenReader(output); err != nil {
+		t.Fatal("user-export didn't write a zip archive. Err: ", err)
+	} else {
+		archive.Close()
+	}
+	if !strings.Contains(out.String(), "Exported 1 sent and 0 received notes of myusername") {
+		t.Fatal("Unexpected output: ", out.String())
+	}
+
+	if err = userExport(e, []string{"-o", output, "nosuchuser"}); err == nil {
+		t.Fatal("user-export should fail for an unknown user.")
+	}
+}
+
+func TestTotpReset(t *testing.T) {
+	e, _ := getTestEnv("")
+	id, err := e.users.RegisterUser("myusername", "password")
+	if err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+
+	setup, err := e.twoFactor.Enroll(id)
+	if err != nil {
+		t.Fatal("Failed to enroll. Err: ", err)
+	}
+	code, _ := userdb.TotpCode(setup.Secret, time.Now())
+	if _, err = e.twoFactor.Confirm(id, code); err != nil {
+		t.Fatal("Failed to confirm. Err: ", err)
+	}
+
+	if err = totpReset(e, []string{"myusername"}); err != nil {
+		t.Fatal("totp-reset failed. Err: ", err)
+	}
+	if enrolled, _ := e.twoFactor.Enrolled(id); enrolled {
+		t.Fatal("Two-factor is still enabled.")
+	}
+}
+
+func TestLogins(t *testing.T) {
+	e, out := getTestEnv("password\n")
+	if err := userCreate(e, []string{"myusername"}); err != nil {
+		t.Fatal("user-create failed. Err: ", err)
+	}
+
+	guard := lockout.NewGuard(e.twoFactor, e.events, lockout.DEFAULT_POLICY)
+	guard.CheckLogin("myusername", "wrong", "", "10.0.0.1")
+	guard.CheckLogin("myusername", "password", "", "10.0.0.2")
+
+	if err := logins(e, []string{"myusername"}); err != nil {
+		t.Fatal("logins failed. Err: ", err)
+	}
+	if !strings.Contains(out.String(), "failure  10.0.0.1") ||
+		!strings.Contains(out.String(), "success  10.0.0.2") {
+		t.Fatal("Unexpected output: ", out.String())
+	}
+}
+
+func TestSendListAndNearby(t *testing.T) {
+	e, out := getTestEnv("")
+	sender := uuid.NewV4().String()
+	recipient := uuid.NewV4().String()
+
+	err := send(e, []string{"-sender", sender, "-recipient", recipient,
+		"-lat", "40.810260", "-lon", "-73.94694", "Look", "up!"})
+	if err != nil {
+		t.Fatal("send failed. Err: ", err)
+	}
+	err = send(e, []string{"-sender", sender, "-recipient", recipient,
+		"-lat", "40.758320", "-lon", "-73.988327", "Too far"})
+	if err != nil {
+		t.Fatal("send failed. Err: ", err)
+	}
+
+	out.Reset()
+	if err = list(e, []string{"-recipient", recipient}); err != nil {
+		t.Fatal("list failed. Err: ", err)
+	}
+	if !strings.Contains(out.String(), `"Look up!"`) || !strings.Contains(out.String(), `"Too far"`) {
+		t.Fatal("list did not show both notes: ", out.String())
+	}
+
+	out.Reset()
+	err = nearby(e, []string{"-recipient", recipient, "-lat", "40.809322", "-lon", "-73.944587",
+		"-radius", "0.5"})
+	if err != nil {
+		t.Fatal("nearby failed. Err: ", err)
+	}
+	if !strings.Contains(out.String(), `"Look up!"`) || strings.Contains(out.String(), `"Too far"`) {
+		t.Fatal("nearby did not show only the nearby note: ", out.String())
+	}
+
+	other := uuid.NewV4().String()
+	err = send(e, []string{"-sender", sender, "-recipient", recipient + "," + other,
+		"-lat", "40.810260", "-lon", "-73.94694", "For you both"})
+	if err != nil {
+		t.Fatal("send failed. Err: ", err)
+	}
+	out.Reset()
+	if err = list(e, []string{"-recipient", other}); err != nil {
+		t.Fatal("list failed. Err: ", err)
+	}
+	if !strings.Contains(out.String(), recipient + "," + other) ||
+		!strings.Contains(out.String(), `"For you both"`) || strings.Contains(out.String(), `"Look up!"`) {
+		t.Fatal("list did not show only the note to both recipients: ", out.String())
+	}
+
+	err = send(e, []string{"-sender", other, "-visibility", notesdb.VISIBILITY_PUBLIC,
+		"-lat", "40.810260", "-lon", "-73.94694", "For anyone"})
+	if err != nil {
+		t.Fatal("send failed. Err: ", err)
+	}
+	out.Reset()
+	err = nearby(e, []string{"-recipient", uuid.NewV4().String(), "-lat", "40.809322", "-lon", "-73.944587",
+		"-radius", "0.5"})
+	if err != nil {
+		t.Fatal("nearby failed. Err: ", err)
+	}
+	if !strings.Contains(out.String(), "(public)") || !strings.Contains(out.String(), `"For anyone"`) ||
+		strings.Contains(out.String(), `"Look up!"`) {
+		t.Fatal("nearby did not show only the public note to a stranger: ", out.String())
+	}
+
+	err = send(e, []string{"-sender", sender, "-recipient", recipient, "-visibility", notesdb.VISIBILITY_PUBLIC,
+		"Both"})
+	if err == nil {
+		t.Fatal("send with both -recipient and a shared visibility should fail.")
+	}
+
+	senderId, recipientId := uuid.FromStringOrNil(sender), uuid.FromStringOrNil(recipient)
+	root := notesdb.NewNote(senderId, recipientId, "Meet here?", 1, 1, e.now())
+	reply := notesdb.NewReply(root, recipientId, []uuid.UUID{senderId}, "On my way", 0, 0, false, e.now())
+	for _, note := range []*notesdb.Note{root, reply} {
+		if err = e.notes.InsertNote(note); err != nil {
+			t.Fatal("Failed to insert note. Err: ", err)
+		}
+	}
+	out.Reset()
+	if err = list(e, []string{"-thread", reply.Id().String()}); err != nil {
+		t.Fatal("list failed. Err: ", err)
+	}
+	first := strings.Index(out.String(), `"Meet here?"`)
+	if first < 0 || first > strings.Index(out.String(), `"On my way"`) || strings.Contains(out.String(), `"Look up!"`) {
+		t.Fatal("list did not show only the thread, in order: ", out.String())
+	}
+
+	if err = list(e, []string{}); err == nil {
+		t.Fatal("list without -sender, -recipient or -thread should fail.")
+	}
+	if err = list(e, []string{"-sender", sender, "-thread", reply.Id().String()}); err == nil {
+		t.Fatal("list with both -sender and -thread should fail.")
+	}
+}
+
+func TestPurgeAndCheck(t *testing.T) {
+	e, out := getTestEnv("")
+
+	note := notesdb.NewNote(uuid.NewV4(), uuid.NewV4(), "This is a test note", 42.2, 24.4,
+		time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC))
+	e.notes.InsertNote(note)
+
+	if err := check(e, []string{}); err == nil {
+		t.Fatal("check should fail when a note is missing from Solr.")
+	}
+	if !strings.Contains(out.String(), "missing\t" + note.Id().String()) {
+		t.Fatal("check did not report the missing note: ", out.String())
+	}
+
+	if err := check(e, []string{"-repair"}); err != nil {
+		t.Fatal("check -repair failed. Err: ", err)
+	}
+	if _, err := e.index.GetDoc(note.Id()); err != nil {
+		t.Fatal("check -repair did not index the note.")
+	}
+
+	if err := purge(e, []string{note.Id().String()}); err != nil {
+		t.Fatal("purge failed. Err: ", err)
+	}
+	if _, err := e.index.GetDoc(note.Id()); err == nil {
+		t.Fatal("purge left the doc in Solr.")
+	}
+	if err := check(e, []string{}); err != nil {
+		t.Fatal("Stores inconsistent after purge. Err: ", err)
+	}
+}
+
+func TestReindexCommand(t *testing.T) {
+	e, _ := getTestEnv("")
+	target := solrnotes.NewMemorySolr()
+	e.openCore = func(core string) (solrnotes.SolrConnection, error) {
+		return target, nil
+	}
+
+	note := notesdb.NewNote(uuid.NewV4(), uuid.NewV4(), "This is a test note", 42.2, 24.4,
+		time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC))
+	e.notes.InsertNote(note)
+
+	if err := runReindex(e, []string{"-alias", "geonotes", "geonotes_v2"}); err != nil {
+		t.Fatal("reindex failed. Err: ", err)
+	}
+	if _, err := target.GetDoc(note.Id()); err != nil {
+		t.Fatal("reindex did not index the note into the target core.")
+	}
+	if target.Alias() != "geonotes" {
+		t.Fatal("reindex did not point the alias.")
+	}
+}
+
+func getTestEnv(stdin string) (*env, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	users := userdb.NewMemoryUserdb()
+	manager, err := sessions.NewManager(
+		sessions.NewMemorySessions(), []byte(strings.Repeat("k", sessions.MIN_KEY_LEN)), sessions.Options{})
+	if err != nil {
+		panic(err)
+	}
+	return &env{
+		users: users,
+		twoFactor: userdb.NewTwoFactor(users, users),
+		notes: notesdb.NewMemoryNotesdb(),
+		index: solrnotes.NewMemorySolr(),
+		events: lockout.NewMemoryLoginEvents(),
+		sessions: manager,
+		contacts: contacts.NewContacts(contacts.NewMemoryContacts()),
+		openCore: func(core string) (solrnotes.SolrConnection, error) {
+			return solrnotes.NewMemorySolr(), nil
+		},
+		in: strings.NewReader(stdin),
+		out: out,
+		now: func() time.Time {
+			return time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+		},
+	}, out
+}
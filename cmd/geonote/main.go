@@ -0,0 +1,61 @@
+// Command geonote is an operator tool for inspecting and repairing GeoNote
+// data. It talks to MySQL and Solr directly using the same YAML config as
+// geonoted.
+//
+//	geonote [-config geonote.yaml] <command> [flags] [args]
+//
+// Run geonote with no command for the list of commands, or
+// geonote <command> -h for a command's flags.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/dbenny42/geonote/config"
+)
+
+func main() {
+	configFile := flag.String("config", "geonote.yaml", "path to the YAML config file")
+	flag.Usage = func() { usage(os.Stderr) }
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	cmd := findCommand(flag.Arg(0))
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "geonote: unknown command %q\n\n", flag.Arg(0))
+		usage(os.Stderr)
+		os.Exit(2)
+	}
+
+	conf, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal("Failed to load config. Err: ", err)
+	}
+
+	e, err := newEnv(conf, os.Stdin, os.Stdout)
+	if err != nil {
+		log.Fatal("Failed to connect. Err: ", err)
+	}
+
+	if err = cmd.run(e, flag.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "geonote %v: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: geonote [-config file] <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14v %v\n", cmd.name, cmd.summary)
+	}
+}
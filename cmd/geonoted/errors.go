@@ -0,0 +1,164 @@
+package main
+
+import (
+	"log"
+	"math"
+	"strconv"
+	"time"
+	"net/http"
+
+	"github.com/dbenny42/geonote/attachments"
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+// apiError is an error the client caused or can act on. Its message is
+// returned in the response body; any other error is logged and reported
+// only as an internal error so store details don't leak to clients.
+type apiError struct {
+	status int
+	message string
+
+	// code, if set, tells clients apart errors that share a status.
+	code string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func badRequest(message string) error {
+	return &apiError{status: http.StatusBadRequest, message: message}
+}
+
+func unauthorized(message string) error {
+	return &apiError{status: http.StatusUnauthorized, message: message}
+}
+
+// secondFactorRequired is a 401 that clients can tell from a wrong
+// password by its code, and answer by asking the user for a code.
+func secondFactorRequired(message string) error {
+	return &apiError{status: http.StatusUnauthorized, message: message, code: "second_factor_required"}
+}
+
+func forbidden(message string) error {
+	return &apiError{status: http.StatusForbidden, message: message}
+}
+
+// tooManyRequests sets Retry-After, in whole seconds, to when the lockout
+// ends.
+func tooManyRequests(w http.ResponseWriter, err *lockout.LockedOutError, now time.Time) error {
+	wait := math.Ceil(err.Until.Sub(now).Seconds())
+	if wait < 1 {
+		wait = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait)))
+	return &apiError{status: http.StatusTooManyRequests, message: err.Error()}
+}
+
+func conflict(message string) error {
+	return &apiError{status: http.StatusConflict, message: message}
+}
+
+func requestTooLarge(message string) error {
+	return &apiError{status: http.StatusRequestEntityTooLarge, message: message}
+}
+
+func unsupportedMediaType(message string) error {
+	return &apiError{status: http.StatusUnsupportedMediaType, message: message}
+}
+
+func notFound(message string) error {
+	return &apiError{status: http.StatusNotFound, message: message}
+}
+
+func methodNotAllowed() error {
+	return &apiError{status: http.StatusMethodNotAllowed, message: "Method not allowed."}
+}
+
+// sessionError reports a bad refresh token as unauthorized, and passes
+// anything else, i.e. a store failure, through.
+func sessionError(err error) error {
+	switch err {
+	case sessions.ErrInvalidToken, sessions.ErrExpiredToken, sessions.ErrRevokedToken:
+		return unauthorized(err.Error())
+	}
+	return err
+}
+
+// validationError reports credentials the policy refused as a bad
+// request, a taken username as a conflict, and passes anything else
+// through.
+func validationError(err error) error {
+	if invalid, ok := err.(*userdb.ValidationError); ok {
+		return badRequest(invalid.Error())
+	}
+	if err == userdb.ErrUsernameTaken {
+		return conflict(err.Error())
+	}
+	return err
+}
+
+// totpError reports two-factor errors the caller can fix, and passes
+// anything else through.
+func totpError(err error) error {
+	switch err {
+	case userdb.ErrInvalidTotpCode:
+		return forbidden(err.Error())
+	case userdb.ErrTotpAlreadyEnrolled, userdb.ErrTotpNotEnrolled:
+		return conflict(err.Error())
+	}
+	return err
+}
+
+// contactsError reports contact requests that don't make sense as a bad
+// request, a conflict or not found, and passes anything else through.
+func contactsError(err error) error {
+	switch err {
+	case contacts.ErrSelf, contacts.ErrGroupName, contacts.ErrTooManyMembers:
+		return badRequest(err.Error())
+	case contacts.ErrAlreadyContacts, contacts.ErrBlocked, contacts.ErrTooManyMuted,
+		contacts.ErrGroupNameTaken, contacts.ErrTooManyGroups:
+		return conflict(err.Error())
+	case contacts.ErrNoRequest, contacts.ErrNotContacts, contacts.ErrNotBlocked, contacts.ErrNotMuted,
+		contacts.ErrNoGroup:
+		return notFound(err.Error())
+	}
+	return err
+}
+
+// attachmentsError reports an attachment that was refused as 415, 400,
+// 413 or a conflict, a missing one as not found, and passes anything
+// else through.
+func attachmentsError(err error) error {
+	switch err {
+	case attachments.ErrContentType:
+		return unsupportedMediaType(err.Error())
+	case attachments.ErrContentMismatch, attachments.ErrBadImage:
+		return badRequest(err.Error())
+	case attachments.ErrTooLarge:
+		return requestTooLarge(err.Error())
+	case attachments.ErrTooManyAttachments:
+		return conflict(err.Error())
+	case attachments.ErrNoAttachment:
+		return notFound(err.Error())
+	}
+	return err
+}
+
+type errorJson struct {
+	Error string `json:"error"`
+	Code string `json:"code,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	if apiErr, ok := err.(*apiError); ok {
+		writeJson(w, apiErr.status, errorJson{Error: apiErr.message, Code: apiErr.code})
+		return
+	}
+
+	log.Printf("Internal error handling %v %v. Err: %v", r.Method, r.URL.Path, err)
+	writeJson(w, http.StatusInternalServerError, errorJson{Error: "Internal server error."})
+}
@@ -0,0 +1,217 @@
+// Command geonoted serves the GeoNote HTTP/JSON API that the phone apps
+// talk to. Connection settings come from a YAML file; see config.Config.
+//
+//	geonoted -config /etc/geonote/geonote.yaml
+//
+// Endpoints:
+//
+//	POST   /users                register {"username", "password"}, returns {"id"}; 409 if taken
+//	GET    /users/available      ?username=, returns {"available", "reason"}
+//	POST   /login                check {"username", "password", "code"}, returns tokens
+//	POST   /sessions/refresh     {"refreshToken"}, returns new tokens
+//	POST   /logout               {"refreshToken"}, ends that session
+//	POST   /logout/all           * ends every session of the caller
+//	POST   /password             * {"oldPassword", "newPassword"}, returns new tokens
+//	POST   /password/reset-request {"username"}, sends a reset token
+//	POST   /password/reset       {"token", "newPassword"}
+//	POST   /totp/enroll          * returns {"secret", "uri"} for an authenticator app
+//	POST   /totp/confirm         * {"code"}, enables two-factor, returns {"recoveryCodes"}
+//	POST   /totp/disable         * {"code"}, a TOTP or recovery code
+//	GET    /profile              * the caller's profile
+//	PUT    /profile              * {"displayName", "avatarRef", "bio", "timeZone", "unlockRadiusKm", "hideReadReceipts"}
+//	GET    /profiles             * ?id=&id=, returns other users' public profiles
+//	GET    /export               * everything held about the caller, as a zip archive
+//	GET    /contacts             * ?list=accepted|incoming|outgoing|blocked|muted&count=&offset=
+//	POST   /contacts/{id}/request * ask to become contacts
+//	POST   /contacts/{id}/accept  * accept their request
+//	POST   /contacts/{id}/decline * decline their request
+//	POST   /contacts/{id}/block   * block them, ending any contact
+//	POST   /contacts/{id}/unblock *
+//	POST   /contacts/{id}/mute    * hide their notes without blocking them
+//	POST   /contacts/{id}/unmute  *
+//	DELETE /contacts/{id}        * remove a contact or withdraw a request
+//	GET    /groups               * the caller's groups, returns {"groups"}
+//	POST   /groups               * create {"name", "members"}
+//	GET    /groups/{id}          *
+//	PUT    /groups/{id}          * replace {"name", "members"}
+//	DELETE /groups/{id}          *
+//	POST   /notes                * send {"recipient", "recipients", "group", "visibility", "parent", "direct", "text", "latitude", "longitude"}
+//	GET    /notes/inbox          * ?count=&offset=
+//	GET    /notes/outbox         * ?count=&offset=
+//	GET    /notes/nearby         * ?latitude=&longitude=&radiusKm=&count=
+//	GET    /notes/summary        * ?latitude=&longitude=&radiusKm=, returns {"unread", "total", "senders", "unreadNearby"}
+//	POST   /notes/{id}/read      * mark read, by a recipient or a reader of a shared note; optional {"latitude", "longitude"}
+//	GET    /notes/{id}/receipts  * the sender's read receipts, returns {"receipts"} of {"reader", "readAt", "latitude", "longitude"}
+//	GET    /notes/{id}/thread    * ?count=&offset=, the conversation the note belongs to, oldest first
+//	PUT    /notes/{id}           * the sender edits {"text"} until anyone reads it
+//	GET    /notes/{id}/versions  * the sender's earlier texts, returns {"versions"} of {"text", "writtenAt", "replacedAt"}
+//	DELETE /notes/{id}           * mark deleted, for everyone by the sender or for themselves by a recipient
+//	POST   /notes/{id}/attachments * the sender adds the body as an attachment, typed by its Content-Type header
+//	GET    /notes/{id}/attachments * returns {"attachments"} of {"id", "note", "contentType", "size", "thumbnail", "createdAt"}
+//	GET    /attachments/{id}     * the attachment's content
+//	GET    /attachments/{id}/thumbnail * a PNG thumbnail of an image attachment
+//
+// "code" at login is only needed by users with two-factor enabled; they
+// get a 401 with {"code": "second_factor_required"} without it.
+//
+// /notes/nearby uses the caller's unlockRadiusKm when radiusKm isn't given,
+// and a server default if that isn't set either.
+//
+// /notes/summary counts the caller's inbox without fetching it, for badges:
+// how many undeleted notes they have and how many are unread, in all and
+// as "senders" of {"sender", "unread", "total"}, most unread first. Given
+// a latitude and longitude, "unreadNearby" is how many of the notes
+// /notes/nearby would return there the caller hasn't read, other than
+// their own shared notes.
+//
+// A note can be left for several users at once: everyone named in
+// "recipient" and "recipients", and the members of the caller's group
+// "group". Each recipient reads and deletes it independently, and sees
+// their own "read" and "deleted"; the sender's "read" means every
+// recipient has read it. Groups are named lists of the caller's contacts,
+// expanded when a note is sent.
+//
+// "visibility" is "private" by default. A "contacts" note can be found
+// nearby by all of the sender's accepted contacts, and a "public" one by
+// anyone; neither has recipients, and each reader tracks their own
+// "read". Notes from blocked users are left out of nearby results.
+//
+// A note with "parent" is a reply. It goes to the parent's sender and
+// recipients other than the caller, who must be able to see the parent,
+// and can't name recipients or be shared. A reply is anchored at the
+// parent's spot unless "direct" is set, in which case it's left at
+// "latitude" and "longitude" but meant to be read wherever its
+// recipients are. Every note has a "thread", the id of the note that
+// started its conversation.
+//
+// Marking a note read leaves the sender a read receipt with the time and,
+// if given, where the reader was, unless the reader's profile has
+// "hideReadReceipts" set. Readers who hide their receipts don't trigger
+// unlock events either, though the note still shows as read.
+//
+// A note's sender can change its text until any recipient, or anyone at
+// all for a shared note, reads it; after that it's refused with 409. An
+// edited note has "editedAt", and its earlier texts are kept as versions
+// that only the sender can see.
+//
+// Attachments are JPEG, PNG or GIF images, or MP3, MP4, Ogg or WAV
+// audio, of up to 8 MiB, and a note can have up to 4. Anyone who can see
+// a note can fetch its attachments; to anyone else they don't exist.
+// They're deleted along with their note when it's purged.
+//
+// Notes can only be sent to accepted contacts, or to yourself; anything
+// else is refused with 403. A user can't tell whether someone has blocked
+// them: requests to that person are accepted but never shown to them.
+// Notes from muted users are still delivered, but left out of the inbox
+// and nearby results.
+//
+// Endpoints marked * need an "Authorization: Bearer <accessToken>" header,
+// and act as the user the token was issued to. Changing or resetting a
+// password ends all of the user's sessions. Tokens are returned as
+// {"id", "accessToken", "accessExpiresAt", "refreshToken",
+// "refreshExpiresAt"}. The sender and recipient fields and parameters
+// above may still be given, but must then be the caller's own id.
+//
+// Errors are returned as {"error": "..."} with a 4xx status for bad
+// requests and 500 for anything else. Repeated failed logins for a user,
+// or from an address, are answered with 429 and a Retry-After header
+// until the lockout passes.
+//
+// If grpcListen is set in the config, the same operations are also served
+// over gRPC there; see geonotepb/geonote.proto.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+
+	"github.com/dbenny42/geonote/attachments"
+	"github.com/dbenny42/geonote/config"
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/erasure"
+	"github.com/dbenny42/geonote/grpcserver"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/unlocks"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+func main() {
+	configFile := flag.String("config", "geonote.yaml", "path to the YAML config file")
+	flag.Parse()
+
+	conf, err := config.Load(*configFile)
+	if err != nil {
+		log.Fatal("Failed to load config. Err: ", err)
+	}
+
+	users, err := conf.OpenUserdb()
+	if err != nil {
+		log.Fatal("Failed to open userdb. Err: ", err)
+	}
+
+	noteStore, err := conf.OpenNotesdb()
+	if err != nil {
+		log.Fatal("Failed to open notesdb. Err: ", err)
+	}
+
+	files, err := conf.OpenAttachments()
+	if err != nil {
+		log.Fatal("Failed to open attachments. Err: ", err)
+	}
+	// Everything that purges notes goes through this, so that their
+	// attachments are purged too.
+	notes := attachments.NewNotesdb(noteStore, files)
+
+	index, err := conf.OpenSolr()
+	if err != nil {
+		log.Fatal("Failed to connect to solr. Err: ", err)
+	}
+
+	sessions, err := conf.OpenSessions()
+	if err != nil {
+		log.Fatal("Failed to set up sessions. Err: ", err)
+	}
+
+	events, err := conf.OpenLoginEvents()
+	if err != nil {
+		log.Fatal("Failed to open login events. Err: ", err)
+	}
+	twoFactor := userdb.NewTwoFactor(users, users)
+	guard := lockout.NewGuard(twoFactor, events, lockout.DEFAULT_POLICY)
+	resetter := conf.NewResetter(users)
+
+	contactStore, err := conf.OpenContacts()
+	if err != nil {
+		log.Fatal("Failed to open contacts. Err: ", err)
+	}
+	contactGraph := contacts.NewContacts(contactStore)
+	eraser := erasure.NewEraser(users, notes, index, sessions, contactGraph, events, erasure.Options{})
+
+	hub := unlocks.NewHub()
+
+	if conf.GrpcListen != "" {
+		listener, err := net.Listen("tcp", conf.GrpcListen)
+		if err != nil {
+			log.Fatal("Failed to listen for grpc. Err: ", err)
+		}
+
+		// AddAttachment carries a whole attachment, which can be larger
+		// than grpc's default limit.
+		grpcServer := grpc.NewServer(grpc.MaxRecvMsgSize(attachments.MAX_ATTACHMENT_BYTES + 64 * 1024))
+		grpcserver.NewServer(
+			users, notes, index, sessions, guard, resetter, twoFactor, contactGraph, files, eraser, hub,
+		).Register(grpcServer)
+		go func() {
+			log.Printf("Serving grpc on %v", conf.GrpcListen)
+			log.Fatal(grpcServer.Serve(listener))
+		}()
+	}
+
+	s := newServer(users, notes, index, sessions, guard, resetter, twoFactor, contactGraph, files, hub)
+	log.Printf("Listening on %v", conf.Listen)
+	log.Fatal(http.ListenAndServe(conf.Listen, s.routes()))
+}
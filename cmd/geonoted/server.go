@@ -0,0 +1,2048 @@
+package main
+
+import (
+	"time"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"strconv"
+	"net/http"
+	"encoding/json"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/attachments"
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/export"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/solrnotes"
+	"github.com/dbenny42/geonote/unlocks"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+const (
+	MAX_BODY_BYTES = 64 * 1024
+	MAX_NOTE_LEN = 2000
+	// MAX_RECIPIENTS is how many users one note can be left for.
+	MAX_RECIPIENTS = contacts.MAX_GROUP_MEMBERS
+	DEFAULT_PAGE_SIZE = 20
+	MAX_PAGE_SIZE = 100
+	DEFAULT_RADIUS_KM = 0.1
+	MAX_RADIUS_KM = 50
+)
+
+type server struct {
+	users userdb.UserdbConnection
+	notes notesdb.NotesdbConnection
+	index solrnotes.SolrConnection
+	sessions *sessions.Manager
+	guard *lockout.Guard
+	resetter *userdb.Resetter
+	twoFactor *userdb.TwoFactor
+	contacts *contacts.Contacts
+	attachments *attachments.Attachments
+	exporter *export.Exporter
+	hub *unlocks.Hub
+	now func() time.Time
+}
+
+func newServer(
+	users userdb.UserdbConnection,
+	notes notesdb.NotesdbConnection,
+	index solrnotes.SolrConnection,
+	sessions *sessions.Manager,
+	guard *lockout.Guard,
+	resetter *userdb.Resetter,
+	twoFactor *userdb.TwoFactor,
+	contacts *contacts.Contacts,
+	attachments *attachments.Attachments,
+	hub *unlocks.Hub) *server {
+	return &server{
+		users: users,
+		notes: notes,
+		index: index,
+		sessions: sessions,
+		guard: guard,
+		resetter: resetter,
+		twoFactor: twoFactor,
+		contacts: contacts,
+		attachments: attachments,
+		exporter: export.NewExporter(users, notes, export.Options{}),
+		hub: hub,
+		now: time.Now,
+	}
+}
+
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// userHandlerFunc is a handler for an endpoint that needs a signed-in
+// user; caller is the id from the request's access token.
+type userHandlerFunc func(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/users", s.handle(http.MethodPost, s.register))
+	mux.Handle("/users/available", s.handle(http.MethodGet, s.usernameAvailable))
+	mux.Handle("/login", s.handle(http.MethodPost, s.login))
+	mux.Handle("/sessions/refresh", s.handle(http.MethodPost, s.refresh))
+	mux.Handle("/logout", s.handle(http.MethodPost, s.logout))
+	mux.Handle("/logout/all", s.handle(http.MethodPost, s.authenticated(s.logoutAll)))
+	mux.Handle("/password", s.handle(http.MethodPost, s.authenticated(s.changePassword)))
+	mux.Handle("/password/reset-request", s.handle(http.MethodPost, s.requestPasswordReset))
+	mux.Handle("/password/reset", s.handle(http.MethodPost, s.resetPassword))
+	mux.Handle("/totp/enroll", s.handle(http.MethodPost, s.authenticated(s.enrollTotp)))
+	mux.Handle("/totp/confirm", s.handle(http.MethodPost, s.authenticated(s.confirmTotp)))
+	mux.Handle("/totp/disable", s.handle(http.MethodPost, s.authenticated(s.disableTotp)))
+	mux.Handle("/profile", s.handle("", s.authenticated(s.profile)))
+	mux.Handle("/profiles", s.handle(http.MethodGet, s.authenticated(s.profiles)))
+	mux.Handle("/export", s.handle(http.MethodGet, s.authenticated(s.export)))
+	mux.Handle("/contacts", s.handle(http.MethodGet, s.authenticated(s.listContacts)))
+	mux.Handle("/contacts/", s.handle("", s.authenticated(s.contactById)))
+	mux.Handle("/groups", s.handle("", s.authenticated(s.groups)))
+	mux.Handle("/groups/", s.handle("", s.authenticated(s.groupById)))
+	mux.Handle("/notes", s.handle(http.MethodPost, s.authenticated(s.sendNote)))
+	mux.Handle("/notes/inbox", s.handle(http.MethodGet, s.authenticated(s.inbox)))
+	mux.Handle("/notes/outbox", s.handle(http.MethodGet, s.authenticated(s.outbox)))
+	mux.Handle("/notes/nearby", s.handle(http.MethodGet, s.authenticated(s.nearby)))
+	mux.Handle("/notes/summary", s.handle(http.MethodGet, s.authenticated(s.summary)))
+	mux.Handle("/notes/", s.handle("", s.authenticated(s.noteById)))
+	mux.Handle("/attachments/", s.handle(http.MethodGet, s.authenticated(s.attachmentById)))
+	return mux
+}
+
+// handle wraps a handler so that it only answers the given method (any
+// method if empty) and has its returned error turned into a response.
+func (s *server) handle(method string, handler handlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if method != "" && r.Method != method {
+			writeError(w, r, methodNotAllowed())
+			return
+		}
+		if err := handler(w, r); err != nil {
+			writeError(w, r, err)
+		}
+	})
+}
+
+// authenticated wraps a handler so that it's only called with a valid
+// "Authorization: Bearer <access token>" header.
+func (s *server) authenticated(handler userHandlerFunc) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		header := r.Header.Get("Authorization")
+		if !strings.HasPrefix(header, "Bearer ") {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			return unauthorized("An access token is required.")
+		}
+
+		caller, err := s.sessions.Authenticate(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			return unauthorized(err.Error())
+		}
+
+		return handler(w, r, caller)
+	}
+}
+
+type credentialsJson struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// loginJson is credentialsJson plus a two-factor code, which only users
+// with two-factor enabled need to send.
+type loginJson struct {
+	credentialsJson
+	Code string `json:"code"`
+}
+
+type totpCodeJson struct {
+	Code string `json:"code"`
+}
+
+type totpSetupJson struct {
+	Secret string `json:"secret"`
+	Uri string `json:"uri"`
+}
+
+type recoveryCodesJson struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type userIdJson struct {
+	Id string `json:"id"`
+}
+
+type availableJson struct {
+	Available bool `json:"available"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// profileJson is a user's own profile. publicProfileJson is the part of
+// it other users see.
+type profileJson struct {
+	Id string `json:"id"`
+	Username string `json:"username"`
+	DisplayName string `json:"displayName"`
+	AvatarRef string `json:"avatarRef"`
+	Bio string `json:"bio"`
+	TimeZone string `json:"timeZone"`
+	UnlockRadiusKm float64 `json:"unlockRadiusKm"`
+	HideReadReceipts bool `json:"hideReadReceipts"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+type updateProfileJson struct {
+	DisplayName string `json:"displayName"`
+	AvatarRef string `json:"avatarRef"`
+	Bio string `json:"bio"`
+	TimeZone string `json:"timeZone"`
+	UnlockRadiusKm float64 `json:"unlockRadiusKm"`
+	HideReadReceipts bool `json:"hideReadReceipts"`
+}
+
+type publicProfileJson struct {
+	Id string `json:"id"`
+	Username string `json:"username"`
+	DisplayName string `json:"displayName"`
+	AvatarRef string `json:"avatarRef"`
+	Bio string `json:"bio"`
+}
+
+type profilesJson struct {
+	Profiles []publicProfileJson `json:"profiles"`
+}
+
+// contactJson is another user's standing with the caller. For incoming
+// requests, id is the requester.
+type contactJson struct {
+	Id string `json:"id"`
+	Status string `json:"status"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type contactsJson struct {
+	Contacts []contactJson `json:"contacts"`
+}
+
+type groupJson struct {
+	Id string `json:"id"`
+	Name string `json:"name"`
+	Members []string `json:"members"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type groupsJson struct {
+	Groups []groupJson `json:"groups"`
+}
+
+type updateGroupJson struct {
+	Name string `json:"name"`
+	Members []string `json:"members"`
+}
+
+type tokensJson struct {
+	Id string `json:"id"`
+	AccessToken string `json:"accessToken"`
+	AccessExpiresAt time.Time `json:"accessExpiresAt"`
+	RefreshToken string `json:"refreshToken"`
+	RefreshExpiresAt time.Time `json:"refreshExpiresAt"`
+}
+
+type refreshTokenJson struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+type changePasswordJson struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+type resetRequestJson struct {
+	Username string `json:"username"`
+}
+
+type resetPasswordJson struct {
+	Token string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+// noteJson is a note as the caller sees it. recipient is the first of
+// recipients, and empty for contacts and public notes, which have none;
+// read and deleted are the caller's own unless they sent the note.
+type noteJson struct {
+	Id string `json:"id"`
+	Sender string `json:"sender"`
+	Visibility string `json:"visibility"`
+	Recipient string `json:"recipient"`
+	Recipients []string `json:"recipients"`
+	Text string `json:"text"`
+	Latitude float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	TimeSent time.Time `json:"timeSent"`
+	Read bool `json:"read"`
+	Deleted bool `json:"deleted"`
+	Parent string `json:"parent,omitempty"`
+	Thread string `json:"thread"`
+	Direct bool `json:"direct,omitempty"`
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+}
+
+type editNoteJson struct {
+	Text string `json:"text"`
+}
+
+type versionJson struct {
+	Text string `json:"text"`
+	WrittenAt time.Time `json:"writtenAt"`
+	ReplacedAt time.Time `json:"replacedAt"`
+}
+
+type versionsJson struct {
+	Versions []versionJson `json:"versions"`
+}
+
+type sendNoteJson struct {
+	Sender string `json:"sender"`
+	Recipient string `json:"recipient"`
+	Recipients []string `json:"recipients"`
+	Group string `json:"group"`
+	Visibility string `json:"visibility"`
+	Parent string `json:"parent"`
+	Direct bool `json:"direct"`
+	Text string `json:"text"`
+	Latitude *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+type notesJson struct {
+	Notes []noteJson `json:"notes"`
+}
+
+type summaryJson struct {
+	Unread int `json:"unread"`
+	Total int `json:"total"`
+	Senders []senderCountJson `json:"senders"`
+	UnreadNearby *int `json:"unreadNearby,omitempty"`
+}
+
+type senderCountJson struct {
+	Sender string `json:"sender"`
+	Unread int `json:"unread"`
+	Total int `json:"total"`
+}
+
+type markReadJson struct {
+	Latitude *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+type receiptJson struct {
+	Reader string `json:"reader"`
+	ReadAt time.Time `json:"readAt"`
+	Latitude *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+type receiptsJson struct {
+	Receipts []receiptJson `json:"receipts"`
+}
+
+type attachmentJson struct {
+	Id string `json:"id"`
+	Note string `json:"note"`
+	ContentType string `json:"contentType"`
+	Size int64 `json:"size"`
+	Thumbnail bool `json:"thumbnail"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type attachmentsJson struct {
+	Attachments []attachmentJson `json:"attachments"`
+}
+
+func (s *server) register(w http.ResponseWriter, r *http.Request) error {
+	var request credentialsJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if err := validateCredentials(&request); err != nil {
+		return err
+	}
+
+	id, err := s.users.RegisterUser(request.Username, request.Password)
+	if err != nil {
+		return validationError(err)
+	}
+
+	writeJson(w, http.StatusCreated, userIdJson{Id: id.String()})
+	return nil
+}
+
+// usernameAvailable says whether ?username= could be registered, and if
+// the policy refuses it, why.
+func (s *server) usernameAvailable(w http.ResponseWriter, r *http.Request) error {
+	username := r.URL.Query().Get("username")
+	if username == "" {
+		return badRequest("username is required.")
+	}
+
+	available, err := s.users.IsUsernameAvailable(username)
+	if invalid, ok := err.(*userdb.ValidationError); ok {
+		writeJson(w, http.StatusOK, availableJson{Available: false, Reason: invalid.Error()})
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	writeJson(w, http.StatusOK, availableJson{Available: available})
+	return nil
+}
+
+func (s *server) login(w http.ResponseWriter, r *http.Request) error {
+	var request loginJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if err := validateCredentials(&request.credentialsJson); err != nil {
+		return err
+	}
+
+	id, validLogin, err := s.guard.CheckLogin(request.Username, request.Password, request.Code, clientAddress(r))
+	if err == userdb.ErrSecondFactorRequired {
+		return secondFactorRequired(err.Error())
+	}
+	if lockedOut, ok := err.(*lockout.LockedOutError); ok {
+		return tooManyRequests(w, lockedOut, s.now())
+	}
+	if err != nil {
+		return err
+	}
+	if !validLogin {
+		return unauthorized("Incorrect username or password.")
+	}
+
+	tokens, err := s.sessions.Issue(id)
+	if err != nil {
+		return err
+	}
+
+	writeJson(w, http.StatusOK, toTokensJson(tokens))
+	return nil
+}
+
+// refresh trades a refresh token for a new access and refresh token.
+func (s *server) refresh(w http.ResponseWriter, r *http.Request) error {
+	var request refreshTokenJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if request.RefreshToken == "" {
+		return badRequest("refreshToken is required.")
+	}
+
+	tokens, err := s.sessions.Refresh(request.RefreshToken)
+	if err != nil {
+		return sessionError(err)
+	}
+
+	writeJson(w, http.StatusOK, toTokensJson(tokens))
+	return nil
+}
+
+// logout ends the session the refresh token belongs to. The matching
+// access token keeps working until it expires.
+func (s *server) logout(w http.ResponseWriter, r *http.Request) error {
+	var request refreshTokenJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if request.RefreshToken == "" {
+		return badRequest("refreshToken is required.")
+	}
+
+	if err := s.sessions.Revoke(request.RefreshToken); err != nil {
+		return sessionError(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *server) logoutAll(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	if err := s.sessions.RevokeAll(caller); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// changePassword ends every session, since a password is usually changed
+// because someone else might know it, and returns a new one for the
+// caller. Wrong old passwords count towards the caller's login lockout,
+// so a stolen access token can't be used to guess it.
+func (s *server) changePassword(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	var request changePasswordJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if request.OldPassword == "" {
+		return badRequest("oldPassword is required.")
+	}
+	if request.NewPassword == "" {
+		return badRequest("newPassword is required.")
+	}
+
+	user, err := s.users.GetUserById(caller)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return unauthorized("No such user.")
+	}
+
+	_, changed, err := s.guard.Attempt(user.Name, clientAddress(r), func(username string) (uuid.UUID, bool, error) {
+		changed, err := s.users.ChangePassword(username, request.OldPassword, request.NewPassword)
+		return caller, changed, err
+	})
+	if lockedOut, ok := err.(*lockout.LockedOutError); ok {
+		return tooManyRequests(w, lockedOut, s.now())
+	}
+	if err != nil {
+		return validationError(err)
+	}
+	if !changed {
+		return forbidden("Incorrect password.")
+	}
+
+	if err = s.sessions.RevokeAll(caller); err != nil {
+		return err
+	}
+	tokens, err := s.sessions.Issue(caller)
+	if err != nil {
+		return err
+	}
+
+	writeJson(w, http.StatusOK, toTokensJson(tokens))
+	return nil
+}
+
+// requestPasswordReset answers 202 whether or not the user exists, so it
+// can't be used to find out which usernames are taken.
+func (s *server) requestPasswordReset(w http.ResponseWriter, r *http.Request) error {
+	var request resetRequestJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if request.Username == "" {
+		return badRequest("username is required.")
+	}
+
+	if err := s.resetter.RequestReset(request.Username); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	return nil
+}
+
+// resetPassword sets a new password with a reset token and ends all of
+// the user's sessions.
+func (s *server) resetPassword(w http.ResponseWriter, r *http.Request) error {
+	var request resetPasswordJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if request.Token == "" {
+		return badRequest("token is required.")
+	}
+	if request.NewPassword == "" {
+		return badRequest("newPassword is required.")
+	}
+
+	id, err := s.resetter.Reset(request.Token, request.NewPassword)
+	if err == userdb.ErrInvalidResetToken {
+		return badRequest(err.Error())
+	}
+	if err != nil {
+		return validationError(err)
+	}
+
+	if err = s.sessions.RevokeAll(id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// enrollTotp starts two-factor enrollment. It isn't enabled until the
+// caller confirms it with a code from their authenticator app.
+func (s *server) enrollTotp(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	setup, err := s.twoFactor.Enroll(caller)
+	if err != nil {
+		return totpError(err)
+	}
+
+	writeJson(w, http.StatusOK, totpSetupJson{Secret: setup.Secret, Uri: setup.Uri})
+	return nil
+}
+
+// confirmTotp enables two-factor and returns the caller's recovery codes,
+// which can't be fetched again.
+func (s *server) confirmTotp(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	var request totpCodeJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if request.Code == "" {
+		return badRequest("code is required.")
+	}
+
+	var recoveryCodes []string
+	err := s.attemptTotpCode(w, r, caller, func() error {
+		var err error
+		recoveryCodes, err = s.twoFactor.Confirm(caller, request.Code)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	writeJson(w, http.StatusOK, recoveryCodesJson{RecoveryCodes: recoveryCodes})
+	return nil
+}
+
+func (s *server) disableTotp(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	var request totpCodeJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if request.Code == "" {
+		return badRequest("code is required.")
+	}
+
+	err := s.attemptTotpCode(w, r, caller, func() error {
+		return s.twoFactor.Disable(caller, request.Code)
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// attemptTotpCode runs check, which confirms or disables two-factor with
+// a code, under the caller's login lockout, so codes can't be guessed off
+// a hijacked session any faster than at login.
+func (s *server) attemptTotpCode(w http.ResponseWriter, r *http.Request, caller uuid.UUID, check func() error) error {
+	user, err := s.users.GetUserById(caller)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return unauthorized("No such user.")
+	}
+
+	_, valid, err := s.guard.AttemptCode(user.Name, clientAddress(r), func(string) (uuid.UUID, bool, error) {
+		err := check()
+		if err == userdb.ErrInvalidTotpCode {
+			return uuid.Nil, false, nil
+		}
+		return caller, err == nil, err
+	})
+	if lockedOut, ok := err.(*lockout.LockedOutError); ok {
+		return tooManyRequests(w, lockedOut, s.now())
+	}
+	if err != nil {
+		return totpError(err)
+	}
+	if !valid {
+		return totpError(userdb.ErrInvalidTotpCode)
+	}
+	return nil
+}
+
+// profile serves GET and PUT /profile, the caller's own profile. PUT
+// replaces the whole profile, so fields left out are cleared.
+func (s *server) profile(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var request updateProfileJson
+		if err := readJson(w, r, &request); err != nil {
+			return err
+		}
+		err := s.users.UpdateProfile(&userdb.Profile{
+			UserId: caller,
+			DisplayName: request.DisplayName,
+			AvatarRef: request.AvatarRef,
+			Bio: request.Bio,
+			TimeZone: request.TimeZone,
+			UnlockRadiusKm: request.UnlockRadiusKm,
+			HideReadReceipts: request.HideReadReceipts,
+		})
+		if err == userdb.ErrNotFound {
+			return unauthorized("No such user.")
+		}
+		if err != nil {
+			return validationError(err)
+		}
+	default:
+		return methodNotAllowed()
+	}
+
+	profile, err := s.users.GetProfile(caller)
+	if err != nil {
+		return err
+	}
+	if profile == nil {
+		return unauthorized("No such user.")
+	}
+
+	writeJson(w, http.StatusOK, toProfileJson(profile))
+	return nil
+}
+
+// profiles looks up other users' public profiles by ?id=, which can be
+// repeated, e.g. to name the senders of a page of notes. Unknown ids are
+// left out.
+func (s *server) profiles(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	values := r.URL.Query()["id"]
+	if len(values) == 0 {
+		return badRequest("id is required.")
+	}
+	if len(values) > MAX_PAGE_SIZE {
+		return badRequest("At most " + strconv.Itoa(MAX_PAGE_SIZE) + " ids can be looked up at once.")
+	}
+
+	ids := make([]uuid.UUID, len(values))
+	for i, value := range values {
+		id, err := parseId("id", value)
+		if err != nil {
+			return err
+		}
+		ids[i] = id
+	}
+
+	profiles, err := s.users.GetProfiles(ids)
+	if err != nil {
+		return err
+	}
+
+	result := profilesJson{Profiles: []publicProfileJson{}}
+	for _, profile := range profiles {
+		result.Profiles = append(result.Profiles, toPublicProfileJson(profile))
+	}
+	writeJson(w, http.StatusOK, result)
+	return nil
+}
+
+// export sends the caller everything we hold about them as a zip
+// archive; see export.Exporter. The archive is streamed, so an error once
+// it has started can only be logged, and the client is left with a
+// truncated archive.
+func (s *server) export(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	archive := &archiveWriter{w: w}
+	_, err := s.exporter.Export(caller, archive)
+	if err == userdb.ErrNotFound {
+		return notFound(err.Error())
+	}
+	if err != nil && archive.started {
+		log.Printf("Failed to stream export to user %v. Err: %v", caller, err)
+		return nil
+	}
+	return err
+}
+
+// archiveWriter holds off sending export headers until there's archive to
+// send, so that errors before then can still be reported as usual.
+type archiveWriter struct {
+	w http.ResponseWriter
+	started bool
+}
+
+func (a *archiveWriter) Write(p []byte) (int, error) {
+	if !a.started {
+		a.w.Header().Set("Content-Type", "application/zip")
+		a.w.Header().Set("Content-Disposition", `attachment; filename="geonote-export.zip"`)
+		a.w.WriteHeader(http.StatusOK)
+		a.started = true
+	}
+	return a.w.Write(p)
+}
+
+// listContacts serves GET /contacts. ?list= picks what to list:
+// "accepted", the default, "incoming" or "outgoing" requests, or
+// "blocked" or "muted" users.
+func (s *server) listContacts(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	count, offset, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+
+	var found []*contacts.Contact
+	switch r.URL.Query().Get("list") {
+	case "", "accepted":
+		found, err = s.contacts.List(caller, contacts.STATUS_ACCEPTED, count, offset)
+	case "outgoing":
+		found, err = s.contacts.List(caller, contacts.STATUS_PENDING, count, offset)
+	case "blocked":
+		found, err = s.contacts.List(caller, contacts.STATUS_BLOCKED, count, offset)
+	case "incoming":
+		found, err = s.contacts.Incoming(caller, count, offset)
+	case "muted":
+		return s.listMuted(w, caller, count, offset)
+	default:
+		return badRequest("list must be one of accepted, incoming, outgoing, blocked or muted.")
+	}
+	if err != nil {
+		return err
+	}
+
+	result := contactsJson{Contacts: []contactJson{}}
+	for _, contact := range found {
+		result.Contacts = append(result.Contacts, toContactJson(contact, caller))
+	}
+	writeJson(w, http.StatusOK, result)
+	return nil
+}
+
+// listMuted lists muted users like contacts, with status "muted".
+func (s *server) listMuted(w http.ResponseWriter, caller uuid.UUID, count int, offset int) error {
+	mutes, err := s.contacts.ListMuted(caller, count, offset)
+	if err != nil {
+		return err
+	}
+
+	result := contactsJson{Contacts: []contactJson{}}
+	for _, mute := range mutes {
+		result.Contacts = append(result.Contacts, contactJson{
+			Id: mute.MutedId.String(),
+			Status: "muted",
+			UpdatedAt: mute.MutedAt,
+		})
+	}
+	writeJson(w, http.StatusOK, result)
+	return nil
+}
+
+// contactById serves POST /contacts/{id}/{request,accept,decline,block,
+// unblock,mute,unmute} and DELETE /contacts/{id}, which removes a contact
+// or withdraws a request.
+func (s *server) contactById(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/contacts/"), "/")
+
+	other, err := parseId("user id", parts[0])
+	if err != nil {
+		return err
+	}
+
+	var action func(uuid.UUID, uuid.UUID) error
+	switch {
+	case len(parts) == 1:
+		if r.Method != http.MethodDelete {
+			return methodNotAllowed()
+		}
+		action = s.contacts.Remove
+	case len(parts) == 2:
+		if r.Method != http.MethodPost {
+			return methodNotAllowed()
+		}
+		switch parts[1] {
+		case "request":
+			action = s.contacts.Request
+		case "accept":
+			action = s.contacts.Accept
+		case "decline":
+			action = s.contacts.Decline
+		case "block":
+			action = s.contacts.Block
+		case "unblock":
+			action = s.contacts.Unblock
+		case "mute":
+			action = s.contacts.Mute
+		case "unmute":
+			action = s.contacts.Unmute
+		}
+	}
+	if action == nil {
+		return notFound("No such endpoint.")
+	}
+
+	// Only requests, blocks and mutes make new rows, so only they need the
+	// other user to exist.
+	if len(parts) == 2 && (parts[1] == "request" || parts[1] == "block" || parts[1] == "mute") {
+		user, err := s.users.GetUserById(other)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return notFound("No user with id " + other.String() + ".")
+		}
+	}
+
+	if err = action(caller, other); err != nil {
+		return contactsError(err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// groups serves GET /groups, listing the caller's groups, and POST
+// /groups, creating one.
+func (s *server) groups(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	switch r.Method {
+	case http.MethodGet:
+		groups, err := s.contacts.Groups(caller)
+		if err != nil {
+			return err
+		}
+
+		result := groupsJson{Groups: []groupJson{}}
+		for _, group := range groups {
+			result.Groups = append(result.Groups, toGroupJson(group))
+		}
+		writeJson(w, http.StatusOK, result)
+		return nil
+
+	case http.MethodPost:
+		var request updateGroupJson
+		if err := readJson(w, r, &request); err != nil {
+			return err
+		}
+		members, err := parseIds("members", request.Members)
+		if err != nil {
+			return err
+		}
+
+		group, err := s.contacts.CreateGroup(caller, request.Name, members)
+		if err != nil {
+			return contactsError(err)
+		}
+		writeJson(w, http.StatusCreated, toGroupJson(group))
+		return nil
+	}
+
+	return methodNotAllowed()
+}
+
+// groupById serves GET, PUT and DELETE /groups/{id}.
+func (s *server) groupById(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	id, err := parseId("group id", strings.TrimPrefix(r.URL.Path, "/groups/"))
+	if err != nil {
+		return err
+	}
+
+	var group *contacts.Group
+	switch r.Method {
+	case http.MethodGet:
+		group, err = s.contacts.Group(caller, id)
+	case http.MethodPut:
+		var request updateGroupJson
+		if err = readJson(w, r, &request); err != nil {
+			return err
+		}
+		var members []uuid.UUID
+		if members, err = parseIds("members", request.Members); err != nil {
+			return err
+		}
+		group, err = s.contacts.UpdateGroup(caller, id, request.Name, members)
+	case http.MethodDelete:
+		if err = s.contacts.DeleteGroup(caller, id); err != nil {
+			return contactsError(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	default:
+		return methodNotAllowed()
+	}
+	if err != nil {
+		return contactsError(err)
+	}
+
+	writeJson(w, http.StatusOK, toGroupJson(group))
+	return nil
+}
+
+// sendNote stores the note in MySQL and then indexes it. If indexing
+// fails the note is purged again rather than left where no one can find
+// it.
+func (s *server) sendNote(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	var request sendNoteJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+
+	sender, err := parseCaller("sender", request.Sender, caller)
+	if err != nil {
+		return err
+	}
+	if request.Visibility == "" {
+		request.Visibility = notesdb.VISIBILITY_PRIVATE
+	}
+	if !notesdb.ValidVisibility(request.Visibility) {
+		return badRequest("visibility must be private, contacts or public.")
+	}
+	named := request.Recipient != "" || len(request.Recipients) > 0 || request.Group != ""
+	var parent *notesdb.Note
+	var recipients []uuid.UUID
+	switch {
+	case request.Parent != "":
+		if request.Visibility != notesdb.VISIBILITY_PRIVATE {
+			return badRequest("Replies can't be contacts or public notes.")
+		}
+		if named {
+			return badRequest("Replies go to the parent note's sender and recipients, and can't name recipients.")
+		}
+		if parent, recipients, err = s.resolveReply(sender, request.Parent); err != nil {
+			return err
+		}
+	case request.Direct:
+		return badRequest("Only replies can be direct.")
+	case request.Visibility == notesdb.VISIBILITY_PRIVATE:
+		if recipients, err = s.resolveRecipients(sender, &request); err != nil {
+			return err
+		}
+	case named:
+		return badRequest("Contacts and public notes can't have recipients.")
+	}
+	if err := validateText(request.Text); err != nil {
+		return err
+	}
+	// An anchored reply is left where its parent is, so it needs no
+	// coordinates of its own.
+	if parent != nil && !request.Direct {
+		request.Latitude, request.Longitude = new(float64), new(float64)
+	}
+	if request.Latitude == nil || request.Longitude == nil {
+		return badRequest("latitude and longitude are required.")
+	}
+	if err = validateCoordinates(*request.Latitude, *request.Longitude); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		allowed, err := s.contacts.CanSend(sender, recipient)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return forbidden("Notes can only be left for contacts.")
+		}
+	}
+
+	timeSent := s.now().UTC().Truncate(time.Second)
+	note := notesdb.NewGroupNote(sender, recipients, request.Text, *request.Latitude, *request.Longitude, timeSent)
+	if parent != nil {
+		note = notesdb.NewReply(parent, sender, recipients, request.Text,
+			*request.Latitude, *request.Longitude, request.Direct, timeSent)
+	} else if request.Visibility != notesdb.VISIBILITY_PRIVATE {
+		note = notesdb.NewSharedNote(sender, request.Visibility, request.Text,
+			*request.Latitude, *request.Longitude, timeSent)
+	}
+
+	if err = s.notes.InsertNote(note); err != nil {
+		return err
+	}
+
+	if err = s.index.AddDoc(solrnotes.DocumentFromNote(note)); err != nil {
+		if purgeErr := s.notes.PurgeNote(note.Id()); purgeErr != nil {
+			log.Printf("Failed to purge unindexed note %v. Err: %v", note.Id(), purgeErr)
+		}
+		return err
+	}
+
+	writeJson(w, http.StatusCreated, toNoteJson(note, caller))
+	return nil
+}
+
+// resolveRecipients gathers the request's recipient, recipients and the
+// members of the sender's group into one list, in that order, without
+// repeats.
+func (s *server) resolveRecipients(sender uuid.UUID, request *sendNoteJson) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if request.Recipient != "" {
+		id, err := parseId("recipient", request.Recipient)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	more, err := parseIds("recipients", request.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, more...)
+
+	if request.Group != "" {
+		id, err := parseId("group", request.Group)
+		if err != nil {
+			return nil, err
+		}
+		group, err := s.contacts.Group(sender, id)
+		if err != nil {
+			return nil, contactsError(err)
+		}
+		ids = append(ids, group.Members...)
+	}
+
+	var unique []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, badRequest("recipient is required.")
+	}
+	if len(unique) > MAX_RECIPIENTS {
+		return nil, badRequest("A note can be left for at most " + strconv.Itoa(MAX_RECIPIENTS) + " users.")
+	}
+	return unique, nil
+}
+
+// resolveReply finds the note parentId that sender is replying to, and
+// who the reply goes to: everyone on the parent but sender, or sender
+// alone if they're answering a note they left for themselves.
+func (s *server) resolveReply(sender uuid.UUID, parentId string) (*notesdb.Note, []uuid.UUID, error) {
+	id, err := parseId("parent", parentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent, err := s.requireNote(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	visible, err := s.canSee(parent, sender)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !visible {
+		return nil, nil, forbidden("Only those who can see a note can reply to it.")
+	}
+
+	var recipients []uuid.UUID
+	for _, participant := range parent.Participants() {
+		if participant != sender {
+			recipients = append(recipients, participant)
+		}
+	}
+	if len(recipients) == 0 {
+		recipients = []uuid.UUID{sender}
+	}
+	return parent, recipients, nil
+}
+
+func (s *server) inbox(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	recipient, err := parseCaller("recipient", r.URL.Query().Get("recipient"), caller)
+	if err != nil {
+		return err
+	}
+	count, offset, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+
+	muted, err := s.contacts.MutedIds(recipient)
+	if err != nil {
+		return err
+	}
+	notes, err := s.notes.GetNotesByRecipient(recipient, muted, count, offset)
+	if err != nil {
+		return err
+	}
+
+	writeJson(w, http.StatusOK, toNotesJson(notes, caller))
+	return nil
+}
+
+func (s *server) outbox(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	sender, err := parseCaller("sender", r.URL.Query().Get("sender"), caller)
+	if err != nil {
+		return err
+	}
+	count, offset, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+
+	notes, err := s.notes.GetNotesBySender(sender, count, offset)
+	if err != nil {
+		return err
+	}
+
+	writeJson(w, http.StatusOK, toNotesJson(notes, caller))
+	return nil
+}
+
+// nearby finds the recipient's notes around a point in Solr, then loads
+// them from MySQL, since the index doesn't hold the note text.
+func (s *server) nearby(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	query := r.URL.Query()
+	recipient, err := parseCaller("recipient", query.Get("recipient"), caller)
+	if err != nil {
+		return err
+	}
+	latitude, longitude, radiusKm, err := s.parseArea(r, caller)
+	if err != nil {
+		return err
+	}
+	count, _, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+
+	contactIds, err := s.contacts.ContactIds(recipient, contacts.STATUS_ACCEPTED)
+	if err != nil {
+		return err
+	}
+	hidden, err := s.contacts.HiddenIds(recipient)
+	if err != nil {
+		return err
+	}
+	docs, err := s.index.FindDocsNearby(recipient, contactIds, hidden, latitude, longitude, radiusKm, count)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uuid.UUID, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.Id()
+	}
+	notes, err := s.notes.GetNotesByIds(ids)
+	if err != nil {
+		return err
+	}
+
+	writeJson(w, http.StatusOK, toNotesJson(notes, caller))
+	return nil
+}
+
+// summary counts the recipient's notes in MySQL and, given a point, their
+// unread notes around it in Solr, without loading any notes.
+func (s *server) summary(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	query := r.URL.Query()
+	recipient, err := parseCaller("recipient", query.Get("recipient"), caller)
+	if err != nil {
+		return err
+	}
+	near := query.Get("latitude") != "" || query.Get("longitude") != ""
+	var latitude, longitude, radiusKm float64
+	if near {
+		if latitude, longitude, radiusKm, err = s.parseArea(r, caller); err != nil {
+			return err
+		}
+	}
+
+	muted, err := s.contacts.MutedIds(recipient)
+	if err != nil {
+		return err
+	}
+	counts, err := s.notes.CountNotesByRecipient(recipient, muted)
+	if err != nil {
+		return err
+	}
+	result := summaryJson{Senders: []senderCountJson{}}
+	for _, count := range counts {
+		result.Unread += count.Unread
+		result.Total += count.Total
+		result.Senders = append(result.Senders, senderCountJson{
+			Sender: count.Sender.String(),
+			Unread: count.Unread,
+			Total: count.Total,
+		})
+	}
+
+	if near {
+		contactIds, err := s.contacts.ContactIds(recipient, contacts.STATUS_ACCEPTED)
+		if err != nil {
+			return err
+		}
+		hidden, err := s.contacts.HiddenIds(recipient)
+		if err != nil {
+			return err
+		}
+		unread, err := s.index.CountUnreadNearby(recipient, contactIds, hidden, latitude, longitude, radiusKm)
+		if err != nil {
+			return err
+		}
+		result.UnreadNearby = &unread
+	}
+
+	writeJson(w, http.StatusOK, result)
+	return nil
+}
+
+// parseArea reads the latitude, longitude and radiusKm parameters of a
+// search around a point, defaulting the radius to the caller's.
+func (s *server) parseArea(r *http.Request, caller uuid.UUID) (float64, float64, float64, error) {
+	query := r.URL.Query()
+	latitude, err := parseFloat("latitude", query.Get("latitude"))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	longitude, err := parseFloat("longitude", query.Get("longitude"))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if err = validateCoordinates(latitude, longitude); err != nil {
+		return 0, 0, 0, err
+	}
+
+	radiusKm, err := s.defaultRadiusKm(caller)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if query.Get("radiusKm") != "" {
+		radiusKm, err = parseFloat("radiusKm", query.Get("radiusKm"))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		if radiusKm <= 0 || radiusKm > MAX_RADIUS_KM {
+			return 0, 0, 0, badRequest("radiusKm must be greater than 0 and at most " +
+				strconv.Itoa(MAX_RADIUS_KM) + ".")
+		}
+	}
+	return latitude, longitude, radiusKm, nil
+}
+
+// defaultRadiusKm is the caller's preferred unlock radius, or
+// DEFAULT_RADIUS_KM if they haven't set one.
+func (s *server) defaultRadiusKm(caller uuid.UUID) (float64, error) {
+	profile, err := s.users.GetProfile(caller)
+	if err != nil {
+		return 0, err
+	}
+	if profile == nil || profile.UnlockRadiusKm == 0 {
+		return DEFAULT_RADIUS_KM, nil
+	}
+	return profile.UnlockRadiusKm, nil
+}
+
+// noteById serves POST /notes/{id}/read, GET /notes/{id}/thread,
+// GET /notes/{id}/receipts, GET /notes/{id}/versions,
+// GET and POST /notes/{id}/attachments, PUT /notes/{id} and
+// DELETE /notes/{id}.
+func (s *server) noteById(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/notes/"), "/")
+
+	id, err := parseId("note id", parts[0])
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodDelete:
+			return s.deleteNote(w, id, caller)
+		case http.MethodPut:
+			return s.editNote(w, r, id, caller)
+		}
+		return methodNotAllowed()
+	case len(parts) == 2 && parts[1] == "read":
+		if r.Method != http.MethodPost {
+			return methodNotAllowed()
+		}
+		return s.markRead(w, r, id, caller)
+	case len(parts) == 2 && parts[1] == "receipts":
+		if r.Method != http.MethodGet {
+			return methodNotAllowed()
+		}
+		return s.receipts(w, id, caller)
+	case len(parts) == 2 && parts[1] == "versions":
+		if r.Method != http.MethodGet {
+			return methodNotAllowed()
+		}
+		return s.versions(w, id, caller)
+	case len(parts) == 2 && parts[1] == "thread":
+		if r.Method != http.MethodGet {
+			return methodNotAllowed()
+		}
+		return s.thread(w, r, id, caller)
+	case len(parts) == 2 && parts[1] == "attachments":
+		switch r.Method {
+		case http.MethodGet:
+			return s.listAttachments(w, id, caller)
+		case http.MethodPost:
+			return s.addAttachment(w, r, id, caller)
+		}
+		return methodNotAllowed()
+	}
+
+	return notFound("No such endpoint.")
+}
+
+// editNote lets the sender change a note's text until anyone reads it.
+// The note is reindexed after the edit; if that fails the edit stands,
+// and the doc's edited time is put right by the reconcile tool.
+func (s *server) editNote(w http.ResponseWriter, r *http.Request, id uuid.UUID, caller uuid.UUID) error {
+	var request editNoteJson
+	if err := readJson(w, r, &request); err != nil {
+		return err
+	}
+	if err := validateText(request.Text); err != nil {
+		return err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return err
+	}
+	if note.Sender() != caller {
+		return forbidden("Only a note's sender can edit it.")
+	}
+	if note.Deleted() {
+		return conflict("The note has been deleted.")
+	}
+	if note.ReadByAnyone() {
+		return conflict(notesdb.ErrNoteRead.Error())
+	}
+	if note.Text() == request.Text {
+		writeJson(w, http.StatusOK, toNoteJson(note, caller))
+		return nil
+	}
+
+	err = s.notes.EditNote(id, request.Text, s.now().UTC().Truncate(time.Second))
+	if err == notesdb.ErrNoteRead {
+		return conflict(err.Error())
+	}
+	if err != nil {
+		return err
+	}
+	if note, err = s.requireNote(id); err != nil {
+		return err
+	}
+	if err = s.index.AddDoc(solrnotes.DocumentFromNote(note)); err != nil {
+		log.Printf("Failed to reindex edited note %v. Err: %v", id, err)
+	}
+
+	writeJson(w, http.StatusOK, toNoteJson(note, caller))
+	return nil
+}
+
+func (s *server) versions(w http.ResponseWriter, id uuid.UUID, caller uuid.UUID) error {
+	note, err := s.requireNote(id)
+	if err != nil {
+		return err
+	}
+	if note.Sender() != caller {
+		return forbidden("Only a note's sender can see its earlier versions.")
+	}
+
+	versions, err := s.notes.GetNoteVersions(id)
+	if err != nil {
+		return err
+	}
+	result := versionsJson{Versions: []versionJson{}}
+	for _, version := range versions {
+		result.Versions = append(result.Versions, versionJson{
+			Text: version.Text,
+			WrittenAt: version.WrittenAt,
+			ReplacedAt: version.ReplacedAt,
+		})
+	}
+	writeJson(w, http.StatusOK, result)
+	return nil
+}
+
+// addAttachment takes the body as it is, typed by its Content-Type
+// header, rather than as JSON.
+func (s *server) addAttachment(w http.ResponseWriter, r *http.Request, id uuid.UUID, caller uuid.UUID) error {
+	note, err := s.requireNote(id)
+	if err != nil {
+		return err
+	}
+	if note.Sender() != caller {
+		return forbidden("Only a note's sender can add attachments to it.")
+	}
+	if note.Deleted() {
+		return conflict("The note has been deleted.")
+	}
+
+	attachment, err := s.attachments.Add(id, r.Header.Get("Content-Type"), r.Body)
+	if err != nil {
+		return attachmentsError(err)
+	}
+	writeJson(w, http.StatusCreated, toAttachmentJson(attachment))
+	return nil
+}
+
+func (s *server) listAttachments(w http.ResponseWriter, id uuid.UUID, caller uuid.UUID) error {
+	note, err := s.requireNote(id)
+	if err != nil {
+		return err
+	}
+	visible, err := s.canSee(note, caller)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return forbidden("Only those who can see a note can see its attachments.")
+	}
+
+	list, err := s.attachments.List(id)
+	if err != nil {
+		return err
+	}
+	result := attachmentsJson{Attachments: []attachmentJson{}}
+	for _, attachment := range list {
+		result.Attachments = append(result.Attachments, toAttachmentJson(attachment))
+	}
+	writeJson(w, http.StatusOK, result)
+	return nil
+}
+
+// attachmentById serves GET /attachments/{id} and its thumbnail. Someone
+// who can't see the note is told there's no such attachment, rather than
+// that there is one they can't have.
+func (s *server) attachmentById(w http.ResponseWriter, r *http.Request, caller uuid.UUID) error {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/attachments/"), "/")
+	id, err := parseId("attachment id", parts[0])
+	if err != nil {
+		return err
+	}
+	thumbnail := len(parts) == 2 && parts[1] == "thumbnail"
+	if len(parts) > 1 && !thumbnail {
+		return notFound("No such endpoint.")
+	}
+
+	attachment, err := s.attachments.Get(id)
+	if err != nil {
+		return attachmentsError(err)
+	}
+	notes, err := s.notes.GetNotesByIds([]uuid.UUID{attachment.NoteId})
+	if err != nil {
+		return err
+	}
+	visible := false
+	if len(notes) == 1 && notes[0] != nil {
+		if visible, err = s.canSee(notes[0], caller); err != nil {
+			return err
+		}
+	}
+	if !visible {
+		return attachmentsError(attachments.ErrNoAttachment)
+	}
+
+	open, contentType := s.attachments.Open, attachment.ContentType
+	if thumbnail {
+		open, contentType = s.attachments.OpenThumbnail, attachments.THUMBNAIL_CONTENT_TYPE
+	}
+	content, err := open(attachment)
+	if err != nil {
+		return attachmentsError(err)
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	if _, err = io.Copy(w, content); err != nil {
+		log.Printf("Failed to send attachment %v. Err: %v", id, err)
+	}
+	return nil
+}
+
+// thread pages through the conversation a note belongs to, oldest first.
+// Notes deleted for the caller, and replies between other people on a
+// contacts or public note's thread, are left out, so a page can come back
+// short.
+func (s *server) thread(w http.ResponseWriter, r *http.Request, id uuid.UUID, caller uuid.UUID) error {
+	count, offset, err := parsePage(r)
+	if err != nil {
+		return err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return err
+	}
+	visible, err := s.canSee(note, caller)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return forbidden("Only those who can see a note can see its thread.")
+	}
+
+	thread, err := s.notes.GetThread(note.ThreadId(), count, offset)
+	if err != nil {
+		return err
+	}
+	var notes []*notesdb.Note
+	for _, note := range thread {
+		if note.DeletedFor(caller) {
+			continue
+		}
+		visible, err := s.canSee(note, caller)
+		if err != nil {
+			return err
+		}
+		if visible {
+			notes = append(notes, note)
+		}
+	}
+
+	writeJson(w, http.StatusOK, toNotesJson(notes, caller))
+	return nil
+}
+
+// markRead is idempotent: marking an already read note read again
+// succeeds without touching either store. Only a recipient, or anyone
+// other than the sender who can see a contacts or public note, can read a
+// note, and only for themselves. The body, which can be left out, gives
+// where the reader is for their read receipt.
+func (s *server) markRead(w http.ResponseWriter, r *http.Request, id uuid.UUID, caller uuid.UUID) error {
+	var request markReadJson
+	if err := readOptionalJson(w, r, &request); err != nil {
+		return err
+	}
+	if (request.Latitude == nil) != (request.Longitude == nil) {
+		return badRequest("Give both latitude and longitude, or neither.")
+	}
+	if request.Latitude != nil {
+		if err := validateCoordinates(*request.Latitude, *request.Longitude); err != nil {
+			return err
+		}
+	}
+
+	note, err := s.requireNote(id)
+	if err != nil {
+		return err
+	}
+	visible, err := s.canSee(note, caller)
+	if err != nil {
+		return err
+	}
+	if !visible || (note.Shared() && note.Sender() == caller) || (!note.Shared() && !note.HasRecipient(caller)) {
+		return forbidden("Only a note's recipients can mark it read.")
+	}
+	if note.ReadBy(caller) {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	receipt, err := s.receipt(caller, request.Latitude, request.Longitude)
+	if err != nil {
+		return err
+	}
+	if note.Shared() {
+		err = s.notes.AddReader(id, caller, receipt)
+	} else {
+		err = s.notes.MarkNoteRead(id, caller, receipt)
+	}
+	if err != nil {
+		return err
+	}
+	if err = s.index.MarkDocRead(id, caller); err != nil {
+		return err
+	}
+
+	// Unlock events are read receipts too, so readers who hide theirs
+	// don't send them.
+	if s.hub != nil && receipt != nil {
+		s.hub.Publish(unlocks.Event{
+			NoteId: id,
+			Sender: note.Sender(),
+			Recipient: caller,
+			UnlockedAt: s.now().UTC(),
+		})
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// receipt is the read receipt for reader reading a note now, at latitude
+// and longitude if they're given, or nil if they hide their read
+// receipts.
+func (s *server) receipt(reader uuid.UUID, latitude *float64, longitude *float64) (*notesdb.Receipt, error) {
+	profile, err := s.users.GetProfile(reader)
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil && profile.HideReadReceipts {
+		return nil, nil
+	}
+
+	receipt := &notesdb.Receipt{Reader: reader, ReadAt: s.now().UTC().Truncate(time.Second)}
+	if latitude != nil && longitude != nil {
+		receipt.Located = true
+		receipt.Latitude, receipt.Longitude = *latitude, *longitude
+	}
+	return receipt, nil
+}
+
+// receipts lists the read receipts of one of the caller's notes.
+func (s *server) receipts(w http.ResponseWriter, id uuid.UUID, caller uuid.UUID) error {
+	note, err := s.requireNote(id)
+	if err != nil {
+		return err
+	}
+	if note.Sender() != caller {
+		return forbidden("Only a note's sender can see its read receipts.")
+	}
+
+	result := receiptsJson{Receipts: []receiptJson{}}
+	for _, receipt := range note.Receipts() {
+		receiptResult := receiptJson{Reader: receipt.Reader.String(), ReadAt: receipt.ReadAt}
+		if receipt.Located {
+			latitude, longitude := receipt.Latitude, receipt.Longitude
+			receiptResult.Latitude, receiptResult.Longitude = &latitude, &longitude
+		}
+		result.Receipts = append(result.Receipts, receiptResult)
+	}
+
+	writeJson(w, http.StatusOK, result)
+	return nil
+}
+
+// canSee is whether viewer can find the note: its sender and recipients
+// always can, anyone can see a public note, and the sender's accepted
+// contacts can see a contacts note.
+func (s *server) canSee(note *notesdb.Note, viewer uuid.UUID) (bool, error) {
+	switch {
+	case note.Sender() == viewer || note.HasRecipient(viewer):
+		return true, nil
+	case note.Visibility() == notesdb.VISIBILITY_PUBLIC:
+		return true, nil
+	case note.Visibility() == notesdb.VISIBILITY_CONTACTS:
+		// The same test as for leaving viewer a private note.
+		return s.contacts.CanSend(note.Sender(), viewer)
+	}
+	return false, nil
+}
+
+// deleteNote lets the sender delete a note for everyone, and each
+// recipient delete it for themselves.
+func (s *server) deleteNote(w http.ResponseWriter, id uuid.UUID, caller uuid.UUID) error {
+	note, err := s.requireNote(id)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case note.Sender() == caller:
+		if !note.Deleted() {
+			if err = s.notes.MarkNoteDeleted(id); err != nil {
+				return err
+			}
+			if err = s.index.MarkDocDeleted(id); err != nil {
+				return err
+			}
+		}
+	case note.HasRecipient(caller):
+		if !note.DeletedFor(caller) {
+			if err = s.notes.MarkNoteDeletedFor(id, caller); err != nil {
+				return err
+			}
+			if err = s.index.MarkDocDeletedFor(id, caller); err != nil {
+				return err
+			}
+		}
+	default:
+		return forbidden("Only a note's sender or recipients can delete it.")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *server) requireNote(id uuid.UUID) (*notesdb.Note, error) {
+	notes, err := s.notes.GetNotesByIds([]uuid.UUID{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(notes) != 1 || notes[0] == nil {
+		return nil, notFound("No note with id " + id.String() + ".")
+	}
+	return notes[0], nil
+}
+
+func toAttachmentJson(attachment *attachments.Attachment) attachmentJson {
+	return attachmentJson{
+		Id: attachment.Id.String(),
+		Note: attachment.NoteId.String(),
+		ContentType: attachment.ContentType,
+		Size: attachment.Size,
+		Thumbnail: attachment.Thumbnail,
+		CreatedAt: attachment.CreatedAt,
+	}
+}
+
+func validateText(text string) error {
+	if text == "" {
+		return badRequest("text is required.")
+	}
+	if len(text) > MAX_NOTE_LEN {
+		return badRequest("text must be at most " + strconv.Itoa(MAX_NOTE_LEN) + " bytes.")
+	}
+	return nil
+}
+
+func validateCredentials(request *credentialsJson) error {
+	if request.Username == "" {
+		return badRequest("username is required.")
+	}
+	if len(request.Username) > userdb.MAX_USERNAME_LEN {
+		return badRequest("username must be at most " +
+			strconv.Itoa(userdb.MAX_USERNAME_LEN) + " bytes.")
+	}
+	if request.Password == "" {
+		return badRequest("password is required.")
+	}
+	return nil
+}
+
+func validateCoordinates(latitude float64, longitude float64) error {
+	if latitude < -90 || latitude > 90 {
+		return badRequest("latitude must be between -90 and 90.")
+	}
+	if longitude < -180 || longitude > 180 {
+		return badRequest("longitude must be between -180 and 180.")
+	}
+	return nil
+}
+
+func parseId(name string, value string) (uuid.UUID, error) {
+	if value == "" {
+		return uuid.Nil, badRequest(name + " is required.")
+	}
+	id, err := uuid.FromString(value)
+	if err != nil {
+		return uuid.Nil, badRequest(name + " is not a valid uuid.")
+	}
+	return id, nil
+}
+
+// clientAddress is the IP address a request came from, for lockout. It
+// deliberately ignores X-Forwarded-For, which clients can set to anything.
+func clientAddress(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// parseIds parses a list of ids, none of which may be empty.
+func parseIds(name string, values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, len(values))
+	for i, value := range values {
+		id, err := parseId(name, value)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// parseCaller reads an optional user id that, if given, must be the
+// caller's own. It defaults to the caller.
+func parseCaller(name string, value string, caller uuid.UUID) (uuid.UUID, error) {
+	if value == "" {
+		return caller, nil
+	}
+	id, err := parseId(name, value)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if id != caller {
+		return uuid.Nil, forbidden(name + " must be the signed-in user.")
+	}
+	return id, nil
+}
+
+func parseFloat(name string, value string) (float64, error) {
+	if value == "" {
+		return 0, badRequest(name + " is required.")
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, badRequest(name + " is not a number.")
+	}
+	return f, nil
+}
+
+// parsePage reads the optional count and offset query parameters.
+func parsePage(r *http.Request) (int, int, error) {
+	query := r.URL.Query()
+	count := DEFAULT_PAGE_SIZE
+	offset := 0
+	var err error
+
+	if query.Get("count") != "" {
+		count, err = strconv.Atoi(query.Get("count"))
+		if err != nil || count < 1 || count > MAX_PAGE_SIZE {
+			return 0, 0, badRequest("count must be between 1 and " + strconv.Itoa(MAX_PAGE_SIZE) + ".")
+		}
+	}
+	if query.Get("offset") != "" {
+		offset, err = strconv.Atoi(query.Get("offset"))
+		if err != nil || offset < 0 {
+			return 0, 0, badRequest("offset must be a non-negative integer.")
+		}
+	}
+
+	return count, offset, nil
+}
+
+// readJson decodes a request body into v, rejecting oversized bodies and
+// unknown fields so that client typos are caught rather than ignored.
+func readJson(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_BODY_BYTES))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return badRequest("Invalid JSON body: " + err.Error())
+	}
+	return nil
+}
+
+// readOptionalJson is readJson for a body that can be left out
+// altogether, leaving v as it was.
+func readOptionalJson(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, MAX_BODY_BYTES))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && err != io.EOF {
+		return badRequest("Invalid JSON body: " + err.Error())
+	}
+	return nil
+}
+
+func writeJson(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response. Err: %v", err)
+	}
+}
+
+func toTokensJson(tokens *sessions.Tokens) tokensJson {
+	return tokensJson{
+		Id: tokens.UserId.String(),
+		AccessToken: tokens.AccessToken,
+		AccessExpiresAt: tokens.AccessExpiresAt,
+		RefreshToken: tokens.RefreshToken,
+		RefreshExpiresAt: tokens.RefreshExpiresAt,
+	}
+}
+
+func toProfileJson(profile *userdb.Profile) profileJson {
+	result := profileJson{
+		Id: profile.UserId.String(),
+		Username: profile.Username,
+		DisplayName: profile.DisplayName,
+		AvatarRef: profile.AvatarRef,
+		Bio: profile.Bio,
+		TimeZone: profile.TimeZone,
+		UnlockRadiusKm: profile.UnlockRadiusKm,
+		HideReadReceipts: profile.HideReadReceipts,
+	}
+	if !profile.UpdatedAt.IsZero() {
+		result.UpdatedAt = &profile.UpdatedAt
+	}
+	return result
+}
+
+func toPublicProfileJson(profile *userdb.Profile) publicProfileJson {
+	return publicProfileJson{
+		Id: profile.UserId.String(),
+		Username: profile.Username,
+		DisplayName: profile.DisplayName,
+		AvatarRef: profile.AvatarRef,
+		Bio: profile.Bio,
+	}
+}
+
+func toContactJson(contact *contacts.Contact, caller uuid.UUID) contactJson {
+	other := contact.ContactId
+	if other == caller {
+		other = contact.UserId
+	}
+	return contactJson{
+		Id: other.String(),
+		Status: contact.Status,
+		UpdatedAt: contact.UpdatedAt,
+	}
+}
+
+func toGroupJson(group *contacts.Group) groupJson {
+	members := make([]string, len(group.Members))
+	for i, member := range group.Members {
+		members[i] = member.String()
+	}
+	return groupJson{
+		Id: group.Id.String(),
+		Name: group.Name,
+		Members: members,
+		UpdatedAt: group.UpdatedAt,
+	}
+}
+
+// toNoteJson shows a recipient, or a reader of a shared note, their own
+// read and deleted state, and the sender the note's as a whole.
+func toNoteJson(note *notesdb.Note, viewer uuid.UUID) noteJson {
+	read, deleted := note.Read(), note.Deleted()
+	if note.HasRecipient(viewer) || (note.Shared() && note.Sender() != viewer) {
+		read, deleted = note.ReadBy(viewer), note.DeletedFor(viewer)
+	}
+
+	ids := note.RecipientIds()
+	recipients := make([]string, len(ids))
+	for i, id := range ids {
+		recipients[i] = id.String()
+	}
+	recipient := ""
+	if len(ids) > 0 {
+		recipient = ids[0].String()
+	}
+	parent := ""
+	if note.IsReply() {
+		parent = note.ParentId().String()
+	}
+	var editedAt *time.Time
+	if note.Edited() {
+		timeEdited := note.TimeEdited()
+		editedAt = &timeEdited
+	}
+
+	return noteJson{
+		Id: note.Id().String(),
+		Sender: note.Sender().String(),
+		Visibility: note.Visibility(),
+		Recipient: recipient,
+		Recipients: recipients,
+		Text: note.Text(),
+		Latitude: note.Latitude(),
+		Longitude: note.Longitude(),
+		TimeSent: note.TimeSent(),
+		Read: read,
+		Deleted: deleted,
+		Parent: parent,
+		Thread: note.ThreadId().String(),
+		Direct: note.Direct(),
+		EditedAt: editedAt,
+	}
+}
+
+// toNotesJson skips nil notes, which GetNotesByIds returns for ids it
+// couldn't find, e.g. when Solr still has a doc that was purged.
+func toNotesJson(notes []*notesdb.Note, viewer uuid.UUID) notesJson {
+	result := notesJson{Notes: []noteJson{}}
+	for _, note := range notes {
+		if note != nil {
+			result.Notes = append(result.Notes, toNoteJson(note, viewer))
+		}
+	}
+	return result
+}
@@ -0,0 +1,1407 @@
+package main
+
+import (
+	"testing"
+	"time"
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"os"
+	"io/ioutil"
+	"archive/zip"
+	"path/filepath"
+	"net/http"
+	"net/http/httptest"
+	"encoding/json"
+	"errors"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/attachments"
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/export"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/solrnotes"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	s := getTestServer()
+
+	body := `{"username": "myusername", "password": "password"}`
+	response := doRequest(s, "POST", "/users", body)
+	if response.Code != http.StatusCreated {
+		t.Fatal("Failed to register. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	var registered userIdJson
+	if err := json.NewDecoder(response.Body).Decode(&registered); err != nil {
+		t.Fatal("Failed to decode registered user. Err: ", err)
+	}
+	if _, err := uuid.FromString(registered.Id); err != nil {
+		t.Fatal("Register did not return a user id. Err: ", err)
+	}
+
+	response = doRequest(s, "POST", "/login", body)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to log in. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	var loggedIn tokensJson
+	if err := json.NewDecoder(response.Body).Decode(&loggedIn); err != nil {
+		t.Fatal("Failed to decode logged in user. Err: ", err)
+	}
+	if loggedIn.Id != registered.Id {
+		t.Fatal("Login returned id ", loggedIn.Id, " but register returned ", registered.Id)
+	}
+	if loggedIn.AccessToken == "" || loggedIn.RefreshToken == "" {
+		t.Fatal("Login did not return tokens: ", loggedIn)
+	}
+
+	response = doRequest(s, "POST", "/login", `{"username": "myusername", "password": "bad"}`)
+	if response.Code != http.StatusUnauthorized {
+		t.Fatal("Accepted a bad password. Status: ", response.Code)
+	}
+}
+
+func TestUsernameAvailable(t *testing.T) {
+	s := getTestServer()
+	signUp(t, s, "myusername")
+
+	cases := []struct {
+		username string
+		available bool
+		reason bool
+	}{
+		{"newuser", true, false},
+		{"MyUsername", false, false},
+		{"root", false, true},
+	}
+
+	for _, c := range cases {
+		response := doRequest(s, "GET", "/users/available?username=" + c.username, ``)
+		if response.Code != http.StatusOK {
+			t.Fatal("Failed to check ", c.username, ". Status: ", response.Code, " Body: ", response.Body)
+		}
+
+		var result availableJson
+		if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+			t.Fatal("Failed to decode availability. Err: ", err)
+		}
+		if result.Available != c.available || (result.Reason != "") != c.reason {
+			t.Error("Unexpected availability for ", c.username, ": ", result)
+		}
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	s := getTestServer()
+	signUp(t, s, "myusername")
+
+	bad := `{"username": "myusername", "password": "bad"}`
+	for i := 0; i < lockout.DEFAULT_POLICY.FreeAttempts; i++ {
+		response := doRequest(s, "POST", "/login", bad)
+		if response.Code != http.StatusUnauthorized {
+			t.Fatal("Expected 401 for a bad password. Status: ", response.Code)
+		}
+	}
+
+	response := doRequest(s, "POST", "/login", credentialsBody("myusername"))
+	if response.Code != http.StatusTooManyRequests || response.Header().Get("Retry-After") == "" {
+		t.Fatal("Expected 429 with Retry-After while locked out. Status: ", response.Code,
+			" Headers: ", response.Header())
+	}
+}
+
+func TestSessions(t *testing.T) {
+	s := getTestServer()
+	_, phone := signUp(t, s, "myusername")
+
+	response := doRequest(s, "POST", "/sessions/refresh", refreshBody(phone.RefreshToken))
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to refresh. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var refreshed tokensJson
+	if err := json.NewDecoder(response.Body).Decode(&refreshed); err != nil {
+		t.Fatal("Failed to decode refreshed tokens. Err: ", err)
+	}
+	if refreshed.Id != phone.Id || refreshed.RefreshToken == phone.RefreshToken {
+		t.Fatal("Unexpected refreshed tokens: ", refreshed)
+	}
+
+	response = doRequest(s, "POST", "/logout", refreshBody(refreshed.RefreshToken))
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to log out. Status: ", response.Code, " Body: ", response.Body)
+	}
+	response = doRequest(s, "POST", "/sessions/refresh", refreshBody(refreshed.RefreshToken))
+	if response.Code != http.StatusUnauthorized {
+		t.Fatal("Refreshed a logged out session. Status: ", response.Code)
+	}
+
+	tablet := login(t, s, "myusername")
+	laptop := login(t, s, "myusername")
+	response = doAuthedRequest(s, tablet.AccessToken, "POST", "/logout/all", "")
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to log out everywhere. Status: ", response.Code, " Body: ", response.Body)
+	}
+	response = doRequest(s, "POST", "/sessions/refresh", refreshBody(laptop.RefreshToken))
+	if response.Code != http.StatusUnauthorized {
+		t.Fatal("Refreshed a session after logging out everywhere. Status: ", response.Code)
+	}
+}
+
+func TestPasswords(t *testing.T) {
+	s := getTestServer()
+	dir, err := ioutil.TempDir("", "geonote-reset")
+	if err != nil {
+		t.Fatal("Failed to make temp dir. Err: ", err)
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "reset-tokens")
+	s.resetter = userdb.NewResetter(s.users, s.users.(*userdb.MemoryUserdb),
+		&userdb.FileResetSink{Filename: tokenFile}, 0)
+	_, tokens := signUp(t, s, "myusername")
+
+	response := doAuthedRequest(s, tokens.AccessToken, "POST", "/password",
+		`{"oldPassword": "wrong", "newPassword": "newpassword"}`)
+	if response.Code != http.StatusForbidden {
+		t.Fatal("Expected 403 for a wrong old password, got ", response.Code)
+	}
+
+	response = doAuthedRequest(s, tokens.AccessToken, "POST", "/password",
+		`{"oldPassword": "password", "newPassword": "newpassword"}`)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to change password. Status: ", response.Code, " Body: ", response.Body)
+	}
+	response = doRequest(s, "POST", "/sessions/refresh", refreshBody(tokens.RefreshToken))
+	if response.Code != http.StatusUnauthorized {
+		t.Fatal("Expected the old session to be ended, got ", response.Code)
+	}
+
+	for _, username := range []string{"myusername", "nosuchuser"} {
+		response = doRequest(s, "POST", "/password/reset-request", `{"username": "` + username + `"}`)
+		if response.Code != http.StatusAccepted {
+			t.Fatal("Failed to request a reset. Status: ", response.Code, " Body: ", response.Body)
+		}
+	}
+
+	contents, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		t.Fatal("Failed to read reset tokens. Err: ", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	if len(lines) != 1 || !strings.HasPrefix(lines[0], "myusername ") {
+		t.Fatal("Expected one reset token for myusername, got: ", string(contents))
+	}
+	resetBody := `{"token": "` + strings.TrimPrefix(lines[0], "myusername ") + `", "newPassword": "password"}`
+
+	response = doRequest(s, "POST", "/password/reset", resetBody)
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to reset password. Status: ", response.Code, " Body: ", response.Body)
+	}
+	login(t, s, "myusername")
+
+	response = doRequest(s, "POST", "/password/reset", resetBody)
+	if response.Code != http.StatusBadRequest {
+		t.Fatal("Expected 400 for a used reset token, got ", response.Code)
+	}
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	s := getTestServer()
+	_, tokens := signUp(t, s, "myusername")
+
+	bad := `{"oldPassword": "wrong", "newPassword": "newpassword"}`
+	for i := 0; i < lockout.DEFAULT_POLICY.FreeAttempts; i++ {
+		response := doAuthedRequest(s, tokens.AccessToken, "POST", "/password", bad)
+		if response.Code != http.StatusForbidden {
+			t.Fatal("Expected 403 for a wrong old password. Status: ", response.Code)
+		}
+	}
+
+	response := doAuthedRequest(s, tokens.AccessToken, "POST", "/password",
+		`{"oldPassword": "password", "newPassword": "newpassword"}`)
+	if response.Code != http.StatusTooManyRequests || response.Header().Get("Retry-After") == "" {
+		t.Fatal("Expected 429 with Retry-After while locked out. Status: ", response.Code,
+			" Headers: ", response.Header())
+	}
+	response = doRequest(s, "POST", "/login", credentialsBody("myusername"))
+	if response.Code != http.StatusTooManyRequests {
+		t.Fatal("Expected wrong old passwords to lock out logins too. Status: ", response.Code)
+	}
+}
+
+func TestTwoFactor(t *testing.T) {
+	s := getTestServer()
+	_, tokens := signUp(t, s, "myusername")
+
+	response := doAuthedRequest(s, tokens.AccessToken, "POST", "/totp/enroll", ``)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to enroll. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var setup totpSetupJson
+	if err := json.NewDecoder(response.Body).Decode(&setup); err != nil {
+		t.Fatal("Failed to decode TOTP setup. Err: ", err)
+	}
+
+	code, err := userdb.TotpCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatal("Failed to make code. Err: ", err)
+	}
+	response = doAuthedRequest(s, tokens.AccessToken, "POST", "/totp/confirm", `{"code": "` + code + `"}`)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to confirm. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var recovery recoveryCodesJson
+	if err = json.NewDecoder(response.Body).Decode(&recovery); err != nil {
+		t.Fatal("Failed to decode recovery codes. Err: ", err)
+	}
+
+	response = doRequest(s, "POST", "/login", credentialsBody("myusername"))
+	var loginError errorJson
+	json.NewDecoder(response.Body).Decode(&loginError)
+	if response.Code != http.StatusUnauthorized || loginError.Code != "second_factor_required" {
+		t.Fatal("Expected a second factor to be required, got ", response.Code, " ", loginError)
+	}
+
+	body := `{"username": "myusername", "password": "password", "code": "` + recovery.RecoveryCodes[0] + `"}`
+	response = doRequest(s, "POST", "/login", body)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to log in with a recovery code. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	response = doAuthedRequest(s, tokens.AccessToken, "POST", "/totp/disable", `{"code": "bad"}`)
+	if response.Code != http.StatusForbidden {
+		t.Fatal("Expected 403 for a bad code, got ", response.Code)
+	}
+	response = doAuthedRequest(s, tokens.AccessToken, "POST", "/totp/disable",
+		`{"code": "` + recovery.RecoveryCodes[1] + `"}`)
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to disable. Status: ", response.Code, " Body: ", response.Body)
+	}
+	login(t, s, "myusername")
+}
+
+func TestTwoFactorLockout(t *testing.T) {
+	s := getTestServer()
+	_, tokens := signUp(t, s, "myusername")
+
+	response := doAuthedRequest(s, tokens.AccessToken, "POST", "/totp/enroll", ``)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to enroll. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var setup totpSetupJson
+	if err := json.NewDecoder(response.Body).Decode(&setup); err != nil {
+		t.Fatal("Failed to decode TOTP setup. Err: ", err)
+	}
+
+	for i := 0; i < lockout.DEFAULT_POLICY.FreeAttempts; i++ {
+		response = doAuthedRequest(s, tokens.AccessToken, "POST", "/totp/confirm", `{"code": "bad"}`)
+		if response.Code != http.StatusForbidden {
+			t.Fatal("Expected 403 for a bad code, got ", response.Code)
+		}
+	}
+
+	code, err := userdb.TotpCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatal("Failed to make code. Err: ", err)
+	}
+	response = doAuthedRequest(s, tokens.AccessToken, "POST", "/totp/confirm", `{"code": "` + code + `"}`)
+	if response.Code != http.StatusTooManyRequests || response.Header().Get("Retry-After") == "" {
+		t.Fatal("Expected 429 with Retry-After while locked out. Status: ", response.Code,
+			" Headers: ", response.Header())
+	}
+	response = doAuthedRequest(s, tokens.AccessToken, "POST", "/totp/disable", `{"code": "bad"}`)
+	if response.Code != http.StatusTooManyRequests {
+		t.Fatal("Expected disabling to be locked out too, got ", response.Code)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	s := getTestServer()
+	senderId, sender := signUp(t, s, "sender")
+	recipientId, recipient := signUp(t, s, "recipient")
+	befriend(t, s, sender, recipient)
+
+	response := doAuthedRequest(s, sender.AccessToken, "PUT", "/profile",
+		`{"displayName": "The Sender", "bio": "Hi!", "timeZone": "America/New_York"}`)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to update profile. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var own profileJson
+	if err := json.NewDecoder(response.Body).Decode(&own); err != nil {
+		t.Fatal("Failed to decode profile. Err: ", err)
+	}
+	if own.Username != "sender" || own.DisplayName != "The Sender" || own.UpdatedAt == nil {
+		t.Fatal("Unexpected profile: ", own)
+	}
+
+	response = doAuthedRequest(s, recipient.AccessToken, "GET",
+		"/profiles?id=" + senderId.String() + "&id=" + uuid.NewV4().String(), ``)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to get profiles. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var others profilesJson
+	if err := json.NewDecoder(response.Body).Decode(&others); err != nil {
+		t.Fatal("Failed to decode profiles. Err: ", err)
+	}
+	if len(others.Profiles) != 1 || others.Profiles[0].DisplayName != "The Sender" {
+		t.Fatal("Expected only the sender's profile, got: ", others.Profiles)
+	}
+	if strings.Contains(response.Body.String(), "timeZone") {
+		t.Fatal("Public profiles shouldn't include preferences: ", response.Body)
+	}
+
+	// Without radiusKm, nearby uses the recipient's preferred radius.
+	sendNote(t, s, sender.AccessToken, recipientId, "Look up!", 40.810260, -73.94694)
+	nearbyPath := "/notes/nearby?latitude=40.809322&longitude=-73.944587"
+	if found := getNotes(t, s, recipient.AccessToken, nearbyPath); len(found) != 0 {
+		t.Fatal("Expected nothing within the default radius, got ", found)
+	}
+	response = doAuthedRequest(s, recipient.AccessToken, "PUT", "/profile", `{"unlockRadiusKm": 0.5}`)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to update profile. Status: ", response.Code, " Body: ", response.Body)
+	}
+	if found := getNotes(t, s, recipient.AccessToken, nearbyPath); len(found) != 1 {
+		t.Fatal("Expected the note within the preferred radius, got ", found)
+	}
+}
+
+func TestContacts(t *testing.T) {
+	s := getTestServer()
+	aliceId, alice := signUp(t, s, "alice")
+	bobId, bob := signUp(t, s, "bob")
+
+	response := doAuthedRequest(s, alice.AccessToken, "POST", "/notes", sendNoteBody(aliceId, bobId, "hi", 1, 1))
+	if response.Code != http.StatusForbidden {
+		t.Fatal("Expected a note to a non-contact to be refused, got ", response.Code, " ", response.Body)
+	}
+	sendNote(t, s, alice.AccessToken, aliceId, "Note to self", 1, 1)
+
+	cases := []struct {
+		token string
+		method string
+		path string
+		status int
+	}{
+		{alice.AccessToken, "POST", "/contacts/" + bobId.String() + "/request", http.StatusNoContent},
+		{alice.AccessToken, "POST", "/contacts/" + bobId.String() + "/accept", http.StatusNotFound},
+		{bob.AccessToken, "POST", "/contacts/" + aliceId.String() + "/accept", http.StatusNoContent},
+		{bob.AccessToken, "POST", "/contacts/" + aliceId.String() + "/request", http.StatusConflict},
+		{alice.AccessToken, "POST", "/contacts/" + aliceId.String() + "/request", http.StatusBadRequest},
+		{alice.AccessToken, "POST", "/contacts/" + uuid.NewV4().String() + "/request", http.StatusNotFound},
+		{alice.AccessToken, "POST", "/contacts/" + bobId.String() + "/befriend", http.StatusNotFound},
+		{alice.AccessToken, "GET", "/contacts/" + bobId.String(), http.StatusMethodNotAllowed},
+		{alice.AccessToken, "GET", "/contacts?list=everyone", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		response := doAuthedRequest(s, c.token, c.method, c.path, ``)
+		if response.Code != c.status {
+			t.Error(c.method, " ", c.path, ": expected ", c.status, ", got ", response.Code, " ", response.Body)
+		}
+	}
+
+	if contacts := getContacts(t, s, alice.AccessToken, "/contacts"); len(contacts) != 1 ||
+		contacts[0].Id != bobId.String() {
+		t.Fatal("Expected bob as alice's contact, got ", contacts)
+	}
+	sendNote(t, s, alice.AccessToken, bobId, "hi", 1, 1)
+
+	response = doAuthedRequest(s, bob.AccessToken, "POST", "/contacts/" + aliceId.String() + "/block", ``)
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to block. Status: ", response.Code, " Body: ", response.Body)
+	}
+	response = doAuthedRequest(s, alice.AccessToken, "POST", "/notes", sendNoteBody(aliceId, bobId, "hi", 1, 1))
+	if response.Code != http.StatusForbidden {
+		t.Fatal("Expected a note to a blocker to be refused, got ", response.Code, " ", response.Body)
+	}
+	if blocked := getContacts(t, s, bob.AccessToken, "/contacts?list=blocked"); len(blocked) != 1 {
+		t.Fatal("Expected alice to be blocked, got ", blocked)
+	}
+
+	// Alice's new request looks like it went through, but bob never sees it.
+	response = doAuthedRequest(s, alice.AccessToken, "POST", "/contacts/" + bobId.String() + "/request", ``)
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Expected a request to a blocker to look normal, got ", response.Code, " ", response.Body)
+	}
+	if incoming := getContacts(t, s, bob.AccessToken, "/contacts?list=incoming"); len(incoming) != 0 {
+		t.Fatal("Expected no visible requests, got ", incoming)
+	}
+	if outgoing := getContacts(t, s, alice.AccessToken, "/contacts?list=outgoing"); len(outgoing) != 1 {
+		t.Fatal("Expected alice's request to be listed, got ", outgoing)
+	}
+}
+
+func TestMute(t *testing.T) {
+	s := getTestServer()
+	_, sender := signUp(t, s, "sender")
+	_, other := signUp(t, s, "other")
+	recipientId, recipient := signUp(t, s, "recipient")
+	befriend(t, s, sender, recipient)
+	befriend(t, s, other, recipient)
+
+	muted := sendNote(t, s, sender.AccessToken, recipientId, "Muted", 40.810260, -73.94694)
+	shown := sendNote(t, s, other.AccessToken, recipientId, "Shown", 40.810260, -73.94694)
+
+	response := doAuthedRequest(s, recipient.AccessToken, "POST", "/contacts/" + sender.Id + "/mute", ``)
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to mute. Status: ", response.Code, " Body: ", response.Body)
+	}
+	if listed := getContacts(t, s, recipient.AccessToken, "/contacts?list=muted"); len(listed) != 1 ||
+		listed[0].Id != sender.Id || listed[0].Status != "muted" {
+		t.Fatal("Expected the sender to be muted, got ", listed)
+	}
+
+	// Muted senders can still leave notes; they just aren't shown.
+	sendNote(t, s, sender.AccessToken, recipientId, "Still muted", 40.810260, -73.94694)
+	for _, path := range []string{
+		"/notes/inbox",
+		"/notes/nearby?latitude=40.809322&longitude=-73.944587&radiusKm=0.5",
+	} {
+		found := getNotes(t, s, recipient.AccessToken, path)
+		if len(found) != 1 || found[0].Id != shown.Id {
+			t.Fatal(path, ": expected only the unmuted note, got ", found)
+		}
+	}
+	if sent := getNotes(t, s, sender.AccessToken, "/notes/outbox"); len(sent) != 2 {
+		t.Fatal("Expected muted notes to be kept, got ", sent)
+	}
+
+	response = doAuthedRequest(s, recipient.AccessToken, "POST", "/contacts/" + sender.Id + "/unmute", ``)
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to unmute. Status: ", response.Code, " Body: ", response.Body)
+	}
+	found := getNotes(t, s, recipient.AccessToken, "/notes/inbox")
+	if len(found) != 3 {
+		t.Fatal("Expected every note after unmuting, got ", found)
+	}
+	if found[0].Id != muted.Id && found[1].Id != muted.Id && found[2].Id != muted.Id {
+		t.Fatal("Expected the muted note back after unmuting, got ", found)
+	}
+	response = doAuthedRequest(s, recipient.AccessToken, "POST", "/contacts/" + sender.Id + "/unmute", ``)
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected 404 unmuting twice, got ", response.Code, " ", response.Body)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	s := getTestServer()
+	senderId, sender := signUp(t, s, "sender")
+	recipientId, recipient := signUp(t, s, "recipient")
+	befriend(t, s, sender, recipient)
+	strangerId, stranger := signUp(t, s, "stranger")
+
+	note := sendNote(t, s, sender.AccessToken, recipientId, "hi", 1, 1)
+
+	cases := []struct {
+		token string
+		method string
+		path string
+		body string
+		status int
+	}{
+		{"", "GET", "/notes/inbox", ``, http.StatusUnauthorized},
+		{"not-a-token", "GET", "/notes/inbox", ``, http.StatusUnauthorized},
+		{"", "POST", "/logout/all", ``, http.StatusUnauthorized},
+		{stranger.AccessToken, "POST", "/notes", sendNoteBody(senderId, recipientId, "hi", 1, 1),
+			http.StatusForbidden},
+		{stranger.AccessToken, "POST", "/notes", sendNoteBody(strangerId, recipientId, "hi", 1, 1),
+			http.StatusForbidden},
+		{"", "GET", "/contacts", ``, http.StatusUnauthorized},
+		{stranger.AccessToken, "GET", "/notes/inbox?recipient=" + recipientId.String(), ``,
+			http.StatusForbidden},
+		{stranger.AccessToken, "GET", "/notes/outbox?sender=" + senderId.String(), ``,
+			http.StatusForbidden},
+		{sender.AccessToken, "POST", "/notes/" + note.Id + "/read", ``, http.StatusForbidden},
+		{stranger.AccessToken, "DELETE", "/notes/" + note.Id, ``, http.StatusForbidden},
+		{recipient.AccessToken, "POST", "/notes/" + note.Id + "/read", ``, http.StatusNoContent},
+		{sender.AccessToken, "DELETE", "/notes/" + note.Id, ``, http.StatusNoContent},
+	}
+
+	for _, c := range cases {
+		response := doAuthedRequest(s, c.token, c.method, c.path, c.body)
+		if response.Code != c.status {
+			t.Error(c.method, " ", c.path, " ", c.body, ": expected ", c.status,
+				", got ", response.Code, " ", response.Body)
+		}
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	s := getTestServer()
+	callerId, caller := signUp(t, s, "myusername")
+
+	cases := []struct {
+		method string
+		path string
+		body string
+		status int
+	}{
+		{"POST", "/users", `{"username": "", "password": "password"}`, http.StatusBadRequest},
+		{"POST", "/users", `{"username": "me", "password": "pw", "extra": 1}`, http.StatusBadRequest},
+		{"POST", "/users", `not json`, http.StatusBadRequest},
+		{"POST", "/users", `{"username": "newuser", "password": "short"}`, http.StatusBadRequest},
+		{"POST", "/users", `{"username": "Admin", "password": "password"}`, http.StatusBadRequest},
+		{"POST", "/users", credentialsBody("MyUsername"), http.StatusConflict},
+		{"GET", "/users/available", ``, http.StatusBadRequest},
+		{"POST", "/password", `{"oldPassword": "password", "newPassword": "short"}`, http.StatusBadRequest},
+		{"GET", "/users", ``, http.StatusMethodNotAllowed},
+		{"PUT", "/profile", `{"timeZone": "Nowhere/Special"}`, http.StatusBadRequest},
+		{"PUT", "/profile", `{"unlockRadiusKm": 51}`, http.StatusBadRequest},
+		{"PUT", "/profile", `{"username": "someoneelse"}`, http.StatusBadRequest},
+		{"DELETE", "/profile", ``, http.StatusMethodNotAllowed},
+		{"GET", "/profiles", ``, http.StatusBadRequest},
+		{"GET", "/profiles?id=not-a-uuid", ``, http.StatusBadRequest},
+		{"POST", "/notes", `{"sender": "nope"}`, http.StatusBadRequest},
+		{"POST", "/notes", sendNoteBody(callerId, uuid.NewV4(), "hi", 91, 0), http.StatusBadRequest},
+		{"POST", "/notes", sendNoteBody(callerId, uuid.NewV4(), "", 1, 1), http.StatusBadRequest},
+		{"GET", "/notes/inbox?recipient=not-a-uuid", ``, http.StatusBadRequest},
+		{"GET", "/notes/inbox?count=0", ``, http.StatusBadRequest},
+		{"GET", "/notes/nearby", ``, http.StatusBadRequest},
+		{"POST", "/sessions/refresh", `{}`, http.StatusBadRequest},
+		{"POST", "/notes/" + uuid.NewV4().String() + "/read", ``, http.StatusNotFound},
+		{"DELETE", "/notes/not-a-uuid", ``, http.StatusBadRequest},
+	}
+
+	for _, c := range cases {
+		response := doAuthedRequest(s, caller.AccessToken, c.method, c.path, c.body)
+		if response.Code != c.status {
+			t.Error(c.method, " ", c.path, " ", c.body, ": expected ", c.status,
+				", got ", response.Code, " ", response.Body)
+		}
+	}
+}
+
+func TestSendAndReadNotes(t *testing.T) {
+	s := getTestServer()
+	senderId, sender := signUp(t, s, "sender")
+	recipientId, recipient := signUp(t, s, "recipient")
+	befriend(t, s, sender, recipient)
+
+	nearby := sendNote(t, s, sender.AccessToken, recipientId, "Look up!", 40.810260, -73.94694)
+	farAway := sendNote(t, s, sender.AccessToken, recipientId, "Too far", 40.758320, -73.988327)
+	if nearby.Sender != senderId.String() {
+		t.Fatal("Note was not sent as the caller: ", nearby.Sender)
+	}
+
+	inbox := getNotes(t, s, recipient.AccessToken, "/notes/inbox?recipient=" + recipientId.String())
+	if len(inbox) != 2 {
+		t.Fatal("Expected 2 notes in inbox, got ", len(inbox))
+	}
+
+	outbox := getNotes(t, s, sender.AccessToken, "/notes/outbox")
+	if len(outbox) != 2 {
+		t.Fatal("Expected 2 notes in outbox, got ", len(outbox))
+	}
+
+	found := getNotes(t, s, recipient.AccessToken,
+		"/notes/nearby?latitude=40.809322&longitude=-73.944587&radiusKm=0.5")
+	if len(found) != 1 || found[0].Id != nearby.Id || found[0].Text != "Look up!" {
+		t.Fatal("Expected to find only the nearby note, got ", found)
+	}
+
+	response := doAuthedRequest(s, recipient.AccessToken, "POST", "/notes/" + nearby.Id + "/read", "")
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to mark note read. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	response = doAuthedRequest(s, recipient.AccessToken, "DELETE", "/notes/" + farAway.Id, "")
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to delete note. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	for _, note := range getNotes(t, s, recipient.AccessToken, "/notes/inbox") {
+		if note.Id == nearby.Id && !note.Read {
+			t.Fatal("Note was not marked read.")
+		}
+		if note.Id == farAway.Id && !note.Deleted {
+			t.Fatal("Note was not marked deleted.")
+		}
+	}
+}
+
+func TestGroupNotes(t *testing.T) {
+	s := getTestServer()
+	_, alice := signUp(t, s, "alice")
+	bobId, bob := signUp(t, s, "bob")
+	carolId, carol := signUp(t, s, "carol")
+	befriend(t, s, alice, bob)
+	befriend(t, s, alice, carol)
+
+	response := doAuthedRequest(s, alice.AccessToken, "POST", "/groups",
+		`{"name": "friends", "members": ["` + bobId.String() + `", "` + carolId.String() + `"]}`)
+	if response.Code != http.StatusCreated {
+		t.Fatal("Failed to create group. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var group groupJson
+	if err := json.NewDecoder(response.Body).Decode(&group); err != nil {
+		t.Fatal("Failed to decode group. Err: ", err)
+	}
+
+	cases := []struct {
+		token string
+		method string
+		path string
+		body string
+		status int
+	}{
+		{alice.AccessToken, "POST", "/groups", `{"name": "Friends"}`, http.StatusConflict},
+		{alice.AccessToken, "POST", "/groups", `{"name": ""}`, http.StatusBadRequest},
+		{alice.AccessToken, "POST", "/groups", `{"name": "x", "members": ["nope"]}`, http.StatusBadRequest},
+		{bob.AccessToken, "GET", "/groups/" + group.Id, ``, http.StatusNotFound},
+		{alice.AccessToken, "PUT", "/groups/" + group.Id, `{"name": "pals", "members": ["` + bobId.String() + `"]}`,
+			http.StatusOK},
+		{alice.AccessToken, "PUT", "/groups/" + group.Id, `{"name": "friends", "members": ["` +
+			bobId.String() + `", "` + carolId.String() + `"]}`, http.StatusOK},
+		{bob.AccessToken, "POST", "/notes", `{"group": "` + group.Id +
+			`", "text": "hi", "latitude": 1, "longitude": 1}`, http.StatusNotFound},
+		{alice.AccessToken, "POST", "/notes", `{"recipients": ["` + bobId.String() + `", "` +
+			uuid.NewV4().String() + `"], "text": "hi", "latitude": 1, "longitude": 1}`, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		response := doAuthedRequest(s, c.token, c.method, c.path, c.body)
+		if response.Code != c.status {
+			t.Error(c.method, " ", c.path, ": expected ", c.status, ", got ", response.Code, " ", response.Body)
+		}
+	}
+
+	response = doAuthedRequest(s, alice.AccessToken, "POST", "/notes",
+		`{"group": "` + group.Id + `", "text": "hi both", "latitude": 1, "longitude": 1}`)
+	if response.Code != http.StatusCreated {
+		t.Fatal("Failed to send to group. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var note noteJson
+	if err := json.NewDecoder(response.Body).Decode(&note); err != nil {
+		t.Fatal("Failed to decode sent note. Err: ", err)
+	}
+	if len(note.Recipients) != 2 {
+		t.Fatal("Expected the group's members as recipients, got ", note)
+	}
+
+	// Bob reading and deleting it doesn't touch carol's copy.
+	for _, request := range []struct{ method, path string }{
+		{"POST", "/notes/" + note.Id + "/read"},
+		{"DELETE", "/notes/" + note.Id},
+	} {
+		response = doAuthedRequest(s, bob.AccessToken, request.method, request.path, "")
+		if response.Code != http.StatusNoContent {
+			t.Fatal(request.method, " ", request.path, " failed. Status: ", response.Code, " Body: ", response.Body)
+		}
+	}
+	if inbox := getNotes(t, s, bob.AccessToken, "/notes/inbox"); len(inbox) != 1 ||
+		!inbox[0].Read || !inbox[0].Deleted {
+		t.Fatal("Expected bob's copy to be read and deleted, got ", inbox)
+	}
+	if inbox := getNotes(t, s, carol.AccessToken, "/notes/inbox"); len(inbox) != 1 ||
+		inbox[0].Read || inbox[0].Deleted {
+		t.Fatal("Expected carol's copy to be untouched, got ", inbox)
+	}
+
+	response = doAuthedRequest(s, alice.AccessToken, "DELETE", "/groups/" + group.Id, "")
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to delete group. Status: ", response.Code, " Body: ", response.Body)
+	}
+	response = doAuthedRequest(s, alice.AccessToken, "GET", "/groups", "")
+	var groups groupsJson
+	if err := json.NewDecoder(response.Body).Decode(&groups); err != nil || len(groups.Groups) != 0 {
+		t.Fatal("Expected no groups left, got ", groups, " ", err)
+	}
+}
+
+func TestSharedNotes(t *testing.T) {
+	s := getTestServer()
+	aliceId, alice := signUp(t, s, "alice")
+	bobId, bob := signUp(t, s, "bob")
+	_, carol := signUp(t, s, "carol")
+	befriend(t, s, alice, bob)
+
+	cases := []struct {
+		body string
+		status int
+	}{
+		{`{"recipient": "` + bobId.String() + `", "visibility": "public", "text": "hi", "latitude": 1, "longitude": 1}`,
+			http.StatusBadRequest},
+		{`{"visibility": "everyone", "text": "hi", "latitude": 1, "longitude": 1}`, http.StatusBadRequest},
+		{`{"visibility": "private", "text": "hi", "latitude": 1, "longitude": 1}`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		response := doAuthedRequest(s, alice.AccessToken, "POST", "/notes", c.body)
+		if response.Code != c.status {
+			t.Error(c.body, ": expected ", c.status, ", got ", response.Code, " ", response.Body)
+		}
+	}
+
+	notes := make(map[string]noteJson)
+	for _, visibility := range []string{"public", "contacts"} {
+		response := doAuthedRequest(s, alice.AccessToken, "POST", "/notes",
+			`{"visibility": "` + visibility + `", "text": "hi", "latitude": 1, "longitude": 1}`)
+		if response.Code != http.StatusCreated {
+			t.Fatal("Failed to send ", visibility, " note. Status: ", response.Code, " Body: ", response.Body)
+		}
+		var note noteJson
+		if err := json.NewDecoder(response.Body).Decode(&note); err != nil {
+			t.Fatal("Failed to decode sent note. Err: ", err)
+		}
+		if note.Visibility != visibility || note.Recipient != "" {
+			t.Fatal("Unexpected ", visibility, " note: ", note)
+		}
+		notes[visibility] = note
+	}
+
+	nearbyPath := "/notes/nearby?latitude=1&longitude=1&radiusKm=1"
+	if found := getNotes(t, s, bob.AccessToken, nearbyPath); len(found) != 2 {
+		t.Fatal("Expected a contact to find both notes, got ", found)
+	}
+	if found := getNotes(t, s, carol.AccessToken, nearbyPath); len(found) != 1 ||
+		found[0].Id != notes["public"].Id {
+		t.Fatal("Expected a stranger to find only the public note, got ", found)
+	}
+
+	reads := []struct {
+		token string
+		note string
+		status int
+	}{
+		{carol.AccessToken, notes["contacts"].Id, http.StatusForbidden},
+		{alice.AccessToken, notes["public"].Id, http.StatusForbidden},
+		{carol.AccessToken, notes["public"].Id, http.StatusNoContent},
+		{carol.AccessToken, notes["public"].Id, http.StatusNoContent},
+	}
+	for _, read := range reads {
+		response := doAuthedRequest(s, read.token, "POST", "/notes/" + read.note + "/read", "")
+		if response.Code != read.status {
+			t.Error("read ", read.note, ": expected ", read.status, ", got ", response.Code, " ", response.Body)
+		}
+	}
+
+	// Reads are per viewer: carol reading the public note doesn't read it
+	// for bob.
+	if found := getNotes(t, s, carol.AccessToken, nearbyPath); len(found) != 1 || !found[0].Read {
+		t.Fatal("Expected the public note to be read for carol, got ", found)
+	}
+	for _, note := range getNotes(t, s, bob.AccessToken, nearbyPath) {
+		if note.Read {
+			t.Fatal("Expected nothing read for bob, got ", note)
+		}
+	}
+
+	response := doAuthedRequest(s, carol.AccessToken, "POST", "/contacts/" + aliceId.String() + "/block", ``)
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to block. Status: ", response.Code, " Body: ", response.Body)
+	}
+	if found := getNotes(t, s, carol.AccessToken, nearbyPath); len(found) != 0 {
+		t.Fatal("Expected a blocked sender's public notes to be hidden, got ", found)
+	}
+}
+
+func TestThreads(t *testing.T) {
+	s := getTestServer()
+	aliceId, alice := signUp(t, s, "alice")
+	bobId, bob := signUp(t, s, "bob")
+	_, carol := signUp(t, s, "carol")
+	befriend(t, s, alice, bob)
+	befriend(t, s, alice, carol)
+
+	root := sendNote(t, s, alice.AccessToken, bobId, "meet here?", 1, 1)
+	reply := func(token string, body string) noteJson {
+		response := doAuthedRequest(s, token, "POST", "/notes", body)
+		if response.Code != http.StatusCreated {
+			t.Fatal("Failed to reply. Status: ", response.Code, " Body: ", response.Body)
+		}
+		var note noteJson
+		if err := json.NewDecoder(response.Body).Decode(&note); err != nil {
+			t.Fatal("Failed to decode reply. Err: ", err)
+		}
+		return note
+	}
+	anchored := reply(bob.AccessToken, `{"parent": "` + root.Id + `", "text": "on my way"}`)
+	if anchored.Parent != root.Id || anchored.Thread != root.Thread || anchored.Latitude != 1 ||
+		anchored.Direct || anchored.Recipient != aliceId.String() {
+		t.Fatal("Expected an anchored reply to alice at the root's spot, got ", anchored)
+	}
+	direct := reply(alice.AccessToken, `{"parent": "` + anchored.Id +
+		`", "direct": true, "text": "see you", "latitude": 2, "longitude": 2}`)
+	if !direct.Direct || direct.Latitude != 2 || direct.Recipient != bobId.String() || direct.Thread != root.Id {
+		t.Fatal("Expected a direct reply to bob, got ", direct)
+	}
+
+	thread := getNotes(t, s, bob.AccessToken, "/notes/" + direct.Id + "/thread")
+	if len(thread) != 3 || thread[0].Id != root.Id || thread[1].Id != anchored.Id || thread[2].Id != direct.Id {
+		t.Fatal("Expected the thread in order, got ", thread)
+	}
+	if thread = getNotes(t, s, bob.AccessToken, "/notes/" + root.Id + "/thread?count=1&offset=1"); len(thread) != 1 ||
+		thread[0].Id != anchored.Id {
+		t.Fatal("Expected the second note of the thread, got ", thread)
+	}
+
+	cases := []struct {
+		method string
+		path string
+		body string
+		status int
+	}{
+		{"POST", "/notes", `{"parent": "` + root.Id + `", "recipient": "` + bobId.String() + `", "text": "hi"}`,
+			http.StatusBadRequest},
+		{"POST", "/notes", `{"parent": "` + root.Id + `", "visibility": "public", "text": "hi"}`, http.StatusBadRequest},
+		{"POST", "/notes", `{"recipient": "` + bobId.String() + `", "direct": true, "text": "hi", "latitude": 1, "longitude": 1}`,
+			http.StatusBadRequest},
+		{"POST", "/notes", `{"parent": "` + root.Id + `", "text": "hi"}`, http.StatusForbidden},
+		{"GET", "/notes/" + root.Id + "/thread", ``, http.StatusForbidden},
+		{"POST", "/notes/" + root.Id + "/thread", ``, http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		response := doAuthedRequest(s, carol.AccessToken, c.method, c.path, c.body)
+		if response.Code != c.status {
+			t.Error(c.method, " ", c.path, " ", c.body, ": expected ", c.status, ", got ", response.Code, " ", response.Body)
+		}
+	}
+
+	// Only anchored replies can do without coordinates.
+	response := doAuthedRequest(s, bob.AccessToken, "POST", "/notes",
+		`{"parent": "` + root.Id + `", "direct": true, "text": "hi"}`)
+	if response.Code != http.StatusBadRequest {
+		t.Fatal("Expected 400 for a direct reply without coordinates, got ", response.Code, " ", response.Body)
+	}
+
+	// A direct reply reaches bob far from where it was left; anchored
+	// notes don't.
+	farPath := "/notes/nearby?latitude=40&longitude=40&radiusKm=1"
+	if nearby := getNotes(t, s, bob.AccessToken, farPath); len(nearby) != 1 || nearby[0].Id != direct.Id {
+		t.Fatal("Expected only the direct reply far away, got ", nearby)
+	}
+	if nearby := getNotes(t, s, alice.AccessToken, farPath); len(nearby) != 0 {
+		t.Fatal("Expected nothing far away for alice, got ", nearby)
+	}
+
+	// bob deleting the root only hides it from him; deleting his own reply
+	// hides it from everyone.
+	for _, id := range []string{root.Id, anchored.Id} {
+		if response = doAuthedRequest(s, bob.AccessToken, "DELETE", "/notes/" + id, ``); response.Code != http.StatusNoContent {
+			t.Fatal("Failed to delete note. Status: ", response.Code, " Body: ", response.Body)
+		}
+	}
+	if thread = getNotes(t, s, bob.AccessToken, "/notes/" + direct.Id + "/thread"); len(thread) != 1 ||
+		thread[0].Id != direct.Id {
+		t.Fatal("Expected deleted notes left out of bob's thread, got ", thread)
+	}
+	if thread = getNotes(t, s, alice.AccessToken, "/notes/" + direct.Id + "/thread"); len(thread) != 2 ||
+		thread[0].Id != root.Id || thread[1].Id != direct.Id {
+		t.Fatal("Expected only the deleted reply left out of alice's thread, got ", thread)
+	}
+}
+
+func TestReadReceipts(t *testing.T) {
+	s := getTestServer()
+	_, alice := signUp(t, s, "alice")
+	bobId, bob := signUp(t, s, "bob")
+	carolId, carol := signUp(t, s, "carol")
+	befriend(t, s, alice, bob)
+	befriend(t, s, alice, carol)
+
+	response := doAuthedRequest(s, carol.AccessToken, "PUT", "/profile", `{"hideReadReceipts": true}`)
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), `"hideReadReceipts":true`) {
+		t.Fatal("Failed to hide read receipts. Status: ", response.Code, " Body: ", response.Body)
+	}
+	response = doAuthedRequest(s, alice.AccessToken, "POST", "/notes", `{"recipients": ["` + bobId.String() +
+		`", "` + carolId.String() + `"], "text": "hi both", "latitude": 1, "longitude": 2}`)
+	if response.Code != http.StatusCreated {
+		t.Fatal("Failed to send note. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var note noteJson
+	if err := json.NewDecoder(response.Body).Decode(&note); err != nil {
+		t.Fatal("Failed to decode sent note. Err: ", err)
+	}
+
+	cases := []struct {
+		token string
+		method string
+		path string
+		body string
+		status int
+	}{
+		{bob.AccessToken, "POST", "/notes/" + note.Id + "/read", `{"latitude": 1.001}`, http.StatusBadRequest},
+		{bob.AccessToken, "POST", "/notes/" + note.Id + "/read", `{"latitude": 91, "longitude": 2}`,
+			http.StatusBadRequest},
+		{bob.AccessToken, "POST", "/notes/" + note.Id + "/read", `{"latitude": 1.001, "longitude": 2}`,
+			http.StatusNoContent},
+		{carol.AccessToken, "POST", "/notes/" + note.Id + "/read", ``, http.StatusNoContent},
+		{bob.AccessToken, "GET", "/notes/" + note.Id + "/receipts", ``, http.StatusForbidden},
+		{alice.AccessToken, "POST", "/notes/" + note.Id + "/receipts", ``, http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		response := doAuthedRequest(s, c.token, c.method, c.path, c.body)
+		if response.Code != c.status {
+			t.Error(c.method, " ", c.path, " ", c.body, ": expected ", c.status, ", got ", response.Code, " ", response.Body)
+		}
+	}
+
+	response = doAuthedRequest(s, alice.AccessToken, "GET", "/notes/" + note.Id + "/receipts", "")
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to list receipts. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var receipts receiptsJson
+	if err := json.NewDecoder(response.Body).Decode(&receipts); err != nil {
+		t.Fatal("Failed to decode receipts. Err: ", err)
+	}
+	if len(receipts.Receipts) != 1 || receipts.Receipts[0].Reader != bobId.String() ||
+		!receipts.Receipts[0].ReadAt.Equal(s.now()) || *receipts.Receipts[0].Latitude != 1.001 {
+		t.Fatal("Expected only bob's receipt, with where he read it, got ", receipts)
+	}
+}
+
+func TestInboxSummary(t *testing.T) {
+	s := getTestServer()
+	aliceId, alice := signUp(t, s, "alice")
+	bobId, bob := signUp(t, s, "bob")
+	carolId, carol := signUp(t, s, "carol")
+	befriend(t, s, alice, bob)
+	befriend(t, s, carol, bob)
+	read := sendNote(t, s, alice.AccessToken, bobId, "first", 1, 2)
+	unread := sendNote(t, s, alice.AccessToken, bobId, "second", 1, 2)
+	sendNote(t, s, carol.AccessToken, bobId, "far away", 10, 10)
+	response := doAuthedRequest(s, bob.AccessToken, "POST", "/notes/" + read.Id + "/read", "")
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to mark read. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	cases := []struct {
+		token string
+		path string
+		status int
+	}{
+		{alice.AccessToken, "/notes/summary?recipient=" + bobId.String(), http.StatusForbidden},
+		{bob.AccessToken, "/notes/summary?latitude=1", http.StatusBadRequest},
+		{bob.AccessToken, "/notes/summary?latitude=1&longitude=2&radiusKm=500", http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		response := doAuthedRequest(s, c.token, "GET", c.path, "")
+		if response.Code != c.status {
+			t.Error(c.path, ": expected ", c.status, ", got ", response.Code, " ", response.Body)
+		}
+	}
+
+	summary := getSummary(t, s, bob.AccessToken, "/notes/summary?latitude=1&longitude=2&radiusKm=1")
+	expected := []senderCountJson{{aliceId.String(), 1, 2}, {carolId.String(), 1, 1}}
+	if summary.Unread != 2 || summary.Total != 3 || summary.UnreadNearby == nil || *summary.UnreadNearby != 1 ||
+		len(summary.Senders) != 2 || summary.Senders[0] != expected[0] || summary.Senders[1] != expected[1] {
+		t.Fatal("Unexpected summary: ", summary)
+	}
+
+	response = doAuthedRequest(s, bob.AccessToken, "DELETE", "/notes/" + unread.Id, "")
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to delete note. Status: ", response.Code, " Body: ", response.Body)
+	}
+	summary = getSummary(t, s, bob.AccessToken, "/notes/summary")
+	if summary.Unread != 1 || summary.Total != 2 || summary.UnreadNearby != nil {
+		t.Fatal("Expected the deleted note not to be counted, and nothing nearby without a point, got: ", summary)
+	}
+}
+
+func TestEditNote(t *testing.T) {
+	s := getTestServer()
+	_, alice := signUp(t, s, "alice")
+	bobId, bob := signUp(t, s, "bob")
+	befriend(t, s, alice, bob)
+	note := sendNote(t, s, alice.AccessToken, bobId, "Meet at noon", 1, 2)
+
+	cases := []struct {
+		token string
+		method string
+		path string
+		body string
+		status int
+	}{
+		{bob.AccessToken, "PUT", "/notes/" + note.Id, `{"text": "mine now"}`, http.StatusForbidden},
+		{alice.AccessToken, "PUT", "/notes/" + note.Id, `{"text": ""}`, http.StatusBadRequest},
+		{alice.AccessToken, "PUT", "/notes/" + uuid.NewV4().String(), `{"text": "hi"}`, http.StatusNotFound},
+		{bob.AccessToken, "GET", "/notes/" + note.Id + "/versions", ``, http.StatusForbidden},
+		{alice.AccessToken, "POST", "/notes/" + note.Id + "/versions", ``, http.StatusMethodNotAllowed},
+	}
+	for _, c := range cases {
+		response := doAuthedRequest(s, c.token, c.method, c.path, c.body)
+		if response.Code != c.status {
+			t.Error(c.method, " ", c.path, " ", c.body, ": expected ", c.status, ", got ", response.Code, " ", response.Body)
+		}
+	}
+
+	response := doAuthedRequest(s, alice.AccessToken, "PUT", "/notes/" + note.Id, `{"text": "Meet at one"}`)
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to edit note. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var edited noteJson
+	if err := json.NewDecoder(response.Body).Decode(&edited); err != nil {
+		t.Fatal("Failed to decode edited note. Err: ", err)
+	}
+	if edited.Text != "Meet at one" || edited.EditedAt == nil || !edited.EditedAt.Equal(s.now()) {
+		t.Fatal("Expected the edited note back, got ", edited)
+	}
+	doc, err := s.index.GetDoc(uuid.FromStringOrNil(note.Id))
+	if err != nil || doc == nil || !doc.TimeEdited().Equal(s.now()) {
+		t.Fatal("Expected the note to be reindexed with its edit, got ", doc, " ", err)
+	}
+
+	response = doAuthedRequest(s, alice.AccessToken, "GET", "/notes/" + note.Id + "/versions", "")
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to list versions. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var versions versionsJson
+	if err := json.NewDecoder(response.Body).Decode(&versions); err != nil {
+		t.Fatal("Failed to decode versions. Err: ", err)
+	}
+	if len(versions.Versions) != 1 || versions.Versions[0].Text != "Meet at noon" {
+		t.Fatal("Expected the original text as the only version, got ", versions)
+	}
+
+	response = doAuthedRequest(s, bob.AccessToken, "POST", "/notes/" + note.Id + "/read", "")
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to mark read. Status: ", response.Code, " Body: ", response.Body)
+	}
+	response = doAuthedRequest(s, alice.AccessToken, "PUT", "/notes/" + note.Id, `{"text": "too late"}`)
+	if response.Code != http.StatusConflict {
+		t.Fatal("Expected 409 editing a read note, got ", response.Code, " ", response.Body)
+	}
+	if notes := getNotes(t, s, alice.AccessToken, "/notes/outbox"); len(notes) != 1 ||
+		notes[0].Text != "Meet at one" {
+		t.Fatal("Expected the note to keep its edit, got ", notes)
+	}
+}
+
+func TestEditNoteReindexFailure(t *testing.T) {
+	s := getTestServer()
+	_, alice := signUp(t, s, "alice")
+	bobId, bob := signUp(t, s, "bob")
+	befriend(t, s, alice, bob)
+	note := sendNote(t, s, alice.AccessToken, bobId, "Meet at noon", 1, 2)
+	s.index = &brokenSolr{s.index.(*solrnotes.MemorySolr)}
+
+	response := doAuthedRequest(s, alice.AccessToken, "PUT", "/notes/" + note.Id, `{"text": "Meet at one"}`)
+	if response.Code != http.StatusOK {
+		t.Fatal("Expected the edit to stand when reindexing fails, got ", response.Code, " ", response.Body)
+	}
+	var edited noteJson
+	if err := json.NewDecoder(response.Body).Decode(&edited); err != nil {
+		t.Fatal("Failed to decode edited note. Err: ", err)
+	}
+	if edited.Text != "Meet at one" || edited.EditedAt == nil {
+		t.Fatal("Expected the edited note back, got ", edited)
+	}
+	if notes := getNotes(t, s, bob.AccessToken, "/notes/inbox"); len(notes) != 1 ||
+		notes[0].Text != "Meet at one" {
+		t.Fatal("Expected bob to see the edit, got ", notes)
+	}
+}
+
+// brokenSolr fails every AddDoc, as when Solr is down.
+type brokenSolr struct {
+	*solrnotes.MemorySolr
+}
+
+func (sc *brokenSolr) AddDoc(doc solrnotes.Document) error {
+	return errors.New("solr is down")
+}
+
+func TestAttachments(t *testing.T) {
+	s := getTestServer()
+	_, alice := signUp(t, s, "alice")
+	bobId, bob := signUp(t, s, "bob")
+	_, carol := signUp(t, s, "carol")
+	befriend(t, s, alice, bob)
+	note := sendNote(t, s, alice.AccessToken, bobId, "look", 1, 2)
+
+	var photo bytes.Buffer
+	if err := png.Encode(&photo, image.NewGray(image.Rect(0, 0, 300, 600))); err != nil {
+		t.Fatal(err)
+	}
+	upload := func(token string, contentType string, body []byte) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("POST", "/notes/" + note.Id + "/attachments", bytes.NewReader(body))
+		request.Header.Set("Authorization", "Bearer " + token)
+		request.Header.Set("Content-Type", contentType)
+		response := httptest.NewRecorder()
+		s.routes().ServeHTTP(response, request)
+		return response
+	}
+
+	cases := []struct {
+		name string
+		token string
+		contentType string
+		body []byte
+		status int
+	}{
+		{"not the sender", bob.AccessToken, "image/png", photo.Bytes(), http.StatusForbidden},
+		{"unsupported type", alice.AccessToken, "text/plain", []byte("hi"), http.StatusUnsupportedMediaType},
+		{"mismatched content", alice.AccessToken, "image/png", []byte("<html></html>"), http.StatusBadRequest},
+		{"too large", alice.AccessToken, "audio/mpeg", make([]byte, attachments.MAX_ATTACHMENT_BYTES + 1),
+			http.StatusRequestEntityTooLarge},
+	}
+	for _, c := range cases {
+		if response := upload(c.token, c.contentType, c.body); response.Code != c.status {
+			t.Error(c.name, ": expected ", c.status, ", got ", response.Code, " ", response.Body)
+		}
+	}
+
+	response := upload(alice.AccessToken, "image/png", photo.Bytes())
+	if response.Code != http.StatusCreated {
+		t.Fatal("Failed to add attachment. Status: ", response.Code, " Body: ", response.Body)
+	}
+	var added attachmentJson
+	if err := json.NewDecoder(response.Body).Decode(&added); err != nil {
+		t.Fatal("Failed to decode attachment. Err: ", err)
+	}
+	if added.Note != note.Id || added.Size != int64(photo.Len()) || !added.Thumbnail {
+		t.Fatal("Unexpected attachment: ", added)
+	}
+
+	response = doAuthedRequest(s, bob.AccessToken, "GET", "/notes/" + note.Id + "/attachments", "")
+	if response.Code != http.StatusOK || !strings.Contains(response.Body.String(), added.Id) {
+		t.Fatal("Expected bob to see the attachment. Status: ", response.Code, " Body: ", response.Body)
+	}
+	response = doAuthedRequest(s, carol.AccessToken, "GET", "/notes/" + note.Id + "/attachments", "")
+	if response.Code != http.StatusForbidden {
+		t.Fatal("Expected 403 for someone who can't see the note, got: ", response.Code)
+	}
+
+	response = doAuthedRequest(s, bob.AccessToken, "GET", "/attachments/" + added.Id, "")
+	if response.Code != http.StatusOK || response.Header().Get("Content-Type") != "image/png" ||
+		!bytes.Equal(response.Body.Bytes(), photo.Bytes()) {
+		t.Fatal("Expected bob to get the photo. Status: ", response.Code)
+	}
+	response = doAuthedRequest(s, bob.AccessToken, "GET", "/attachments/" + added.Id + "/thumbnail", "")
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to get thumbnail. Status: ", response.Code, " Body: ", response.Body)
+	}
+	thumbnail, err := png.Decode(response.Body)
+	if err != nil || thumbnail.Bounds().Dy() != attachments.THUMBNAIL_SIZE {
+		t.Fatal("Expected a thumbnail ", attachments.THUMBNAIL_SIZE, " high, got: ", err)
+	}
+	response = doAuthedRequest(s, carol.AccessToken, "GET", "/attachments/" + added.Id, "")
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected 404 for someone who can't see the note, got: ", response.Code)
+	}
+
+	if err = s.notes.PurgeNote(uuid.FromStringOrNil(note.Id)); err != nil {
+		t.Fatal("Failed to purge note. Err: ", err)
+	}
+	response = doAuthedRequest(s, bob.AccessToken, "GET", "/attachments/" + added.Id, "")
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected the attachment to be purged with the note, got: ", response.Code)
+	}
+}
+
+func TestExport(t *testing.T) {
+	s := getTestServer()
+	_, sender := signUp(t, s, "sender")
+	recipientId, recipient := signUp(t, s, "recipient")
+	befriend(t, s, sender, recipient)
+	sendNote(t, s, sender.AccessToken, recipientId, "Look up!", 40.810260, -73.94694)
+	sendNote(t, s, sender.AccessToken, recipientId, "Again", 40.810260, -73.94694)
+
+	response := doRequest(s, "GET", "/export", "")
+	if response.Code != http.StatusUnauthorized {
+		t.Fatal("Expected 401 without a token, got: ", response.Code)
+	}
+
+	response = doAuthedRequest(s, recipient.AccessToken, "GET", "/export", "")
+	if response.Code != http.StatusOK || response.Header().Get("Content-Type") != "application/zip" {
+		t.Fatal("Failed to export. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	body := response.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		t.Fatal("Export isn't a zip archive. Err: ", err)
+	}
+	for _, file := range archive.File {
+		if file.Name != export.NOTES_FILE {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal("Failed to open notes. Err: ", err)
+		}
+		defer f.Close()
+		var notes []noteJson
+		if err = json.NewDecoder(f).Decode(&notes); err != nil || len(notes) != 2 {
+			t.Fatal("Expected the recipient's 2 notes, got: ", notes, " ", err)
+		}
+		return
+	}
+	t.Fatal("No notes in the export.")
+}
+
+func getTestServer() *server {
+	manager, err := sessions.NewManager(
+		sessions.NewMemorySessions(), []byte(strings.Repeat("k", sessions.MIN_KEY_LEN)), sessions.Options{})
+	if err != nil {
+		panic(err)
+	}
+
+	users := userdb.NewMemoryUserdb()
+	twoFactor := userdb.NewTwoFactor(users, users)
+	guard := lockout.NewGuard(twoFactor, lockout.NewMemoryLoginEvents(), lockout.DEFAULT_POLICY)
+	resetter := userdb.NewResetter(users, users, userdb.LogResetSink{}, 0)
+	files := attachments.NewAttachments(attachments.NewMemoryAttachments(), attachments.NewMemoryBlobStore())
+	s := newServer(users, attachments.NewNotesdb(notesdb.NewMemoryNotesdb(), files), solrnotes.NewMemorySolr(),
+		manager, guard, resetter, twoFactor, contacts.NewContacts(contacts.NewMemoryContacts()), files, nil)
+	s.now = func() time.Time {
+		return time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	}
+	return s
+}
+
+func doRequest(s *server, method string, path string, body string) *httptest.ResponseRecorder {
+	return doAuthedRequest(s, "", method, path, body)
+}
+
+// doAuthedRequest sends token as a bearer token, unless it's empty.
+func doAuthedRequest(
+	s *server,
+	token string,
+	method string,
+	path string,
+	body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		request.Header.Set("Authorization", "Bearer " + token)
+	}
+	response := httptest.NewRecorder()
+	s.routes().ServeHTTP(response, request)
+	return response
+}
+
+// signUp registers username with a fixed password and logs them in.
+func signUp(t *testing.T, s *server, username string) (uuid.UUID, tokensJson) {
+	response := doRequest(s, "POST", "/users", credentialsBody(username))
+	if response.Code != http.StatusCreated {
+		t.Fatal("Failed to register. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	tokens := login(t, s, username)
+	id, err := uuid.FromString(tokens.Id)
+	if err != nil {
+		t.Fatal("Login returned a bad id. Err: ", err)
+	}
+	return id, tokens
+}
+
+// befriend makes two signed-up users contacts, so they can leave each
+// other notes.
+func befriend(t *testing.T, s *server, requester tokensJson, accepter tokensJson) {
+	response := doAuthedRequest(s, requester.AccessToken, "POST", "/contacts/" + accepter.Id + "/request", "")
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to request contact. Status: ", response.Code, " Body: ", response.Body)
+	}
+	response = doAuthedRequest(s, accepter.AccessToken, "POST", "/contacts/" + requester.Id + "/accept", "")
+	if response.Code != http.StatusNoContent {
+		t.Fatal("Failed to accept contact. Status: ", response.Code, " Body: ", response.Body)
+	}
+}
+
+func login(t *testing.T, s *server, username string) tokensJson {
+	response := doRequest(s, "POST", "/login", credentialsBody(username))
+	if response.Code != http.StatusOK {
+		t.Fatal("Failed to log in. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	var tokens tokensJson
+	if err := json.NewDecoder(response.Body).Decode(&tokens); err != nil {
+		t.Fatal("Failed to decode tokens. Err: ", err)
+	}
+	return tokens
+}
+
+func credentialsBody(username string) string {
+	return `{"username": "` + username + `", "password": "password"}`
+}
+
+func refreshBody(refreshToken string) string {
+	return `{"refreshToken": "` + refreshToken + `"}`
+}
+
+func sendNoteBody(
+	sender uuid.UUID,
+	recipient uuid.UUID,
+	text string,
+	latitude float64,
+	longitude float64) string {
+	var buffer bytes.Buffer
+	json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"sender": sender.String(),
+		"recipient": recipient.String(),
+		"text": text,
+		"latitude": latitude,
+		"longitude": longitude,
+	})
+	return buffer.String()
+}
+
+// sendNote sends a note as whoever token belongs to.
+func sendNote(
+	t *testing.T,
+	s *server,
+	token string,
+	recipient uuid.UUID,
+	text string,
+	latitude float64,
+	longitude float64) noteJson {
+	var buffer bytes.Buffer
+	json.NewEncoder(&buffer).Encode(map[string]interface{}{
+		"recipient": recipient.String(),
+		"text": text,
+		"latitude": latitude,
+		"longitude": longitude,
+	})
+
+	response := doAuthedRequest(s, token, "POST", "/notes", buffer.String())
+	if response.Code != http.StatusCreated {
+		t.Fatal("Failed to send note. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	var note noteJson
+	if err := json.NewDecoder(response.Body).Decode(&note); err != nil {
+		t.Fatal("Failed to decode sent note. Err: ", err)
+	}
+	return note
+}
+
+func getNotes(t *testing.T, s *server, token string, path string) []noteJson {
+	response := doAuthedRequest(s, token, "GET", path, "")
+	if response.Code != http.StatusOK {
+		t.Fatal("GET ", path, " failed. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	var notes notesJson
+	if err := json.NewDecoder(response.Body).Decode(&notes); err != nil {
+		t.Fatal("Failed to decode notes. Err: ", err)
+	}
+	return notes.Notes
+}
+
+func getSummary(t *testing.T, s *server, token string, path string) summaryJson {
+	response := doAuthedRequest(s, token, "GET", path, "")
+	if response.Code != http.StatusOK {
+		t.Fatal("GET ", path, " failed. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	var summary summaryJson
+	if err := json.NewDecoder(response.Body).Decode(&summary); err != nil {
+		t.Fatal("Failed to decode summary. Err: ", err)
+	}
+	return summary
+}
+
+func getContacts(t *testing.T, s *server, token string, path string) []contactJson {
+	response := doAuthedRequest(s, token, "GET", path, "")
+	if response.Code != http.StatusOK {
+		t.Fatal("GET ", path, " failed. Status: ", response.Code, " Body: ", response.Body)
+	}
+
+	var result contactsJson
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		t.Fatal("Failed to decode contacts. Err: ", err)
+	}
+	return result.Contacts
+}
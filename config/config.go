@@ -0,0 +1,281 @@
+package config
+
+import (
+	"errors"
+	"log"
+	"io/ioutil"
+	"time"
+
+	"github.com/go-yaml/yaml"
+
+	"github.com/dbenny42/geonote/attachments"
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/solrnotes"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+// Config holds the connection settings shared by the geonote binaries.
+// It's read from YAML, e.g.
+//
+//	listen: ":8080"
+//	grpcListen: ":8081"
+//	mysql:
+//	  user: geonote
+//	  password: secret
+//	  host: localhost
+//	  port: "3306"
+//	solr:
+//	  host: localhost
+//	  port: 8983
+//	  core: geonotes
+//	sessions:
+//	  key: <at least 32 random bytes>
+//	  accessTtl: 15m
+//	  refreshTtl: 720h
+//	passwordReset:
+//	  tokenFile: /var/spool/geonote/reset-tokens
+//	  tokenTtl: 1h
+//	credentials:
+//	  minPasswordLen: 10
+//	  breachedPasswordsFile: /etc/geonote/breached-passwords.txt
+//	  reservedNames: [admin, root, geonote]
+//	attachments:
+//	  dir: /var/lib/geonote/attachments
+type Config struct {
+	Listen string `yaml:"listen"`
+
+	// GrpcListen is where geonoted serves gRPC. It's left off if empty.
+	GrpcListen string `yaml:"grpcListen"`
+
+	Mysql MysqlConfig `yaml:"mysql"`
+	Solr SolrConfig `yaml:"solr"`
+	Sessions SessionsConfig `yaml:"sessions"`
+	PasswordReset PasswordResetConfig `yaml:"passwordReset"`
+	Credentials CredentialsConfig `yaml:"credentials"`
+	Attachments AttachmentsConfig `yaml:"attachments"`
+}
+
+type MysqlConfig struct {
+	User string `yaml:"user"`
+	Password string `yaml:"password"`
+	Host string `yaml:"host"`
+	Port string `yaml:"port"`
+}
+
+type SolrConfig struct {
+	Host string `yaml:"host"`
+	Port int `yaml:"port"`
+	Core string `yaml:"core"`
+}
+
+// SessionsConfig sets how login sessions are signed and how long they
+// last. Key must be kept secret; changing it logs everyone out once their
+// access tokens expire.
+type SessionsConfig struct {
+	Key string `yaml:"key"`
+	AccessTtl time.Duration `yaml:"accessTtl"`
+	RefreshTtl time.Duration `yaml:"refreshTtl"`
+}
+
+// PasswordResetConfig sets where reset tokens are delivered. Tokens are
+// appended to TokenFile for some other process to send on; if it's empty
+// they're only logged, which is fine for development and nothing else.
+type PasswordResetConfig struct {
+	TokenFile string `yaml:"tokenFile"`
+	TokenTtl time.Duration `yaml:"tokenTtl"`
+}
+
+// CredentialsConfig overrides parts of userdb.DEFAULT_CREDENTIAL_POLICY.
+// Zero values keep the default; ReservedNames replaces the default list
+// rather than adding to it.
+type CredentialsConfig struct {
+	MinUsernameLen int `yaml:"minUsernameLen"`
+	MinPasswordLen int `yaml:"minPasswordLen"`
+	ReservedNames []string `yaml:"reservedNames"`
+
+	// BreachedPasswordsFile has one breached password per line.
+	BreachedPasswordsFile string `yaml:"breachedPasswordsFile"`
+}
+
+// AttachmentsConfig sets where attachment content is kept. Dir is made
+// if it doesn't exist, and must be shared by every geonoted that serves
+// the same database.
+type AttachmentsConfig struct {
+	Dir string `yaml:"dir"`
+}
+
+const (
+	DEFAULT_LISTEN = ":8080"
+	DEFAULT_ATTACHMENTS_DIR = "/var/lib/geonote/attachments"
+)
+
+// Load reads a config file, filling in defaults for anything left unset.
+func Load(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		log.Printf("Failed to open config file %v. Err: %v", filename, err)
+		return nil, err
+	}
+
+	var config Config
+	if err = yaml.Unmarshal(data, &config); err != nil {
+		log.Printf("Failed to parse config file %v. Err: %v", filename, err)
+		return nil, err
+	}
+
+	if config.Listen == "" {
+		config.Listen = DEFAULT_LISTEN
+	}
+	if config.Solr.Host == "" {
+		config.Solr.Host = solrnotes.DEFAULT_HOST
+	}
+	if config.Solr.Port == 0 {
+		config.Solr.Port = solrnotes.DEFAULT_PORT
+	}
+	if config.Solr.Core == "" {
+		config.Solr.Core = solrnotes.DEFAULT_CORE
+	}
+	if config.Attachments.Dir == "" {
+		config.Attachments.Dir = DEFAULT_ATTACHMENTS_DIR
+	}
+
+	return &config, nil
+}
+
+func (c *Config) OpenNotesdb() (*notesdb.MysqlNotesdb, error) {
+	return notesdb.NewMysqlNotesdb(&notesdb.DbCredentials{
+		User: c.Mysql.User,
+		Password: c.Mysql.Password,
+		Host: c.Mysql.Host,
+		Port: c.Mysql.Port,
+	})
+}
+
+// OpenUserdb connects to the users database, with the configured
+// credential policy.
+func (c *Config) OpenUserdb() (*userdb.MysqlUserdb, error) {
+	policy, err := c.CredentialPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := userdb.NewMysqlUserdb(&userdb.DbCredentials{
+		User: c.Mysql.User,
+		Password: c.Mysql.Password,
+		Host: c.Mysql.Host,
+		Port: c.Mysql.Port,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users.SetCredentialPolicy(policy)
+	return users, nil
+}
+
+// CredentialPolicy returns userdb's default policy with the configured
+// overrides, loading the breached passwords file if there is one.
+func (c *Config) CredentialPolicy() (userdb.CredentialPolicy, error) {
+	policy := userdb.DEFAULT_CREDENTIAL_POLICY
+	if c.Credentials.MinUsernameLen != 0 {
+		policy.MinUsernameLen = c.Credentials.MinUsernameLen
+	}
+	if c.Credentials.MinPasswordLen != 0 {
+		policy.MinPasswordLen = c.Credentials.MinPasswordLen
+	}
+	if c.Credentials.ReservedNames != nil {
+		policy.ReservedNames = c.Credentials.ReservedNames
+	}
+
+	if c.Credentials.BreachedPasswordsFile != "" {
+		breached, err := userdb.LoadBreachedPasswords(c.Credentials.BreachedPasswordsFile)
+		if err != nil {
+			return policy, err
+		}
+		policy.BreachedPasswords = breached
+	}
+
+	return policy, nil
+}
+
+// NewResetter returns a password resetter that stores its tokens in the
+// users database and shares its credential policy.
+func (c *Config) NewResetter(users *userdb.MysqlUserdb) *userdb.Resetter {
+	var sink userdb.ResetSink = userdb.LogResetSink{}
+	if c.PasswordReset.TokenFile != "" {
+		sink = &userdb.FileResetSink{Filename: c.PasswordReset.TokenFile}
+	}
+
+	resetter := userdb.NewResetter(users, users, sink, c.PasswordReset.TokenTtl)
+	resetter.SetCredentialPolicy(users.CredentialPolicy())
+	return resetter
+}
+
+func (c *Config) OpenLoginEvents() (*lockout.MysqlLoginEvents, error) {
+	return lockout.NewMysqlLoginEvents(&lockout.DbCredentials{
+		User: c.Mysql.User,
+		Password: c.Mysql.Password,
+		Host: c.Mysql.Host,
+		Port: c.Mysql.Port,
+	})
+}
+
+func (c *Config) OpenContacts() (*contacts.MysqlContacts, error) {
+	return contacts.NewMysqlContacts(&contacts.DbCredentials{
+		User: c.Mysql.User,
+		Password: c.Mysql.Password,
+		Host: c.Mysql.Host,
+		Port: c.Mysql.Port,
+	})
+}
+
+// OpenAttachments returns attachments with their metadata in MySQL and
+// their content under the configured directory.
+func (c *Config) OpenAttachments() (*attachments.Attachments, error) {
+	store, err := attachments.NewMysqlAttachments(&attachments.DbCredentials{
+		User: c.Mysql.User,
+		Password: c.Mysql.Password,
+		Host: c.Mysql.Host,
+		Port: c.Mysql.Port,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	blobs, err := attachments.NewLocalBlobStore(c.Attachments.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return attachments.NewAttachments(store, blobs), nil
+}
+
+func (c *Config) OpenSolr() (*solrnotes.SolrNoteConnection, error) {
+	return solrnotes.NewSolrNoteConnectionToCore(c.Solr.Host, c.Solr.Port, c.Solr.Core)
+}
+
+// OpenSessions returns a session manager backed by MySQL. It fails if no
+// signing key is configured, rather than sign tokens with an empty key.
+func (c *Config) OpenSessions() (*sessions.Manager, error) {
+	if c.Sessions.Key == "" {
+		return nil, errors.New("No sessions key configured.")
+	}
+
+	store, err := sessions.NewMysqlSessions(&sessions.DbCredentials{
+		User: c.Mysql.User,
+		Password: c.Mysql.Password,
+		Host: c.Mysql.Host,
+		Port: c.Mysql.Port,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions.NewManager(store, []byte(c.Sessions.Key), sessions.Options{
+		AccessTtl: c.Sessions.AccessTtl,
+		RefreshTtl: c.Sessions.RefreshTtl,
+	})
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+func TestLoadFillsDefaults(t *testing.T) {
+	file, err := ioutil.TempFile("", "geonote-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	contents := "mysql:\n" +
+		"  user: geonote\n" +
+		"  password: secret\n" +
+		"  host: db.example.com\n" +
+		"  port: \"3306\"\n" +
+		"solr:\n" +
+		"  core: rebuilt\n" +
+		"sessions:\n" +
+		"  key: 0123456789abcdef0123456789abcdef\n" +
+		"  accessTtl: 5m\n"
+	if _, err = file.WriteString(contents); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	config, err := Load(file.Name())
+	if err != nil {
+		t.Fatal("Failed to load config. Err: ", err)
+	}
+
+	if config.Mysql.User != "geonote" || config.Mysql.Host != "db.example.com" ||
+		config.Mysql.Port != "3306" {
+		t.Fatal("Mysql settings were not read: ", config.Mysql)
+	}
+
+	if config.Solr.Host != "localhost" || config.Solr.Port != 8983 || config.Solr.Core != "rebuilt" {
+		t.Fatal("Unexpected solr settings: ", config.Solr)
+	}
+
+	if config.Sessions.AccessTtl != 5 * time.Minute || config.Sessions.RefreshTtl != 0 {
+		t.Fatal("Unexpected session settings: ", config.Sessions)
+	}
+
+	if config.Listen != DEFAULT_LISTEN {
+		t.Fatal("Listen address was not defaulted: ", config.Listen)
+	}
+	if config.Attachments.Dir != DEFAULT_ATTACHMENTS_DIR {
+		t.Fatal("Attachments directory was not defaulted: ", config.Attachments.Dir)
+	}
+}
@@ -0,0 +1,627 @@
+package contacts
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// STATUS_PENDING is a request the user sent that hasn't been answered.
+	STATUS_PENDING = "pending"
+	// STATUS_ACCEPTED is always stored in both directions.
+	STATUS_ACCEPTED = "accepted"
+	STATUS_BLOCKED = "blocked"
+
+	// MAX_MUTED is how many users one user can mute, which keeps the
+	// filter on their notes a sensible size.
+	MAX_MUTED = 500
+
+	// MAX_CONTACT_IDS is how many ids ContactIds returns. It bounds the
+	// filters built from them; a user with more contacts than this won't
+	// find every contacts note nearby.
+	MAX_CONTACT_IDS = 1000
+)
+
+var (
+	ErrSelf = errors.New("You can't be your own contact.")
+	ErrAlreadyContacts = errors.New("Already contacts.")
+	ErrNoRequest = errors.New("No such contact request.")
+	ErrNotContacts = errors.New("Not a contact.")
+	ErrNotBlocked = errors.New("User isn't blocked.")
+	ErrBlocked = errors.New("You have blocked this user; unblock them first.")
+	ErrNotMuted = errors.New("User isn't muted.")
+	ErrTooManyMuted = errors.New("Too many muted users; unmute some first.")
+)
+
+// Contact is a row in the contacts table: how UserId stands towards
+// ContactId. Each user of a pair has their own row, so that one of them
+// blocking the other doesn't need the other's row to change.
+type Contact struct {
+	UserId uuid.UUID
+	ContactId uuid.UUID
+	Status string
+	UpdatedAt time.Time
+}
+
+// Mute is a row in the mutes table. Unlike a block, a mute doesn't
+// change whether MutedId can leave notes for UserId; their notes are
+// just left out of UserId's inbox and nearby results. It's independent
+// of contacts, so a contact can be muted without being removed.
+type Mute struct {
+	UserId uuid.UUID
+	MutedId uuid.UUID
+	MutedAt time.Time
+}
+
+type ContactConnection interface {
+	// GetContact returns userId's row for contactId, or nil if there is
+	// none.
+	GetContact(userId uuid.UUID, contactId uuid.UUID) (*Contact, error)
+
+	// PutContacts inserts or replaces the rows together.
+	PutContacts(contacts []*Contact) error
+
+	// DeleteContact is idempotent; deleting a missing row isn't an error.
+	DeleteContact(userId uuid.UUID, contactId uuid.UUID) error
+
+	// ListContacts returns userId's rows with the given status, newest
+	// first.
+	ListContacts(userId uuid.UUID, status string, count int, offset int) ([]*Contact, error)
+
+	// ListIncomingRequests returns the pending rows whose ContactId is
+	// userId, newest first, leaving out those from users userId has
+	// blocked.
+	ListIncomingRequests(userId uuid.UUID, count int, offset int) ([]*Contact, error)
+
+	// PutMute is idempotent; muting again keeps the original MutedAt.
+	PutMute(mute *Mute) error
+
+	// DeleteMute reports whether there was a mute to delete.
+	DeleteMute(userId uuid.UUID, mutedId uuid.UUID) (bool, error)
+
+	// ListMutes returns userId's mutes, newest first.
+	ListMutes(userId uuid.UUID, count int, offset int) ([]*Mute, error)
+
+	// GetGroup returns the group, or nil if there is none.
+	GetGroup(id uuid.UUID) (*Group, error)
+
+	// ListGroups returns ownerId's groups, by name.
+	ListGroups(ownerId uuid.UUID) ([]*Group, error)
+
+	// PutGroup inserts or replaces the group, members and all.
+	PutGroup(group *Group) error
+
+	// DeleteGroup is idempotent; deleting a missing group isn't an error.
+	DeleteGroup(id uuid.UUID) error
+
+	// DeleteUserContacts deletes every contact row and mute on either
+	// side of userId, userId's groups and their place in anyone else's,
+	// and returns how many rows it deleted.
+	DeleteUserContacts(userId uuid.UUID) (int, error)
+}
+
+// Contacts manages who is whose contact, and whom each user has blocked
+// or muted. Notes can only be left for accepted contacts; see CanSend.
+//
+// A request to someone who has blocked the sender is stored as usual but
+// never shown to them, so the sender can't tell they've been blocked.
+type Contacts struct {
+	store ContactConnection
+	now func() time.Time
+}
+
+func NewContacts(store ContactConnection) *Contacts {
+	return &Contacts{store: store, now: time.Now}
+}
+
+// Request asks to, on behalf of from, to become contacts. If to had
+// already asked from, they become contacts straight away. Asking again
+// while a request is pending does nothing.
+func (c *Contacts) Request(from uuid.UUID, to uuid.UUID) error {
+	if from == to {
+		return ErrSelf
+	}
+
+	mine, err := c.store.GetContact(from, to)
+	if err != nil {
+		return err
+	}
+	if mine != nil {
+		switch mine.Status {
+		case STATUS_ACCEPTED:
+			return ErrAlreadyContacts
+		case STATUS_BLOCKED:
+			return ErrBlocked
+		case STATUS_PENDING:
+			return nil
+		}
+	}
+
+	theirs, err := c.store.GetContact(to, from)
+	if err != nil {
+		return err
+	}
+	if theirs != nil && theirs.Status == STATUS_PENDING {
+		return c.accept(from, to)
+	}
+
+	return c.store.PutContacts([]*Contact{c.contact(from, to, STATUS_PENDING)})
+}
+
+// Accept answers requester's pending request to user.
+func (c *Contacts) Accept(user uuid.UUID, requester uuid.UUID) error {
+	if err := c.requirePendingRequest(user, requester); err != nil {
+		return err
+	}
+	return c.accept(user, requester)
+}
+
+// Decline drops requester's pending request to user. The requester isn't
+// told, and can ask again.
+func (c *Contacts) Decline(user uuid.UUID, requester uuid.UUID) error {
+	if err := c.requirePendingRequest(user, requester); err != nil {
+		return err
+	}
+	return c.store.DeleteContact(requester, user)
+}
+
+// Remove ends user's contact with other for both of them, or withdraws
+// user's pending request to other.
+func (c *Contacts) Remove(user uuid.UUID, other uuid.UUID) error {
+	mine, err := c.store.GetContact(user, other)
+	if err != nil {
+		return err
+	}
+	if mine == nil || mine.Status == STATUS_BLOCKED {
+		return ErrNotContacts
+	}
+
+	if err = c.store.DeleteContact(user, other); err != nil {
+		return err
+	}
+	if mine.Status != STATUS_ACCEPTED {
+		return nil
+	}
+
+	theirs, err := c.store.GetContact(other, user)
+	if err != nil {
+		return err
+	}
+	if theirs != nil && theirs.Status == STATUS_ACCEPTED {
+		return c.store.DeleteContact(other, user)
+	}
+	return nil
+}
+
+// Block stops other from leaving notes for or sending requests to user.
+// It ends any contact or request between them. Blocking someone who has
+// also blocked user leaves their block in place.
+func (c *Contacts) Block(user uuid.UUID, other uuid.UUID) error {
+	if user == other {
+		return ErrSelf
+	}
+
+	if err := c.store.PutContacts([]*Contact{c.contact(user, other, STATUS_BLOCKED)}); err != nil {
+		return err
+	}
+
+	theirs, err := c.store.GetContact(other, user)
+	if err != nil {
+		return err
+	}
+	if theirs != nil && theirs.Status != STATUS_BLOCKED {
+		return c.store.DeleteContact(other, user)
+	}
+	return nil
+}
+
+// Unblock lifts user's block on other. They aren't contacts again until
+// one of them asks.
+func (c *Contacts) Unblock(user uuid.UUID, other uuid.UUID) error {
+	mine, err := c.store.GetContact(user, other)
+	if err != nil {
+		return err
+	}
+	if mine == nil || mine.Status != STATUS_BLOCKED {
+		return ErrNotBlocked
+	}
+	return c.store.DeleteContact(user, other)
+}
+
+// List returns user's contacts, outgoing requests or blocked users,
+// depending on status.
+func (c *Contacts) List(user uuid.UUID, status string, count int, offset int) ([]*Contact, error) {
+	return c.store.ListContacts(user, status, count, offset)
+}
+
+// Incoming returns the requests waiting for user to answer.
+func (c *Contacts) Incoming(user uuid.UUID, count int, offset int) ([]*Contact, error) {
+	return c.store.ListIncomingRequests(user, count, offset)
+}
+
+// Mute hides other's notes from user without blocking them.
+func (c *Contacts) Mute(user uuid.UUID, other uuid.UUID) error {
+	if user == other {
+		return ErrSelf
+	}
+
+	muted, err := c.MutedIds(user)
+	if err != nil {
+		return err
+	}
+	for _, id := range muted {
+		if id == other {
+			return nil
+		}
+	}
+	if len(muted) >= MAX_MUTED {
+		return ErrTooManyMuted
+	}
+
+	return c.store.PutMute(&Mute{
+		UserId: user,
+		MutedId: other,
+		MutedAt: c.now().UTC().Truncate(time.Second),
+	})
+}
+
+func (c *Contacts) Unmute(user uuid.UUID, other uuid.UUID) error {
+	deleted, err := c.store.DeleteMute(user, other)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotMuted
+	}
+	return nil
+}
+
+func (c *Contacts) ListMuted(user uuid.UUID, count int, offset int) ([]*Mute, error) {
+	return c.store.ListMutes(user, count, offset)
+}
+
+// MutedIds returns everyone user has muted, for leaving their notes out
+// of user's results.
+func (c *Contacts) MutedIds(user uuid.UUID) ([]uuid.UUID, error) {
+	mutes, err := c.store.ListMutes(user, MAX_MUTED, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(mutes))
+	for i, mute := range mutes {
+		ids[i] = mute.MutedId
+	}
+	return ids, nil
+}
+
+// HiddenIds returns everyone whose notes are left out of what user finds
+// nearby: the users they've muted or blocked.
+func (c *Contacts) HiddenIds(user uuid.UUID) ([]uuid.UUID, error) {
+	muted, err := c.MutedIds(user)
+	if err != nil {
+		return nil, err
+	}
+	blocked, err := c.ContactIds(user, STATUS_BLOCKED)
+	if err != nil {
+		return nil, err
+	}
+	return append(muted, blocked...), nil
+}
+
+// ContactIds returns the ids of up to MAX_CONTACT_IDS of user's contacts
+// with the given status, e.g. for finding their contacts notes or leaving
+// out the notes of users they've blocked.
+func (c *Contacts) ContactIds(user uuid.UUID, status string) ([]uuid.UUID, error) {
+	found, err := c.store.ListContacts(user, status, MAX_CONTACT_IDS, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(found))
+	for i, contact := range found {
+		ids[i] = contact.ContactId
+	}
+	return ids, nil
+}
+
+// Forget removes user from everyone's contacts, blocks, mutes and groups,
+// and everyone from theirs, for when user's account is erased. It returns how
+// many rows were deleted.
+func (c *Contacts) Forget(user uuid.UUID) (int, error) {
+	return c.store.DeleteUserContacts(user)
+}
+
+// CanSend reports whether sender may leave a note for recipient: they
+// must be accepted contacts, which also means recipient hasn't blocked
+// sender. Anyone may leave a note for themselves.
+func (c *Contacts) CanSend(sender uuid.UUID, recipient uuid.UUID) (bool, error) {
+	if sender == recipient {
+		return true, nil
+	}
+
+	theirs, err := c.store.GetContact(recipient, sender)
+	if err != nil {
+		return false, err
+	}
+	return theirs != nil && theirs.Status == STATUS_ACCEPTED, nil
+}
+
+func (c *Contacts) requirePendingRequest(user uuid.UUID, requester uuid.UUID) error {
+	theirs, err := c.store.GetContact(requester, user)
+	if err != nil {
+		return err
+	}
+	if theirs == nil || theirs.Status != STATUS_PENDING {
+		return ErrNoRequest
+	}
+
+	// Requests from blocked users are hidden, so they can't be answered
+	// either.
+	mine, err := c.store.GetContact(user, requester)
+	if err != nil {
+		return err
+	}
+	if mine != nil && mine.Status == STATUS_BLOCKED {
+		return ErrNoRequest
+	}
+	return nil
+}
+
+func (c *Contacts) accept(user uuid.UUID, other uuid.UUID) error {
+	return c.store.PutContacts([]*Contact{
+		c.contact(user, other, STATUS_ACCEPTED),
+		c.contact(other, user, STATUS_ACCEPTED),
+	})
+}
+
+func (c *Contacts) contact(user uuid.UUID, other uuid.UUID, status string) *Contact {
+	return &Contact{
+		UserId: user,
+		ContactId: other,
+		Status: status,
+		UpdatedAt: c.now().UTC().Truncate(time.Second),
+	}
+}
+
+type MysqlContacts struct {
+	conn *sql.DB
+}
+
+type DbCredentials struct {
+	User string
+	Password string
+	Host string
+	Port string
+}
+
+func NewMysqlContacts(credentials *DbCredentials) (*MysqlContacts, error) {
+	dsn := credentials.User + ":" + credentials.Password + "@tcp(" +
+		credentials.Host + ":" + credentials.Port + ")/geonote?parseTime=true"
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Print("Failed to open db:", err)
+		return nil, err
+	}
+
+	return &MysqlContacts{conn: db}, nil
+}
+
+// GetContact returns userId's row for contactId, or nil if there is none.
+func (db MysqlContacts) GetContact(userId uuid.UUID, contactId uuid.UUID) (*Contact, error) {
+	contacts, err := db.query("SELECT user_id, contact_id, status, updated_at FROM contacts " +
+		" WHERE user_id = ? AND contact_id = ?", userId.String(), contactId.String())
+	if err != nil || len(contacts) == 0 {
+		return nil, err
+	}
+	return contacts[0], nil
+}
+
+// PutContacts writes every row in one statement, so both directions of
+// an accepted contact are stored or neither is.
+func (db MysqlContacts) PutContacts(contacts []*Contact) error {
+	if len(contacts) == 0 {
+		return nil
+	}
+
+	sql := "INSERT INTO contacts (user_id, contact_id, status, updated_at) VALUES (?, ?, ?, ?)"
+	args := []interface{}{}
+	for i, contact := range contacts {
+		if i > 0 {
+			sql += ", (?, ?, ?, ?)"
+		}
+		args = append(args, contact.UserId.String(), contact.ContactId.String(), contact.Status, contact.UpdatedAt)
+	}
+	sql += " ON DUPLICATE KEY UPDATE status = VALUES(status), updated_at = VALUES(updated_at)"
+
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(args...); err != nil {
+		log.Printf("Failed to store contacts for user %v. Err: %v", contacts[0].UserId, err)
+		return err
+	}
+
+	return nil
+}
+
+func (db MysqlContacts) DeleteContact(userId uuid.UUID, contactId uuid.UUID) error {
+	sql := "DELETE FROM contacts WHERE user_id = ? AND contact_id = ?"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userId.String(), contactId.String()); err != nil {
+		log.Printf("Failed to delete contact %v of user %v. Err: %v", contactId, userId, err)
+		return err
+	}
+
+	return nil
+}
+
+func (db MysqlContacts) ListContacts(
+	userId uuid.UUID,
+	status string,
+	count int,
+	offset int) ([]*Contact, error) {
+	return db.query("SELECT user_id, contact_id, status, updated_at FROM contacts " +
+		" WHERE user_id = ? AND status = ? " +
+		" ORDER BY updated_at DESC LIMIT ? OFFSET ?", userId.String(), status, count, offset)
+}
+
+func (db MysqlContacts) ListIncomingRequests(userId uuid.UUID, count int, offset int) ([]*Contact, error) {
+	return db.query("SELECT requests.user_id, requests.contact_id, requests.status, requests.updated_at " +
+		" FROM contacts requests LEFT JOIN contacts blocks " +
+		" ON blocks.user_id = requests.contact_id AND blocks.contact_id = requests.user_id " +
+		" AND blocks.status = ? " +
+		" WHERE requests.contact_id = ? AND requests.status = ? AND blocks.user_id IS NULL " +
+		" ORDER BY requests.updated_at DESC LIMIT ? OFFSET ?",
+		STATUS_BLOCKED, userId.String(), STATUS_PENDING, count, offset)
+}
+
+func (db MysqlContacts) PutMute(mute *Mute) error {
+	sql := "INSERT IGNORE INTO mutes (user_id, muted_id, muted_at) VALUES (?, ?, ?)"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(mute.UserId.String(), mute.MutedId.String(), mute.MutedAt); err != nil {
+		log.Printf("Failed to mute %v for user %v. Err: %v", mute.MutedId, mute.UserId, err)
+		return err
+	}
+
+	return nil
+}
+
+func (db MysqlContacts) DeleteMute(userId uuid.UUID, mutedId uuid.UUID) (bool, error) {
+	sql := "DELETE FROM mutes WHERE user_id = ? AND muted_id = ?"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(userId.String(), mutedId.String())
+	if err != nil {
+		log.Printf("Failed to unmute %v for user %v. Err: %v", mutedId, userId, err)
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error while fetching rows affected during unmute. Err: %v", err)
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+func (db MysqlContacts) ListMutes(userId uuid.UUID, count int, offset int) ([]*Mute, error) {
+	sql := "SELECT user_id, muted_id, muted_at FROM mutes WHERE user_id = ? " +
+		" ORDER BY muted_at DESC LIMIT ? OFFSET ?"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(userId.String(), count, offset)
+	if err != nil {
+		log.Printf("Failed to query mutes of user %v. Err: %v", userId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mutes []*Mute
+	for rows.Next() {
+		var mute Mute
+		if err = rows.Scan(&mute.UserId, &mute.MutedId, &mute.MutedAt); err != nil {
+			log.Printf("Failed to scan row while fetching mutes. Err: %v", err)
+			return nil, err
+		}
+		mutes = append(mutes, &mute)
+	}
+
+	return mutes, rows.Err()
+}
+
+func (db MysqlContacts) DeleteUserContacts(userId uuid.UUID) (int, error) {
+	deleted := 0
+	for _, sql := range []string{
+		"DELETE FROM contacts WHERE user_id = ? OR contact_id = ?",
+		"DELETE FROM mutes WHERE user_id = ? OR muted_id = ?",
+		"DELETE FROM contact_group_members WHERE member_id = ? OR group_id IN " +
+			" (SELECT id FROM contact_groups WHERE owner_id = ?)",
+		"DELETE FROM contact_groups WHERE owner_id = ?",
+	} {
+		args := make([]interface{}, strings.Count(sql, "?"))
+		for i := range args {
+			args[i] = userId.String()
+		}
+		result, err := db.conn.Exec(sql, args...)
+		if err != nil {
+			log.Printf("Failed to delete contacts of user %v. Err: %v", userId, err)
+			return deleted, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			log.Printf("Error while fetching rows affected during contacts delete. Err: %v", err)
+			return deleted, err
+		}
+		deleted += int(rowsAffected)
+	}
+
+	return deleted, nil
+}
+
+func (db MysqlContacts) query(sql string, args ...interface{}) ([]*Contact, error) {
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(args...)
+	if err != nil {
+		log.Printf("Failed to query contacts. Err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []*Contact
+	for rows.Next() {
+		var contact Contact
+		err = rows.Scan(
+			&contact.UserId,
+			&contact.ContactId,
+			&contact.Status,
+			&contact.UpdatedAt,
+		)
+		if err != nil {
+			log.Printf("Failed to scan row while fetching contacts. Err: %v", err)
+			return nil, err
+		}
+		contacts = append(contacts, &contact)
+	}
+
+	return contacts, rows.Err()
+}
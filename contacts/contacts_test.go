@@ -0,0 +1,292 @@
+package contacts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+func TestRequestAndAccept(t *testing.T) {
+	c, clock := getTestContacts()
+	alice, bob := uuid.NewV4(), uuid.NewV4()
+
+	if err := c.Request(alice, bob); err != nil {
+		t.Fatal("Failed to send request. Err: ", err)
+	}
+	if err := c.Request(alice, bob); err != nil {
+		t.Fatal("Expected asking again to do nothing, got: ", err)
+	}
+	assertCanSend(t, c, alice, bob, false)
+
+	incoming, err := c.Incoming(bob, 10, 0)
+	if err != nil || len(incoming) != 1 || incoming[0].UserId != alice {
+		t.Fatal("Expected alice's request, got: ", incoming, " ", err)
+	}
+	outgoing, err := c.List(alice, STATUS_PENDING, 10, 0)
+	if err != nil || len(outgoing) != 1 || outgoing[0].ContactId != bob {
+		t.Fatal("Expected alice's outgoing request, got: ", outgoing, " ", err)
+	}
+
+	if err = c.Accept(alice, bob); err != ErrNoRequest {
+		t.Fatal("Expected ErrNoRequest accepting your own request, got: ", err)
+	}
+	*clock = clock.Add(time.Minute)
+	if err = c.Accept(bob, alice); err != nil {
+		t.Fatal("Failed to accept. Err: ", err)
+	}
+	assertCanSend(t, c, alice, bob, true)
+	assertCanSend(t, c, bob, alice, true)
+
+	if err = c.Request(bob, alice); err != ErrAlreadyContacts {
+		t.Fatal("Expected ErrAlreadyContacts, got: ", err)
+	}
+	for _, user := range []uuid.UUID{alice, bob} {
+		if contacts, _ := c.List(user, STATUS_ACCEPTED, 10, 0); len(contacts) != 1 {
+			t.Fatal("Expected one contact, got: ", contacts)
+		}
+	}
+
+	if err = c.Remove(bob, alice); err != nil {
+		t.Fatal("Failed to remove contact. Err: ", err)
+	}
+	assertCanSend(t, c, alice, bob, false)
+	if contacts, _ := c.List(alice, STATUS_ACCEPTED, 10, 0); len(contacts) != 0 {
+		t.Fatal("Expected the contact to be removed for both, got: ", contacts)
+	}
+	if err = c.Remove(bob, alice); err != ErrNotContacts {
+		t.Fatal("Expected ErrNotContacts, got: ", err)
+	}
+}
+
+func TestCrossedRequests(t *testing.T) {
+	c, _ := getTestContacts()
+	alice, bob := uuid.NewV4(), uuid.NewV4()
+
+	if err := c.Request(alice, bob); err != nil {
+		t.Fatal("Failed to send request. Err: ", err)
+	}
+	if err := c.Request(bob, alice); err != nil {
+		t.Fatal("Failed to send request. Err: ", err)
+	}
+	assertCanSend(t, c, alice, bob, true)
+}
+
+func TestDecline(t *testing.T) {
+	c, _ := getTestContacts()
+	alice, bob := uuid.NewV4(), uuid.NewV4()
+
+	if err := c.Decline(bob, alice); err != ErrNoRequest {
+		t.Fatal("Expected ErrNoRequest, got: ", err)
+	}
+	c.Request(alice, bob)
+	if err := c.Decline(bob, alice); err != nil {
+		t.Fatal("Failed to decline. Err: ", err)
+	}
+	if incoming, _ := c.Incoming(bob, 10, 0); len(incoming) != 0 {
+		t.Fatal("Expected no requests after declining, got: ", incoming)
+	}
+	if err := c.Request(alice, bob); err != nil {
+		t.Fatal("Expected to be able to ask again, got: ", err)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	c, _ := getTestContacts()
+	alice, bob := uuid.NewV4(), uuid.NewV4()
+
+	c.Request(alice, bob)
+	c.Accept(bob, alice)
+	if err := c.Block(bob, alice); err != nil {
+		t.Fatal("Failed to block. Err: ", err)
+	}
+	assertCanSend(t, c, alice, bob, false)
+	assertCanSend(t, c, bob, alice, false)
+
+	// Alice can still ask, but bob never sees it and can't accept it.
+	if err := c.Request(alice, bob); err != nil {
+		t.Fatal("Expected a request to a blocker to look normal, got: ", err)
+	}
+	if incoming, _ := c.Incoming(bob, 10, 0); len(incoming) != 0 {
+		t.Fatal("Expected requests from blocked users to be hidden, got: ", incoming)
+	}
+	if err := c.Accept(bob, alice); err != ErrNoRequest {
+		t.Fatal("Expected ErrNoRequest for a blocked user's request, got: ", err)
+	}
+	if err := c.Request(bob, alice); err != ErrBlocked {
+		t.Fatal("Expected ErrBlocked asking someone you blocked, got: ", err)
+	}
+	if blocked, _ := c.List(bob, STATUS_BLOCKED, 10, 0); len(blocked) != 1 || blocked[0].ContactId != alice {
+		t.Fatal("Expected alice to be blocked, got: ", blocked)
+	}
+
+	if err := c.Unblock(bob, alice); err != nil {
+		t.Fatal("Failed to unblock. Err: ", err)
+	}
+	if err := c.Unblock(bob, alice); err != ErrNotBlocked {
+		t.Fatal("Expected ErrNotBlocked, got: ", err)
+	}
+	if err := c.Accept(bob, alice); err != nil {
+		t.Fatal("Expected alice's request to be answerable after unblocking, got: ", err)
+	}
+	assertCanSend(t, c, alice, bob, true)
+
+	if err := c.Block(alice, alice); err != ErrSelf {
+		t.Fatal("Expected ErrSelf, got: ", err)
+	}
+	assertCanSend(t, c, alice, alice, true)
+}
+
+func TestContactIds(t *testing.T) {
+	c, _ := getTestContacts()
+	alice, bob, carol := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+
+	c.Request(alice, bob)
+	c.Accept(bob, alice)
+	if err := c.Block(alice, carol); err != nil {
+		t.Fatal("Failed to block. Err: ", err)
+	}
+
+	if ids, err := c.ContactIds(alice, STATUS_ACCEPTED); err != nil || len(ids) != 1 || ids[0] != bob {
+		t.Fatal("Expected bob as alice's only contact, got: ", ids, " ", err)
+	}
+	if ids, err := c.ContactIds(alice, STATUS_BLOCKED); err != nil || len(ids) != 1 || ids[0] != carol {
+		t.Fatal("Expected carol to be blocked, got: ", ids, " ", err)
+	}
+	if ids, err := c.ContactIds(carol, STATUS_ACCEPTED); err != nil || len(ids) != 0 {
+		t.Fatal("Expected carol to have no contacts, got: ", ids, " ", err)
+	}
+
+	c.Mute(alice, bob)
+	if ids, err := c.HiddenIds(alice); err != nil || len(ids) != 2 {
+		t.Fatal("Expected bob and carol to be hidden, got: ", ids, " ", err)
+	}
+}
+
+func TestMute(t *testing.T) {
+	c, clock := getTestContacts()
+	alice, bob, carol := uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+
+	c.Request(alice, bob)
+	c.Accept(bob, alice)
+	if err := c.Mute(bob, alice); err != nil {
+		t.Fatal("Failed to mute. Err: ", err)
+	}
+	*clock = clock.Add(time.Minute)
+	if err := c.Mute(bob, carol); err != nil {
+		t.Fatal("Failed to mute. Err: ", err)
+	}
+	if err := c.Mute(bob, alice); err != nil {
+		t.Fatal("Expected muting again to do nothing, got: ", err)
+	}
+
+	// Muting doesn't block.
+	assertCanSend(t, c, alice, bob, true)
+
+	muted, err := c.MutedIds(bob)
+	if err != nil || len(muted) != 2 || muted[0] != carol || muted[1] != alice {
+		t.Fatal("Expected carol then alice to be muted, got: ", muted, " ", err)
+	}
+	if muted, _ = c.MutedIds(alice); len(muted) != 0 {
+		t.Fatal("Expected mutes to be one way, got: ", muted)
+	}
+
+	if err = c.Unmute(bob, alice); err != nil {
+		t.Fatal("Failed to unmute. Err: ", err)
+	}
+	if err = c.Unmute(bob, alice); err != ErrNotMuted {
+		t.Fatal("Expected ErrNotMuted, got: ", err)
+	}
+	if err = c.Mute(bob, bob); err != ErrSelf {
+		t.Fatal("Expected ErrSelf, got: ", err)
+	}
+
+	for i := 1; i < MAX_MUTED; i++ {
+		if err = c.Mute(bob, uuid.NewV4()); err != nil {
+			t.Fatal("Failed to mute. Err: ", err)
+		}
+	}
+	if err = c.Mute(bob, alice); err != ErrTooManyMuted {
+		t.Fatal("Expected ErrTooManyMuted, got: ", err)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	c, _ := getTestContacts()
+	alice, bob, carol, dave := uuid.NewV4(), uuid.NewV4(), uuid.NewV4(), uuid.NewV4()
+	for _, other := range []uuid.UUID{bob, carol} {
+		c.Request(alice, other)
+		c.Accept(other, alice)
+	}
+
+	family, err := c.CreateGroup(alice, " Family ", []uuid.UUID{bob, carol, bob})
+	if err != nil {
+		t.Fatal("Failed to create group. Err: ", err)
+	}
+	if family.Name != "Family" || len(family.Members) != 2 || family.Members[0] != bob {
+		t.Fatal("Unexpected group: ", family)
+	}
+
+	if _, err = c.CreateGroup(alice, "family", nil); err != ErrGroupNameTaken {
+		t.Fatal("Expected ErrGroupNameTaken, got: ", err)
+	}
+	if _, err = c.CreateGroup(alice, "", nil); err != ErrGroupName {
+		t.Fatal("Expected ErrGroupName, got: ", err)
+	}
+	if _, err = c.CreateGroup(alice, "Strangers", []uuid.UUID{dave}); err != ErrNotContacts {
+		t.Fatal("Expected ErrNotContacts, got: ", err)
+	}
+	if _, err = c.CreateGroup(alice, "Me", []uuid.UUID{alice}); err != ErrSelf {
+		t.Fatal("Expected ErrSelf, got: ", err)
+	}
+
+	if _, err = c.Group(bob, family.Id); err != ErrNoGroup {
+		t.Fatal("Expected ErrNoGroup for someone else's group, got: ", err)
+	}
+	if _, err = c.UpdateGroup(bob, family.Id, "Mine now", nil); err != ErrNoGroup {
+		t.Fatal("Expected ErrNoGroup updating someone else's group, got: ", err)
+	}
+
+	family, err = c.UpdateGroup(alice, family.Id, "family", []uuid.UUID{carol})
+	if err != nil {
+		t.Fatal("Failed to update group. Err: ", err)
+	}
+	groups, err := c.Groups(alice)
+	if err != nil || len(groups) != 1 || groups[0].Name != "family" ||
+		len(groups[0].Members) != 1 || groups[0].Members[0] != carol {
+		t.Fatal("Expected the updated group, got: ", groups, " ", err)
+	}
+
+	if deleted, err := c.Forget(carol); err != nil || deleted != 3 {
+		t.Fatal("Expected carol's contacts and membership to be deleted, got: ", deleted, " ", err)
+	}
+	if family, _ = c.Group(alice, family.Id); len(family.Members) != 0 {
+		t.Fatal("Expected carol to be taken out of the group, got: ", family.Members)
+	}
+
+	if err = c.DeleteGroup(alice, family.Id); err != nil {
+		t.Fatal("Failed to delete group. Err: ", err)
+	}
+	if err = c.DeleteGroup(alice, family.Id); err != ErrNoGroup {
+		t.Fatal("Expected ErrNoGroup deleting again, got: ", err)
+	}
+}
+
+func getTestContacts() (*Contacts, *time.Time) {
+	c := NewContacts(NewMemoryContacts())
+	clock := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	c.now = func() time.Time {
+		return clock
+	}
+	return c, &clock
+}
+
+func assertCanSend(t *testing.T, c *Contacts, sender uuid.UUID, recipient uuid.UUID, expected bool) {
+	allowed, err := c.CanSend(sender, recipient)
+	if err != nil {
+		t.Fatal("Failed to check contacts. Err: ", err)
+	}
+	if allowed != expected {
+		t.Fatal("Expected CanSend(", sender, ", ", recipient, ") to be ", expected)
+	}
+}
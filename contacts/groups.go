@@ -0,0 +1,275 @@
+package contacts
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/satori/go.uuid"
+)
+
+const (
+	MAX_GROUP_NAME_LEN = 64
+	MAX_GROUP_MEMBERS = 50
+
+	// MAX_GROUPS is how many groups one user can have.
+	MAX_GROUPS = 100
+)
+
+var (
+	ErrNoGroup = errors.New("No such group.")
+	ErrGroupName = errors.New("Group names must be 1 to 64 characters.")
+	ErrGroupNameTaken = errors.New("You already have a group with that name.")
+	ErrTooManyGroups = errors.New("Too many groups; delete some first.")
+	ErrTooManyMembers = errors.New("Too many group members.")
+)
+
+// Group is a named list of a user's contacts, e.g. "family", that a note
+// can be addressed to as a whole. It's expanded into its members when a
+// note is sent, so changing a group doesn't change who can see notes
+// already left for it. Groups are private to their owner.
+type Group struct {
+	Id uuid.UUID
+	OwnerId uuid.UUID
+	Name string
+	Members []uuid.UUID
+	UpdatedAt time.Time
+}
+
+// CreateGroup makes a new group of owner's. Every member must be one of
+// owner's accepted contacts.
+func (c *Contacts) CreateGroup(owner uuid.UUID, name string, members []uuid.UUID) (*Group, error) {
+	groups, err := c.store.ListGroups(owner)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) >= MAX_GROUPS {
+		return nil, ErrTooManyGroups
+	}
+
+	group := &Group{Id: uuid.NewV4(), OwnerId: owner}
+	if err = c.setGroup(group, groups, name, members); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// UpdateGroup replaces the name and members of one of owner's groups.
+func (c *Contacts) UpdateGroup(
+	owner uuid.UUID,
+	id uuid.UUID,
+	name string,
+	members []uuid.UUID) (*Group, error) {
+	group, err := c.Group(owner, id)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := c.store.ListGroups(owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = c.setGroup(group, groups, name, members); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (c *Contacts) DeleteGroup(owner uuid.UUID, id uuid.UUID) error {
+	if _, err := c.Group(owner, id); err != nil {
+		return err
+	}
+	return c.store.DeleteGroup(id)
+}
+
+// Group returns one of owner's groups, or ErrNoGroup if owner has no
+// group with that id.
+func (c *Contacts) Group(owner uuid.UUID, id uuid.UUID) (*Group, error) {
+	group, err := c.store.GetGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil || group.OwnerId != owner {
+		return nil, ErrNoGroup
+	}
+	return group, nil
+}
+
+// Groups returns all of owner's groups, by name.
+func (c *Contacts) Groups(owner uuid.UUID) ([]*Group, error) {
+	return c.store.ListGroups(owner)
+}
+
+// setGroup validates name and members against the owner's other groups
+// and contacts, then stores them as group's.
+func (c *Contacts) setGroup(group *Group, groups []*Group, name string, members []uuid.UUID) error {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MAX_GROUP_NAME_LEN {
+		return ErrGroupName
+	}
+	for _, other := range groups {
+		if other.Id != group.Id && strings.EqualFold(other.Name, name) {
+			return ErrGroupNameTaken
+		}
+	}
+
+	var unique []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, member := range members {
+		if seen[member] {
+			continue
+		}
+		seen[member] = true
+		unique = append(unique, member)
+	}
+	if len(unique) > MAX_GROUP_MEMBERS {
+		return ErrTooManyMembers
+	}
+
+	for _, member := range unique {
+		if member == group.OwnerId {
+			return ErrSelf
+		}
+		mine, err := c.store.GetContact(group.OwnerId, member)
+		if err != nil {
+			return err
+		}
+		if mine == nil || mine.Status != STATUS_ACCEPTED {
+			return ErrNotContacts
+		}
+	}
+
+	group.Name = name
+	group.Members = unique
+	group.UpdatedAt = c.now().UTC().Truncate(time.Second)
+	return c.store.PutGroup(group)
+}
+
+// GetGroup returns the group with its members, or nil if there is none.
+func (db MysqlContacts) GetGroup(id uuid.UUID) (*Group, error) {
+	groups, err := db.queryGroups("SELECT id, owner_id, name, updated_at FROM contact_groups " +
+		" WHERE id = ?", id.String())
+	if err != nil || len(groups) == 0 {
+		return nil, err
+	}
+	return groups[0], nil
+}
+
+func (db MysqlContacts) ListGroups(ownerId uuid.UUID) ([]*Group, error) {
+	return db.queryGroups("SELECT id, owner_id, name, updated_at FROM contact_groups " +
+		" WHERE owner_id = ? ORDER BY name", ownerId.String())
+}
+
+// PutGroup inserts or replaces the group and its members in one
+// transaction.
+func (db MysqlContacts) PutGroup(group *Group) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction to store group %v. Err: %v", group.Id, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO contact_groups (id, owner_id, name, updated_at) VALUES (?, ?, ?, ?) " +
+		" ON DUPLICATE KEY UPDATE name = VALUES(name), updated_at = VALUES(updated_at)",
+		group.Id.String(), group.OwnerId.String(), group.Name, group.UpdatedAt)
+	if err != nil {
+		log.Printf("Failed to store group %v. Err: %v", group.Id, err)
+		return err
+	}
+
+	if _, err = tx.Exec("DELETE FROM contact_group_members WHERE group_id = ?", group.Id.String()); err != nil {
+		log.Printf("Failed to clear members of group %v. Err: %v", group.Id, err)
+		return err
+	}
+
+	if len(group.Members) > 0 {
+		sql := "INSERT INTO contact_group_members (group_id, member_id, position) VALUES (?, ?, ?)"
+		args := []interface{}{}
+		for i, member := range group.Members {
+			if i > 0 {
+				sql += ", (?, ?, ?)"
+			}
+			args = append(args, group.Id.String(), member.String(), i)
+		}
+		if _, err = tx.Exec(sql, args...); err != nil {
+			log.Printf("Failed to store members of group %v. Err: %v", group.Id, err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Failed to commit group %v. Err: %v", group.Id, err)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteGroup is idempotent; deleting a missing group isn't an error.
+func (db MysqlContacts) DeleteGroup(id uuid.UUID) error {
+	for _, sql := range []string{
+		"DELETE FROM contact_group_members WHERE group_id = ?",
+		"DELETE FROM contact_groups WHERE id = ?",
+	} {
+		if _, err := db.conn.Exec(sql, id.String()); err != nil {
+			log.Printf("Failed to delete group %v. Err: %v", id, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (db MysqlContacts) queryGroups(sql string, args ...interface{}) ([]*Group, error) {
+	rows, err := db.conn.Query(sql, args...)
+	if err != nil {
+		log.Printf("Failed to query groups. Err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*Group
+	for rows.Next() {
+		var group Group
+		if err = rows.Scan(&group.Id, &group.OwnerId, &group.Name, &group.UpdatedAt); err != nil {
+			log.Printf("Failed to scan row while fetching groups. Err: %v", err)
+			return nil, err
+		}
+		groups = append(groups, &group)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, group := range groups {
+		if group.Members, err = db.groupMembers(group.Id); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+func (db MysqlContacts) groupMembers(id uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := db.conn.Query("SELECT member_id FROM contact_group_members " +
+		" WHERE group_id = ? ORDER BY position", id.String())
+	if err != nil {
+		log.Printf("Failed to query members of group %v. Err: %v", id, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []uuid.UUID
+	for rows.Next() {
+		var member uuid.UUID
+		if err = rows.Scan(&member); err != nil {
+			log.Printf("Failed to scan row while fetching group members. Err: %v", err)
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
@@ -0,0 +1,273 @@
+package contacts
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/satori/go.uuid"
+)
+
+// MemoryContacts is an in-memory ContactConnection for tests in packages
+// that sit on top of contacts and shouldn't need a live MySQL.
+type MemoryContacts struct {
+	mutex sync.Mutex
+	contacts []Contact
+	mutes []Mute
+	groups []Group
+}
+
+func NewMemoryContacts() *MemoryContacts {
+	return &MemoryContacts{}
+}
+
+func (db *MemoryContacts) GetContact(userId uuid.UUID, contactId uuid.UUID) (*Contact, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if i := db.find(userId, contactId); i >= 0 {
+		contact := db.contacts[i]
+		return &contact, nil
+	}
+	return nil, nil
+}
+
+func (db *MemoryContacts) PutContacts(contacts []*Contact) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, contact := range contacts {
+		if i := db.find(contact.UserId, contact.ContactId); i >= 0 {
+			db.contacts[i] = *contact
+		} else {
+			db.contacts = append(db.contacts, *contact)
+		}
+	}
+	return nil
+}
+
+func (db *MemoryContacts) DeleteContact(userId uuid.UUID, contactId uuid.UUID) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if i := db.find(userId, contactId); i >= 0 {
+		db.contacts = append(db.contacts[:i], db.contacts[i+1:]...)
+	}
+	return nil
+}
+
+func (db *MemoryContacts) ListContacts(
+	userId uuid.UUID,
+	status string,
+	count int,
+	offset int) ([]*Contact, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	return db.list(func(contact *Contact) bool {
+		return contact.UserId == userId && contact.Status == status
+	}, count, offset), nil
+}
+
+func (db *MemoryContacts) ListIncomingRequests(userId uuid.UUID, count int, offset int) ([]*Contact, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	return db.list(func(contact *Contact) bool {
+		if contact.ContactId != userId || contact.Status != STATUS_PENDING {
+			return false
+		}
+		block := db.find(userId, contact.UserId)
+		return block < 0 || db.contacts[block].Status != STATUS_BLOCKED
+	}, count, offset), nil
+}
+
+func (db *MemoryContacts) PutMute(mute *Mute) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if db.findMute(mute.UserId, mute.MutedId) < 0 {
+		db.mutes = append(db.mutes, *mute)
+	}
+	return nil
+}
+
+func (db *MemoryContacts) DeleteMute(userId uuid.UUID, mutedId uuid.UUID) (bool, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	i := db.findMute(userId, mutedId)
+	if i < 0 {
+		return false, nil
+	}
+	db.mutes = append(db.mutes[:i], db.mutes[i+1:]...)
+	return true, nil
+}
+
+func (db *MemoryContacts) ListMutes(userId uuid.UUID, count int, offset int) ([]*Mute, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var mutes []*Mute
+	for _, mute := range db.mutes {
+		if mute.UserId == userId {
+			copied := mute
+			mutes = append(mutes, &copied)
+		}
+	}
+	sort.SliceStable(mutes, func(i, j int) bool {
+		return mutes[i].MutedAt.After(mutes[j].MutedAt)
+	})
+
+	if offset >= len(mutes) {
+		return nil, nil
+	}
+	mutes = mutes[offset:]
+	if len(mutes) > count {
+		mutes = mutes[:count]
+	}
+	return mutes, nil
+}
+
+func (db *MemoryContacts) DeleteUserContacts(userId uuid.UUID) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var contacts []Contact
+	for _, contact := range db.contacts {
+		if contact.UserId != userId && contact.ContactId != userId {
+			contacts = append(contacts, contact)
+		}
+	}
+	var mutes []Mute
+	for _, mute := range db.mutes {
+		if mute.UserId != userId && mute.MutedId != userId {
+			mutes = append(mutes, mute)
+		}
+	}
+
+	deleted := len(db.contacts) - len(contacts) + len(db.mutes) - len(mutes)
+	var groups []Group
+	for _, group := range db.groups {
+		if group.OwnerId == userId {
+			deleted += 1 + len(group.Members)
+			continue
+		}
+		var members []uuid.UUID
+		for _, member := range group.Members {
+			if member != userId {
+				members = append(members, member)
+			}
+		}
+		deleted += len(group.Members) - len(members)
+		group.Members = members
+		groups = append(groups, group)
+	}
+
+	db.contacts, db.mutes, db.groups = contacts, mutes, groups
+	return deleted, nil
+}
+
+func (db *MemoryContacts) GetGroup(id uuid.UUID) (*Group, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if i := db.findGroup(id); i >= 0 {
+		return copyGroup(&db.groups[i]), nil
+	}
+	return nil, nil
+}
+
+func (db *MemoryContacts) ListGroups(ownerId uuid.UUID) ([]*Group, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var groups []*Group
+	for i := range db.groups {
+		if db.groups[i].OwnerId == ownerId {
+			groups = append(groups, copyGroup(&db.groups[i]))
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups, nil
+}
+
+func (db *MemoryContacts) PutGroup(group *Group) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if i := db.findGroup(group.Id); i >= 0 {
+		db.groups[i] = *copyGroup(group)
+	} else {
+		db.groups = append(db.groups, *copyGroup(group))
+	}
+	return nil
+}
+
+func (db *MemoryContacts) DeleteGroup(id uuid.UUID) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if i := db.findGroup(id); i >= 0 {
+		db.groups = append(db.groups[:i], db.groups[i+1:]...)
+	}
+	return nil
+}
+
+func (db *MemoryContacts) findGroup(id uuid.UUID) int {
+	for i, group := range db.groups {
+		if group.Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// copyGroup copies the group along with its members, which a plain
+// struct copy would share.
+func copyGroup(group *Group) *Group {
+	copied := *group
+	copied.Members = append([]uuid.UUID(nil), group.Members...)
+	return &copied
+}
+
+func (db *MemoryContacts) findMute(userId uuid.UUID, mutedId uuid.UUID) int {
+	for i, mute := range db.mutes {
+		if mute.UserId == userId && mute.MutedId == mutedId {
+			return i
+		}
+	}
+	return -1
+}
+
+func (db *MemoryContacts) find(userId uuid.UUID, contactId uuid.UUID) int {
+	for i, contact := range db.contacts {
+		if contact.UserId == userId && contact.ContactId == contactId {
+			return i
+		}
+	}
+	return -1
+}
+
+func (db *MemoryContacts) list(matches func(*Contact) bool, count int, offset int) []*Contact {
+	var contacts []*Contact
+	for i, _ := range db.contacts {
+		if matches(&db.contacts[i]) {
+			copied := db.contacts[i]
+			contacts = append(contacts, &copied)
+		}
+	}
+	sort.SliceStable(contacts, func(i, j int) bool {
+		return contacts[i].UpdatedAt.After(contacts[j].UpdatedAt)
+	})
+
+	if offset >= len(contacts) {
+		return nil
+	}
+	contacts = contacts[offset:]
+	if len(contacts) > count {
+		contacts = contacts[:count]
+	}
+	return contacts
+}
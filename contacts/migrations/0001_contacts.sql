@@ -0,0 +1,12 @@
+-- Who is whose contact. Each user of a pair has their own row: an
+-- accepted contact is two "accepted" rows, a request is one "pending" row
+-- from the requester, and a block is one "blocked" row from the blocker.
+
+CREATE TABLE contacts (
+	user_id CHAR(36) NOT NULL,
+	contact_id CHAR(36) NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (user_id, contact_id),
+	KEY contacts_contact_id (contact_id, status)
+);
@@ -0,0 +1,9 @@
+-- Users whose notes another user doesn't want to see. Muted users can
+-- still leave notes; they're only left out of the muter's results.
+
+CREATE TABLE mutes (
+	user_id CHAR(36) NOT NULL,
+	muted_id CHAR(36) NOT NULL,
+	muted_at DATETIME NOT NULL,
+	PRIMARY KEY (user_id, muted_id)
+);
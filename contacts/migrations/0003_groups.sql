@@ -0,0 +1,19 @@
+-- Named groups of a user's contacts that a note can be addressed to as a
+-- whole. position keeps the members in the order the owner gave them.
+
+CREATE TABLE contact_groups (
+	id CHAR(36) NOT NULL,
+	owner_id CHAR(36) NOT NULL,
+	name VARCHAR(64) NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (id),
+	UNIQUE KEY contact_groups_owner_name (owner_id, name)
+);
+
+CREATE TABLE contact_group_members (
+	group_id CHAR(36) NOT NULL,
+	member_id CHAR(36) NOT NULL,
+	position SMALLINT NOT NULL,
+	PRIMARY KEY (group_id, member_id),
+	KEY contact_group_members_member_id (member_id)
+);
@@ -0,0 +1,397 @@
+package erasure
+
+import (
+	"errors"
+	"time"
+	"log"
+	"os"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/solrnotes"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+const (
+	DEFAULT_BATCH_SIZE = 500
+
+	// The steps of an erasure, in the order they run.
+	STEP_SESSIONS = "sessions"
+	STEP_CONTACTS = "contacts"
+	STEP_SENT = "sent"
+	STEP_RECEIVED = "received"
+	STEP_READS = "reads"
+	STEP_LOGINS = "logins"
+	STEP_ACCOUNT = "account"
+	STEP_DONE = "done"
+)
+
+var (
+	ErrCheckpointMismatch = errors.New("Checkpoint file is for a different user.")
+)
+
+// Eraser removes everything GeoNote keeps about a user: every note they
+// sent or received, from both MySQL and Solr, their contacts, blocks and
+// mutes on either side, their login history, and finally their account,
+// profile and two-factor enrollment. Their sessions are revoked first so
+// they can't add anything while it runs.
+//
+// Notes are deleted rather than anonymised, with one exception: a note
+// the user received along with other recipients is kept for them, and
+// just loses the user as a recipient. Anything the user sent is deleted
+// for everyone. The user is also taken off the readers of any contacts or
+// public notes they've read.
+type Eraser struct {
+	users userdb.UserdbConnection
+	notes notesdb.NotesdbConnection
+	index solrnotes.SolrConnection
+	sessions *sessions.Manager
+	contacts *contacts.Contacts
+	events lockout.LoginEventConnection
+	options Options
+	now func() time.Time
+}
+
+type Options struct {
+	// BatchSize is how many notes are purged per round trip. Defaults to
+	// DEFAULT_BATCH_SIZE.
+	BatchSize int
+
+	// CheckpointFile, if set, records the report so far after every step
+	// and batch. A later run for the same user with the same file carries
+	// on from it. The file is removed once a run completes.
+	CheckpointFile string
+
+	// Progress, if set, is called after every step and batch, and once at
+	// the end.
+	Progress func(Report)
+}
+
+// Report is what an erasure did. Step is the step running, or STEP_DONE
+// once the erasure has completed.
+type Report struct {
+	UserId uuid.UUID
+	Username string
+	Step string
+	SentPurged int
+	ReceivedPurged int
+
+	// RemovedFromNotes counts received notes that were kept for their
+	// other recipients rather than purged.
+	RemovedFromNotes int
+
+	// ReadsRemoved counts shared notes the user was taken off the readers
+	// of.
+	ReadsRemoved int
+	ContactsDeleted int
+	LoginEventsDeleted int
+	StartedAt time.Time
+	CompletedAt time.Time
+}
+
+func NewEraser(
+	users userdb.UserdbConnection,
+	notes notesdb.NotesdbConnection,
+	index solrnotes.SolrConnection,
+	sessions *sessions.Manager,
+	contacts *contacts.Contacts,
+	events lockout.LoginEventConnection,
+	options Options) *Eraser {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DEFAULT_BATCH_SIZE
+	}
+	return &Eraser{
+		users: users,
+		notes: notes,
+		index: index,
+		sessions: sessions,
+		contacts: contacts,
+		events: events,
+		options: options,
+		now: time.Now,
+	}
+}
+
+// Erase erases the user with the given name, or returns userdb.ErrNotFound
+// if there's no such user and no checkpoint for one.
+//
+// Every step can safely be run again, and the account itself is deleted
+// last, so an interrupted erasure can always be finished by running it
+// again, with or without a checkpoint; the checkpoint just keeps the
+// report's counts. Each batch of notes is purged from Solr before MySQL,
+// so a note is never left in Solr once nothing points at it.
+func (e *Eraser) Erase(username string) (Report, error) {
+	report, err := e.loadCheckpoint(username)
+	if err != nil {
+		return report, err
+	}
+
+	if report.Step == "" {
+		user, err := e.users.GetUserByName(username)
+		if err != nil {
+			return report, err
+		}
+		if user == nil {
+			return report, userdb.ErrNotFound
+		}
+		report = Report{
+			UserId: user.Id,
+			Username: user.Name,
+			Step: STEP_SESSIONS,
+			StartedAt: e.now().UTC(),
+		}
+	} else {
+		log.Printf("Resuming erasure of user %v at step %v.", report.UserId, report.Step)
+	}
+
+	for report.Step != STEP_DONE {
+		if err = e.runStep(&report); err != nil {
+			log.Printf("Failed to erase user %v at step %v. Err: %v", report.UserId, report.Step, err)
+			return report, err
+		}
+	}
+
+	if err = e.clearCheckpoint(); err != nil {
+		return report, err
+	}
+
+	report.CompletedAt = e.now().UTC()
+	e.report(report)
+	return report, nil
+}
+
+// runStep runs report.Step and moves report on to the next step.
+func (e *Eraser) runStep(report *Report) error {
+	var err error
+	next := ""
+
+	switch report.Step {
+	case STEP_SESSIONS:
+		err = e.sessions.RevokeAll(report.UserId)
+		next = STEP_CONTACTS
+
+	case STEP_CONTACTS:
+		var deleted int
+		deleted, err = e.contacts.Forget(report.UserId)
+		report.ContactsDeleted += deleted
+		next = STEP_SENT
+
+	case STEP_SENT:
+		err = e.purgeNotes(report, &report.SentPurged, func() ([]*notesdb.Note, error) {
+			return e.notes.GetNotesBySender(report.UserId, e.options.BatchSize, 0)
+		})
+		next = STEP_RECEIVED
+
+	case STEP_RECEIVED:
+		err = e.purgeNotes(report, &report.ReceivedPurged, func() ([]*notesdb.Note, error) {
+			return e.notes.GetNotesByRecipient(report.UserId, nil, e.options.BatchSize, 0)
+		})
+		next = STEP_READS
+
+	case STEP_READS:
+		err = e.removeReads(report)
+		next = STEP_LOGINS
+
+	case STEP_LOGINS:
+		var deleted int
+		deleted, err = e.events.DeleteLoginEvents(report.Username)
+		report.LoginEventsDeleted += deleted
+		next = STEP_ACCOUNT
+
+	case STEP_ACCOUNT:
+		// Already gone if an earlier run stopped after deleting it.
+		err = e.users.DeleteUser(report.Username)
+		if err == userdb.ErrNotFound {
+			err = nil
+		}
+		next = STEP_DONE
+
+	default:
+		return errors.New("Unknown erasure step: " + report.Step)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	report.Step = next
+	if next == STEP_DONE {
+		return nil
+	}
+	if err = e.saveCheckpoint(*report); err != nil {
+		return err
+	}
+	e.report(*report)
+	return nil
+}
+
+// purgeNotes purges batches from next until it runs dry, adding each
+// batch to purged. Purged notes no longer match, and neither do notes the
+// user was taken off, so next always reads from the start.
+func (e *Eraser) purgeNotes(report *Report, purged *int, next func() ([]*notesdb.Note, error)) error {
+	for {
+		notes, err := next()
+		if err != nil {
+			return err
+		}
+		if len(notes) == 0 {
+			return nil
+		}
+
+		var ids []uuid.UUID
+		for _, note := range notes {
+			if note.Sender() == report.UserId || len(note.RecipientIds()) == 1 {
+				ids = append(ids, note.Id())
+				continue
+			}
+			if err = e.removeRecipient(note, report.UserId); err != nil {
+				return err
+			}
+			report.RemovedFromNotes++
+		}
+
+		if len(ids) > 0 {
+			if err = e.index.PurgeDocs(ids); err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			if err = e.notes.PurgeNote(id); err != nil {
+				return err
+			}
+		}
+
+		*purged += len(ids)
+		if err = e.saveCheckpoint(*report); err != nil {
+			return err
+		}
+		e.report(*report)
+
+		if len(notes) < e.options.BatchSize {
+			return nil
+		}
+	}
+}
+
+// removeRecipient takes the user off a note they received with others,
+// reindexing it first for the same reason purges go to Solr first.
+func (e *Eraser) removeRecipient(note *notesdb.Note, userId uuid.UUID) error {
+	if err := e.index.AddDoc(solrnotes.DocumentFromNote(note.WithoutRecipient(userId))); err != nil {
+		return err
+	}
+	return e.notes.RemoveRecipient(note.Id(), userId)
+}
+
+// removeReads takes the user off the readers of every shared note they've
+// read, a batch at a time, reindexing each note first as removeRecipient
+// does.
+func (e *Eraser) removeReads(report *Report) error {
+	for {
+		notes, err := e.notes.GetNotesReadBy(report.UserId, e.options.BatchSize, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, note := range notes {
+			err = e.index.AddDoc(solrnotes.DocumentFromNote(note.WithoutRecipient(report.UserId)))
+			if err != nil {
+				return err
+			}
+			if err = e.notes.RemoveReader(note.Id(), report.UserId); err != nil {
+				return err
+			}
+		}
+		report.ReadsRemoved += len(notes)
+
+		if err = e.saveCheckpoint(*report); err != nil {
+			return err
+		}
+		e.report(*report)
+
+		if len(notes) < e.options.BatchSize {
+			return nil
+		}
+	}
+}
+
+func (e *Eraser) report(report Report) {
+	if e.options.Progress != nil {
+		e.options.Progress(report)
+	}
+}
+
+// loadCheckpoint returns the saved report, or an empty one if there's no
+// checkpoint. A checkpoint for anyone but username is refused rather than
+// resumed.
+func (e *Eraser) loadCheckpoint(username string) (Report, error) {
+	var report Report
+	if e.options.CheckpointFile == "" {
+		return report, nil
+	}
+
+	data, err := ioutil.ReadFile(e.options.CheckpointFile)
+	if os.IsNotExist(err) {
+		return report, nil
+	}
+	if err != nil {
+		log.Printf("Failed to read checkpoint file %v. Err: %v", e.options.CheckpointFile, err)
+		return report, err
+	}
+
+	if err = json.Unmarshal(data, &report); err != nil {
+		log.Printf("Failed to parse checkpoint file %v. Err: %v", e.options.CheckpointFile, err)
+		return Report{}, err
+	}
+	if report.Username != userdb.NormalizeUsername(username) {
+		return Report{}, ErrCheckpointMismatch
+	}
+
+	return report, nil
+}
+
+// saveCheckpoint writes to a temporary file and renames it into place so
+// that a crash mid-write can't leave a truncated checkpoint behind.
+func (e *Eraser) saveCheckpoint(report Report) error {
+	if e.options.CheckpointFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	tmpFile := e.options.CheckpointFile + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		log.Printf("Failed to write checkpoint file %v. Err: %v", tmpFile, err)
+		return err
+	}
+
+	if err = os.Rename(tmpFile, e.options.CheckpointFile); err != nil {
+		log.Printf("Failed to move checkpoint into place at %v. Err: %v",
+			e.options.CheckpointFile, err)
+		return err
+	}
+
+	return nil
+}
+
+func (e *Eraser) clearCheckpoint() error {
+	if e.options.CheckpointFile == "" {
+		return nil
+	}
+
+	err := os.Remove(e.options.CheckpointFile)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove checkpoint file %v. Err: %v", e.options.CheckpointFile, err)
+		return err
+	}
+
+	return nil
+}
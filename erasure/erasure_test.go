@@ -0,0 +1,284 @@
+package erasure
+
+import (
+	"testing"
+	"time"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/solrnotes"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+func TestErase(t *testing.T) {
+	f := newFixture(t)
+
+	var reports []Report
+	eraser := f.eraser(Options{
+		BatchSize: 2,
+		Progress: func(r Report) { reports = append(reports, r) },
+	})
+
+	report, err := eraser.Erase("Alice")
+	if err != nil {
+		t.Fatal("Erase failed. Err: ", err)
+	}
+
+	// Alice's note to herself counts as sent, not received.
+	if report.UserId != f.alice || report.Step != STEP_DONE ||
+		report.SentPurged != 5 || report.ReceivedPurged != 3 || report.RemovedFromNotes != 1 ||
+		report.ReadsRemoved != 1 || report.ContactsDeleted != 3 || report.LoginEventsDeleted != 2 ||
+		report.CompletedAt.IsZero() {
+		t.Fatal("Unexpected report: ", report)
+	}
+	if last := reports[len(reports) - 1]; last != report {
+		t.Fatal("Expected the final report last, got: ", last)
+	}
+
+	f.assertErased(t)
+
+	if _, err = eraser.Erase("alice"); err != userdb.ErrNotFound {
+		t.Fatal("Expected ErrNotFound erasing again, got: ", err)
+	}
+}
+
+func TestEraseResumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "erasure")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	checkpointFile := filepath.Join(dir, "checkpoint")
+
+	f := newFixture(t)
+	f.index.failAfter = 2
+	options := Options{BatchSize: 2, CheckpointFile: checkpointFile}
+
+	report, err := f.eraser(options).Erase("alice")
+	if err == nil {
+		t.Fatal("Expected the first run to fail.")
+	}
+	if report.Step != STEP_SENT || report.SentPurged != 4 {
+		t.Fatal("Expected to stop after two batches of sent notes, got: ", report)
+	}
+
+	if _, err = f.eraser(options).Erase("bob"); err != ErrCheckpointMismatch {
+		t.Fatal("Expected ErrCheckpointMismatch, got: ", err)
+	}
+
+	f.index.failAfter = -1
+	report, err = f.eraser(options).Erase("alice")
+	if err != nil {
+		t.Fatal("Resumed erase failed. Err: ", err)
+	}
+	if report.SentPurged != 5 || report.ReceivedPurged != 3 || report.RemovedFromNotes != 1 ||
+		report.ContactsDeleted != 3 {
+		t.Fatal("Expected the counts to carry over, got: ", report)
+	}
+
+	f.assertErased(t)
+
+	if _, err = os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Fatal("Checkpoint file was not removed after completion.")
+	}
+}
+
+// fixture has alice, who is being erased, and her contacts bob and
+// carol, who aren't.
+type fixture struct {
+	users *userdb.MemoryUserdb
+	notes *notesdb.MemoryNotesdb
+	index *flakySolr
+	sessions *sessions.Manager
+	contacts *contacts.Contacts
+	events *lockout.MemoryLoginEvents
+
+	alice uuid.UUID
+	bob uuid.UUID
+	carol uuid.UUID
+	refreshToken string
+	erased []*notesdb.Note
+	kept []*notesdb.Note
+	group *notesdb.Note
+	public *notesdb.Note
+}
+
+func newFixture(t *testing.T) *fixture {
+	f := &fixture{
+		users: userdb.NewMemoryUserdb(),
+		notes: notesdb.NewMemoryNotesdb(),
+		index: newFlakySolr(),
+		contacts: contacts.NewContacts(contacts.NewMemoryContacts()),
+		events: lockout.NewMemoryLoginEvents(),
+	}
+
+	var err error
+	f.sessions, err = sessions.NewManager(sessions.NewMemorySessions(),
+		[]byte(strings.Repeat("k", sessions.MIN_KEY_LEN)), sessions.Options{})
+	if err != nil {
+		t.Fatal("Failed to make session manager. Err: ", err)
+	}
+
+	for _, name := range []string{"alice", "bob", "carol"} {
+		id, err := f.users.RegisterUser(name, "correct horse battery")
+		if err != nil {
+			t.Fatal("Failed to register ", name, ". Err: ", err)
+		}
+		switch name {
+		case "alice":
+			f.alice = id
+		case "bob":
+			f.bob = id
+		case "carol":
+			f.carol = id
+		}
+	}
+
+	f.contacts.Request(f.alice, f.bob)
+	f.contacts.Accept(f.bob, f.alice)
+	f.contacts.Request(f.bob, f.carol)
+	f.contacts.Accept(f.carol, f.bob)
+	f.contacts.Mute(f.carol, f.alice)
+
+	for i := 0; i < 4; i++ {
+		f.erased = append(f.erased, f.send(t, f.alice, f.bob))
+	}
+	f.erased = append(f.erased, f.send(t, f.alice, f.alice))
+	for i := 0; i < 3; i++ {
+		f.erased = append(f.erased, f.send(t, f.bob, f.alice))
+	}
+	f.kept = append(f.kept, f.send(t, f.bob, f.carol), f.send(t, f.carol, f.bob))
+	f.group = f.send(t, f.bob, f.alice, f.carol)
+	f.kept = append(f.kept, f.group)
+
+	// Carol's public note, read by alice and bob.
+	f.public = notesdb.NewSharedNote(f.carol, notesdb.VISIBILITY_PUBLIC, "For anyone", 42.2, 24.4,
+		time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC))
+	if err = f.notes.InsertNote(f.public); err != nil {
+		t.Fatal("Failed to insert note. Err: ", err)
+	}
+	if err = f.index.AddDoc(solrnotes.DocumentFromNote(f.public)); err != nil {
+		t.Fatal("Failed to index note. Err: ", err)
+	}
+	for _, reader := range []uuid.UUID{f.alice, f.bob} {
+		f.notes.AddReader(f.public.Id(), reader, nil)
+		f.index.MarkDocRead(f.public.Id(), reader)
+	}
+	f.kept = append(f.kept, f.public)
+
+	tokens, err := f.sessions.Issue(f.alice)
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+	f.refreshToken = tokens.RefreshToken
+
+	for _, username := range []string{"alice", "alice", "bob"} {
+		f.events.InsertLoginEvent(&lockout.LoginEvent{
+			Id: uuid.NewV4(),
+			Username: username,
+			Outcome: lockout.OUTCOME_SUCCESS,
+			At: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+		})
+	}
+
+	return f
+}
+
+func (f *fixture) eraser(options Options) *Eraser {
+	return NewEraser(f.users, f.notes, f.index, f.sessions, f.contacts, f.events, options)
+}
+
+func (f *fixture) send(t *testing.T, sender uuid.UUID, recipients ...uuid.UUID) *notesdb.Note {
+	note := notesdb.NewGroupNote(sender, recipients, "This is a test note", 42.2, 24.4,
+		time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC))
+	if err := f.notes.InsertNote(note); err != nil {
+		t.Fatal("Failed to insert note. Err: ", err)
+	}
+	if err := f.index.AddDoc(solrnotes.DocumentFromNote(note)); err != nil {
+		t.Fatal("Failed to index note. Err: ", err)
+	}
+	return note
+}
+
+func (f *fixture) assertErased(t *testing.T) {
+	if user, _ := f.users.GetUserById(f.alice); user != nil {
+		t.Fatal("Expected alice's account to be deleted.")
+	}
+	if _, err := f.sessions.Refresh(f.refreshToken); err == nil {
+		t.Fatal("Expected alice's sessions to be revoked.")
+	}
+
+	sent, _ := f.notes.GetNotesBySender(f.alice, 100, 0)
+	received, _ := f.notes.GetNotesByRecipient(f.alice, nil, 100, 0)
+	if len(sent) != 0 || len(received) != 0 {
+		t.Fatal("Expected alice's notes to be purged, got: ", sent, " ", received)
+	}
+	for _, note := range f.erased {
+		if doc, err := f.index.GetDoc(note.Id()); err == nil {
+			t.Fatal("Expected alice's docs to be purged, got: ", doc)
+		}
+	}
+	for _, note := range f.kept {
+		if _, err := f.index.GetDoc(note.Id()); err != nil {
+			t.Fatal("Expected note ", note.Id(), " between other users to be kept.")
+		}
+	}
+	if notes, _ := f.notes.GetNotesByIds([]uuid.UUID{f.group.Id()}); len(notes) != 1 ||
+		len(notes[0].RecipientIds()) != 1 || !notes[0].HasRecipient(f.carol) {
+		t.Fatal("Expected the group note to be kept for carol only, got: ", notes)
+	}
+	if doc, _ := f.index.GetDoc(f.group.Id()); doc == nil || doc.HasRecipient(f.alice) {
+		t.Fatal("Expected alice to be taken off the group note's doc, got: ", doc)
+	}
+	if notes, _ := f.notes.GetNotesByIds([]uuid.UUID{f.public.Id()}); len(notes) != 1 ||
+		notes[0].ReadBy(f.alice) || !notes[0].ReadBy(f.bob) {
+		t.Fatal("Expected alice to be taken off the public note's readers, got: ", notes)
+	}
+	if doc, _ := f.index.GetDoc(f.public.Id()); doc == nil || doc.ReadBy(f.alice) || !doc.ReadBy(f.bob) {
+		t.Fatal("Expected alice to be taken off the public note's doc, got: ", doc)
+	}
+
+	if left, _ := f.contacts.List(f.bob, contacts.STATUS_ACCEPTED, 10, 0); len(left) != 1 ||
+		left[0].ContactId != f.carol {
+		t.Fatal("Expected only bob's contact with carol to be left, got: ", left)
+	}
+	if muted, _ := f.contacts.MutedIds(f.carol); len(muted) != 0 {
+		t.Fatal("Expected carol's mute of alice to be deleted, got: ", muted)
+	}
+
+	if events, _ := f.events.GetLoginEvents("alice", 10, 0); len(events) != 0 {
+		t.Fatal("Expected alice's login events to be deleted, got: ", events)
+	}
+	if events, _ := f.events.GetLoginEvents("bob", 10, 0); len(events) != 1 {
+		t.Fatal("Expected bob's login events to be kept, got: ", events)
+	}
+}
+
+// flakySolr can be told to start failing purges after a given number of
+// them.
+type flakySolr struct {
+	*solrnotes.MemorySolr
+	purges int
+	failAfter int
+}
+
+func newFlakySolr() *flakySolr {
+	return &flakySolr{MemorySolr: solrnotes.NewMemorySolr(), failAfter: -1}
+}
+
+func (sc *flakySolr) PurgeDocs(ids []uuid.UUID) error {
+	if sc.failAfter >= 0 && sc.purges >= sc.failAfter {
+		return errors.New("solr is down")
+	}
+	sc.purges++
+	return sc.MemorySolr.PurgeDocs(ids)
+}
@@ -0,0 +1,436 @@
+package export
+
+import (
+	"io"
+	"log"
+	"time"
+	"archive/zip"
+	"encoding/json"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+const (
+	DEFAULT_BATCH_SIZE = 500
+
+	// FORMAT_VERSION is bumped whenever a file in the archive changes in a
+	// way a reader would notice. 2 added recipients to notes, 3 added
+	// visibility and the shared notes the user has read, 4 added threads,
+	// 5 added the user's read receipts, 6 added when a note was edited, and
+	// 7 added the texts the user's edits replaced.
+	FORMAT_VERSION = 7
+
+	// The files in an archive.
+	MANIFEST_FILE = "export.json"
+	PROFILE_FILE = "profile.json"
+	NOTES_FILE = "notes.json"
+	NOTES_GEOJSON_FILE = "notes.geojson"
+
+	// A note's direction, from the exporting user's point of view.
+	DIRECTION_SENT = "sent"
+	DIRECTION_RECEIVED = "received"
+	DIRECTION_SELF = "self"
+	// DIRECTION_READ is a contacts or public note the user has read.
+	DIRECTION_READ = "read"
+)
+
+// Exporter writes out everything GeoNote holds about a user as a zip
+// archive: their profile, every note they sent or received, and every
+// shared note they've read, with its text, coordinates, visibility,
+// recipients, timestamps and read and deleted state, and for notes they
+// sent, the earlier texts their edits replaced.
+//
+// The notes are there twice, as a plain JSON array and as a GeoJSON
+// FeatureCollection for loading into mapping tools.
+type Exporter struct {
+	users userdb.UserdbConnection
+	notes notesdb.NotesdbConnection
+	options Options
+	now func() time.Time
+}
+
+type Options struct {
+	// BatchSize is how many notes are read per round trip. Defaults to
+	// DEFAULT_BATCH_SIZE.
+	BatchSize int
+}
+
+// Summary is what an export contains. It's also written to the archive
+// as MANIFEST_FILE. Notes a user left for themselves count as sent.
+type Summary struct {
+	Format int `json:"format"`
+	UserId uuid.UUID `json:"userId"`
+	Username string `json:"username"`
+	SentNotes int `json:"sentNotes"`
+	ReceivedNotes int `json:"receivedNotes"`
+	ReadNotes int `json:"readNotes"`
+	ExportedAt time.Time `json:"exportedAt"`
+	Files []string `json:"files"`
+}
+
+type profileJson struct {
+	Id string `json:"id"`
+	Username string `json:"username"`
+	DisplayName string `json:"displayName"`
+	AvatarRef string `json:"avatarRef"`
+	Bio string `json:"bio"`
+	TimeZone string `json:"timeZone"`
+	UnlockRadiusKm float64 `json:"unlockRadiusKm"`
+	HideReadReceipts bool `json:"hideReadReceipts"`
+	UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+}
+
+type noteJson struct {
+	Id string `json:"id"`
+	Direction string `json:"direction"`
+	Sender string `json:"sender"`
+	Visibility string `json:"visibility"`
+	Recipient string `json:"recipient"`
+	Recipients []string `json:"recipients"`
+	Text string `json:"text"`
+	Latitude float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	TimeSent time.Time `json:"timeSent"`
+	Read bool `json:"read"`
+	Deleted bool `json:"deleted"`
+	Parent string `json:"parent,omitempty"`
+	Thread string `json:"thread"`
+	Direct bool `json:"direct,omitempty"`
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+	Receipt *receiptJson `json:"receipt,omitempty"`
+	Versions []versionJson `json:"versions,omitempty"`
+}
+
+// receiptJson is the user's own read receipt for a note they received or
+// read. Receipts other users left on the user's notes are theirs, and
+// aren't exported.
+type receiptJson struct {
+	ReadAt time.Time `json:"readAt"`
+	Latitude *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+// versionJson is a note's text as it was before one of the user's edits
+// replaced it.
+type versionJson struct {
+	Text string `json:"text"`
+	WrittenAt time.Time `json:"writtenAt"`
+	ReplacedAt time.Time `json:"replacedAt"`
+}
+
+// featureJson is a note as a GeoJSON Feature. Coordinates are
+// [longitude, latitude], as GeoJSON has them, and the rest of the note is
+// in its properties.
+type featureJson struct {
+	Type string `json:"type"`
+	Id string `json:"id"`
+	Geometry pointJson `json:"geometry"`
+	Properties noteJson `json:"properties"`
+}
+
+type pointJson struct {
+	Type string `json:"type"`
+	Coordinates [2]float64 `json:"coordinates"`
+}
+
+func NewExporter(users userdb.UserdbConnection, notes notesdb.NotesdbConnection, options Options) *Exporter {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DEFAULT_BATCH_SIZE
+	}
+	return &Exporter{users: users, notes: notes, options: options, now: time.Now}
+}
+
+// Export writes the user's archive to w, or returns userdb.ErrNotFound
+// before writing anything if there's no such user. Notes are read a
+// batch at a time rather than all at once; ones sent or purged while an
+// export is running may or may not be in it.
+func (x *Exporter) Export(userId uuid.UUID, w io.Writer) (Summary, error) {
+	profile, err := x.users.GetProfile(userId)
+	if err != nil {
+		return Summary{}, err
+	}
+	if profile == nil {
+		return Summary{}, userdb.ErrNotFound
+	}
+
+	summary := Summary{
+		Format: FORMAT_VERSION,
+		UserId: userId,
+		Username: profile.Username,
+		ExportedAt: x.now().UTC().Truncate(time.Second),
+	}
+	archive := zip.NewWriter(w)
+
+	err = x.writeFile(archive, &summary, PROFILE_FILE, func(f io.Writer) error {
+		return json.NewEncoder(f).Encode(toProfileJson(profile))
+	})
+	if err != nil {
+		return summary, err
+	}
+
+	err = x.writeFile(archive, &summary, NOTES_FILE, func(f io.Writer) error {
+		return x.writeNotes(f, userId, "[", "]\n", func(note noteJson) interface{} {
+			switch note.Direction {
+			case DIRECTION_RECEIVED:
+				summary.ReceivedNotes++
+			case DIRECTION_READ:
+				summary.ReadNotes++
+			default:
+				summary.SentNotes++
+			}
+			return note
+		})
+	})
+	if err != nil {
+		return summary, err
+	}
+
+	err = x.writeFile(archive, &summary, NOTES_GEOJSON_FILE, func(f io.Writer) error {
+		return x.writeNotes(f, userId, `{"type":"FeatureCollection","features":[`, "]}\n",
+			func(note noteJson) interface{} {
+				return featureJson{
+					Type: "Feature",
+					Id: note.Id,
+					Geometry: pointJson{Type: "Point", Coordinates: [2]float64{note.Longitude, note.Latitude}},
+					Properties: note,
+				}
+			})
+	})
+	if err != nil {
+		return summary, err
+	}
+
+	// The manifest lists itself too, so it's added before it's written.
+	summary.Files = append(summary.Files, MANIFEST_FILE)
+	f, err := x.create(archive, MANIFEST_FILE, summary.ExportedAt)
+	if err == nil {
+		err = json.NewEncoder(f).Encode(summary)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		log.Printf("Failed to finish export of user %v. Err: %v", userId, err)
+		return summary, err
+	}
+
+	return summary, nil
+}
+
+func (x *Exporter) writeFile(
+	archive *zip.Writer,
+	summary *Summary,
+	name string,
+	write func(f io.Writer) error) error {
+	f, err := x.create(archive, name, summary.ExportedAt)
+	if err == nil {
+		err = write(f)
+	}
+	if err != nil {
+		log.Printf("Failed to write %v for user %v. Err: %v", name, summary.UserId, err)
+		return err
+	}
+
+	summary.Files = append(summary.Files, name)
+	return nil
+}
+
+func (x *Exporter) create(archive *zip.Writer, name string, modified time.Time) (io.Writer, error) {
+	return archive.CreateHeader(&zip.FileHeader{
+		Name: name,
+		Method: zip.Deflate,
+		Modified: modified,
+	})
+}
+
+// writeNotes writes the user's notes, each as convert makes it, as JSON
+// array elements between start and end.
+func (x *Exporter) writeNotes(
+	f io.Writer,
+	userId uuid.UUID,
+	start string,
+	end string,
+	convert func(noteJson) interface{}) error {
+	if _, err := io.WriteString(f, start); err != nil {
+		return err
+	}
+
+	separator := "\n"
+	err := x.walkNotes(userId, func(note *notesdb.Note) error {
+		exported := toNoteJson(userId, note)
+		if exported.Direction == DIRECTION_SENT || exported.Direction == DIRECTION_SELF {
+			versions, err := x.versions(note)
+			if err != nil {
+				return err
+			}
+			exported.Versions = versions
+		}
+
+		data, err := json.Marshal(convert(exported))
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(f, separator); err != nil {
+			return err
+		}
+		separator = ",\n"
+		_, err = f.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(f, "\n" + end)
+	return err
+}
+
+// walkNotes calls fn with each of the user's notes once: the ones they
+// sent, newest first, then the ones they received, then the shared ones
+// they've read. Offsets can shift if notes arrive mid-walk, so notes
+// already seen are skipped rather than repeated.
+func (x *Exporter) walkNotes(userId uuid.UUID, fn func(*notesdb.Note) error) error {
+	pages := []func(offset int) ([]*notesdb.Note, error){
+		func(offset int) ([]*notesdb.Note, error) {
+			return x.notes.GetNotesBySender(userId, x.options.BatchSize, offset)
+		},
+		func(offset int) ([]*notesdb.Note, error) {
+			return x.notes.GetNotesByRecipient(userId, nil, x.options.BatchSize, offset)
+		},
+		func(offset int) ([]*notesdb.Note, error) {
+			return x.notes.GetNotesReadBy(userId, x.options.BatchSize, offset)
+		},
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	for _, page := range pages {
+		for offset := 0; ; offset += x.options.BatchSize {
+			notes, err := page(offset)
+			if err != nil {
+				return err
+			}
+
+			for _, note := range notes {
+				if seen[note.Id()] {
+					continue
+				}
+				seen[note.Id()] = true
+				if err = fn(note); err != nil {
+					return err
+				}
+			}
+
+			if len(notes) < x.options.BatchSize {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// versions returns the note's earlier texts, oldest first. Only edited
+// notes have any, so the rest aren't looked up.
+func (x *Exporter) versions(note *notesdb.Note) ([]versionJson, error) {
+	if !note.Edited() {
+		return nil, nil
+	}
+
+	versions, err := x.notes.GetNoteVersions(note.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]versionJson, len(versions))
+	for i, version := range versions {
+		result[i] = versionJson{
+			Text: version.Text,
+			WrittenAt: version.WrittenAt.UTC(),
+			ReplacedAt: version.ReplacedAt.UTC(),
+		}
+	}
+	return result, nil
+}
+
+func toProfileJson(profile *userdb.Profile) profileJson {
+	result := profileJson{
+		Id: profile.UserId.String(),
+		Username: profile.Username,
+		DisplayName: profile.DisplayName,
+		AvatarRef: profile.AvatarRef,
+		Bio: profile.Bio,
+		TimeZone: profile.TimeZone,
+		UnlockRadiusKm: profile.UnlockRadiusKm,
+		HideReadReceipts: profile.HideReadReceipts,
+	}
+	if !profile.UpdatedAt.IsZero() {
+		result.UpdatedAt = &profile.UpdatedAt
+	}
+	return result
+}
+
+// toNoteJson gives the user their own read and deleted state for notes
+// they received or read, and the note's as a whole for notes they sent.
+func toNoteJson(userId uuid.UUID, note *notesdb.Note) noteJson {
+	ids := note.RecipientIds()
+	direction := DIRECTION_RECEIVED
+	if note.Sender() == userId && len(ids) == 1 && ids[0] == userId {
+		direction = DIRECTION_SELF
+	} else if note.Sender() == userId {
+		direction = DIRECTION_SENT
+	} else if note.Shared() {
+		direction = DIRECTION_READ
+	}
+
+	read, deleted := note.Read(), note.Deleted()
+	var receipt *receiptJson
+	if direction == DIRECTION_RECEIVED || direction == DIRECTION_READ {
+		read, deleted = note.ReadBy(userId), note.DeletedFor(userId)
+		if own := note.ReceiptFor(userId); own != nil {
+			receipt = &receiptJson{ReadAt: own.ReadAt.UTC()}
+			if own.Located {
+				receipt.Latitude, receipt.Longitude = &own.Latitude, &own.Longitude
+			}
+		}
+	}
+
+	recipients := make([]string, len(ids))
+	for i, id := range ids {
+		recipients[i] = id.String()
+	}
+	recipient := ""
+	if len(ids) > 0 {
+		recipient = ids[0].String()
+	}
+	parent := ""
+	if note.IsReply() {
+		parent = note.ParentId().String()
+	}
+	var editedAt *time.Time
+	if note.Edited() {
+		timeEdited := note.TimeEdited().UTC()
+		editedAt = &timeEdited
+	}
+
+	return noteJson{
+		Id: note.Id().String(),
+		Direction: direction,
+		Sender: note.Sender().String(),
+		Visibility: note.Visibility(),
+		Recipient: recipient,
+		Recipients: recipients,
+		Text: note.Text(),
+		Latitude: note.Latitude(),
+		Longitude: note.Longitude(),
+		TimeSent: note.TimeSent().UTC(),
+		Read: read,
+		Deleted: deleted,
+		Parent: parent,
+		Thread: note.ThreadId().String(),
+		Direct: note.Direct(),
+		EditedAt: editedAt,
+		Receipt: receipt,
+	}
+}
@@ -0,0 +1,182 @@
+package export
+
+import (
+	"testing"
+	"time"
+	"bytes"
+	"archive/zip"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+func TestExport(t *testing.T) {
+	users := userdb.NewMemoryUserdb()
+	notes := notesdb.NewMemoryNotesdb()
+	alice := register(t, users, "alice")
+	bob := register(t, users, "bob")
+	carol := register(t, users, "carol")
+	if err := users.UpdateProfile(&userdb.Profile{UserId: alice, DisplayName: "Alice", Bio: "hi"}); err != nil {
+		t.Fatal("Failed to update profile. Err: ", err)
+	}
+
+	sent := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		insert(t, notes, notesdb.NewNote(alice, bob, "to bob", 42.2, 24.4, sent))
+	}
+	edited := notesdb.NewNote(alice, carol, "first", 42.2, 24.4, sent)
+	insert(t, notes, edited)
+	for i, text := range []string{"second", "to carol"} {
+		if err := notes.EditNote(edited.Id(), text, sent.Add(time.Duration(i + 1) * time.Minute)); err != nil {
+			t.Fatal("Failed to edit note. Err: ", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		insert(t, notes, notesdb.NewNote(bob, alice, "to alice", -1.5, 2.5, sent))
+	}
+	insert(t, notes, notesdb.NewNote(alice, alice, "to self", 0, 0, sent))
+	insert(t, notes, notesdb.NewNote(bob, carol, "not alice's", 1, 1, sent))
+	group := notesdb.NewGroupNote(bob, []uuid.UUID{carol, alice}, "to both", -1.5, 2.5, sent)
+	insert(t, notes, group)
+	receipt := &notesdb.Receipt{ReadAt: sent.Add(time.Minute), Located: true, Latitude: -1.5, Longitude: 2.5}
+	if err := notes.MarkNoteRead(group.Id(), alice, receipt); err != nil {
+		t.Fatal("Failed to mark note read. Err: ", err)
+	}
+	public := notesdb.NewSharedNote(carol, notesdb.VISIBILITY_PUBLIC, "for anyone", -1.5, 2.5, sent)
+	insert(t, notes, public)
+	if err := notes.AddReader(public.Id(), alice, nil); err != nil {
+		t.Fatal("Failed to add reader. Err: ", err)
+	}
+	insert(t, notes, notesdb.NewSharedNote(carol, notesdb.VISIBILITY_PUBLIC, "unread", -1.5, 2.5, sent))
+
+	x := NewExporter(users, notes, Options{BatchSize: 2})
+	x.now = func() time.Time {
+		return sent.Add(time.Hour)
+	}
+
+	var buf bytes.Buffer
+	summary, err := x.Export(alice, &buf)
+	if err != nil {
+		t.Fatal("Export failed. Err: ", err)
+	}
+	if summary.Username != "alice" || summary.SentNotes != 5 || summary.ReceivedNotes != 3 ||
+		summary.ReadNotes != 1 || len(summary.Files) != 4 {
+		t.Fatal("Unexpected summary: ", summary)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal("Export isn't a zip archive. Err: ", err)
+	}
+	if len(archive.File) != len(summary.Files) {
+		t.Fatal("Expected the files in the summary, got: ", archive.File)
+	}
+
+	var manifest Summary
+	readJson(t, archive, MANIFEST_FILE, &manifest)
+	if manifest.Format != FORMAT_VERSION || manifest.SentNotes != 5 || manifest.ReceivedNotes != 3 ||
+		!manifest.ExportedAt.Equal(sent.Add(time.Hour)) {
+		t.Fatal("Unexpected manifest: ", manifest)
+	}
+
+	var profile profileJson
+	readJson(t, archive, PROFILE_FILE, &profile)
+	if profile.Id != alice.String() || profile.DisplayName != "Alice" || profile.Bio != "hi" {
+		t.Fatal("Unexpected profile: ", profile)
+	}
+
+	var exported []noteJson
+	readJson(t, archive, NOTES_FILE, &exported)
+	directions := make(map[string]int)
+	for _, note := range exported {
+		directions[note.Direction]++
+		if note.Thread != note.Id || note.Parent != "" {
+			t.Fatal("Expected every note to start its own thread, got: ", note)
+		}
+		if note.Direction == DIRECTION_RECEIVED && (note.Text == "not alice's" || note.Latitude != -1.5) {
+			t.Fatal("Unexpected received note: ", note)
+		}
+		if note.Direction == DIRECTION_READ && (note.Text != "for anyone" || !note.Read ||
+			note.Visibility != notesdb.VISIBILITY_PUBLIC || note.Recipient != "") {
+			t.Fatal("Unexpected read note: ", note)
+		}
+		// The group note is read by alice, but not by carol.
+		if note.Text == "to both" && (!note.Read || len(note.Recipients) != 2 || note.Recipients[1] != alice.String() ||
+			note.Receipt == nil || !note.Receipt.ReadAt.Equal(receipt.ReadAt) || *note.Receipt.Latitude != -1.5) {
+			t.Fatal("Expected alice's own state on the group note, got: ", note)
+		}
+		if note.Text != "to both" && note.Receipt != nil {
+			t.Fatal("Expected a receipt only where alice left one, got: ", note)
+		}
+		if note.Id == edited.Id().String() && (len(note.Versions) != 2 || note.Versions[0].Text != "first" ||
+			note.Versions[1].Text != "second" || !note.Versions[1].ReplacedAt.Equal(sent.Add(2 * time.Minute))) {
+			t.Fatal("Expected the texts alice's edits replaced, got: ", note)
+		}
+		if note.Id != edited.Id().String() && len(note.Versions) != 0 {
+			t.Fatal("Expected versions only on the edited note, got: ", note)
+		}
+	}
+	if len(exported) != 9 || directions[DIRECTION_SENT] != 4 || directions[DIRECTION_READ] != 1 ||
+		directions[DIRECTION_RECEIVED] != 3 || directions[DIRECTION_SELF] != 1 {
+		t.Fatal("Expected each of alice's notes once, got: ", exported)
+	}
+
+	var collection struct {
+		Type string
+		Features []featureJson
+	}
+	readJson(t, archive, NOTES_GEOJSON_FILE, &collection)
+	if collection.Type != "FeatureCollection" || len(collection.Features) != 9 {
+		t.Fatal("Unexpected GeoJSON: ", collection)
+	}
+	for _, feature := range collection.Features {
+		point := feature.Geometry.Coordinates
+		if feature.Properties.Direction == DIRECTION_RECEIVED && (point[0] != 2.5 || point[1] != -1.5) {
+			t.Fatal("Expected [longitude, latitude] coordinates, got: ", point)
+		}
+	}
+
+	if _, err = x.Export(uuid.NewV4(), &buf); err != userdb.ErrNotFound {
+		t.Fatal("Expected ErrNotFound, got: ", err)
+	}
+}
+
+func register(t *testing.T, users *userdb.MemoryUserdb, username string) uuid.UUID {
+	id, err := users.RegisterUser(username, "correct horse battery")
+	if err != nil {
+		t.Fatal("Failed to register ", username, ". Err: ", err)
+	}
+	return id
+}
+
+func insert(t *testing.T, notes *notesdb.MemoryNotesdb, note *notesdb.Note) {
+	if err := notes.InsertNote(note); err != nil {
+		t.Fatal("Failed to insert note. Err: ", err)
+	}
+}
+
+func readJson(t *testing.T, archive *zip.Reader, name string, v interface{}) {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal("Failed to open ", name, ". Err: ", err)
+		}
+		defer f.Close()
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatal("Failed to read ", name, ". Err: ", err)
+		}
+		if err = json.Unmarshal(data, v); err != nil {
+			t.Fatal(name, " isn't valid JSON. Err: ", err, "\n", string(data))
+		}
+		return
+	}
+	t.Fatal("No ", name, " in the archive.")
+}
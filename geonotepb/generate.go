@@ -0,0 +1,5 @@
+// Package geonotepb holds the protobuf contract for the GeoNote service and
+// the Go code generated from it. Edit geonote.proto, then run go generate.
+package geonotepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative geonote.proto
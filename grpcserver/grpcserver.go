@@ -0,0 +1,1983 @@
+package grpcserver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"time"
+	"log"
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/attachments"
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/erasure"
+	"github.com/dbenny42/geonote/export"
+	"github.com/dbenny42/geonote/geonotepb"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/solrnotes"
+	"github.com/dbenny42/geonote/unlocks"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+const (
+	MAX_NOTE_LEN = 2000
+	// MAX_RECIPIENTS is how many users one note can be left for.
+	MAX_RECIPIENTS = contacts.MAX_GROUP_MEMBERS
+	DEFAULT_PAGE_SIZE = 20
+	MAX_PAGE_SIZE = 100
+	DEFAULT_RADIUS_KM = 0.1
+	MAX_RADIUS_KM = 50
+
+	// EXPORT_CHUNK_BYTES is how much archive is buffered up for each
+	// ExportChunk.
+	EXPORT_CHUNK_BYTES = 64 * 1024
+)
+
+// Server implements the GeoNote gRPC service over the same stores that
+// back the HTTP API.
+type Server struct {
+	geonotepb.UnimplementedGeoNoteServer
+
+	users userdb.UserdbConnection
+	notes notesdb.NotesdbConnection
+	index solrnotes.SolrConnection
+	sessions *sessions.Manager
+	guard *lockout.Guard
+	resetter *userdb.Resetter
+	twoFactor *userdb.TwoFactor
+	contacts *contacts.Contacts
+	attachments *attachments.Attachments
+	eraser *erasure.Eraser
+	exporter *export.Exporter
+	hub *unlocks.Hub
+	now func() time.Time
+}
+
+func NewServer(
+	users userdb.UserdbConnection,
+	notes notesdb.NotesdbConnection,
+	index solrnotes.SolrConnection,
+	sessions *sessions.Manager,
+	guard *lockout.Guard,
+	resetter *userdb.Resetter,
+	twoFactor *userdb.TwoFactor,
+	contacts *contacts.Contacts,
+	attachments *attachments.Attachments,
+	eraser *erasure.Eraser,
+	hub *unlocks.Hub) *Server {
+	return &Server{
+		users: users,
+		notes: notes,
+		index: index,
+		sessions: sessions,
+		guard: guard,
+		resetter: resetter,
+		twoFactor: twoFactor,
+		contacts: contacts,
+		attachments: attachments,
+		eraser: eraser,
+		exporter: export.NewExporter(users, notes, export.Options{}),
+		hub: hub,
+		now: time.Now,
+	}
+}
+
+// Register adds the GeoNote service to a grpc.Server.
+func (s *Server) Register(grpcServer *grpc.Server) {
+	geonotepb.RegisterGeoNoteServer(grpcServer, s)
+}
+
+func (s *Server) RegisterUser(
+	ctx context.Context,
+	request *geonotepb.RegisterUserRequest) (*geonotepb.RegisterUserResponse, error) {
+	if err := validateCredentials(request.Username, request.Password); err != nil {
+		return nil, err
+	}
+
+	id, err := s.users.RegisterUser(request.Username, request.Password)
+	if err != nil {
+		return nil, validationError(err)
+	}
+
+	return &geonotepb.RegisterUserResponse{UserId: id.String()}, nil
+}
+
+func (s *Server) IsUsernameAvailable(
+	ctx context.Context,
+	request *geonotepb.IsUsernameAvailableRequest) (*geonotepb.IsUsernameAvailableResponse, error) {
+	if request.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "username is required.")
+	}
+
+	available, err := s.users.IsUsernameAvailable(request.Username)
+	if invalid, ok := err.(*userdb.ValidationError); ok {
+		return &geonotepb.IsUsernameAvailableResponse{Available: false, Reason: invalid.Error()}, nil
+	}
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return &geonotepb.IsUsernameAvailableResponse{Available: available}, nil
+}
+
+func (s *Server) Login(
+	ctx context.Context,
+	request *geonotepb.LoginRequest) (*geonotepb.LoginResponse, error) {
+	if err := validateCredentials(request.Username, request.Password); err != nil {
+		return nil, err
+	}
+
+	id, validLogin, err := s.guard.CheckLogin(request.Username, request.Password, request.Code, peerAddress(ctx))
+	if err == userdb.ErrSecondFactorRequired {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if _, ok := err.(*lockout.LockedOutError); ok {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, internal(err)
+	}
+	if !validLogin {
+		return nil, status.Error(codes.Unauthenticated, "Incorrect username or password.")
+	}
+
+	tokens, err := s.sessions.Issue(id)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return &geonotepb.LoginResponse{UserId: id.String(), Tokens: toTokensProto(tokens)}, nil
+}
+
+// DeleteUser erases the caller's own account: their sessions, contacts,
+// and every note they sent or received. See erasure.Eraser.
+func (s *Server) DeleteUser(
+	ctx context.Context,
+	request *geonotepb.DeleteUserRequest) (*geonotepb.DeleteUserResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "username is required.")
+	}
+
+	user, err := s.users.GetUserById(caller)
+	if err != nil {
+		return nil, internal(err)
+	}
+	if user == nil || user.Name != userdb.NormalizeUsername(request.Username) {
+		return nil, status.Error(codes.PermissionDenied, "username must be the signed-in user.")
+	}
+
+	_, err = s.eraser.Erase(user.Name)
+	if err == userdb.ErrNotFound {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return &geonotepb.DeleteUserResponse{}, nil
+}
+
+func (s *Server) RefreshSession(
+	ctx context.Context,
+	request *geonotepb.RefreshSessionRequest) (*geonotepb.SessionTokens, error) {
+	if request.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required.")
+	}
+
+	tokens, err := s.sessions.Refresh(request.RefreshToken)
+	if err != nil {
+		return nil, sessionError(err)
+	}
+
+	return toTokensProto(tokens), nil
+}
+
+// Logout ends the session the refresh token belongs to. The matching
+// access token keeps working until it expires.
+func (s *Server) Logout(
+	ctx context.Context,
+	request *geonotepb.LogoutRequest) (*geonotepb.LogoutResponse, error) {
+	if request.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required.")
+	}
+
+	if err := s.sessions.Revoke(request.RefreshToken); err != nil {
+		return nil, sessionError(err)
+	}
+
+	return &geonotepb.LogoutResponse{}, nil
+}
+
+func (s *Server) LogoutEverywhere(
+	ctx context.Context,
+	request *geonotepb.LogoutEverywhereRequest) (*geonotepb.LogoutEverywhereResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.sessions.RevokeAll(caller); err != nil {
+		return nil, internal(err)
+	}
+
+	return &geonotepb.LogoutEverywhereResponse{}, nil
+}
+
+// ChangePassword ends every session of the caller, since a password is
+// usually changed because someone else might know it, and returns a new
+// one. Wrong old passwords count towards the caller's login lockout, so
+// a stolen access token can't be used to guess it.
+func (s *Server) ChangePassword(
+	ctx context.Context,
+	request *geonotepb.ChangePasswordRequest) (*geonotepb.SessionTokens, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.OldPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "old_password is required.")
+	}
+	if request.NewPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "new_password is required.")
+	}
+
+	user, err := s.users.GetUserById(caller)
+	if err != nil {
+		return nil, internal(err)
+	}
+	if user == nil {
+		return nil, status.Error(codes.Unauthenticated, "No such user.")
+	}
+
+	_, changed, err := s.guard.Attempt(user.Name, peerAddress(ctx), func(username string) (uuid.UUID, bool, error) {
+		changed, err := s.users.ChangePassword(username, request.OldPassword, request.NewPassword)
+		return caller, changed, err
+	})
+	if _, ok := err.(*lockout.LockedOutError); ok {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, validationError(err)
+	}
+	if !changed {
+		return nil, status.Error(codes.PermissionDenied, "Incorrect password.")
+	}
+
+	if err = s.sessions.RevokeAll(caller); err != nil {
+		return nil, internal(err)
+	}
+	tokens, err := s.sessions.Issue(caller)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return toTokensProto(tokens), nil
+}
+
+func (s *Server) RequestPasswordReset(
+	ctx context.Context,
+	request *geonotepb.RequestPasswordResetRequest) (*geonotepb.RequestPasswordResetResponse, error) {
+	if request.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "username is required.")
+	}
+
+	if err := s.resetter.RequestReset(request.Username); err != nil {
+		return nil, internal(err)
+	}
+
+	return &geonotepb.RequestPasswordResetResponse{}, nil
+}
+
+func (s *Server) ResetPassword(
+	ctx context.Context,
+	request *geonotepb.ResetPasswordRequest) (*geonotepb.ResetPasswordResponse, error) {
+	if request.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required.")
+	}
+	if request.NewPassword == "" {
+		return nil, status.Error(codes.InvalidArgument, "new_password is required.")
+	}
+
+	id, err := s.resetter.Reset(request.Token, request.NewPassword)
+	if err == userdb.ErrInvalidResetToken {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, validationError(err)
+	}
+
+	if err = s.sessions.RevokeAll(id); err != nil {
+		return nil, internal(err)
+	}
+
+	return &geonotepb.ResetPasswordResponse{}, nil
+}
+
+func (s *Server) EnrollTotp(
+	ctx context.Context,
+	request *geonotepb.EnrollTotpRequest) (*geonotepb.TotpSetup, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	setup, err := s.twoFactor.Enroll(caller)
+	if err != nil {
+		return nil, totpError(err)
+	}
+
+	return &geonotepb.TotpSetup{Secret: setup.Secret, Uri: setup.Uri}, nil
+}
+
+func (s *Server) ConfirmTotp(
+	ctx context.Context,
+	request *geonotepb.ConfirmTotpRequest) (*geonotepb.ConfirmTotpResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required.")
+	}
+
+	var recoveryCodes []string
+	err = s.attemptTotpCode(ctx, caller, func() error {
+		var err error
+		recoveryCodes, err = s.twoFactor.Confirm(caller, request.Code)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &geonotepb.ConfirmTotpResponse{RecoveryCodes: recoveryCodes}, nil
+}
+
+func (s *Server) DisableTotp(
+	ctx context.Context,
+	request *geonotepb.DisableTotpRequest) (*geonotepb.DisableTotpResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if request.Code == "" {
+		return nil, status.Error(codes.InvalidArgument, "code is required.")
+	}
+
+	err = s.attemptTotpCode(ctx, caller, func() error {
+		return s.twoFactor.Disable(caller, request.Code)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &geonotepb.DisableTotpResponse{}, nil
+}
+
+// attemptTotpCode runs check, which confirms or disables two-factor with
+// a code, under the caller's login lockout, so codes can't be guessed off
+// a hijacked session any faster than at login.
+func (s *Server) attemptTotpCode(ctx context.Context, caller uuid.UUID, check func() error) error {
+	user, err := s.users.GetUserById(caller)
+	if err != nil {
+		return internal(err)
+	}
+	if user == nil {
+		return status.Error(codes.Unauthenticated, "No such user.")
+	}
+
+	_, valid, err := s.guard.AttemptCode(user.Name, peerAddress(ctx), func(string) (uuid.UUID, bool, error) {
+		err := check()
+		if err == userdb.ErrInvalidTotpCode {
+			return uuid.Nil, false, nil
+		}
+		return caller, err == nil, err
+	})
+	if _, ok := err.(*lockout.LockedOutError); ok {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return totpError(err)
+	}
+	if !valid {
+		return totpError(userdb.ErrInvalidTotpCode)
+	}
+	return nil
+}
+
+func (s *Server) GetProfile(
+	ctx context.Context,
+	request *geonotepb.GetProfileRequest) (*geonotepb.Profile, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ownProfile(caller)
+}
+
+func (s *Server) UpdateProfile(
+	ctx context.Context,
+	request *geonotepb.UpdateProfileRequest) (*geonotepb.Profile, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.users.UpdateProfile(&userdb.Profile{
+		UserId: caller,
+		DisplayName: request.DisplayName,
+		AvatarRef: request.AvatarRef,
+		Bio: request.Bio,
+		TimeZone: request.TimeZone,
+		UnlockRadiusKm: request.UnlockRadiusKm,
+		HideReadReceipts: request.HideReadReceipts,
+	})
+	if err == userdb.ErrNotFound {
+		return nil, status.Error(codes.Unauthenticated, "No such user.")
+	}
+	if err != nil {
+		return nil, validationError(err)
+	}
+
+	return s.ownProfile(caller)
+}
+
+func (s *Server) GetProfiles(
+	ctx context.Context,
+	request *geonotepb.GetProfilesRequest) (*geonotepb.GetProfilesResponse, error) {
+	if _, err := s.authenticate(ctx); err != nil {
+		return nil, err
+	}
+	if len(request.UserIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_ids is required.")
+	}
+	if len(request.UserIds) > MAX_PAGE_SIZE {
+		return nil, status.Error(codes.InvalidArgument,
+			"At most " + strconv.Itoa(MAX_PAGE_SIZE) + " user_ids can be looked up at once.")
+	}
+
+	ids := make([]uuid.UUID, len(request.UserIds))
+	for i, value := range request.UserIds {
+		id, err := parseId("user_id", value)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+
+	profiles, err := s.users.GetProfiles(ids)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	response := &geonotepb.GetProfilesResponse{}
+	for _, profile := range profiles {
+		response.Profiles = append(response.Profiles, &geonotepb.Profile{
+			UserId: profile.UserId.String(),
+			Username: profile.Username,
+			DisplayName: profile.DisplayName,
+			AvatarRef: profile.AvatarRef,
+			Bio: profile.Bio,
+		})
+	}
+	return response, nil
+}
+
+func (s *Server) ownProfile(caller uuid.UUID) (*geonotepb.Profile, error) {
+	profile, err := s.users.GetProfile(caller)
+	if err != nil {
+		return nil, internal(err)
+	}
+	if profile == nil {
+		return nil, status.Error(codes.Unauthenticated, "No such user.")
+	}
+	return toProfileProto(profile), nil
+}
+
+func (s *Server) ListContacts(
+	ctx context.Context,
+	request *geonotepb.ListContactsRequest) (*geonotepb.ListContactsResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	count, offset, err := parsePage(request.Count, request.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []*contacts.Contact
+	switch request.List {
+	case geonotepb.ContactList_CONTACT_LIST_ACCEPTED:
+		found, err = s.contacts.List(caller, contacts.STATUS_ACCEPTED, count, offset)
+	case geonotepb.ContactList_CONTACT_LIST_OUTGOING:
+		found, err = s.contacts.List(caller, contacts.STATUS_PENDING, count, offset)
+	case geonotepb.ContactList_CONTACT_LIST_BLOCKED:
+		found, err = s.contacts.List(caller, contacts.STATUS_BLOCKED, count, offset)
+	case geonotepb.ContactList_CONTACT_LIST_INCOMING:
+		found, err = s.contacts.Incoming(caller, count, offset)
+	case geonotepb.ContactList_CONTACT_LIST_MUTED:
+		return s.listMuted(caller, count, offset)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "Unknown contact list.")
+	}
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	response := &geonotepb.ListContactsResponse{}
+	for _, contact := range found {
+		response.Contacts = append(response.Contacts, toContactProto(contact, caller))
+	}
+	return response, nil
+}
+
+func (s *Server) listMuted(caller uuid.UUID, count int, offset int) (*geonotepb.ListContactsResponse, error) {
+	mutes, err := s.contacts.ListMuted(caller, count, offset)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	response := &geonotepb.ListContactsResponse{}
+	for _, mute := range mutes {
+		response.Contacts = append(response.Contacts, &geonotepb.Contact{
+			UserId: mute.MutedId.String(),
+			Status: geonotepb.ContactStatus_CONTACT_STATUS_MUTED,
+			UpdatedAt: timestamppb.New(mute.MutedAt),
+		})
+	}
+	return response, nil
+}
+
+func (s *Server) RequestContact(
+	ctx context.Context,
+	request *geonotepb.RequestContactRequest) (*geonotepb.RequestContactResponse, error) {
+	if err := s.contactAction(ctx, request.UserId, s.contacts.Request, true); err != nil {
+		return nil, err
+	}
+	return &geonotepb.RequestContactResponse{}, nil
+}
+
+func (s *Server) AcceptContact(
+	ctx context.Context,
+	request *geonotepb.AcceptContactRequest) (*geonotepb.AcceptContactResponse, error) {
+	if err := s.contactAction(ctx, request.UserId, s.contacts.Accept, false); err != nil {
+		return nil, err
+	}
+	return &geonotepb.AcceptContactResponse{}, nil
+}
+
+func (s *Server) DeclineContact(
+	ctx context.Context,
+	request *geonotepb.DeclineContactRequest) (*geonotepb.DeclineContactResponse, error) {
+	if err := s.contactAction(ctx, request.UserId, s.contacts.Decline, false); err != nil {
+		return nil, err
+	}
+	return &geonotepb.DeclineContactResponse{}, nil
+}
+
+func (s *Server) RemoveContact(
+	ctx context.Context,
+	request *geonotepb.RemoveContactRequest) (*geonotepb.RemoveContactResponse, error) {
+	if err := s.contactAction(ctx, request.UserId, s.contacts.Remove, false); err != nil {
+		return nil, err
+	}
+	return &geonotepb.RemoveContactResponse{}, nil
+}
+
+func (s *Server) BlockUser(
+	ctx context.Context,
+	request *geonotepb.BlockUserRequest) (*geonotepb.BlockUserResponse, error) {
+	if err := s.contactAction(ctx, request.UserId, s.contacts.Block, true); err != nil {
+		return nil, err
+	}
+	return &geonotepb.BlockUserResponse{}, nil
+}
+
+func (s *Server) UnblockUser(
+	ctx context.Context,
+	request *geonotepb.UnblockUserRequest) (*geonotepb.UnblockUserResponse, error) {
+	if err := s.contactAction(ctx, request.UserId, s.contacts.Unblock, false); err != nil {
+		return nil, err
+	}
+	return &geonotepb.UnblockUserResponse{}, nil
+}
+
+func (s *Server) MuteUser(
+	ctx context.Context,
+	request *geonotepb.MuteUserRequest) (*geonotepb.MuteUserResponse, error) {
+	if err := s.contactAction(ctx, request.UserId, s.contacts.Mute, true); err != nil {
+		return nil, err
+	}
+	return &geonotepb.MuteUserResponse{}, nil
+}
+
+func (s *Server) UnmuteUser(
+	ctx context.Context,
+	request *geonotepb.UnmuteUserRequest) (*geonotepb.UnmuteUserResponse, error) {
+	if err := s.contactAction(ctx, request.UserId, s.contacts.Unmute, false); err != nil {
+		return nil, err
+	}
+	return &geonotepb.UnmuteUserResponse{}, nil
+}
+
+// contactAction calls action with the caller and the user named by
+// userId. Actions that make new rows need that user to exist.
+func (s *Server) contactAction(
+	ctx context.Context,
+	userId string,
+	action func(uuid.UUID, uuid.UUID) error,
+	mustExist bool) error {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return err
+	}
+	other, err := parseId("user_id", userId)
+	if err != nil {
+		return err
+	}
+
+	if mustExist {
+		user, err := s.users.GetUserById(other)
+		if err != nil {
+			return internal(err)
+		}
+		if user == nil {
+			return status.Error(codes.NotFound, "No user with id " + other.String() + ".")
+		}
+	}
+
+	if err = action(caller, other); err != nil {
+		return contactsError(err)
+	}
+	return nil
+}
+
+func (s *Server) ListGroups(
+	ctx context.Context,
+	request *geonotepb.ListGroupsRequest) (*geonotepb.ListGroupsResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.contacts.Groups(caller)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	response := &geonotepb.ListGroupsResponse{}
+	for _, group := range groups {
+		response.Groups = append(response.Groups, toGroupProto(group))
+	}
+	return response, nil
+}
+
+func (s *Server) CreateGroup(
+	ctx context.Context,
+	request *geonotepb.CreateGroupRequest) (*geonotepb.Group, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	members, err := parseIds("members", request.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.contacts.CreateGroup(caller, request.Name, members)
+	if err != nil {
+		return nil, contactsError(err)
+	}
+	return toGroupProto(group), nil
+}
+
+func (s *Server) UpdateGroup(
+	ctx context.Context,
+	request *geonotepb.UpdateGroupRequest) (*geonotepb.Group, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := parseIds("members", request.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.contacts.UpdateGroup(caller, id, request.Name, members)
+	if err != nil {
+		return nil, contactsError(err)
+	}
+	return toGroupProto(group), nil
+}
+
+func (s *Server) DeleteGroup(
+	ctx context.Context,
+	request *geonotepb.DeleteGroupRequest) (*geonotepb.DeleteGroupResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.contacts.DeleteGroup(caller, id); err != nil {
+		return nil, contactsError(err)
+	}
+	return &geonotepb.DeleteGroupResponse{}, nil
+}
+
+// SendNote stores the note in MySQL and then indexes it. If indexing
+// fails the note is purged again rather than left where no one can find
+// it.
+func (s *Server) SendNote(
+	ctx context.Context,
+	request *geonotepb.SendNoteRequest) (*geonotepb.Note, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := parseCaller("sender", request.Sender, caller)
+	if err != nil {
+		return nil, err
+	}
+	visibility, err := parseVisibility(request.Visibility)
+	if err != nil {
+		return nil, err
+	}
+	named := request.Recipient != "" || len(request.Recipients) > 0 || request.GroupId != ""
+	var parent *notesdb.Note
+	var recipients []uuid.UUID
+	switch {
+	case request.ParentId != "":
+		if visibility != notesdb.VISIBILITY_PRIVATE {
+			return nil, status.Error(codes.InvalidArgument, "Replies can't be contacts or public notes.")
+		}
+		if named {
+			return nil, status.Error(codes.InvalidArgument,
+				"Replies go to the parent note's sender and recipients, and can't name recipients.")
+		}
+		parent, recipients, err = s.resolveReply(sender, request.ParentId)
+		if err != nil {
+			return nil, err
+		}
+	case request.Direct:
+		return nil, status.Error(codes.InvalidArgument, "Only replies can be direct.")
+	case visibility == notesdb.VISIBILITY_PRIVATE:
+		recipients, err = s.resolveRecipients(sender, request.Recipient, request.Recipients, request.GroupId)
+		if err != nil {
+			return nil, err
+		}
+	case named:
+		return nil, status.Error(codes.InvalidArgument, "Contacts and public notes can't have recipients.")
+	}
+	if err = validateText(request.Text); err != nil {
+		return nil, err
+	}
+	// An anchored reply is left where its parent is, whatever the request
+	// says.
+	if parent == nil || request.Direct {
+		if err = validateCoordinates(request.Latitude, request.Longitude); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, recipient := range recipients {
+		allowed, err := s.contacts.CanSend(sender, recipient)
+		if err != nil {
+			return nil, internal(err)
+		}
+		if !allowed {
+			return nil, status.Error(codes.PermissionDenied, "Notes can only be left for contacts.")
+		}
+	}
+
+	timeSent := s.now().UTC().Truncate(time.Second)
+	note := notesdb.NewGroupNote(sender, recipients, request.Text, request.Latitude, request.Longitude, timeSent)
+	if parent != nil {
+		note = notesdb.NewReply(parent, sender, recipients, request.Text, request.Latitude, request.Longitude,
+			request.Direct, timeSent)
+	} else if visibility != notesdb.VISIBILITY_PRIVATE {
+		note = notesdb.NewSharedNote(sender, visibility, request.Text, request.Latitude, request.Longitude, timeSent)
+	}
+
+	if err = s.notes.InsertNote(note); err != nil {
+		return nil, internal(err)
+	}
+
+	if err = s.index.AddDoc(solrnotes.DocumentFromNote(note)); err != nil {
+		if purgeErr := s.notes.PurgeNote(note.Id()); purgeErr != nil {
+			log.Printf("Failed to purge unindexed note %v. Err: %v", note.Id(), purgeErr)
+		}
+		return nil, internal(err)
+	}
+
+	return toNoteProto(note, caller), nil
+}
+
+// resolveRecipients gathers recipient, recipients and the members of the
+// sender's group groupId into one list, in that order, without repeats.
+func (s *Server) resolveRecipients(
+	sender uuid.UUID,
+	recipient string,
+	recipients []string,
+	groupId string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if recipient != "" {
+		id, err := parseId("recipient", recipient)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	more, err := parseIds("recipients", recipients)
+	if err != nil {
+		return nil, err
+	}
+	ids = append(ids, more...)
+
+	if groupId != "" {
+		id, err := parseId("group_id", groupId)
+		if err != nil {
+			return nil, err
+		}
+		group, err := s.contacts.Group(sender, id)
+		if err != nil {
+			return nil, contactsError(err)
+		}
+		ids = append(ids, group.Members...)
+	}
+
+	var unique []uuid.UUID
+	seen := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "recipient is required.")
+	}
+	if len(unique) > MAX_RECIPIENTS {
+		return nil, status.Error(codes.InvalidArgument,
+			"A note can be left for at most " + strconv.Itoa(MAX_RECIPIENTS) + " users.")
+	}
+	return unique, nil
+}
+
+// resolveReply finds the note parentId that sender is replying to, and
+// who the reply goes to: everyone on the parent but sender, or sender
+// alone if they're answering a note they left for themselves.
+func (s *Server) resolveReply(sender uuid.UUID, parentId string) (*notesdb.Note, []uuid.UUID, error) {
+	id, err := parseId("parent_id", parentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	parent, err := s.requireNote(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	visible, err := s.canSee(parent, sender)
+	if err != nil {
+		return nil, nil, internal(err)
+	}
+	if !visible {
+		return nil, nil, status.Error(codes.PermissionDenied, "Only those who can see a note can reply to it.")
+	}
+
+	var recipients []uuid.UUID
+	for _, participant := range parent.Participants() {
+		if participant != sender {
+			recipients = append(recipients, participant)
+		}
+	}
+	if len(recipients) == 0 {
+		recipients = []uuid.UUID{sender}
+	}
+	return parent, recipients, nil
+}
+
+// canSee is whether viewer can find the note: its sender and recipients
+// always can, anyone can see a public note, and the sender's accepted
+// contacts can see a contacts note.
+func (s *Server) canSee(note *notesdb.Note, viewer uuid.UUID) (bool, error) {
+	switch {
+	case note.Sender() == viewer || note.HasRecipient(viewer):
+		return true, nil
+	case note.Visibility() == notesdb.VISIBILITY_PUBLIC:
+		return true, nil
+	case note.Visibility() == notesdb.VISIBILITY_CONTACTS:
+		// The same test as for leaving viewer a private note.
+		return s.contacts.CanSend(note.Sender(), viewer)
+	}
+	return false, nil
+}
+
+func (s *Server) ListInbox(
+	ctx context.Context,
+	request *geonotepb.ListInboxRequest) (*geonotepb.ListNotesResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := parseCaller("recipient", request.Recipient, caller)
+	if err != nil {
+		return nil, err
+	}
+	count, offset, err := parsePage(request.Count, request.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	muted, err := s.contacts.MutedIds(recipient)
+	if err != nil {
+		return nil, internal(err)
+	}
+	notes, err := s.notes.GetNotesByRecipient(recipient, muted, count, offset)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return &geonotepb.ListNotesResponse{Notes: toNoteProtos(notes, caller)}, nil
+}
+
+func (s *Server) ListOutbox(
+	ctx context.Context,
+	request *geonotepb.ListOutboxRequest) (*geonotepb.ListNotesResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := parseCaller("sender", request.Sender, caller)
+	if err != nil {
+		return nil, err
+	}
+	count, offset, err := parsePage(request.Count, request.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := s.notes.GetNotesBySender(sender, count, offset)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return &geonotepb.ListNotesResponse{Notes: toNoteProtos(notes, caller)}, nil
+}
+
+func (s *Server) GetInboxSummary(
+	ctx context.Context,
+	request *geonotepb.GetInboxSummaryRequest) (*geonotepb.InboxSummary, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := parseCaller("recipient", request.Recipient, caller)
+	if err != nil {
+		return nil, err
+	}
+	var radiusKm float64
+	if request.Near != nil {
+		if err = validateCoordinates(request.Near.Latitude, request.Near.Longitude); err != nil {
+			return nil, err
+		}
+		if radiusKm, err = s.radiusKm(caller, request.RadiusKm); err != nil {
+			return nil, err
+		}
+	}
+
+	muted, err := s.contacts.MutedIds(recipient)
+	if err != nil {
+		return nil, internal(err)
+	}
+	counts, err := s.notes.CountNotesByRecipient(recipient, muted)
+	if err != nil {
+		return nil, internal(err)
+	}
+	summary := &geonotepb.InboxSummary{Senders: make([]*geonotepb.SenderCount, len(counts))}
+	for i, count := range counts {
+		summary.Unread += int32(count.Unread)
+		summary.Total += int32(count.Total)
+		summary.Senders[i] = &geonotepb.SenderCount{
+			Sender: count.Sender.String(),
+			Unread: int32(count.Unread),
+			Total: int32(count.Total),
+		}
+	}
+
+	if request.Near != nil {
+		contactIds, err := s.contacts.ContactIds(recipient, contacts.STATUS_ACCEPTED)
+		if err != nil {
+			return nil, internal(err)
+		}
+		hidden, err := s.contacts.HiddenIds(recipient)
+		if err != nil {
+			return nil, internal(err)
+		}
+		unread, err := s.index.CountUnreadNearby(
+			recipient, contactIds, hidden, request.Near.Latitude, request.Near.Longitude, radiusKm)
+		if err != nil {
+			return nil, internal(err)
+		}
+		summary.UnreadNearby = int32(unread)
+	}
+
+	return summary, nil
+}
+
+func (s *Server) MarkNoteRead(
+	ctx context.Context,
+	request *geonotepb.MarkNoteReadRequest) (*geonotepb.MarkNoteReadResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.canSee(note, caller)
+	if err != nil {
+		return nil, internal(err)
+	}
+	if !visible || (note.Shared() && note.Sender() == caller) || (!note.Shared() && !note.HasRecipient(caller)) {
+		return nil, status.Error(codes.PermissionDenied, "Only a note's recipients can mark it read.")
+	}
+	if request.Location != nil {
+		if err = validateCoordinates(request.Location.Latitude, request.Location.Longitude); err != nil {
+			return nil, err
+		}
+	}
+	if note.ReadBy(caller) {
+		return &geonotepb.MarkNoteReadResponse{}, nil
+	}
+
+	receipt, err := s.receipt(caller, request.Location)
+	if err != nil {
+		return nil, internal(err)
+	}
+	if note.Shared() {
+		err = s.notes.AddReader(id, caller, receipt)
+	} else {
+		err = s.notes.MarkNoteRead(id, caller, receipt)
+	}
+	if err != nil {
+		return nil, internal(err)
+	}
+	if err = s.index.MarkDocRead(id, caller); err != nil {
+		return nil, internal(err)
+	}
+
+	// Unlock events are read receipts too, so readers who hide theirs
+	// don't send them.
+	if s.hub != nil && receipt != nil {
+		s.hub.Publish(unlocks.Event{
+			NoteId: id,
+			Sender: note.Sender(),
+			Recipient: caller,
+			UnlockedAt: s.now().UTC(),
+		})
+	}
+
+	return &geonotepb.MarkNoteReadResponse{}, nil
+}
+
+// receipt is the read receipt for reader reading a note now at location,
+// or nil if they hide their read receipts.
+func (s *Server) receipt(reader uuid.UUID, location *geonotepb.Location) (*notesdb.Receipt, error) {
+	profile, err := s.users.GetProfile(reader)
+	if err != nil {
+		return nil, err
+	}
+	if profile != nil && profile.HideReadReceipts {
+		return nil, nil
+	}
+
+	receipt := &notesdb.Receipt{Reader: reader, ReadAt: s.now().UTC().Truncate(time.Second)}
+	if location != nil {
+		receipt.Located = true
+		receipt.Latitude, receipt.Longitude = location.Latitude, location.Longitude
+	}
+	return receipt, nil
+}
+
+func (s *Server) ListReceipts(
+	ctx context.Context,
+	request *geonotepb.ListReceiptsRequest) (*geonotepb.ListReceiptsResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return nil, err
+	}
+	if note.Sender() != caller {
+		return nil, status.Error(codes.PermissionDenied, "Only a note's sender can see its read receipts.")
+	}
+
+	response := &geonotepb.ListReceiptsResponse{}
+	for _, receipt := range note.Receipts() {
+		result := &geonotepb.Receipt{
+			Reader: receipt.Reader.String(),
+			ReadAt: timestamppb.New(receipt.ReadAt),
+		}
+		if receipt.Located {
+			result.Location = &geonotepb.Location{Latitude: receipt.Latitude, Longitude: receipt.Longitude}
+		}
+		response.Receipts = append(response.Receipts, result)
+	}
+	return response, nil
+}
+
+func (s *Server) DeleteNote(
+	ctx context.Context,
+	request *geonotepb.DeleteNoteRequest) (*geonotepb.DeleteNoteResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The sender deletes a note for everyone; a recipient only for
+	// themselves.
+	switch {
+	case note.Sender() == caller:
+		if note.Deleted() {
+			return &geonotepb.DeleteNoteResponse{}, nil
+		}
+		if err = s.notes.MarkNoteDeleted(id); err != nil {
+			return nil, internal(err)
+		}
+		if err = s.index.MarkDocDeleted(id); err != nil {
+			return nil, internal(err)
+		}
+	case note.HasRecipient(caller):
+		if note.DeletedFor(caller) {
+			return &geonotepb.DeleteNoteResponse{}, nil
+		}
+		if err = s.notes.MarkNoteDeletedFor(id, caller); err != nil {
+			return nil, internal(err)
+		}
+		if err = s.index.MarkDocDeletedFor(id, caller); err != nil {
+			return nil, internal(err)
+		}
+	default:
+		return nil, status.Error(codes.PermissionDenied,
+			"Only a note's sender or recipients can delete it.")
+	}
+
+	return &geonotepb.DeleteNoteResponse{}, nil
+}
+
+// EditNote reindexes the note after editing it. If that fails the edit
+// stands, and the doc's edited time is put right by the reconcile tool.
+func (s *Server) EditNote(
+	ctx context.Context,
+	request *geonotepb.EditNoteRequest) (*geonotepb.Note, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateText(request.Text); err != nil {
+		return nil, err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return nil, err
+	}
+	if note.Sender() != caller {
+		return nil, status.Error(codes.PermissionDenied, "Only a note's sender can edit it.")
+	}
+	if note.Deleted() {
+		return nil, status.Error(codes.FailedPrecondition, "The note has been deleted.")
+	}
+	if note.ReadByAnyone() {
+		return nil, status.Error(codes.FailedPrecondition, notesdb.ErrNoteRead.Error())
+	}
+	if note.Text() == request.Text {
+		return toNoteProto(note, caller), nil
+	}
+
+	err = s.notes.EditNote(id, request.Text, s.now().UTC().Truncate(time.Second))
+	if err == notesdb.ErrNoteRead {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err != nil {
+		return nil, internal(err)
+	}
+	if note, err = s.requireNote(id); err != nil {
+		return nil, err
+	}
+	if err = s.index.AddDoc(solrnotes.DocumentFromNote(note)); err != nil {
+		log.Printf("Failed to reindex edited note %v. Err: %v", id, err)
+	}
+
+	return toNoteProto(note, caller), nil
+}
+
+func (s *Server) ListNoteVersions(
+	ctx context.Context,
+	request *geonotepb.ListNoteVersionsRequest) (*geonotepb.ListNoteVersionsResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return nil, err
+	}
+	if note.Sender() != caller {
+		return nil, status.Error(codes.PermissionDenied, "Only a note's sender can see its earlier versions.")
+	}
+
+	versions, err := s.notes.GetNoteVersions(id)
+	if err != nil {
+		return nil, internal(err)
+	}
+	response := &geonotepb.ListNoteVersionsResponse{}
+	for _, version := range versions {
+		response.Versions = append(response.Versions, &geonotepb.NoteVersion{
+			Text: version.Text,
+			WrittenAt: timestamppb.New(version.WrittenAt),
+			ReplacedAt: timestamppb.New(version.ReplacedAt),
+		})
+	}
+	return response, nil
+}
+
+// GetThread pages through the conversation a note belongs to. Notes
+// deleted for the caller, and replies between other people on a contacts
+// or public note's thread, are left out, so a page can come back short.
+func (s *Server) GetThread(
+	ctx context.Context,
+	request *geonotepb.GetThreadRequest) (*geonotepb.ListNotesResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+	count, offset, err := parsePage(request.Count, request.Offset)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.canSee(note, caller)
+	if err != nil {
+		return nil, internal(err)
+	}
+	if !visible {
+		return nil, status.Error(codes.PermissionDenied, "Only those who can see a note can see its thread.")
+	}
+
+	thread, err := s.notes.GetThread(note.ThreadId(), count, offset)
+	if err != nil {
+		return nil, internal(err)
+	}
+	var notes []*notesdb.Note
+	for _, note := range thread {
+		if note.DeletedFor(caller) {
+			continue
+		}
+		visible, err := s.canSee(note, caller)
+		if err != nil {
+			return nil, internal(err)
+		}
+		if visible {
+			notes = append(notes, note)
+		}
+	}
+
+	return &geonotepb.ListNotesResponse{Notes: toNoteProtos(notes, caller)}, nil
+}
+
+// AddAttachment reads the whole attachment from the request, so it's
+// bounded by the server's maximum receive message size as well as
+// attachments.MAX_ATTACHMENT_BYTES.
+func (s *Server) AddAttachment(
+	ctx context.Context,
+	request *geonotepb.AddAttachmentRequest) (*geonotepb.Attachment, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("note_id", request.NoteId)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return nil, err
+	}
+	if note.Sender() != caller {
+		return nil, status.Error(codes.PermissionDenied, "Only a note's sender can add attachments to it.")
+	}
+	if note.Deleted() {
+		return nil, status.Error(codes.FailedPrecondition, "The note has been deleted.")
+	}
+
+	attachment, err := s.attachments.Add(id, request.ContentType, bytes.NewReader(request.Data))
+	if err != nil {
+		return nil, attachmentsError(err)
+	}
+	return toAttachmentProto(attachment), nil
+}
+
+func (s *Server) ListAttachments(
+	ctx context.Context,
+	request *geonotepb.ListAttachmentsRequest) (*geonotepb.ListAttachmentsResponse, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("note_id", request.NoteId)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.requireNote(id)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.canSee(note, caller)
+	if err != nil {
+		return nil, internal(err)
+	}
+	if !visible {
+		return nil, status.Error(codes.PermissionDenied, "Only those who can see a note can see its attachments.")
+	}
+
+	list, err := s.attachments.List(id)
+	if err != nil {
+		return nil, internal(err)
+	}
+	response := &geonotepb.ListAttachmentsResponse{}
+	for _, attachment := range list {
+		response.Attachments = append(response.Attachments, toAttachmentProto(attachment))
+	}
+	return response, nil
+}
+
+func (s *Server) GetAttachment(
+	ctx context.Context,
+	request *geonotepb.GetAttachmentRequest) (*geonotepb.AttachmentContent, error) {
+	caller, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := parseId("id", request.Id)
+	if err != nil {
+		return nil, err
+	}
+	attachment, err := s.attachments.Get(id)
+	if err != nil {
+		return nil, attachmentsError(err)
+	}
+	// Someone who can't see the note is told there's no such attachment,
+	// rather than that there is one they can't have.
+	notes, err := s.notes.GetNotesByIds([]uuid.UUID{attachment.NoteId})
+	if err != nil {
+		return nil, internal(err)
+	}
+	visible := false
+	if len(notes) == 1 && notes[0] != nil {
+		if visible, err = s.canSee(notes[0], caller); err != nil {
+			return nil, internal(err)
+		}
+	}
+	if !visible {
+		return nil, attachmentsError(attachments.ErrNoAttachment)
+	}
+
+	open, contentType := s.attachments.Open, attachment.ContentType
+	if request.Thumbnail {
+		open, contentType = s.attachments.OpenThumbnail, attachments.THUMBNAIL_CONTENT_TYPE
+	}
+	content, err := open(attachment)
+	if err != nil {
+		return nil, attachmentsError(err)
+	}
+	defer content.Close()
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil, internal(err)
+	}
+
+	return &geonotepb.AttachmentContent{
+		Attachment: toAttachmentProto(attachment),
+		ContentType: contentType,
+		Data: data,
+	}, nil
+}
+
+// FindNearby finds the recipient's notes around a point in Solr, then
+// loads and streams them from MySQL, since the index doesn't hold the
+// note text.
+func (s *Server) FindNearby(
+	request *geonotepb.FindNearbyRequest,
+	stream geonotepb.GeoNote_FindNearbyServer) error {
+	caller, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	recipient, err := parseCaller("recipient", request.Recipient, caller)
+	if err != nil {
+		return err
+	}
+	if err = validateCoordinates(request.Latitude, request.Longitude); err != nil {
+		return err
+	}
+	radiusKm, err := s.radiusKm(caller, request.RadiusKm)
+	if err != nil {
+		return err
+	}
+	maxResults, _, err := parsePage(request.MaxResults, 0)
+	if err != nil {
+		return err
+	}
+
+	contactIds, err := s.contacts.ContactIds(recipient, contacts.STATUS_ACCEPTED)
+	if err != nil {
+		return internal(err)
+	}
+	hidden, err := s.contacts.HiddenIds(recipient)
+	if err != nil {
+		return internal(err)
+	}
+	docs, err := s.index.FindDocsNearby(
+		recipient, contactIds, hidden, request.Latitude, request.Longitude, radiusKm, maxResults)
+	if err != nil {
+		return internal(err)
+	}
+
+	ids := make([]uuid.UUID, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.Id()
+	}
+	notes, err := s.notes.GetNotesByIds(ids)
+	if err != nil {
+		return internal(err)
+	}
+
+	for _, note := range notes {
+		if note == nil {
+			continue
+		}
+		if err = stream.Send(toNoteProto(note, caller)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) WatchUnlocks(
+	request *geonotepb.WatchUnlocksRequest,
+	stream geonotepb.GeoNote_WatchUnlocksServer) error {
+	caller, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	sender, err := parseCaller("sender", request.Sender, caller)
+	if err != nil {
+		return err
+	}
+	if s.hub == nil {
+		return status.Error(codes.Unimplemented, "Unlock events are not enabled.")
+	}
+
+	events, cancel := s.hub.Subscribe(sender)
+	defer cancel()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			err := stream.Send(&geonotepb.UnlockEvent{
+				NoteId: event.NoteId.String(),
+				Sender: event.Sender.String(),
+				Recipient: event.Recipient.String(),
+				UnlockedAt: timestamppb.New(event.UnlockedAt),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Server) ExportData(
+	request *geonotepb.ExportDataRequest,
+	stream geonotepb.GeoNote_ExportDataServer) error {
+	caller, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	chunks := bufio.NewWriterSize(chunkWriter{stream}, EXPORT_CHUNK_BYTES)
+	_, err = s.exporter.Export(caller, chunks)
+	if err == nil {
+		err = chunks.Flush()
+	}
+	if err == userdb.ErrNotFound {
+		return status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return internal(err)
+	}
+	return nil
+}
+
+// chunkWriter sends everything written to it as an ExportChunk.
+type chunkWriter struct {
+	stream geonotepb.GeoNote_ExportDataServer
+}
+
+func (c chunkWriter) Write(p []byte) (int, error) {
+	if err := c.stream.Send(&geonotepb.ExportChunk{Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// radiusKm checks a requested search radius, and swaps 0 for the
+// caller's default.
+func (s *Server) radiusKm(caller uuid.UUID, requested float64) (float64, error) {
+	radiusKm := requested
+	if radiusKm == 0 {
+		var err error
+		if radiusKm, err = s.defaultRadiusKm(caller); err != nil {
+			return 0, err
+		}
+	}
+	if radiusKm < 0 || radiusKm > MAX_RADIUS_KM {
+		return 0, status.Error(codes.InvalidArgument,
+			"radius_km must be greater than 0 and at most " + strconv.Itoa(MAX_RADIUS_KM) + ".")
+	}
+	return radiusKm, nil
+}
+
+// defaultRadiusKm is the caller's preferred unlock radius, or
+// DEFAULT_RADIUS_KM if they haven't set one.
+func (s *Server) defaultRadiusKm(caller uuid.UUID) (float64, error) {
+	profile, err := s.users.GetProfile(caller)
+	if err != nil {
+		return 0, internal(err)
+	}
+	if profile == nil || profile.UnlockRadiusKm == 0 {
+		return DEFAULT_RADIUS_KM, nil
+	}
+	return profile.UnlockRadiusKm, nil
+}
+
+func (s *Server) requireNote(id uuid.UUID) (*notesdb.Note, error) {
+	notes, err := s.notes.GetNotesByIds([]uuid.UUID{id})
+	if err != nil {
+		return nil, internal(err)
+	}
+	if len(notes) != 1 || notes[0] == nil {
+		return nil, status.Error(codes.NotFound, "No note with id " + id.String() + ".")
+	}
+	return notes[0], nil
+}
+
+// authenticate returns the user whose access token is in the call's
+// "authorization: Bearer <token>" metadata.
+func (s *Server) authenticate(ctx context.Context) (uuid.UUID, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) != 1 || !strings.HasPrefix(values[0], "Bearer ") {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "An access token is required.")
+	}
+
+	caller, err := s.sessions.Authenticate(strings.TrimPrefix(values[0], "Bearer "))
+	if err != nil {
+		return uuid.Nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return caller, nil
+}
+
+// peerAddress is the IP address a call came from, for lockout.
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// sessionError reports a bad refresh token as Unauthenticated, and
+// anything else as internal.
+func sessionError(err error) error {
+	switch err {
+	case sessions.ErrInvalidToken, sessions.ErrExpiredToken, sessions.ErrRevokedToken:
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	return internal(err)
+}
+
+// validationError reports credentials the policy refused as
+// InvalidArgument, a taken username as AlreadyExists, and anything else
+// as internal.
+func validationError(err error) error {
+	if invalid, ok := err.(*userdb.ValidationError); ok {
+		return status.Error(codes.InvalidArgument, invalid.Error())
+	}
+	if err == userdb.ErrUsernameTaken {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	return internal(err)
+}
+
+// totpError reports a wrong code as PermissionDenied, enrolling twice or
+// confirming without enrolling as FailedPrecondition, and anything else
+// as internal.
+func totpError(err error) error {
+	switch err {
+	case userdb.ErrInvalidTotpCode:
+		return status.Error(codes.PermissionDenied, err.Error())
+	case userdb.ErrTotpAlreadyEnrolled, userdb.ErrTotpNotEnrolled:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return internal(err)
+}
+
+// contactsError reports contact and group requests that don't make sense
+// as InvalidArgument, AlreadyExists, ResourceExhausted or NotFound, and
+// anything else as internal.
+func contactsError(err error) error {
+	switch err {
+	case contacts.ErrSelf, contacts.ErrGroupName, contacts.ErrTooManyMembers:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case contacts.ErrAlreadyContacts, contacts.ErrBlocked, contacts.ErrGroupNameTaken:
+		return status.Error(codes.AlreadyExists, err.Error())
+	case contacts.ErrTooManyMuted, contacts.ErrTooManyGroups:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case contacts.ErrNoRequest, contacts.ErrNotContacts, contacts.ErrNotBlocked, contacts.ErrNotMuted,
+		contacts.ErrNoGroup:
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return internal(err)
+}
+
+// attachmentsError reports attachments that were refused as
+// InvalidArgument or ResourceExhausted, a missing one as NotFound, and
+// anything else as internal.
+func attachmentsError(err error) error {
+	switch err {
+	case attachments.ErrContentType, attachments.ErrContentMismatch, attachments.ErrBadImage:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case attachments.ErrTooLarge, attachments.ErrTooManyAttachments:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case attachments.ErrNoAttachment:
+		return status.Error(codes.NotFound, err.Error())
+	}
+	return internal(err)
+}
+
+// internal logs err and hides its details from the client.
+func internal(err error) error {
+	log.Printf("Internal error in grpc server. Err: %v", err)
+	return status.Error(codes.Internal, "Internal server error.")
+}
+
+func validateCredentials(username string, password string) error {
+	if username == "" {
+		return status.Error(codes.InvalidArgument, "username is required.")
+	}
+	if len(username) > userdb.MAX_USERNAME_LEN {
+		return status.Error(codes.InvalidArgument,
+			"username must be at most " + strconv.Itoa(userdb.MAX_USERNAME_LEN) + " bytes.")
+	}
+	if password == "" {
+		return status.Error(codes.InvalidArgument, "password is required.")
+	}
+	return nil
+}
+
+func validateText(text string) error {
+	if text == "" {
+		return status.Error(codes.InvalidArgument, "text is required.")
+	}
+	if len(text) > MAX_NOTE_LEN {
+		return status.Error(codes.InvalidArgument, "text must be at most " + strconv.Itoa(MAX_NOTE_LEN) + " bytes.")
+	}
+	return nil
+}
+
+func validateCoordinates(latitude float64, longitude float64) error {
+	if latitude < -90 || latitude > 90 {
+		return status.Error(codes.InvalidArgument, "latitude must be between -90 and 90.")
+	}
+	if longitude < -180 || longitude > 180 {
+		return status.Error(codes.InvalidArgument, "longitude must be between -180 and 180.")
+	}
+	return nil
+}
+
+func parseId(name string, value string) (uuid.UUID, error) {
+	if value == "" {
+		return uuid.Nil, status.Error(codes.InvalidArgument, name + " is required.")
+	}
+	id, err := uuid.FromString(value)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, name + " is not a valid uuid.")
+	}
+	return id, nil
+}
+
+// parseIds parses a list of ids, none of which may be empty.
+func parseVisibility(visibility geonotepb.Visibility) (string, error) {
+	switch visibility {
+	case geonotepb.Visibility_VISIBILITY_PRIVATE:
+		return notesdb.VISIBILITY_PRIVATE, nil
+	case geonotepb.Visibility_VISIBILITY_CONTACTS:
+		return notesdb.VISIBILITY_CONTACTS, nil
+	case geonotepb.Visibility_VISIBILITY_PUBLIC:
+		return notesdb.VISIBILITY_PUBLIC, nil
+	}
+	return "", status.Error(codes.InvalidArgument, "Unknown visibility.")
+}
+
+func parseIds(name string, values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, len(values))
+	for i, value := range values {
+		id, err := parseId(name, value)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// parseCaller reads an optional user id that, if given, must be the
+// caller's own. It defaults to the caller.
+func parseCaller(name string, value string, caller uuid.UUID) (uuid.UUID, error) {
+	if value == "" {
+		return caller, nil
+	}
+	id, err := parseId(name, value)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if id != caller {
+		return uuid.Nil, status.Error(codes.PermissionDenied, name + " must be the signed-in user.")
+	}
+	return id, nil
+}
+
+// parsePage applies defaults and limits to a count and offset; a zero
+// count means DEFAULT_PAGE_SIZE.
+func parsePage(count int32, offset int32) (int, int, error) {
+	if count == 0 {
+		count = DEFAULT_PAGE_SIZE
+	}
+	if count < 0 || count > MAX_PAGE_SIZE {
+		return 0, 0, status.Error(codes.InvalidArgument,
+			"count must be between 1 and " + strconv.Itoa(MAX_PAGE_SIZE) + ".")
+	}
+	if offset < 0 {
+		return 0, 0, status.Error(codes.InvalidArgument, "offset must not be negative.")
+	}
+	return int(count), int(offset), nil
+}
+
+func toTokensProto(tokens *sessions.Tokens) *geonotepb.SessionTokens {
+	return &geonotepb.SessionTokens{
+		UserId: tokens.UserId.String(),
+		AccessToken: tokens.AccessToken,
+		AccessExpiresAt: timestamppb.New(tokens.AccessExpiresAt),
+		RefreshToken: tokens.RefreshToken,
+		RefreshExpiresAt: timestamppb.New(tokens.RefreshExpiresAt),
+	}
+}
+
+func toProfileProto(profile *userdb.Profile) *geonotepb.Profile {
+	result := &geonotepb.Profile{
+		UserId: profile.UserId.String(),
+		Username: profile.Username,
+		DisplayName: profile.DisplayName,
+		AvatarRef: profile.AvatarRef,
+		Bio: profile.Bio,
+		TimeZone: profile.TimeZone,
+		UnlockRadiusKm: profile.UnlockRadiusKm,
+		HideReadReceipts: profile.HideReadReceipts,
+	}
+	if !profile.UpdatedAt.IsZero() {
+		result.UpdatedAt = timestamppb.New(profile.UpdatedAt)
+	}
+	return result
+}
+
+func toContactProto(contact *contacts.Contact, caller uuid.UUID) *geonotepb.Contact {
+	other := contact.ContactId
+	if other == caller {
+		other = contact.UserId
+	}
+
+	var contactStatus geonotepb.ContactStatus
+	switch contact.Status {
+	case contacts.STATUS_ACCEPTED:
+		contactStatus = geonotepb.ContactStatus_CONTACT_STATUS_ACCEPTED
+	case contacts.STATUS_PENDING:
+		contactStatus = geonotepb.ContactStatus_CONTACT_STATUS_PENDING
+	case contacts.STATUS_BLOCKED:
+		contactStatus = geonotepb.ContactStatus_CONTACT_STATUS_BLOCKED
+	}
+
+	return &geonotepb.Contact{
+		UserId: other.String(),
+		Status: contactStatus,
+		UpdatedAt: timestamppb.New(contact.UpdatedAt),
+	}
+}
+
+func toGroupProto(group *contacts.Group) *geonotepb.Group {
+	members := make([]string, len(group.Members))
+	for i, member := range group.Members {
+		members[i] = member.String()
+	}
+	return &geonotepb.Group{
+		Id: group.Id.String(),
+		Name: group.Name,
+		Members: members,
+		UpdatedAt: timestamppb.New(group.UpdatedAt),
+	}
+}
+
+func toAttachmentProto(attachment *attachments.Attachment) *geonotepb.Attachment {
+	return &geonotepb.Attachment{
+		Id: attachment.Id.String(),
+		NoteId: attachment.NoteId.String(),
+		ContentType: attachment.ContentType,
+		Size: attachment.Size,
+		HasThumbnail: attachment.Thumbnail,
+		CreatedAt: timestamppb.New(attachment.CreatedAt),
+	}
+}
+
+// toNoteProto shows a recipient their own read and deleted state, and
+// anyone else the note's as a whole.
+func toNoteProto(note *notesdb.Note, viewer uuid.UUID) *geonotepb.Note {
+	read, deleted := note.Read(), note.Deleted()
+	if note.HasRecipient(viewer) || (note.Shared() && note.Sender() != viewer) {
+		read, deleted = note.ReadBy(viewer), note.DeletedFor(viewer)
+	}
+
+	ids := note.RecipientIds()
+	recipients := make([]string, len(ids))
+	for i, id := range ids {
+		recipients[i] = id.String()
+	}
+	recipient := ""
+	if len(ids) > 0 {
+		recipient = ids[0].String()
+	}
+
+	parentId := ""
+	if note.IsReply() {
+		parentId = note.ParentId().String()
+	}
+
+	var editedAt *timestamppb.Timestamp
+	if note.Edited() {
+		editedAt = timestamppb.New(note.TimeEdited())
+	}
+
+	visibility := geonotepb.Visibility_VISIBILITY_PRIVATE
+	switch note.Visibility() {
+	case notesdb.VISIBILITY_CONTACTS:
+		visibility = geonotepb.Visibility_VISIBILITY_CONTACTS
+	case notesdb.VISIBILITY_PUBLIC:
+		visibility = geonotepb.Visibility_VISIBILITY_PUBLIC
+	}
+
+	return &geonotepb.Note{
+		Id: note.Id().String(),
+		Sender: note.Sender().String(),
+		Recipient: recipient,
+		Recipients: recipients,
+		Visibility: visibility,
+		Text: note.Text(),
+		Latitude: note.Latitude(),
+		Longitude: note.Longitude(),
+		TimeSent: timestamppb.New(note.TimeSent()),
+		Read: read,
+		Deleted: deleted,
+		ParentId: parentId,
+		ThreadId: note.ThreadId().String(),
+		Direct: note.Direct(),
+		EditedAt: editedAt,
+	}
+}
+
+// toNoteProtos skips nil notes, which GetNotesByIds returns for ids it
+// couldn't find.
+func toNoteProtos(notes []*notesdb.Note, viewer uuid.UUID) []*geonotepb.Note {
+	var results []*geonotepb.Note
+	for _, note := range notes {
+		if note != nil {
+			results = append(results, toNoteProto(note, viewer))
+		}
+	}
+	return results
+}
@@ -0,0 +1,1377 @@
+package grpcserver
+
+import (
+	"testing"
+	"time"
+	"net"
+	"io"
+	"bytes"
+	"image"
+	"image/png"
+	"archive/zip"
+	"strings"
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/attachments"
+	"github.com/dbenny42/geonote/contacts"
+	"github.com/dbenny42/geonote/erasure"
+	"github.com/dbenny42/geonote/geonotepb"
+	"github.com/dbenny42/geonote/lockout"
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/sessions"
+	"github.com/dbenny42/geonote/solrnotes"
+	"github.com/dbenny42/geonote/unlocks"
+	"github.com/dbenny42/geonote/userdb"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	registered, err := client.RegisterUser(ctx, &geonotepb.RegisterUserRequest{
+		Username: "myusername",
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal("Failed to register. Err: ", err)
+	}
+	if _, err = uuid.FromString(registered.UserId); err != nil {
+		t.Fatal("Register did not return a user id. Err: ", err)
+	}
+
+	loggedIn, err := client.Login(ctx, &geonotepb.LoginRequest{Username: "myusername", Password: "password"})
+	if err != nil {
+		t.Fatal("Failed to log in. Err: ", err)
+	}
+	if loggedIn.UserId != registered.UserId {
+		t.Fatal("Login returned id ", loggedIn.UserId, " but register returned ", registered.UserId)
+	}
+	if loggedIn.Tokens.GetAccessToken() == "" || loggedIn.Tokens.GetRefreshToken() == "" {
+		t.Fatal("Login did not return tokens: ", loggedIn.Tokens)
+	}
+
+	_, err = client.Login(ctx, &geonotepb.LoginRequest{Username: "myusername", Password: "bad"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatal("Expected Unauthenticated for a bad password, got: ", err)
+	}
+
+	_, err = client.RegisterUser(ctx, &geonotepb.RegisterUserRequest{
+		Username: "MyUsername",
+		Password: "password",
+	})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatal("Expected AlreadyExists for a taken username, got: ", err)
+	}
+
+	available, err := client.IsUsernameAvailable(ctx, &geonotepb.IsUsernameAvailableRequest{Username: "myusername"})
+	if err != nil || available.Available {
+		t.Fatal("Expected myusername to be taken: ", available, " ", err)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+	signUp(t, client, "myusername")
+
+	for i := 0; i < lockout.DEFAULT_POLICY.FreeAttempts; i++ {
+		_, err := client.Login(ctx, &geonotepb.LoginRequest{Username: "myusername", Password: "bad"})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Fatal("Expected Unauthenticated for a bad password, got: ", err)
+		}
+	}
+
+	_, err := client.Login(ctx, &geonotepb.LoginRequest{Username: "myusername", Password: "password"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatal("Expected ResourceExhausted while locked out, got: ", err)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, phone := signUp(t, client, "myusername")
+
+	refreshed, err := client.RefreshSession(ctx, &geonotepb.RefreshSessionRequest{
+		RefreshToken: phone.RefreshToken,
+	})
+	if err != nil {
+		t.Fatal("Failed to refresh. Err: ", err)
+	}
+	if refreshed.RefreshToken == phone.RefreshToken {
+		t.Fatal("Refresh did not issue a new refresh token.")
+	}
+
+	_, err = client.Logout(ctx, &geonotepb.LogoutRequest{RefreshToken: refreshed.RefreshToken})
+	if err != nil {
+		t.Fatal("Failed to log out. Err: ", err)
+	}
+	_, err = client.RefreshSession(ctx, &geonotepb.RefreshSessionRequest{
+		RefreshToken: refreshed.RefreshToken,
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatal("Expected Unauthenticated for a logged out session, got: ", err)
+	}
+
+	tablet := login(t, client, "myusername")
+	_, err = client.LogoutEverywhere(withToken(ctx, tablet), &geonotepb.LogoutEverywhereRequest{})
+	if err != nil {
+		t.Fatal("Failed to log out everywhere. Err: ", err)
+	}
+	_, err = client.RefreshSession(ctx, &geonotepb.RefreshSessionRequest{
+		RefreshToken: tablet.RefreshToken,
+	})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatal("Expected Unauthenticated after logging out everywhere, got: ", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, tokens := signUp(t, client, "myusername")
+
+	_, err := client.ChangePassword(withToken(ctx, tokens), &geonotepb.ChangePasswordRequest{
+		OldPassword: "wrong",
+		NewPassword: "newpassword",
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied for a wrong old password, got: ", err)
+	}
+
+	changed, err := client.ChangePassword(withToken(ctx, tokens), &geonotepb.ChangePasswordRequest{
+		OldPassword: "password",
+		NewPassword: "newpassword",
+	})
+	if err != nil {
+		t.Fatal("Failed to change password. Err: ", err)
+	}
+	if changed.RefreshToken == "" {
+		t.Fatal("ChangePassword did not return new tokens.")
+	}
+
+	_, err = client.RefreshSession(ctx, &geonotepb.RefreshSessionRequest{RefreshToken: tokens.RefreshToken})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatal("Expected the old session to be ended, got: ", err)
+	}
+	_, err = client.Login(ctx, &geonotepb.LoginRequest{Username: "myusername", Password: "newpassword"})
+	if err != nil {
+		t.Fatal("Failed to log in with the new password. Err: ", err)
+	}
+
+	_, err = client.ResetPassword(ctx, &geonotepb.ResetPasswordRequest{Token: "bad", NewPassword: "password"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("Expected InvalidArgument for a bad reset token, got: ", err)
+	}
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, tokens := signUp(t, client, "myusername")
+
+	for i := 0; i < lockout.DEFAULT_POLICY.FreeAttempts; i++ {
+		_, err := client.ChangePassword(withToken(ctx, tokens), &geonotepb.ChangePasswordRequest{
+			OldPassword: "wrong",
+			NewPassword: "newpassword",
+		})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatal("Expected PermissionDenied for a wrong old password, got: ", err)
+		}
+	}
+
+	_, err := client.ChangePassword(withToken(ctx, tokens), &geonotepb.ChangePasswordRequest{
+		OldPassword: "password",
+		NewPassword: "newpassword",
+	})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatal("Expected ResourceExhausted while locked out, got: ", err)
+	}
+	_, err = client.Login(ctx, &geonotepb.LoginRequest{Username: "myusername", Password: "password"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatal("Expected wrong old passwords to lock out logins too, got: ", err)
+	}
+}
+
+func TestTwoFactor(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, tokens := signUp(t, client, "myusername")
+	setup, err := client.EnrollTotp(withToken(ctx, tokens), &geonotepb.EnrollTotpRequest{})
+	if err != nil {
+		t.Fatal("Failed to enroll. Err: ", err)
+	}
+
+	code, err := userdb.TotpCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatal("Failed to make code. Err: ", err)
+	}
+	confirmed, err := client.ConfirmTotp(withToken(ctx, tokens), &geonotepb.ConfirmTotpRequest{Code: code})
+	if err != nil {
+		t.Fatal("Failed to confirm. Err: ", err)
+	}
+
+	_, err = client.Login(ctx, &geonotepb.LoginRequest{Username: "myusername", Password: "password"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatal("Expected FailedPrecondition without a code, got: ", err)
+	}
+	_, err = client.Login(ctx, &geonotepb.LoginRequest{
+		Username: "myusername",
+		Password: "password",
+		Code: confirmed.RecoveryCodes[0],
+	})
+	if err != nil {
+		t.Fatal("Failed to log in with a recovery code. Err: ", err)
+	}
+
+	_, err = client.DisableTotp(withToken(ctx, tokens), &geonotepb.DisableTotpRequest{Code: "bad"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied for a bad code, got: ", err)
+	}
+}
+
+func TestTwoFactorLockout(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, tokens := signUp(t, client, "myusername")
+	setup, err := client.EnrollTotp(withToken(ctx, tokens), &geonotepb.EnrollTotpRequest{})
+	if err != nil {
+		t.Fatal("Failed to enroll. Err: ", err)
+	}
+
+	for i := 0; i < lockout.DEFAULT_POLICY.FreeAttempts; i++ {
+		_, err = client.ConfirmTotp(withToken(ctx, tokens), &geonotepb.ConfirmTotpRequest{Code: "bad"})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Fatal("Expected PermissionDenied for a bad code, got: ", err)
+		}
+	}
+
+	code, err := userdb.TotpCode(setup.Secret, time.Now())
+	if err != nil {
+		t.Fatal("Failed to make code. Err: ", err)
+	}
+	_, err = client.ConfirmTotp(withToken(ctx, tokens), &geonotepb.ConfirmTotpRequest{Code: code})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatal("Expected ResourceExhausted while locked out, got: ", err)
+	}
+	_, err = client.DisableTotp(withToken(ctx, tokens), &geonotepb.DisableTotpRequest{Code: "bad"})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatal("Expected disabling to be locked out too, got: ", err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	senderId, sender := signUp(t, client, "sender")
+	_, recipient := signUp(t, client, "recipient")
+
+	updated, err := client.UpdateProfile(withToken(ctx, sender), &geonotepb.UpdateProfileRequest{
+		DisplayName: "The Sender",
+		TimeZone: "America/New_York",
+	})
+	if err != nil {
+		t.Fatal("Failed to update profile. Err: ", err)
+	}
+	if updated.Username != "sender" || updated.DisplayName != "The Sender" || updated.UpdatedAt == nil {
+		t.Fatal("Unexpected profile: ", updated)
+	}
+
+	_, err = client.UpdateProfile(withToken(ctx, sender), &geonotepb.UpdateProfileRequest{UnlockRadiusKm: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("Expected InvalidArgument for a negative radius, got: ", err)
+	}
+
+	found, err := client.GetProfiles(withToken(ctx, recipient), &geonotepb.GetProfilesRequest{
+		UserIds: []string{senderId.String(), uuid.NewV4().String()},
+	})
+	if err != nil {
+		t.Fatal("Failed to get profiles. Err: ", err)
+	}
+	if len(found.Profiles) != 1 || found.Profiles[0].DisplayName != "The Sender" ||
+		found.Profiles[0].TimeZone != "" {
+		t.Fatal("Expected only the sender's public profile, got: ", found.Profiles)
+	}
+}
+
+func TestContacts(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	aliceId, alice := signUp(t, client, "alice")
+	bobId, bob := signUp(t, client, "bob")
+
+	_, err := client.AcceptContact(withToken(ctx, bob), &geonotepb.AcceptContactRequest{UserId: aliceId.String()})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("Expected NotFound accepting a request that wasn't made, got: ", err)
+	}
+	_, err = client.RequestContact(withToken(ctx, alice),
+		&geonotepb.RequestContactRequest{UserId: uuid.NewV4().String()})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("Expected NotFound asking an unknown user, got: ", err)
+	}
+
+	befriend(t, client, alice, bob)
+	listed, err := client.ListContacts(withToken(ctx, bob), &geonotepb.ListContactsRequest{})
+	if err != nil {
+		t.Fatal("Failed to list contacts. Err: ", err)
+	}
+	if len(listed.Contacts) != 1 || listed.Contacts[0].UserId != aliceId.String() ||
+		listed.Contacts[0].Status != geonotepb.ContactStatus_CONTACT_STATUS_ACCEPTED {
+		t.Fatal("Expected alice as bob's contact, got: ", listed.Contacts)
+	}
+	sendNote(t, client, alice, bobId, 1, 1)
+
+	_, err = client.BlockUser(withToken(ctx, bob), &geonotepb.BlockUserRequest{UserId: aliceId.String()})
+	if err != nil {
+		t.Fatal("Failed to block. Err: ", err)
+	}
+	_, err = client.SendNote(withToken(ctx, alice), &geonotepb.SendNoteRequest{
+		Recipient: bobId.String(),
+		Text: "hi",
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied sending to a blocker, got: ", err)
+	}
+	listed, err = client.ListContacts(withToken(ctx, bob),
+		&geonotepb.ListContactsRequest{List: geonotepb.ContactList_CONTACT_LIST_BLOCKED})
+	if err != nil || len(listed.Contacts) != 1 {
+		t.Fatal("Expected alice to be blocked, got: ", listed, " ", err)
+	}
+}
+
+func TestMute(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	senderId, sender := signUp(t, client, "sender")
+	_, other := signUp(t, client, "other")
+	recipientId, recipient := signUp(t, client, "recipient")
+	befriend(t, client, sender, recipient)
+	befriend(t, client, other, recipient)
+
+	_, err := client.MuteUser(withToken(ctx, recipient), &geonotepb.MuteUserRequest{UserId: senderId.String()})
+	if err != nil {
+		t.Fatal("Failed to mute. Err: ", err)
+	}
+	sendNote(t, client, sender, recipientId, 1, 1)
+	shown := sendNote(t, client, other, recipientId, 1, 1)
+
+	inbox, err := client.ListInbox(withToken(ctx, recipient), &geonotepb.ListInboxRequest{})
+	if err != nil {
+		t.Fatal("Failed to list inbox. Err: ", err)
+	}
+	if len(inbox.Notes) != 1 || inbox.Notes[0].Id != shown.Id {
+		t.Fatal("Expected only the unmuted note, got: ", inbox.Notes)
+	}
+
+	listed, err := client.ListContacts(withToken(ctx, recipient),
+		&geonotepb.ListContactsRequest{List: geonotepb.ContactList_CONTACT_LIST_MUTED})
+	if err != nil || len(listed.Contacts) != 1 ||
+		listed.Contacts[0].Status != geonotepb.ContactStatus_CONTACT_STATUS_MUTED {
+		t.Fatal("Expected the sender to be muted, got: ", listed, " ", err)
+	}
+
+	_, err = client.UnmuteUser(withToken(ctx, recipient), &geonotepb.UnmuteUserRequest{UserId: senderId.String()})
+	if err != nil {
+		t.Fatal("Failed to unmute. Err: ", err)
+	}
+	inbox, err = client.ListInbox(withToken(ctx, recipient), &geonotepb.ListInboxRequest{})
+	if err != nil || len(inbox.Notes) != 2 {
+		t.Fatal("Expected both notes after unmuting, got: ", inbox, " ", err)
+	}
+}
+
+func TestGroupNotes(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, alice := signUp(t, client, "alice")
+	bobId, bob := signUp(t, client, "bob")
+	carolId, carol := signUp(t, client, "carol")
+	daveId, _ := signUp(t, client, "dave")
+	befriend(t, client, alice, bob)
+	befriend(t, client, alice, carol)
+
+	_, err := client.CreateGroup(withToken(ctx, alice), &geonotepb.CreateGroupRequest{
+		Name: "friends",
+		Members: []string{bobId.String(), daveId.String()},
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("Expected NotFound adding a non-contact, got: ", err)
+	}
+	group, err := client.CreateGroup(withToken(ctx, alice), &geonotepb.CreateGroupRequest{
+		Name: " friends ",
+		Members: []string{bobId.String(), carolId.String(), bobId.String()},
+	})
+	if err != nil {
+		t.Fatal("Failed to create group. Err: ", err)
+	}
+	if group.Name != "friends" || len(group.Members) != 2 {
+		t.Fatal("Unexpected group: ", group)
+	}
+	_, err = client.CreateGroup(withToken(ctx, alice), &geonotepb.CreateGroupRequest{Name: "Friends"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatal("Expected AlreadyExists reusing a name, got: ", err)
+	}
+	listed, err := client.ListGroups(withToken(ctx, bob), &geonotepb.ListGroupsRequest{})
+	if err != nil || len(listed.Groups) != 0 {
+		t.Fatal("Expected groups to be private to their owner, got: ", listed, " ", err)
+	}
+
+	note, err := client.SendNote(withToken(ctx, alice), &geonotepb.SendNoteRequest{
+		GroupId: group.Id,
+		Text: "hi both",
+		Latitude: 1,
+		Longitude: 1,
+	})
+	if err != nil {
+		t.Fatal("Failed to send to group. Err: ", err)
+	}
+	if len(note.Recipients) != 2 || note.Recipient != bobId.String() {
+		t.Fatal("Expected the group's members as recipients, got: ", note)
+	}
+
+	// Bob reading and deleting it doesn't touch carol's copy.
+	_, err = client.MarkNoteRead(withToken(ctx, bob), &geonotepb.MarkNoteReadRequest{Id: note.Id})
+	if err != nil {
+		t.Fatal("Failed to mark read. Err: ", err)
+	}
+	_, err = client.DeleteNote(withToken(ctx, bob), &geonotepb.DeleteNoteRequest{Id: note.Id})
+	if err != nil {
+		t.Fatal("Failed to delete. Err: ", err)
+	}
+	inbox, err := client.ListInbox(withToken(ctx, carol), &geonotepb.ListInboxRequest{})
+	if err != nil || len(inbox.Notes) != 1 || inbox.Notes[0].Read || inbox.Notes[0].Deleted {
+		t.Fatal("Expected carol's copy to be unread and undeleted, got: ", inbox, " ", err)
+	}
+	outbox, err := client.ListOutbox(withToken(ctx, alice), &geonotepb.ListOutboxRequest{})
+	if err != nil || len(outbox.Notes) != 1 || outbox.Notes[0].Read || outbox.Notes[0].Deleted {
+		t.Fatal("Expected the note to be unread until everyone reads it, got: ", outbox, " ", err)
+	}
+
+	_, err = client.SendNote(withToken(ctx, alice), &geonotepb.SendNoteRequest{
+		Recipients: []string{bobId.String(), daveId.String()},
+		Text: "hi",
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied with a non-contact among recipients, got: ", err)
+	}
+	_, err = client.SendNote(withToken(ctx, carol), &geonotepb.SendNoteRequest{GroupId: group.Id, Text: "hi"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("Expected NotFound sending to someone else's group, got: ", err)
+	}
+
+	_, err = client.DeleteGroup(withToken(ctx, alice), &geonotepb.DeleteGroupRequest{Id: group.Id})
+	if err != nil {
+		t.Fatal("Failed to delete group. Err: ", err)
+	}
+	inbox, err = client.ListInbox(withToken(ctx, carol), &geonotepb.ListInboxRequest{})
+	if err != nil || len(inbox.Notes) != 1 {
+		t.Fatal("Expected notes sent to a group to outlive it, got: ", inbox, " ", err)
+	}
+}
+
+func TestSharedNotes(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	aliceId, alice := signUp(t, client, "alice")
+	bobId, bob := signUp(t, client, "bob")
+	_, carol := signUp(t, client, "carol")
+	befriend(t, client, alice, bob)
+
+	_, err := client.SendNote(withToken(ctx, alice), &geonotepb.SendNoteRequest{
+		Recipient: bobId.String(),
+		Text: "hi",
+		Visibility: geonotepb.Visibility_VISIBILITY_PUBLIC,
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("Expected InvalidArgument for a public note with a recipient, got: ", err)
+	}
+	public, err := client.SendNote(withToken(ctx, alice), &geonotepb.SendNoteRequest{
+		Text: "for anyone",
+		Latitude: 1,
+		Longitude: 1,
+		Visibility: geonotepb.Visibility_VISIBILITY_PUBLIC,
+	})
+	if err != nil {
+		t.Fatal("Failed to send public note. Err: ", err)
+	}
+	if public.Visibility != geonotepb.Visibility_VISIBILITY_PUBLIC || public.Recipient != "" {
+		t.Fatal("Unexpected public note: ", public)
+	}
+	shared, err := client.SendNote(withToken(ctx, alice), &geonotepb.SendNoteRequest{
+		Text: "for contacts",
+		Latitude: 1,
+		Longitude: 1,
+		Visibility: geonotepb.Visibility_VISIBILITY_CONTACTS,
+	})
+	if err != nil {
+		t.Fatal("Failed to send contacts note. Err: ", err)
+	}
+
+	found := findNearby(t, client, bob, 1, 1)
+	if len(found) != 2 || found[public.Id] == nil || found[shared.Id] == nil {
+		t.Fatal("Expected a contact to find both notes, got: ", found)
+	}
+	found = findNearby(t, client, carol, 1, 1)
+	if len(found) != 1 || found[public.Id] == nil {
+		t.Fatal("Expected a stranger to find only the public note, got: ", found)
+	}
+
+	_, err = client.MarkNoteRead(withToken(ctx, carol), &geonotepb.MarkNoteReadRequest{Id: shared.Id})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied reading a contacts note as a stranger, got: ", err)
+	}
+	_, err = client.MarkNoteRead(withToken(ctx, alice), &geonotepb.MarkNoteReadRequest{Id: public.Id})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied reading your own public note, got: ", err)
+	}
+	_, err = client.MarkNoteRead(withToken(ctx, carol), &geonotepb.MarkNoteReadRequest{Id: public.Id})
+	if err != nil {
+		t.Fatal("Failed to mark public note read. Err: ", err)
+	}
+
+	// Reads are per viewer: carol reading the public note doesn't read it
+	// for bob, but the sender sees that someone has.
+	if found = findNearby(t, client, carol, 1, 1); !found[public.Id].Read {
+		t.Fatal("Expected the public note to be read for carol, got: ", found)
+	}
+	if found = findNearby(t, client, bob, 1, 1); found[public.Id].Read {
+		t.Fatal("Expected the public note to be unread for bob, got: ", found)
+	}
+	outbox, err := client.ListOutbox(withToken(ctx, alice), &geonotepb.ListOutboxRequest{})
+	if err != nil || len(outbox.Notes) != 2 {
+		t.Fatal("Expected both shared notes in the outbox, got: ", outbox, " ", err)
+	}
+	for _, note := range outbox.Notes {
+		if note.Read != (note.Id == public.Id) {
+			t.Fatal("Expected only the public note to be read, got: ", note)
+		}
+	}
+
+	_, err = client.BlockUser(withToken(ctx, carol), &geonotepb.BlockUserRequest{UserId: aliceId.String()})
+	if err != nil {
+		t.Fatal("Failed to block. Err: ", err)
+	}
+	if found = findNearby(t, client, carol, 1, 1); len(found) != 0 {
+		t.Fatal("Expected a blocked sender's public notes to be hidden, got: ", found)
+	}
+}
+
+func TestThreads(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	aliceId, alice := signUp(t, client, "alice")
+	bobId, bob := signUp(t, client, "bob")
+	_, carol := signUp(t, client, "carol")
+	befriend(t, client, alice, bob)
+	befriend(t, client, alice, carol)
+
+	root := sendNote(t, client, alice, bobId, 1, 1)
+	anchored, err := client.SendNote(withToken(ctx, bob), &geonotepb.SendNoteRequest{
+		ParentId: root.Id,
+		Text: "on my way",
+		Latitude: 5,
+		Longitude: 5,
+	})
+	if err != nil {
+		t.Fatal("Failed to reply. Err: ", err)
+	}
+	if anchored.ParentId != root.Id || anchored.ThreadId != root.ThreadId || anchored.Latitude != 1 ||
+		anchored.Direct || len(anchored.Recipients) != 1 || anchored.Recipient != aliceId.String() {
+		t.Fatal("Expected an anchored reply to alice at the root's spot, got: ", anchored)
+	}
+	direct, err := client.SendNote(withToken(ctx, alice), &geonotepb.SendNoteRequest{
+		ParentId: anchored.Id,
+		Direct: true,
+		Text: "see you",
+		Latitude: 2,
+		Longitude: 2,
+	})
+	if err != nil {
+		t.Fatal("Failed to reply directly. Err: ", err)
+	}
+	if !direct.Direct || direct.Latitude != 2 || direct.Recipient != bobId.String() || direct.ThreadId != root.Id {
+		t.Fatal("Expected a direct reply to bob, got: ", direct)
+	}
+
+	thread, err := client.GetThread(withToken(ctx, bob), &geonotepb.GetThreadRequest{Id: direct.Id})
+	if err != nil {
+		t.Fatal("Failed to get thread. Err: ", err)
+	}
+	if len(thread.Notes) != 3 || thread.Notes[0].Id != root.Id || thread.Notes[1].Id != anchored.Id ||
+		thread.Notes[2].Id != direct.Id {
+		t.Fatal("Expected the thread in order, got: ", thread.Notes)
+	}
+
+	cases := []struct {
+		request *geonotepb.SendNoteRequest
+		code codes.Code
+	}{
+		{&geonotepb.SendNoteRequest{ParentId: root.Id, Recipient: bobId.String(), Text: "hi"}, codes.InvalidArgument},
+		{&geonotepb.SendNoteRequest{ParentId: root.Id, Text: "hi",
+			Visibility: geonotepb.Visibility_VISIBILITY_PUBLIC}, codes.InvalidArgument},
+		{&geonotepb.SendNoteRequest{Recipient: bobId.String(), Direct: true, Text: "hi"}, codes.InvalidArgument},
+		{&geonotepb.SendNoteRequest{ParentId: "nope", Text: "hi"}, codes.InvalidArgument},
+		{&geonotepb.SendNoteRequest{ParentId: root.Id, Text: "hi"}, codes.PermissionDenied},
+	}
+	for _, c := range cases {
+		_, err := client.SendNote(withToken(ctx, carol), c.request)
+		if status.Code(err) != c.code {
+			t.Error("SendNote ", c.request, ": expected ", c.code, ", got ", err)
+		}
+	}
+	_, err = client.GetThread(withToken(ctx, carol), &geonotepb.GetThreadRequest{Id: root.Id})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied getting someone else's thread, got: ", err)
+	}
+
+	// Replies to a public note are private to the replier and its sender.
+	public, err := client.SendNote(withToken(ctx, alice), &geonotepb.SendNoteRequest{
+		Text: "for anyone",
+		Latitude: 1,
+		Longitude: 1,
+		Visibility: geonotepb.Visibility_VISIBILITY_PUBLIC,
+	})
+	if err != nil {
+		t.Fatal("Failed to send public note. Err: ", err)
+	}
+	for _, replier := range []*geonotepb.SessionTokens{bob, carol} {
+		_, err = client.SendNote(withToken(ctx, replier), &geonotepb.SendNoteRequest{ParentId: public.Id, Text: "hi"})
+		if err != nil {
+			t.Fatal("Failed to reply to public note. Err: ", err)
+		}
+	}
+	thread, err = client.GetThread(withToken(ctx, bob), &geonotepb.GetThreadRequest{Id: public.Id})
+	if err != nil || len(thread.Notes) != 2 || thread.Notes[1].Sender != bobId.String() {
+		t.Fatal("Expected the public note and bob's own reply, got: ", thread, " ", err)
+	}
+	thread, err = client.GetThread(withToken(ctx, alice), &geonotepb.GetThreadRequest{Id: public.Id})
+	if err != nil || len(thread.Notes) != 3 {
+		t.Fatal("Expected the sender to see every reply, got: ", thread, " ", err)
+	}
+
+	// A direct reply reaches bob far from where it was left; anchored
+	// notes don't.
+	if found := findNearby(t, client, bob, 40, 40); len(found) != 1 || found[direct.Id] == nil {
+		t.Fatal("Expected only the direct reply far away, got: ", found)
+	}
+	if found := findNearby(t, client, alice, 40, 40); len(found) != 0 {
+		t.Fatal("Expected nothing far away for alice, got: ", found)
+	}
+
+	// bob deleting the root only hides it from him; deleting his own reply
+	// hides it from everyone.
+	for _, id := range []string{root.Id, anchored.Id} {
+		if _, err = client.DeleteNote(withToken(ctx, bob), &geonotepb.DeleteNoteRequest{Id: id}); err != nil {
+			t.Fatal("Failed to delete note. Err: ", err)
+		}
+	}
+	thread, err = client.GetThread(withToken(ctx, bob), &geonotepb.GetThreadRequest{Id: direct.Id})
+	if err != nil || len(thread.Notes) != 1 || thread.Notes[0].Id != direct.Id {
+		t.Fatal("Expected deleted notes left out of bob's thread, got: ", thread, " ", err)
+	}
+	thread, err = client.GetThread(withToken(ctx, alice), &geonotepb.GetThreadRequest{Id: direct.Id})
+	if err != nil || len(thread.Notes) != 2 || thread.Notes[0].Id != root.Id || thread.Notes[1].Id != direct.Id {
+		t.Fatal("Expected only the deleted reply left out of alice's thread, got: ", thread, " ", err)
+	}
+}
+
+func TestReadReceipts(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, alice := signUp(t, client, "alice")
+	bobId, bob := signUp(t, client, "bob")
+	carolId, carol := signUp(t, client, "carol")
+	befriend(t, client, alice, bob)
+	befriend(t, client, alice, carol)
+
+	profile, err := client.UpdateProfile(withToken(ctx, carol), &geonotepb.UpdateProfileRequest{HideReadReceipts: true})
+	if err != nil || !profile.HideReadReceipts {
+		t.Fatal("Expected carol to hide her read receipts, got: ", profile, " ", err)
+	}
+	note, err := client.SendNote(withToken(ctx, alice), &geonotepb.SendNoteRequest{
+		Recipients: []string{bobId.String(), carolId.String()},
+		Text: "hi both",
+		Latitude: 1,
+		Longitude: 2,
+	})
+	if err != nil {
+		t.Fatal("Failed to send note. Err: ", err)
+	}
+
+	_, err = client.MarkNoteRead(withToken(ctx, bob), &geonotepb.MarkNoteReadRequest{
+		Id: note.Id,
+		Location: &geonotepb.Location{Latitude: 91, Longitude: 2},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("Expected InvalidArgument for a bad location, got: ", err)
+	}
+	_, err = client.MarkNoteRead(withToken(ctx, bob), &geonotepb.MarkNoteReadRequest{
+		Id: note.Id,
+		Location: &geonotepb.Location{Latitude: 1.001, Longitude: 2},
+	})
+	if err != nil {
+		t.Fatal("Failed to mark read. Err: ", err)
+	}
+	_, err = client.MarkNoteRead(withToken(ctx, carol), &geonotepb.MarkNoteReadRequest{Id: note.Id})
+	if err != nil {
+		t.Fatal("Failed to mark read. Err: ", err)
+	}
+
+	receipts, err := client.ListReceipts(withToken(ctx, alice), &geonotepb.ListReceiptsRequest{Id: note.Id})
+	if err != nil {
+		t.Fatal("Failed to list receipts. Err: ", err)
+	}
+	if len(receipts.Receipts) != 1 || receipts.Receipts[0].Reader != bobId.String() ||
+		receipts.Receipts[0].ReadAt == nil || receipts.Receipts[0].Location.GetLatitude() != 1.001 {
+		t.Fatal("Expected only bob's receipt, with where he read it, got: ", receipts)
+	}
+	_, err = client.ListReceipts(withToken(ctx, bob), &geonotepb.ListReceiptsRequest{Id: note.Id})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied listing someone else's receipts, got: ", err)
+	}
+
+	// Carol's read still counts towards the note being read.
+	outbox, err := client.ListOutbox(withToken(ctx, alice), &geonotepb.ListOutboxRequest{})
+	if err != nil || len(outbox.Notes) != 1 || !outbox.Notes[0].Read {
+		t.Fatal("Expected the note to be read by everyone, got: ", outbox, " ", err)
+	}
+}
+
+func TestInboxSummary(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	aliceId, alice := signUp(t, client, "alice")
+	bobId, bob := signUp(t, client, "bob")
+	carolId, carol := signUp(t, client, "carol")
+	befriend(t, client, alice, bob)
+	befriend(t, client, carol, bob)
+	read := sendNote(t, client, alice, bobId, 1, 2)
+	unread := sendNote(t, client, alice, bobId, 1, 2)
+	sendNote(t, client, carol, bobId, 10, 10)
+	if _, err := client.MarkNoteRead(withToken(ctx, bob), &geonotepb.MarkNoteReadRequest{Id: read.Id}); err != nil {
+		t.Fatal("Failed to mark read. Err: ", err)
+	}
+
+	near := &geonotepb.GetInboxSummaryRequest{Near: &geonotepb.Location{Latitude: 1, Longitude: 2}, RadiusKm: 1}
+	summary, err := client.GetInboxSummary(withToken(ctx, bob), near)
+	if err != nil {
+		t.Fatal("Failed to get inbox summary. Err: ", err)
+	}
+	if summary.Unread != 2 || summary.Total != 3 || summary.UnreadNearby != 1 || len(summary.Senders) != 2 ||
+		summary.Senders[0].Sender != aliceId.String() || summary.Senders[0].Unread != 1 ||
+		summary.Senders[0].Total != 2 || summary.Senders[1].Sender != carolId.String() ||
+		summary.Senders[1].Unread != 1 || summary.Senders[1].Total != 1 {
+		t.Fatal("Unexpected summary: ", summary)
+	}
+
+	_, err = client.DeleteNote(withToken(ctx, bob), &geonotepb.DeleteNoteRequest{Id: unread.Id})
+	if err != nil {
+		t.Fatal("Failed to delete note. Err: ", err)
+	}
+	summary, err = client.GetInboxSummary(withToken(ctx, bob), near)
+	if err != nil || summary.Unread != 1 || summary.Total != 2 || summary.UnreadNearby != 0 {
+		t.Fatal("Expected the deleted note not to be counted, got: ", summary, " ", err)
+	}
+
+	_, err = client.GetInboxSummary(withToken(ctx, alice), &geonotepb.GetInboxSummaryRequest{Recipient: bobId.String()})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied summarizing someone else's inbox, got: ", err)
+	}
+	_, err = client.GetInboxSummary(withToken(ctx, bob),
+		&geonotepb.GetInboxSummaryRequest{Near: &geonotepb.Location{Latitude: 1, Longitude: 2}, RadiusKm: 500})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("Expected InvalidArgument for too large a radius, got: ", err)
+	}
+}
+
+func TestEditNote(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, alice := signUp(t, client, "alice")
+	bobId, bob := signUp(t, client, "bob")
+	befriend(t, client, alice, bob)
+	note := sendNote(t, client, alice, bobId, 1, 2)
+
+	_, err := client.EditNote(withToken(ctx, bob), &geonotepb.EditNoteRequest{Id: note.Id, Text: "mine now"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied editing someone else's note, got: ", err)
+	}
+	_, err = client.EditNote(withToken(ctx, alice), &geonotepb.EditNoteRequest{Id: note.Id})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("Expected InvalidArgument for empty text, got: ", err)
+	}
+
+	unchanged, err := client.EditNote(withToken(ctx, alice),
+		&geonotepb.EditNoteRequest{Id: note.Id, Text: note.Text})
+	if err != nil || unchanged.EditedAt != nil {
+		t.Fatal("Expected unchanged text not to count as an edit, got: ", unchanged, " ", err)
+	}
+	edited, err := client.EditNote(withToken(ctx, alice),
+		&geonotepb.EditNoteRequest{Id: note.Id, Text: "Meet by the fountain"})
+	if err != nil {
+		t.Fatal("Failed to edit note. Err: ", err)
+	}
+	if edited.Text != "Meet by the fountain" || edited.EditedAt == nil {
+		t.Fatal("Expected the edited note back, got: ", edited)
+	}
+
+	inbox, err := client.ListInbox(withToken(ctx, bob), &geonotepb.ListInboxRequest{})
+	if err != nil || len(inbox.Notes) != 1 || inbox.Notes[0].Text != "Meet by the fountain" ||
+		inbox.Notes[0].EditedAt == nil {
+		t.Fatal("Expected bob to see the edit, got: ", inbox, " ", err)
+	}
+
+	_, err = client.ListNoteVersions(withToken(ctx, bob), &geonotepb.ListNoteVersionsRequest{Id: note.Id})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied listing someone else's versions, got: ", err)
+	}
+	versions, err := client.ListNoteVersions(withToken(ctx, alice),
+		&geonotepb.ListNoteVersionsRequest{Id: note.Id})
+	if err != nil || len(versions.Versions) != 1 || versions.Versions[0].Text != note.Text {
+		t.Fatal("Expected the original text as the only version, got: ", versions, " ", err)
+	}
+
+	if _, err = client.MarkNoteRead(withToken(ctx, bob), &geonotepb.MarkNoteReadRequest{Id: note.Id}); err != nil {
+		t.Fatal("Failed to mark read. Err: ", err)
+	}
+	_, err = client.EditNote(withToken(ctx, alice), &geonotepb.EditNoteRequest{Id: note.Id, Text: "too late"})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatal("Expected FailedPrecondition editing a read note, got: ", err)
+	}
+}
+
+func TestEditNoteReindexFailure(t *testing.T) {
+	client, done := getTestClientWithIndex(t, &brokenSolr{solrnotes.NewMemorySolr()})
+	defer done()
+	ctx := context.Background()
+
+	_, alice := signUp(t, client, "alice")
+	bobId, bob := signUp(t, client, "bob")
+	befriend(t, client, alice, bob)
+	note := sendNote(t, client, alice, bobId, 1, 2)
+
+	edited, err := client.EditNote(withToken(ctx, alice),
+		&geonotepb.EditNoteRequest{Id: note.Id, Text: "Meet by the fountain"})
+	if err != nil {
+		t.Fatal("Expected the edit to stand when reindexing fails, got: ", err)
+	}
+	if edited.Text != "Meet by the fountain" || edited.EditedAt == nil {
+		t.Fatal("Expected the edited note back, got: ", edited)
+	}
+	inbox, err := client.ListInbox(withToken(ctx, bob), &geonotepb.ListInboxRequest{})
+	if err != nil || len(inbox.Notes) != 1 || inbox.Notes[0].Text != "Meet by the fountain" {
+		t.Fatal("Expected bob to see the edit, got: ", inbox, " ", err)
+	}
+}
+
+// brokenSolr fails to re-add a doc it already holds, so notes can still
+// be sent but not reindexed after an edit.
+type brokenSolr struct {
+	*solrnotes.MemorySolr
+}
+
+func (sc *brokenSolr) AddDoc(doc solrnotes.Document) error {
+	if existing, _ := sc.GetDoc(doc.Id()); existing != nil {
+		return errors.New("solr is down")
+	}
+	return sc.MemorySolr.AddDoc(doc)
+}
+
+func TestAttachments(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, alice := signUp(t, client, "alice")
+	bobId, bob := signUp(t, client, "bob")
+	_, carol := signUp(t, client, "carol")
+	befriend(t, client, alice, bob)
+	note := sendNote(t, client, alice, bobId, 1, 2)
+
+	var photo bytes.Buffer
+	if err := png.Encode(&photo, image.NewGray(image.Rect(0, 0, 600, 300))); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name string
+		tokens *geonotepb.SessionTokens
+		contentType string
+		data []byte
+		expected codes.Code
+	}{
+		{"not the sender", bob, "image/png", photo.Bytes(), codes.PermissionDenied},
+		{"unsupported type", alice, "text/html", []byte("<html></html>"), codes.InvalidArgument},
+		{"mismatched content", alice, "image/png", []byte("<html></html>"), codes.InvalidArgument},
+		{"too large", alice, "audio/wav", make([]byte, attachments.MAX_ATTACHMENT_BYTES + 1), codes.ResourceExhausted},
+	}
+	for _, c := range cases {
+		_, err := client.AddAttachment(withToken(ctx, c.tokens), &geonotepb.AddAttachmentRequest{
+			NoteId: note.Id,
+			ContentType: c.contentType,
+			Data: c.data,
+		})
+		if status.Code(err) != c.expected {
+			t.Fatal(c.name, ": expected ", c.expected, ", got: ", err)
+		}
+	}
+
+	added, err := client.AddAttachment(withToken(ctx, alice), &geonotepb.AddAttachmentRequest{
+		NoteId: note.Id,
+		ContentType: "image/png",
+		Data: photo.Bytes(),
+	})
+	if err != nil {
+		t.Fatal("Failed to add attachment. Err: ", err)
+	}
+	if added.NoteId != note.Id || added.Size != int64(photo.Len()) || !added.HasThumbnail {
+		t.Fatal("Unexpected attachment: ", added)
+	}
+
+	list, err := client.ListAttachments(withToken(ctx, bob), &geonotepb.ListAttachmentsRequest{NoteId: note.Id})
+	if err != nil || len(list.Attachments) != 1 || list.Attachments[0].Id != added.Id {
+		t.Fatal("Expected bob to see the attachment, got: ", list, " ", err)
+	}
+	_, err = client.ListAttachments(withToken(ctx, carol), &geonotepb.ListAttachmentsRequest{NoteId: note.Id})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied for someone who can't see the note, got: ", err)
+	}
+
+	content, err := client.GetAttachment(withToken(ctx, bob), &geonotepb.GetAttachmentRequest{Id: added.Id})
+	if err != nil || content.ContentType != "image/png" || !bytes.Equal(content.Data, photo.Bytes()) {
+		t.Fatal("Expected bob to get the photo, got: ", err)
+	}
+	thumbnail, err := client.GetAttachment(withToken(ctx, bob),
+		&geonotepb.GetAttachmentRequest{Id: added.Id, Thumbnail: true})
+	if err != nil {
+		t.Fatal("Failed to get thumbnail. Err: ", err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(thumbnail.Data))
+	if err != nil || decoded.Bounds().Dx() != attachments.THUMBNAIL_SIZE {
+		t.Fatal("Expected a thumbnail ", attachments.THUMBNAIL_SIZE, " wide, got: ", err)
+	}
+	_, err = client.GetAttachment(withToken(ctx, carol), &geonotepb.GetAttachmentRequest{Id: added.Id})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("Expected NotFound for someone who can't see the note, got: ", err)
+	}
+
+	// Erasing alice purges her note, and its attachments with it.
+	if _, err = client.DeleteUser(withToken(ctx, alice), &geonotepb.DeleteUserRequest{Username: "alice"}); err != nil {
+		t.Fatal("Failed to delete alice. Err: ", err)
+	}
+	_, err = client.GetAttachment(withToken(ctx, bob), &geonotepb.GetAttachmentRequest{Id: added.Id})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("Expected the attachment to be purged with the note, got: ", err)
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	senderId, sender := signUp(t, client, "sender")
+	recipientId, recipient := signUp(t, client, "recipient")
+	_, stranger := signUp(t, client, "stranger")
+	befriend(t, client, sender, recipient)
+
+	_, err := client.ListInbox(ctx, &geonotepb.ListInboxRequest{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatal("Expected Unauthenticated without a token, got: ", err)
+	}
+
+	_, err = client.SendNote(withToken(ctx, stranger), &geonotepb.SendNoteRequest{
+		Sender: senderId.String(),
+		Recipient: recipientId.String(),
+		Text: "hi",
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied sending as someone else, got: ", err)
+	}
+	_, err = client.SendNote(withToken(ctx, stranger), &geonotepb.SendNoteRequest{
+		Recipient: recipientId.String(),
+		Text: "hi",
+	})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied sending to a non-contact, got: ", err)
+	}
+
+	note := sendNote(t, client, sender, recipientId, 1, 1)
+	_, err = client.MarkNoteRead(withToken(ctx, sender), &geonotepb.MarkNoteReadRequest{Id: note.Id})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied reading as the sender, got: ", err)
+	}
+	_, err = client.MarkNoteRead(withToken(ctx, recipient), &geonotepb.MarkNoteReadRequest{Id: note.Id})
+	if err != nil {
+		t.Fatal("Failed to mark note read. Err: ", err)
+	}
+
+	_, err = client.DeleteUser(withToken(ctx, stranger), &geonotepb.DeleteUserRequest{Username: "sender"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatal("Expected PermissionDenied deleting someone else, got: ", err)
+	}
+	_, err = client.DeleteUser(withToken(ctx, stranger), &geonotepb.DeleteUserRequest{Username: "Stranger"})
+	if err != nil {
+		t.Fatal("Failed to delete own account typed in another case. Err: ", err)
+	}
+
+	// Deleting an account takes its notes and contacts with it.
+	_, err = client.DeleteUser(withToken(ctx, recipient), &geonotepb.DeleteUserRequest{Username: "recipient"})
+	if err != nil {
+		t.Fatal("Failed to delete own account. Err: ", err)
+	}
+	outbox, err := client.ListOutbox(withToken(ctx, sender), &geonotepb.ListOutboxRequest{})
+	if err != nil || len(outbox.Notes) != 0 {
+		t.Fatal("Expected notes to a deleted user to be gone, got: ", outbox, " ", err)
+	}
+	list, err := client.ListContacts(withToken(ctx, sender), &geonotepb.ListContactsRequest{})
+	if err != nil || len(list.Contacts) != 0 {
+		t.Fatal("Expected a deleted user to be gone from contacts, got: ", list, " ", err)
+	}
+	_, err = client.RefreshSession(ctx, &geonotepb.RefreshSessionRequest{RefreshToken: recipient.RefreshToken})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatal("Expected a deleted user's sessions to be revoked, got: ", err)
+	}
+}
+
+func TestSendNoteValidation(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	_, tokens := signUp(t, client, "myusername")
+	ctx := withToken(context.Background(), tokens)
+
+	_, err := client.SendNote(ctx, &geonotepb.SendNoteRequest{
+		Recipient: "not-a-uuid",
+		Text: "hi",
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("Expected InvalidArgument, got: ", err)
+	}
+
+	_, err = client.MarkNoteRead(ctx, &geonotepb.MarkNoteReadRequest{
+		Id: uuid.NewV4().String(),
+	})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("Expected NotFound, got: ", err)
+	}
+}
+
+func TestFindNearbyStreamsNotes(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, sender := signUp(t, client, "sender")
+	recipientId, recipient := signUp(t, client, "recipient")
+	befriend(t, client, sender, recipient)
+	nearby1 := sendNote(t, client, sender, recipientId, 40.810260, -73.94694)
+	nearby2 := sendNote(t, client, sender, recipientId, 40.808612, -73.944443)
+	sendNote(t, client, sender, recipientId, 40.758320, -73.988327)
+
+	stream, err := client.FindNearby(withToken(ctx, recipient), &geonotepb.FindNearbyRequest{
+		Latitude: 40.809322,
+		Longitude: -73.944587,
+		RadiusKm: 0.5,
+	})
+	if err != nil {
+		t.Fatal("FindNearby failed. Err: ", err)
+	}
+
+	found := make(map[string]bool)
+	for {
+		note, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Failed to receive note. Err: ", err)
+		}
+		found[note.Id] = true
+	}
+
+	if len(found) != 2 || !found[nearby1.Id] || !found[nearby2.Id] {
+		t.Fatal("Expected the two nearby notes, got ", found)
+	}
+}
+
+func TestWatchUnlocks(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+	defer cancel()
+
+	senderId, sender := signUp(t, client, "sender")
+	_, other := signUp(t, client, "other")
+	recipientId, recipient := signUp(t, client, "recipient")
+	befriend(t, client, sender, recipient)
+	befriend(t, client, other, recipient)
+
+	stream, err := client.WatchUnlocks(withToken(ctx, sender), &geonotepb.WatchUnlocksRequest{})
+	if err != nil {
+		t.Fatal("WatchUnlocks failed. Err: ", err)
+	}
+
+	// The subscription is made asynchronously on the server, so keep
+	// sending and reading notes until an event arrives. A note from
+	// someone else is read each time too, and must never show up.
+	go func() {
+		for ctx.Err() == nil {
+			for _, from := range []*geonotepb.SessionTokens{other, sender} {
+				note, err := client.SendNote(withToken(ctx, from), &geonotepb.SendNoteRequest{
+					Recipient: recipientId.String(),
+					Text: "This is a test note",
+				})
+				if err == nil {
+					client.MarkNoteRead(withToken(ctx, recipient),
+						&geonotepb.MarkNoteReadRequest{Id: note.Id})
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	event, err := stream.Recv()
+	if err != nil {
+		t.Fatal("Failed to receive unlock event. Err: ", err)
+	}
+	if event.Sender != senderId.String() || event.NoteId == "" {
+		t.Fatal("Unexpected unlock event: ", event)
+	}
+}
+
+func TestExportData(t *testing.T) {
+	client, done := getTestClient(t)
+	defer done()
+	ctx := context.Background()
+
+	_, sender := signUp(t, client, "sender")
+	recipientId, recipient := signUp(t, client, "recipient")
+	befriend(t, client, sender, recipient)
+	sendNote(t, client, sender, recipientId, 1, 1)
+
+	stream, err := client.ExportData(withToken(ctx, sender), &geonotepb.ExportDataRequest{})
+	if err != nil {
+		t.Fatal("Failed to start export. Err: ", err)
+	}
+	var archive bytes.Buffer
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Export failed. Err: ", err)
+		}
+		archive.Write(chunk.Data)
+	}
+
+	files, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		t.Fatal("Export isn't a zip archive. Err: ", err)
+	}
+	if len(files.File) != 4 {
+		t.Fatal("Unexpected files in export: ", files.File)
+	}
+
+	stream, err = client.ExportData(ctx, &geonotepb.ExportDataRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatal("Expected Unauthenticated without a token, got: ", err)
+	}
+}
+
+func getTestClient(t *testing.T) (geonotepb.GeoNoteClient, func()) {
+	return getTestClientWithIndex(t, solrnotes.NewMemorySolr())
+}
+
+func getTestClientWithIndex(
+	t *testing.T,
+	index solrnotes.SolrConnection) (geonotepb.GeoNoteClient, func()) {
+	manager, err := sessions.NewManager(
+		sessions.NewMemorySessions(), []byte(strings.Repeat("k", sessions.MIN_KEY_LEN)), sessions.Options{})
+	if err != nil {
+		t.Fatal("Failed to make session manager. Err: ", err)
+	}
+
+	users := userdb.NewMemoryUserdb()
+	files := attachments.NewAttachments(attachments.NewMemoryAttachments(), attachments.NewMemoryBlobStore())
+	notes := attachments.NewNotesdb(notesdb.NewMemoryNotesdb(), files)
+	events := lockout.NewMemoryLoginEvents()
+	contactGraph := contacts.NewContacts(contacts.NewMemoryContacts())
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	NewServer(
+		users,
+		notes,
+		index,
+		manager,
+		lockout.NewGuard(userdb.NewTwoFactor(users, users), events, lockout.DEFAULT_POLICY),
+		userdb.NewResetter(users, users, userdb.LogResetSink{}, 0),
+		userdb.NewTwoFactor(users, users),
+		contactGraph,
+		files,
+		erasure.NewEraser(users, notes, index, manager, contactGraph, events, erasure.Options{}),
+		unlocks.NewHub(),
+	).Register(grpcServer)
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal("Failed to dial test server. Err: ", err)
+	}
+
+	return geonotepb.NewGeoNoteClient(conn), func() {
+		conn.Close()
+		grpcServer.Stop()
+	}
+}
+
+// signUp registers username with a fixed password and logs them in.
+func signUp(
+	t *testing.T,
+	client geonotepb.GeoNoteClient,
+	username string) (uuid.UUID, *geonotepb.SessionTokens) {
+	_, err := client.RegisterUser(context.Background(), &geonotepb.RegisterUserRequest{
+		Username: username,
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal("Failed to register. Err: ", err)
+	}
+
+	tokens := login(t, client, username)
+	id, err := uuid.FromString(tokens.UserId)
+	if err != nil {
+		t.Fatal("Login returned a bad id. Err: ", err)
+	}
+	return id, tokens
+}
+
+func login(t *testing.T, client geonotepb.GeoNoteClient, username string) *geonotepb.SessionTokens {
+	response, err := client.Login(context.Background(), &geonotepb.LoginRequest{
+		Username: username,
+		Password: "password",
+	})
+	if err != nil {
+		t.Fatal("Failed to log in. Err: ", err)
+	}
+	return response.Tokens
+}
+
+// befriend makes two signed-up users contacts, so they can leave each
+// other notes.
+func befriend(t *testing.T, client geonotepb.GeoNoteClient, requester *geonotepb.SessionTokens,
+	accepter *geonotepb.SessionTokens) {
+	ctx := context.Background()
+	_, err := client.RequestContact(withToken(ctx, requester),
+		&geonotepb.RequestContactRequest{UserId: accepter.UserId})
+	if err != nil {
+		t.Fatal("Failed to request contact. Err: ", err)
+	}
+	_, err = client.AcceptContact(withToken(ctx, accepter),
+		&geonotepb.AcceptContactRequest{UserId: requester.UserId})
+	if err != nil {
+		t.Fatal("Failed to accept contact. Err: ", err)
+	}
+}
+
+// withToken returns a context that sends tokens' access token on calls.
+func withToken(ctx context.Context, tokens *geonotepb.SessionTokens) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer " + tokens.AccessToken)
+}
+
+// sendNote sends a note as whoever tokens belong to.
+func sendNote(
+	t *testing.T,
+	client geonotepb.GeoNoteClient,
+	sender *geonotepb.SessionTokens,
+	recipient uuid.UUID,
+	latitude float64,
+	longitude float64) *geonotepb.Note {
+	note, err := client.SendNote(withToken(context.Background(), sender), &geonotepb.SendNoteRequest{
+		Recipient: recipient.String(),
+		Text: "This is a test note",
+		Latitude: latitude,
+		Longitude: longitude,
+	})
+	if err != nil {
+		t.Fatal("Failed to send note. Err: ", err)
+	}
+	return note
+}
+
+// findNearby returns the notes within a kilometre of a point that whoever
+// tokens belong to can see, keyed by id.
+func findNearby(
+	t *testing.T,
+	client geonotepb.GeoNoteClient,
+	viewer *geonotepb.SessionTokens,
+	latitude float64,
+	longitude float64) map[string]*geonotepb.Note {
+	stream, err := client.FindNearby(withToken(context.Background(), viewer), &geonotepb.FindNearbyRequest{
+		Latitude: latitude,
+		Longitude: longitude,
+		RadiusKm: 1,
+	})
+	if err != nil {
+		t.Fatal("FindNearby failed. Err: ", err)
+	}
+
+	found := make(map[string]*geonotepb.Note)
+	for {
+		note, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal("Failed to receive note. Err: ", err)
+		}
+		found[note.Id] = note
+	}
+	return found
+}
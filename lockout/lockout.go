@@ -0,0 +1,401 @@
+package lockout
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"database/sql"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/userdb"
+)
+
+const (
+	OUTCOME_SUCCESS = "success"
+	OUTCOME_FAILURE = "failure"
+	// OUTCOME_LOCKED is an attempt refused without checking the password.
+	// It doesn't count as a failure, so it doesn't extend the lockout.
+	OUTCOME_LOCKED = "locked"
+	// OUTCOME_SECOND_FACTOR is a right password from a user with
+	// two-factor enabled, but no code. It counts as neither a success nor
+	// a failure.
+	OUTCOME_SECOND_FACTOR = "second_factor"
+	// OUTCOME_CODE is a right two-factor code given outside login, e.g. to
+	// turn two-factor off. It isn't a login, so unlike a success it
+	// doesn't start the user's failure count over; otherwise enrolling
+	// and confirming a secret of one's own would.
+	OUTCOME_CODE = "code"
+
+	SCOPE_USER = "user"
+	SCOPE_SOURCE = "source"
+)
+
+// Policy decides how long a user or source has to wait after failing to
+// log in. The first FreeAttempts failures cost nothing; after that each
+// failure doubles the wait, starting at BaseDelay and capped at MaxDelay.
+// Only failures within Window count, and a user's count starts over when
+// they log in successfully. A source's doesn't, since one account the
+// attacker controls would otherwise reset it.
+type Policy struct {
+	FreeAttempts int
+	SourceFreeAttempts int
+	BaseDelay time.Duration
+	MaxDelay time.Duration
+	Window time.Duration
+}
+
+var DEFAULT_POLICY = Policy{
+	FreeAttempts: 3,
+	SourceFreeAttempts: 20,
+	BaseDelay: time.Second,
+	MaxDelay: 15 * time.Minute,
+	Window: 24 * time.Hour,
+}
+
+// LockedOutError is returned instead of checking credentials while a user
+// or source is locked out.
+type LockedOutError struct {
+	// Scope is SCOPE_USER or SCOPE_SOURCE.
+	Scope string
+	Until time.Time
+}
+
+func (e *LockedOutError) Error() string {
+	return fmt.Sprintf("Too many failed logins for this %v; try again after %v.",
+		e.Scope, e.Until.UTC().Format(time.RFC3339))
+}
+
+// LoginEvent is a row in the login_events table. UserId is uuid.Nil when
+// the username doesn't exist or the attempt was refused.
+type LoginEvent struct {
+	Id uuid.UUID
+	Username string
+	UserId uuid.UUID
+	Source string
+	Outcome string
+	At time.Time
+}
+
+type LoginEventConnection interface {
+	InsertLoginEvent(event *LoginEvent) error
+
+	// GetUserFailures counts failed logins for username after both since
+	// and the user's last successful login, and returns the latest one's
+	// time.
+	GetUserFailures(username string, since time.Time) (int, time.Time, error)
+
+	// GetSourceFailures counts failed logins from source after since, and
+	// returns the latest one's time.
+	GetSourceFailures(source string, since time.Time) (int, time.Time, error)
+
+	// GetLoginEvents returns a user's login events, newest first.
+	GetLoginEvents(username string, count int, offset int) ([]*LoginEvent, error)
+
+	// DeleteLoginEvents deletes every login event for username and
+	// returns how many there were.
+	DeleteLoginEvents(username string) (int, error)
+}
+
+// CredentialChecker checks a login's password and, for users who have it
+// enabled, second factor. userdb.TwoFactor is one.
+type CredentialChecker interface {
+	CheckLogin(username string, password string, code string) (uuid.UUID, bool, error)
+}
+
+// Guard checks credentials on behalf of login endpoints, refusing
+// attempts while the user or source is locked out and recording every
+// attempt.
+type Guard struct {
+	checker CredentialChecker
+	events LoginEventConnection
+	policy Policy
+	now func() time.Time
+}
+
+func NewGuard(checker CredentialChecker, events LoginEventConnection, policy Policy) *Guard {
+	return &Guard{checker: checker, events: events, policy: policy, now: time.Now}
+}
+
+// CheckLogin is the checker's CheckLogin, plus lockout. source identifies
+// where the attempt came from, e.g. the client's IP address. While locked
+// out it returns a *LockedOutError. A wrong two-factor code counts as a
+// failure like a wrong password, so codes can't be guessed any faster.
+//
+// Attempts are counted against the normalized username, so changing its
+// case doesn't get around a lockout.
+func (g *Guard) CheckLogin(
+	username string,
+	password string,
+	code string,
+	source string) (uuid.UUID, bool, error) {
+	return g.Attempt(username, source, func(username string) (uuid.UUID, bool, error) {
+		return g.checker.CheckLogin(username, password, code)
+	})
+}
+
+// Attempt runs check, which tries a secret of username's such as their
+// password, under the same lockout as CheckLogin and recorded the same
+// way. check is given the normalized username and reports whether the
+// secret was right. Guessing through any endpoint that checks a secret
+// then uses up the same attempts as logging in.
+func (g *Guard) Attempt(
+	username string,
+	source string,
+	check func(username string) (uuid.UUID, bool, error)) (uuid.UUID, bool, error) {
+	return g.attempt(username, source, OUTCOME_SUCCESS, check)
+}
+
+// AttemptCode is Attempt for checks of a two-factor code alone, such as
+// confirming or disabling two-factor. A wrong code counts as a failure,
+// but a right one is recorded as OUTCOME_CODE and doesn't undo earlier
+// failures.
+func (g *Guard) AttemptCode(
+	username string,
+	source string,
+	check func(username string) (uuid.UUID, bool, error)) (uuid.UUID, bool, error) {
+	return g.attempt(username, source, OUTCOME_CODE, check)
+}
+
+// attempt records success as the outcome of a right secret.
+func (g *Guard) attempt(
+	username string,
+	source string,
+	success string,
+	check func(username string) (uuid.UUID, bool, error)) (uuid.UUID, bool, error) {
+	now := g.now().UTC()
+	username = userdb.NormalizeUsername(username)
+
+	until, scope, err := g.lockedUntil(username, source, now)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if now.Before(until) {
+		g.record(username, uuid.Nil, source, OUTCOME_LOCKED, now)
+		return uuid.Nil, false, &LockedOutError{Scope: scope, Until: until}
+	}
+
+	id, valid, err := check(username)
+	if err == userdb.ErrSecondFactorRequired {
+		g.record(username, uuid.Nil, source, OUTCOME_SECOND_FACTOR, now)
+		return uuid.Nil, false, err
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	if valid {
+		g.record(username, id, source, success, now)
+	} else {
+		g.record(username, uuid.Nil, source, OUTCOME_FAILURE, now)
+	}
+	return id, valid, nil
+}
+
+// LockedUntil reports when username, from source, may next try to log
+// in. It's the zero time if they may try now.
+func (g *Guard) LockedUntil(username string, source string) (time.Time, error) {
+	now := g.now().UTC()
+	until, _, err := g.lockedUntil(userdb.NormalizeUsername(username), source, now)
+	if err != nil || !now.Before(until) {
+		return time.Time{}, err
+	}
+	return until, nil
+}
+
+func (g *Guard) lockedUntil(username string, source string, now time.Time) (time.Time, string, error) {
+	since := now.Add(-g.policy.Window)
+
+	failures, last, err := g.events.GetUserFailures(username, since)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	userUntil := last.Add(g.delay(failures, g.policy.FreeAttempts))
+
+	failures, last, err = g.events.GetSourceFailures(source, since)
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	sourceUntil := last.Add(g.delay(failures, g.policy.SourceFreeAttempts))
+
+	if sourceUntil.After(userUntil) {
+		return sourceUntil, SCOPE_SOURCE, nil
+	}
+	return userUntil, SCOPE_USER, nil
+}
+
+// delay is how long to wait after the latest of failures failed logins.
+func (g *Guard) delay(failures int, free int) time.Duration {
+	if failures < free {
+		return 0
+	}
+
+	delay := g.policy.BaseDelay
+	for i := free; i < failures && delay < g.policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > g.policy.MaxDelay {
+		delay = g.policy.MaxDelay
+	}
+	return delay
+}
+
+// record logs rather than returns failures to store an event, so that
+// an audit log outage doesn't stop people logging in.
+func (g *Guard) record(username string, userId uuid.UUID, source string, outcome string, at time.Time) {
+	err := g.events.InsertLoginEvent(&LoginEvent{
+		Id: uuid.NewV4(),
+		Username: username,
+		UserId: userId,
+		Source: source,
+		Outcome: outcome,
+		At: at,
+	})
+	if err != nil {
+		log.Printf("Failed to record %v login for %v from %v. Err: %v", outcome, username, source, err)
+	}
+}
+
+type MysqlLoginEvents struct {
+	conn *sql.DB
+}
+
+type DbCredentials struct {
+	User string
+	Password string
+	Host string
+	Port string
+}
+
+func NewMysqlLoginEvents(credentials *DbCredentials) (*MysqlLoginEvents, error) {
+	dsn := credentials.User + ":" + credentials.Password + "@tcp(" +
+		credentials.Host + ":" + credentials.Port + ")/geonote?parseTime=true"
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Print("Failed to open db:", err)
+		return nil, err
+	}
+
+	return &MysqlLoginEvents{conn: db}, nil
+}
+
+func (db MysqlLoginEvents) InsertLoginEvent(event *LoginEvent) error {
+	insertSql := "INSERT INTO login_events " +
+		" (id, username, user_id, source, outcome, attempted_at) VALUES " +
+		" (?, ?, ?, ?, ?, ?) "
+
+	statement, err := db.conn.Prepare(insertSql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", insertSql, err)
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(
+		event.Id.String(),
+		event.Username,
+		event.UserId.String(),
+		event.Source,
+		event.Outcome,
+		event.At,
+	)
+	if err != nil {
+		log.Printf("Failed to insert login event. Err: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+func (db MysqlLoginEvents) GetUserFailures(username string, since time.Time) (int, time.Time, error) {
+	sql := "SELECT COUNT(*), MAX(attempted_at) FROM login_events " +
+		" WHERE username = ? AND outcome = ? AND attempted_at > ? AND attempted_at > COALESCE(" +
+		"  (SELECT MAX(attempted_at) FROM login_events WHERE username = ? AND outcome = ?), ?)"
+	return db.countFailures(sql, username, OUTCOME_FAILURE, since, username, OUTCOME_SUCCESS, since)
+}
+
+func (db MysqlLoginEvents) GetSourceFailures(source string, since time.Time) (int, time.Time, error) {
+	sql := "SELECT COUNT(*), MAX(attempted_at) FROM login_events WHERE source = ? AND outcome = ? AND attempted_at > ?"
+	return db.countFailures(sql, source, OUTCOME_FAILURE, since)
+}
+
+func (db MysqlLoginEvents) countFailures(query string, args ...interface{}) (int, time.Time, error) {
+	statement, err := db.conn.Prepare(query)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", query, err)
+		return 0, time.Time{}, err
+	}
+	defer statement.Close()
+
+	var count int
+	// MAX(attempted_at) is NULL when nothing matched, which leaves last zero.
+	var last sql.NullTime
+	if err = statement.QueryRow(args...).Scan(&count, &last); err != nil {
+		log.Printf("Failed to count failed logins. Err: %v", err)
+		return 0, time.Time{}, err
+	}
+
+	return count, last.Time, nil
+}
+
+func (db MysqlLoginEvents) GetLoginEvents(username string, count int, offset int) ([]*LoginEvent, error) {
+	sql := "SELECT id, username, user_id, source, outcome, attempted_at FROM login_events " +
+		" WHERE username = ? ORDER BY attempted_at DESC LIMIT ? OFFSET ?"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(username, count, offset)
+	if err != nil {
+		log.Printf("Failed to query login events for %v. Err: %v", username, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*LoginEvent
+	for rows.Next() {
+		var event LoginEvent
+		err = rows.Scan(
+			&event.Id,
+			&event.Username,
+			&event.UserId,
+			&event.Source,
+			&event.Outcome,
+			&event.At,
+		)
+		if err != nil {
+			log.Printf("Failed to scan row while fetching login events. Err: %v", err)
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+
+	return events, rows.Err()
+}
+
+func (db MysqlLoginEvents) DeleteLoginEvents(username string) (int, error) {
+	sql := "DELETE FROM login_events WHERE username = ?"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return 0, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(username)
+	if err != nil {
+		log.Printf("Failed to delete login events for %v. Err: %v", username, err)
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error while fetching rows affected during login events delete. Err: %v", err)
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
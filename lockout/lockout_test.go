@@ -0,0 +1,231 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/userdb"
+)
+
+func TestUserBackoff(t *testing.T) {
+	g, clock := getTestGuard(t, DEFAULT_POLICY)
+
+	for i := 0; i < DEFAULT_POLICY.FreeAttempts; i++ {
+		if _, valid, err := g.CheckLogin("myusername", "wrong", "", "10.0.0.1"); err != nil || valid {
+			t.Fatal("Expected a plain failed login, got: ", valid, " ", err)
+		}
+	}
+
+	_, _, err := g.CheckLogin("myusername", "password", "", "10.0.0.1")
+	lockedOut, ok := err.(*LockedOutError)
+	if !ok || lockedOut.Scope != SCOPE_USER || !lockedOut.Until.Equal(clock.Add(time.Second)) {
+		t.Fatal("Expected a one second user lockout, got: ", err)
+	}
+
+	// Locked out attempts don't count, so the wait hasn't grown.
+	*clock = clock.Add(time.Second)
+	if _, valid, err := g.CheckLogin("myusername", "wrong", "", "10.0.0.1"); err != nil || valid {
+		t.Fatal("Expected a plain failed login after waiting, got: ", valid, " ", err)
+	}
+
+	*clock = clock.Add(time.Second)
+	if _, _, err = g.CheckLogin("myusername", "password", "", "10.0.0.1"); err == nil {
+		t.Fatal("Expected the wait to have doubled.")
+	}
+
+	*clock = clock.Add(time.Second)
+	if _, valid, err := g.CheckLogin("myusername", "password", "", "10.0.0.1"); err != nil || !valid {
+		t.Fatal("Failed to log in after the lockout. Err: ", err)
+	}
+
+	// A successful login starts the count over.
+	*clock = clock.Add(time.Second)
+	if _, valid, err := g.CheckLogin("myusername", "wrong", "", "10.0.0.1"); err != nil || valid {
+		t.Fatal("Expected a plain failed login after a success, got: ", valid, " ", err)
+	}
+	if until, err := g.LockedUntil("myusername", "10.0.0.1"); err != nil || !until.IsZero() {
+		t.Fatal("Expected no lockout after a success, got: ", until, " ", err)
+	}
+}
+
+func TestMaxDelay(t *testing.T) {
+	g, clock := getTestGuard(t, DEFAULT_POLICY)
+
+	for i := 0; i < 30; i++ {
+		g.CheckLogin("myusername", "wrong", "", "10.0.0.1")
+		*clock = clock.Add(DEFAULT_POLICY.MaxDelay)
+	}
+
+	*clock = clock.Add(-time.Second)
+	until, err := g.LockedUntil("myusername", "10.0.0.1")
+	if err != nil || !until.Equal(clock.Add(time.Second)) {
+		t.Fatal("Expected the wait to be capped at MaxDelay, got: ", until, " ", err)
+	}
+}
+
+func TestSourceLockout(t *testing.T) {
+	policy := DEFAULT_POLICY
+	policy.SourceFreeAttempts = 2
+	g, _ := getTestGuard(t, policy)
+
+	g.CheckLogin("alice", "wrong", "", "10.0.0.1")
+	g.CheckLogin("bob", "wrong", "", "10.0.0.1")
+
+	_, _, err := g.CheckLogin("myusername", "password", "", "10.0.0.1")
+	if lockedOut, ok := err.(*LockedOutError); !ok || lockedOut.Scope != SCOPE_SOURCE {
+		t.Fatal("Expected a source lockout, got: ", err)
+	}
+
+	if _, valid, err := g.CheckLogin("myusername", "password", "", "10.0.0.2"); err != nil || !valid {
+		t.Fatal("Another source was locked out. Err: ", err)
+	}
+}
+
+func TestLoginEvents(t *testing.T) {
+	g, clock := getTestGuard(t, DEFAULT_POLICY)
+	events := g.events
+
+	for _, password := range []string{"wrong", "wrong", "wrong", "password"} {
+		g.CheckLogin("myusername", password, "", "10.0.0.1")
+		*clock = clock.Add(time.Millisecond)
+	}
+	*clock = clock.Add(time.Second)
+	id, _, _ := g.CheckLogin("myusername", "password", "", "10.0.0.1")
+
+	recorded, err := events.GetLoginEvents("myusername", 10, 0)
+	if err != nil {
+		t.Fatal("Failed to get login events. Err: ", err)
+	}
+
+	expected := []string{OUTCOME_SUCCESS, OUTCOME_LOCKED, OUTCOME_FAILURE, OUTCOME_FAILURE, OUTCOME_FAILURE}
+	if len(recorded) != len(expected) {
+		t.Fatal("Expected ", len(expected), " events, got ", len(recorded))
+	}
+	for i, event := range recorded {
+		if event.Outcome != expected[i] || event.Source != "10.0.0.1" {
+			t.Fatal("Unexpected event ", i, ": ", event)
+		}
+	}
+	if recorded[0].UserId != id {
+		t.Fatal("Successful login was not recorded with the user's id.")
+	}
+}
+
+// secondFactorChecker accepts "password" but always wants a code, and
+// accepts only "123456".
+type secondFactorChecker struct{}
+
+func (secondFactorChecker) CheckLogin(username string, password string, code string) (uuid.UUID, bool, error) {
+	if password != "password" {
+		return uuid.Nil, false, nil
+	}
+	if code == "" {
+		return uuid.Nil, false, userdb.ErrSecondFactorRequired
+	}
+	return uuid.Nil, code == "123456", nil
+}
+
+func TestSecondFactor(t *testing.T) {
+	g := NewGuard(secondFactorChecker{}, NewMemoryLoginEvents(), DEFAULT_POLICY)
+
+	for i := 0; i < DEFAULT_POLICY.FreeAttempts + 1; i++ {
+		if _, _, err := g.CheckLogin("myusername", "password", "", "10.0.0.1"); err != userdb.ErrSecondFactorRequired {
+			t.Fatal("Expected ErrSecondFactorRequired, got: ", err)
+		}
+	}
+
+	for i := 0; i < DEFAULT_POLICY.FreeAttempts; i++ {
+		if _, valid, err := g.CheckLogin("myusername", "password", "000000", "10.0.0.1"); err != nil || valid {
+			t.Fatal("Expected a plain failed login for a wrong code, got: ", valid, " ", err)
+		}
+	}
+	_, _, err := g.CheckLogin("myusername", "password", "123456", "10.0.0.1")
+	if _, ok := err.(*LockedOutError); !ok {
+		t.Fatal("Expected wrong codes to lock the user out, got: ", err)
+	}
+}
+
+func TestAttemptSharesLoginLockout(t *testing.T) {
+	g, _ := getTestGuard(t, DEFAULT_POLICY)
+
+	var checked string
+	wrongCode := func(username string) (uuid.UUID, bool, error) {
+		checked = username
+		return uuid.Nil, false, nil
+	}
+	for i := 0; i < DEFAULT_POLICY.FreeAttempts; i++ {
+		if _, valid, err := g.Attempt("MyUsername", "10.0.0.1", wrongCode); err != nil || valid {
+			t.Fatal("Expected a plain failed attempt, got: ", valid, " ", err)
+		}
+	}
+	if checked != "myusername" {
+		t.Fatal("Attempt checked ", checked, " rather than the normalized username.")
+	}
+
+	_, _, err := g.CheckLogin("myusername", "password", "", "10.0.0.1")
+	if lockedOut, ok := err.(*LockedOutError); !ok || lockedOut.Scope != SCOPE_USER {
+		t.Fatal("Expected failed attempts to lock out logins, got: ", err)
+	}
+	_, _, err = g.Attempt("myusername", "10.0.0.2", func(string) (uuid.UUID, bool, error) {
+		t.Fatal("Attempt ran its check while locked out.")
+		return uuid.Nil, false, nil
+	})
+	if _, ok := err.(*LockedOutError); !ok {
+		t.Fatal("Expected Attempt to be locked out too, got: ", err)
+	}
+}
+
+func TestAttemptCodeDoesNotReset(t *testing.T) {
+	g, clock := getTestGuard(t, DEFAULT_POLICY)
+	code := func(right bool) func(string) (uuid.UUID, bool, error) {
+		return func(string) (uuid.UUID, bool, error) {
+			return uuid.Nil, right, nil
+		}
+	}
+
+	for i := 0; i < DEFAULT_POLICY.FreeAttempts - 1; i++ {
+		if _, valid, err := g.AttemptCode("myusername", "10.0.0.1", code(false)); err != nil || valid {
+			t.Fatal("Expected a plain failed attempt, got: ", valid, " ", err)
+		}
+	}
+	if _, valid, err := g.AttemptCode("myusername", "10.0.0.1", code(true)); err != nil || !valid {
+		t.Fatal("Expected a right code to be accepted, got: ", valid, " ", err)
+	}
+
+	// The right code didn't start the count over, so one more wrong one
+	// locks the user out.
+	g.AttemptCode("myusername", "10.0.0.1", code(false))
+	if until, err := g.LockedUntil("myusername", "10.0.0.1"); err != nil || !until.Equal(clock.Add(time.Second)) {
+		t.Fatal("Expected a right code not to reset failures, got: ", until, " ", err)
+	}
+
+	recorded, err := g.events.GetLoginEvents("myusername", 10, 0)
+	if err != nil {
+		t.Fatal("Failed to get login events. Err: ", err)
+	}
+	codes := 0
+	for _, event := range recorded {
+		if event.Outcome == OUTCOME_CODE {
+			codes++
+		}
+	}
+	if len(recorded) != DEFAULT_POLICY.FreeAttempts + 1 || codes != 1 {
+		t.Fatal("Expected the right code to be recorded as ", OUTCOME_CODE, ", got ", codes, " of ", len(recorded))
+	}
+}
+
+// getTestGuard returns a guard for a single user, "myusername" with
+// password "password", whose clock only moves when the test moves it.
+func getTestGuard(t *testing.T, policy Policy) (*Guard, *time.Time) {
+	users := userdb.NewMemoryUserdb()
+	if _, err := users.RegisterUser("myusername", "password"); err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+
+	g := NewGuard(userdb.NewTwoFactor(users, users), NewMemoryLoginEvents(), policy)
+	clock := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	g.now = func() time.Time { return clock }
+	return g, &clock
+}
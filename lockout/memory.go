@@ -0,0 +1,104 @@
+package lockout
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryLoginEvents is an in-memory LoginEventConnection for tests in
+// packages that sit on top of lockout and shouldn't need a live MySQL.
+type MemoryLoginEvents struct {
+	mutex sync.Mutex
+	events []LoginEvent
+}
+
+func NewMemoryLoginEvents() *MemoryLoginEvents {
+	return &MemoryLoginEvents{}
+}
+
+func (db *MemoryLoginEvents) InsertLoginEvent(event *LoginEvent) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.events = append(db.events, *event)
+	return nil
+}
+
+func (db *MemoryLoginEvents) GetUserFailures(username string, since time.Time) (int, time.Time, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, event := range db.events {
+		if event.Username == username && event.Outcome == OUTCOME_SUCCESS && event.At.After(since) {
+			since = event.At
+		}
+	}
+	return db.countFailures(func(event *LoginEvent) bool {
+		return event.Username == username
+	}, since)
+}
+
+func (db *MemoryLoginEvents) GetSourceFailures(source string, since time.Time) (int, time.Time, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	return db.countFailures(func(event *LoginEvent) bool {
+		return event.Source == source
+	}, since)
+}
+
+func (db *MemoryLoginEvents) countFailures(matches func(*LoginEvent) bool, since time.Time) (int, time.Time, error) {
+	var count int
+	var last time.Time
+	for i, _ := range db.events {
+		event := &db.events[i]
+		if matches(event) && event.Outcome == OUTCOME_FAILURE && event.At.After(since) {
+			count++
+			if event.At.After(last) {
+				last = event.At
+			}
+		}
+	}
+	return count, last, nil
+}
+
+func (db *MemoryLoginEvents) GetLoginEvents(username string, count int, offset int) ([]*LoginEvent, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var events []*LoginEvent
+	for _, event := range db.events {
+		if event.Username == username {
+			copied := event
+			events = append(events, &copied)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].At.After(events[j].At)
+	})
+
+	if offset >= len(events) {
+		return nil, nil
+	}
+	events = events[offset:]
+	if len(events) > count {
+		events = events[:count]
+	}
+	return events, nil
+}
+
+func (db *MemoryLoginEvents) DeleteLoginEvents(username string) (int, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var kept []LoginEvent
+	for _, event := range db.events {
+		if event.Username != username {
+			kept = append(kept, event)
+		}
+	}
+	deleted := len(db.events) - len(kept)
+	db.events = kept
+	return deleted, nil
+}
@@ -0,0 +1,15 @@
+-- Every login attempt, kept both for auditing and to work out lockouts.
+-- username is whatever was typed, so it may not be a real user; user_id
+-- is only set on success.
+
+CREATE TABLE login_events (
+	id CHAR(36) NOT NULL,
+	username VARCHAR(124) NOT NULL,
+	user_id CHAR(36) NOT NULL,
+	source VARCHAR(64) NOT NULL,
+	outcome VARCHAR(16) NOT NULL,
+	attempted_at DATETIME NOT NULL,
+	PRIMARY KEY (id),
+	KEY login_events_username (username, attempted_at),
+	KEY login_events_source (source, attempted_at)
+);
@@ -0,0 +1,327 @@
+package notesdb
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+// MemoryNotesdb is an in-memory NotesdbConnection for tests in packages
+// that sit on top of notesdb and shouldn't need a live MySQL.
+type MemoryNotesdb struct {
+	mutex sync.Mutex
+	notes map[uuid.UUID]Note
+	versions map[uuid.UUID][]Version
+}
+
+func NewMemoryNotesdb() *MemoryNotesdb {
+	return &MemoryNotesdb{notes: make(map[uuid.UUID]Note), versions: make(map[uuid.UUID][]Version)}
+}
+
+func (db *MemoryNotesdb) InsertNote(note *Note) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, ok := db.notes[note.id]; ok {
+		return errors.New("Duplicate note id: " + note.id.String())
+	}
+	if err := validateNote(note); err != nil {
+		return err
+	}
+	db.notes[note.id] = copyNote(note)
+	return nil
+}
+
+func (db *MemoryNotesdb) PurgeNote(id uuid.UUID) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, ok := db.notes[id]; !ok {
+		return errors.New("Note delete did not delete one row. Id: " + id.String())
+	}
+	delete(db.notes, id)
+	delete(db.versions, id)
+	return nil
+}
+
+func (db *MemoryNotesdb) MarkNoteRead(id uuid.UUID, recipientId uuid.UUID, receipt *Receipt) error {
+	return db.update(id, func(note *Note) error {
+		recipient := note.recipient(recipientId)
+		if recipient == nil {
+			return errors.New("Update failed to update exactly one row. Id: " + id.String())
+		}
+		recipient.Read = true
+		note.setReceipt(recipientId, receipt)
+		return nil
+	})
+}
+
+func (db *MemoryNotesdb) MarkNoteDeleted(id uuid.UUID) error {
+	return db.update(id, func(note *Note) error {
+		note.deleted = true
+		return nil
+	})
+}
+
+func (db *MemoryNotesdb) MarkNoteDeletedFor(id uuid.UUID, recipientId uuid.UUID) error {
+	return db.updateRecipient(id, recipientId, func(recipient *Recipient) { recipient.Deleted = true })
+}
+
+func (db *MemoryNotesdb) RemoveRecipient(id uuid.UUID, recipientId uuid.UUID) error {
+	return db.update(id, func(note *Note) error {
+		if !note.HasRecipient(recipientId) {
+			return errors.New("Remove recipient failed to delete exactly one row. Id: " + id.String())
+		}
+		*note = *note.WithoutRecipient(recipientId)
+		return nil
+	})
+}
+
+func (db *MemoryNotesdb) AddReader(id uuid.UUID, readerId uuid.UUID, receipt *Receipt) error {
+	return db.update(id, func(note *Note) error {
+		if !note.ReadBy(readerId) {
+			note.readers = append(note.readers, readerId)
+			note.setReceipt(readerId, receipt)
+		}
+		return nil
+	})
+}
+
+func (db *MemoryNotesdb) RemoveReader(id uuid.UUID, readerId uuid.UUID) error {
+	return db.update(id, func(note *Note) error {
+		if note.HasRecipient(readerId) || !note.ReadBy(readerId) {
+			return errors.New("Remove reader failed to delete exactly one row. Id: " + id.String())
+		}
+		*note = *note.WithoutRecipient(readerId)
+		return nil
+	})
+}
+
+func (db *MemoryNotesdb) GetNotesBySender(
+	senderId uuid.UUID,
+	count int,
+	offset int) ([]*Note, error) {
+	return db.newestFirst(func(note *Note) bool { return note.sender == senderId }, count, offset), nil
+}
+
+func (db *MemoryNotesdb) GetNotesByRecipient(
+	recipientId uuid.UUID,
+	excludeSenders []uuid.UUID,
+	count int,
+	offset int) ([]*Note, error) {
+	excluded := make(map[uuid.UUID]bool)
+	for _, sender := range excludeSenders {
+		excluded[sender] = true
+	}
+	return db.newestFirst(func(note *Note) bool {
+		return note.HasRecipient(recipientId) && !excluded[note.sender]
+	}, count, offset), nil
+}
+
+func (db *MemoryNotesdb) CountNotesByRecipient(
+	recipientId uuid.UUID,
+	excludeSenders []uuid.UUID) ([]*SenderCount, error) {
+	excluded := make(map[uuid.UUID]bool)
+	for _, sender := range excludeSenders {
+		excluded[sender] = true
+	}
+	notes := db.matching(func(note *Note) bool {
+		return note.HasRecipient(recipientId) && !note.DeletedFor(recipientId) && !excluded[note.sender]
+	})
+
+	bySender := make(map[uuid.UUID]*SenderCount)
+	var counts []*SenderCount
+	for _, note := range notes {
+		count, ok := bySender[note.sender]
+		if !ok {
+			count = &SenderCount{Sender: note.sender}
+			bySender[note.sender] = count
+			counts = append(counts, count)
+		}
+		count.Total++
+		if !note.ReadBy(recipientId) {
+			count.Unread++
+		}
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Unread != counts[j].Unread {
+			return counts[i].Unread > counts[j].Unread
+		}
+		if counts[i].Total != counts[j].Total {
+			return counts[i].Total > counts[j].Total
+		}
+		return counts[i].Sender.String() < counts[j].Sender.String()
+	})
+	return counts, nil
+}
+
+func (db *MemoryNotesdb) GetNotesReadBy(readerId uuid.UUID, count int, offset int) ([]*Note, error) {
+	return db.newestFirst(func(note *Note) bool {
+		return note.Shared() && note.ReadBy(readerId)
+	}, count, offset), nil
+}
+
+func (db *MemoryNotesdb) GetNotesByIds(ids []uuid.UUID) ([]*Note, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var notes []*Note
+	for _, id := range ids {
+		note, ok := db.notes[id]
+		if !ok {
+			notes = append(notes, nil)
+			continue
+		}
+		note = copyNote(&note)
+		notes = append(notes, &note)
+	}
+	return notes, nil
+}
+
+func (db *MemoryNotesdb) GetNotesAfterId(afterId uuid.UUID, count int) ([]*Note, error) {
+	notes := db.matching(func(note *Note) bool {
+		return note.id.String() > afterId.String()
+	})
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].id.String() < notes[j].id.String()
+	})
+	return page(notes, count, 0), nil
+}
+
+func (db *MemoryNotesdb) GetThread(threadId uuid.UUID, count int, offset int) ([]*Note, error) {
+	notes := db.matching(func(note *Note) bool { return note.threadId == threadId })
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].timeSent.Equal(notes[j].timeSent) {
+			return notes[i].id.String() < notes[j].id.String()
+		}
+		return notes[i].timeSent.Before(notes[j].timeSent)
+	})
+	return page(notes, count, offset), nil
+}
+
+func (db *MemoryNotesdb) EditNote(id uuid.UUID, text string, timeEdited time.Time) error {
+	return db.update(id, func(note *Note) error {
+		if note.ReadByAnyone() {
+			return ErrNoteRead
+		}
+		writtenAt := note.timeSent
+		if note.Edited() {
+			writtenAt = note.timeEdited
+		}
+		db.versions[id] = append(db.versions[id], Version{Text: note.note, WrittenAt: writtenAt, ReplacedAt: timeEdited})
+		note.note = text
+		note.timeEdited = timeEdited
+		return nil
+	})
+}
+
+func (db *MemoryNotesdb) GetNoteVersions(id uuid.UUID) ([]*Version, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var versions []*Version
+	for _, version := range db.versions[id] {
+		version := version
+		versions = append(versions, &version)
+	}
+	return versions, nil
+}
+
+func (db *MemoryNotesdb) update(id uuid.UUID, apply func(note *Note) error) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	note, ok := db.notes[id]
+	if !ok {
+		return errors.New("Update failed to update exactly one row. Id: " + id.String())
+	}
+	note = copyNote(&note)
+	if err := apply(&note); err != nil {
+		return err
+	}
+	db.notes[id] = note
+	return nil
+}
+
+func (db *MemoryNotesdb) updateRecipient(
+	id uuid.UUID,
+	recipientId uuid.UUID,
+	apply func(recipient *Recipient)) error {
+	return db.update(id, func(note *Note) error {
+		recipient := note.recipient(recipientId)
+		if recipient == nil {
+			return errors.New("Update failed to update exactly one row. Id: " + id.String())
+		}
+		apply(recipient)
+		return nil
+	})
+}
+
+// matching returns copies of every note for which keep returns true, so
+// callers can't modify the stored notes behind the store's back.
+func (db *MemoryNotesdb) matching(keep func(note *Note) bool) []*Note {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var notes []*Note
+	for _, note := range db.notes {
+		note := copyNote(&note)
+		if keep(&note) {
+			notes = append(notes, &note)
+		}
+	}
+	return notes
+}
+
+func (db *MemoryNotesdb) newestFirst(keep func(note *Note) bool, count int, offset int) []*Note {
+	notes := db.matching(keep)
+	// Ties are broken by id, as in MySQL, so that pages don't overlap.
+	sort.Slice(notes, func(i, j int) bool {
+		if notes[i].timeSent.Equal(notes[j].timeSent) {
+			return notes[i].id.String() > notes[j].id.String()
+		}
+		return notes[i].timeSent.After(notes[j].timeSent)
+	})
+	return page(notes, count, offset)
+}
+
+func page(notes []*Note, count int, offset int) []*Note {
+	if offset >= len(notes) {
+		return nil
+	}
+	notes = notes[offset:]
+	if count < len(notes) {
+		notes = notes[:count]
+	}
+	return notes
+}
+
+// copyNote copies the note along with its recipients, readers and
+// receipts, which a plain struct copy would share.
+func copyNote(note *Note) Note {
+	result := *note
+	result.recipients = note.Recipients()
+	result.readers = note.Readers()
+	result.receipts = note.Receipts()
+	return result
+}
+
+// setReceipt replaces the user's receipt with receipt, or drops it if
+// receipt is nil, as MySQL's update of the row does.
+func (note *Note) setReceipt(userId uuid.UUID, receipt *Receipt) {
+	var kept []Receipt
+	for _, existing := range note.receipts {
+		if existing.Reader != userId {
+			kept = append(kept, existing)
+		}
+	}
+	if receipt != nil {
+		receipt := *receipt
+		receipt.Reader = userId
+		kept = append(kept, receipt)
+	}
+	note.receipts = kept
+}
@@ -0,0 +1,21 @@
+-- Notes can be addressed to several users at once. Each recipient has a
+-- row here with their own read and deleted state; notes.isdeleted is kept
+-- for the sender deleting a note for everyone. position keeps the
+-- recipients in the order the sender gave them.
+
+CREATE TABLE note_recipients (
+	note_id CHAR(36) NOT NULL,
+	recipient CHAR(36) NOT NULL,
+	position SMALLINT NOT NULL,
+	isread BOOLEAN NOT NULL,
+	isdeleted BOOLEAN NOT NULL,
+	PRIMARY KEY (note_id, recipient),
+	KEY note_recipients_recipient (recipient, note_id)
+);
+
+INSERT INTO note_recipients (note_id, recipient, position, isread, isdeleted)
+	SELECT id, recipient, 0, isread, isdeleted FROM notes;
+
+ALTER TABLE notes
+	DROP COLUMN recipient,
+	DROP COLUMN isread;
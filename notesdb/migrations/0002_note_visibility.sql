@@ -0,0 +1,13 @@
+-- Notes can be shared with all of the sender's contacts, or made public,
+-- instead of being left for particular recipients. Shared notes have no
+-- note_recipients rows; each user who reads one gets a row in note_reads.
+
+ALTER TABLE notes
+	ADD COLUMN visibility VARCHAR(16) NOT NULL DEFAULT 'private' AFTER sender;
+
+CREATE TABLE note_reads (
+	note_id CHAR(36) NOT NULL,
+	reader CHAR(36) NOT NULL,
+	PRIMARY KEY (note_id, reader),
+	KEY note_reads_reader (reader, note_id)
+);
@@ -0,0 +1,15 @@
+-- Notes can answer one another. parent_id is the note a reply answers,
+-- and thread_id the note that started the conversation, which is a
+-- note's own id unless it's a reply. isdirect marks replies delivered
+-- straight to their recipients rather than left at a spot.
+
+ALTER TABLE notes
+	ADD COLUMN parent_id CHAR(36) NULL AFTER id,
+	ADD COLUMN thread_id CHAR(36) NOT NULL DEFAULT '' AFTER parent_id,
+	ADD COLUMN isdirect BOOLEAN NOT NULL DEFAULT 0 AFTER thread_id;
+
+UPDATE notes SET thread_id = id;
+
+ALTER TABLE notes
+	ALTER COLUMN thread_id DROP DEFAULT,
+	ADD KEY notes_thread (thread_id, timesent, id);
@@ -0,0 +1,13 @@
+-- Read receipts: when each recipient, or reader of a shared note, read
+-- it, and where they were if they said. All three are NULL for readers
+-- who don't send receipts, and the location for those who gave none.
+
+ALTER TABLE note_recipients
+	ADD COLUMN readat DATETIME NULL,
+	ADD COLUMN readlatitude DOUBLE NULL,
+	ADD COLUMN readlongitude DOUBLE NULL;
+
+ALTER TABLE note_reads
+	ADD COLUMN readat DATETIME NULL,
+	ADD COLUMN readlatitude DOUBLE NULL,
+	ADD COLUMN readlongitude DOUBLE NULL;
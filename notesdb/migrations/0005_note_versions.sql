@@ -0,0 +1,16 @@
+-- Notes can be edited by their sender until anyone reads them. timeedited
+-- is when the text last changed, NULL if it never has, and note_versions
+-- keeps every earlier text, oldest first by id.
+
+ALTER TABLE notes
+	ADD COLUMN timeedited DATETIME NULL;
+
+CREATE TABLE note_versions (
+	id BIGINT NOT NULL AUTO_INCREMENT,
+	note_id CHAR(36) NOT NULL,
+	note TEXT NOT NULL,
+	writtenat DATETIME NOT NULL,
+	replacedat DATETIME NOT NULL,
+	PRIMARY KEY (id),
+	KEY note_versions_note_id (note_id, id)
+);
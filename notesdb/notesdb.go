@@ -6,21 +6,45 @@ import (
 	"database/sql"
 	"errors"
 	"strconv"
+	"strings"
 	
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/satori/go.uuid"
 )
 
+const (
+	// A note's visibility decides who can find it. Private notes are only
+	// for their recipients. Contacts notes are for every accepted contact
+	// of the sender's, and public notes for anyone; neither kind has
+	// recipients, and each user who reads one is added to its readers.
+	VISIBILITY_PRIVATE = "private"
+	VISIBILITY_CONTACTS = "contacts"
+	VISIBILITY_PUBLIC = "public"
+)
+
 type NotesdbConnection interface {
 	InsertNote(note *Note) error
 	PurgeNote(id uuid.UUID) error
-	MarkNoteRead(id uuid.UUID) error
+	MarkNoteRead(id uuid.UUID, recipientId uuid.UUID, receipt *Receipt) error
 	MarkNoteDeleted(id uuid.UUID) error
+	MarkNoteDeletedFor(id uuid.UUID, recipientId uuid.UUID) error
+	RemoveRecipient(id uuid.UUID, recipientId uuid.UUID) error
+	AddReader(id uuid.UUID, readerId uuid.UUID, receipt *Receipt) error
+	RemoveReader(id uuid.UUID, readerId uuid.UUID) error
 	GetNotesBySender(senderId uuid.UUID, count int, offset int) ([]*Note, error)
-	GetNotesByRecipient(recipientId uuid.UUID, count int, offset int) ([]*Note, error)
+	GetNotesByRecipient(recipientId uuid.UUID, excludeSenders []uuid.UUID, count int, offset int) ([]*Note, error)
+	CountNotesByRecipient(recipientId uuid.UUID, excludeSenders []uuid.UUID) ([]*SenderCount, error)
+	GetNotesReadBy(readerId uuid.UUID, count int, offset int) ([]*Note, error)
 	GetNotesByIds(ids []uuid.UUID) ([]*Note, error)
+	GetNotesAfterId(afterId uuid.UUID, count int) ([]*Note, error)
+	GetThread(threadId uuid.UUID, count int, offset int) ([]*Note, error)
+	EditNote(id uuid.UUID, text string, timeEdited time.Time) error
+	GetNoteVersions(id uuid.UUID) ([]*Version, error)
 }
 
+// ErrNoteRead is returned by EditNote once anyone has read the note.
+var ErrNoteRead = errors.New("The note has been read and can no longer be edited.")
+
 type MysqlNotesdb struct {
 	conn *sql.DB
 }
@@ -34,16 +58,361 @@ type DbCredentials struct {
 
 type Note struct {
 	id uuid.UUID
+	parentId uuid.UUID
+	threadId uuid.UUID
+	direct bool
 	sender uuid.UUID
-	recipient uuid.UUID
+	visibility string
+	recipients []Recipient
+	readers []uuid.UUID
+	receipts []Receipt
 	note string
 	latitude float64
 	longitude float64
 	timeSent time.Time
-	read bool
+	timeEdited time.Time
 	deleted bool
 }
 
+// Recipient is one of the users a note is addressed to. Each recipient
+// reads and deletes the note independently of the others.
+type Recipient struct {
+	Id uuid.UUID
+	Read bool
+	Deleted bool
+}
+
+// Receipt is when a recipient, or reader of a shared note, read it, and
+// where they were if they said. Readers who don't send read receipts
+// have none.
+type Receipt struct {
+	Reader uuid.UUID
+	ReadAt time.Time
+	Located bool
+	Latitude float64
+	Longitude float64
+}
+
+// Version is a note's text as it was before an edit replaced it.
+// WrittenAt is when the note was sent, or when the edit before that one
+// was made.
+type Version struct {
+	Text string
+	WrittenAt time.Time
+	ReplacedAt time.Time
+}
+
+// SenderCount is how many notes one sender has left for a recipient, and
+// how many of those the recipient hasn't read yet.
+type SenderCount struct {
+	Sender uuid.UUID
+	Unread int
+	Total int
+}
+
+// NewNote builds an unread, undeleted note with a freshly generated id.
+func NewNote(
+	sender uuid.UUID,
+	recipient uuid.UUID,
+	text string,
+	latitude float64,
+	longitude float64,
+	timeSent time.Time) *Note {
+	return NewGroupNote(sender, []uuid.UUID{recipient}, text, latitude, longitude, timeSent)
+}
+
+// NewGroupNote builds a note addressed to every one of recipients, in
+// order, with any repeats dropped.
+func NewGroupNote(
+	sender uuid.UUID,
+	recipients []uuid.UUID,
+	text string,
+	latitude float64,
+	longitude float64,
+	timeSent time.Time) *Note {
+	id := uuid.NewV4()
+	note := &Note{
+		id: id,
+		threadId: id,
+		sender: sender,
+		visibility: VISIBILITY_PRIVATE,
+		note: text,
+		latitude: latitude,
+		longitude: longitude,
+		timeSent: timeSent,
+		deleted: false,
+	}
+	for _, recipient := range recipients {
+		if !note.HasRecipient(recipient) {
+			note.recipients = append(note.recipients, Recipient{Id: recipient})
+		}
+	}
+	return note
+}
+
+// NewSharedNote builds a contacts or public note, which has no
+// recipients.
+func NewSharedNote(
+	sender uuid.UUID,
+	visibility string,
+	text string,
+	latitude float64,
+	longitude float64,
+	timeSent time.Time) *Note {
+	note := NewGroupNote(sender, nil, text, latitude, longitude, timeSent)
+	note.visibility = visibility
+	return note
+}
+
+// NewReply builds a private note answering parent, in parent's thread.
+// An anchored reply is left at the same spot as parent, ignoring latitude
+// and longitude; a direct one is left where given, but its recipients
+// find it nearby wherever they are. A reply is never timed before its
+// parent, since times are only kept to the second and threads are
+// ordered by them.
+func NewReply(
+	parent *Note,
+	sender uuid.UUID,
+	recipients []uuid.UUID,
+	text string,
+	latitude float64,
+	longitude float64,
+	direct bool,
+	timeSent time.Time) *Note {
+	if !direct {
+		latitude, longitude = parent.latitude, parent.longitude
+	}
+	if !timeSent.After(parent.timeSent) {
+		timeSent = parent.timeSent.Add(time.Second)
+	}
+	note := NewGroupNote(sender, recipients, text, latitude, longitude, timeSent)
+	note.parentId = parent.id
+	note.threadId = parent.threadId
+	note.direct = direct
+	return note
+}
+
+// ValidVisibility is whether visibility is one of the VISIBILITY_
+// constants.
+func ValidVisibility(visibility string) bool {
+	switch visibility {
+	case VISIBILITY_PRIVATE, VISIBILITY_CONTACTS, VISIBILITY_PUBLIC:
+		return true
+	}
+	return false
+}
+
+func (note *Note) Id() uuid.UUID {
+	return note.id
+}
+
+// ParentId is the note this one replies to, or uuid.Nil if it doesn't
+// reply to anything.
+func (note *Note) ParentId() uuid.UUID {
+	return note.parentId
+}
+
+// ThreadId is the id of the note that started the conversation this one
+// belongs to, which is its own id unless it's a reply.
+func (note *Note) ThreadId() uuid.UUID {
+	return note.threadId
+}
+
+func (note *Note) IsReply() bool {
+	return note.parentId != uuid.Nil
+}
+
+// Direct is whether the note is a reply delivered straight to its
+// recipients rather than left at a spot for them to find.
+func (note *Note) Direct() bool {
+	return note.direct
+}
+
+func (note *Note) Sender() uuid.UUID {
+	return note.sender
+}
+
+func (note *Note) Visibility() string {
+	return note.visibility
+}
+
+// Shared is whether the note is a contacts or public note rather than a
+// private one.
+func (note *Note) Shared() bool {
+	return note.visibility != VISIBILITY_PRIVATE
+}
+
+// Recipient returns the note's first recipient, which is its only one
+// unless it was left for several users.
+func (note *Note) Recipient() uuid.UUID {
+	if len(note.recipients) == 0 {
+		return uuid.Nil
+	}
+	return note.recipients[0].Id
+}
+
+func (note *Note) Recipients() []Recipient {
+	return append([]Recipient(nil), note.recipients...)
+}
+
+func (note *Note) RecipientIds() []uuid.UUID {
+	ids := make([]uuid.UUID, len(note.recipients))
+	for i, recipient := range note.recipients {
+		ids[i] = recipient.Id
+	}
+	return ids
+}
+
+func (note *Note) HasRecipient(id uuid.UUID) bool {
+	return note.recipient(id) != nil
+}
+
+// Participants returns the sender followed by the recipients, which is
+// everyone a reply to the note goes to apart from whoever is replying.
+func (note *Note) Participants() []uuid.UUID {
+	ids := []uuid.UUID{note.sender}
+	for _, id := range note.RecipientIds() {
+		if id != note.sender {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Readers returns the users who have read a shared note.
+func (note *Note) Readers() []uuid.UUID {
+	return append([]uuid.UUID(nil), note.readers...)
+}
+
+// Receipts returns the read receipts of the note's recipients or readers,
+// in no particular order.
+func (note *Note) Receipts() []Receipt {
+	return append([]Receipt(nil), note.receipts...)
+}
+
+// ReceiptFor returns the user's read receipt, or nil if they haven't read
+// the note or didn't send one.
+func (note *Note) ReceiptFor(userId uuid.UUID) *Receipt {
+	for i := range note.receipts {
+		if note.receipts[i].Reader == userId {
+			receipt := note.receipts[i]
+			return &receipt
+		}
+	}
+	return nil
+}
+
+func (note *Note) Text() string {
+	return note.note
+}
+
+func (note *Note) Latitude() float64 {
+	return note.latitude
+}
+
+func (note *Note) Longitude() float64 {
+	return note.longitude
+}
+
+func (note *Note) TimeSent() time.Time {
+	return note.timeSent
+}
+
+// TimeEdited is when the note's text was last changed, or the zero time
+// if it never has been.
+func (note *Note) TimeEdited() time.Time {
+	return note.timeEdited
+}
+
+func (note *Note) Edited() bool {
+	return !note.timeEdited.IsZero()
+}
+
+// Read is whether every recipient has read the note, or for a shared
+// note, whether anyone has.
+func (note *Note) Read() bool {
+	if note.Shared() {
+		return len(note.readers) > 0
+	}
+	for _, recipient := range note.recipients {
+		if !recipient.Read {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadByAnyone is whether any recipient, or anyone at all for a shared
+// note, has read it. Only notes no one has read can be edited.
+func (note *Note) ReadByAnyone() bool {
+	for _, recipient := range note.recipients {
+		if recipient.Read {
+			return true
+		}
+	}
+	return len(note.readers) > 0
+}
+
+// ReadBy is whether the given recipient, or reader of a shared note, has
+// read it.
+func (note *Note) ReadBy(userId uuid.UUID) bool {
+	if recipient := note.recipient(userId); recipient != nil {
+		return recipient.Read
+	}
+	for _, reader := range note.readers {
+		if reader == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// Deleted is whether the sender deleted the note, for every recipient.
+func (note *Note) Deleted() bool {
+	return note.deleted
+}
+
+// DeletedFor is whether the note is deleted as far as the given recipient
+// is concerned, either by them or for everyone by the sender.
+func (note *Note) DeletedFor(recipientId uuid.UUID) bool {
+	recipient := note.recipient(recipientId)
+	return note.deleted || (recipient != nil && recipient.Deleted)
+}
+
+// WithoutRecipient returns a copy of the note with the given user taken
+// off its recipients and readers, along with their read receipt.
+func (note *Note) WithoutRecipient(userId uuid.UUID) *Note {
+	result := *note
+	result.recipients = nil
+	for _, recipient := range note.recipients {
+		if recipient.Id != userId {
+			result.recipients = append(result.recipients, recipient)
+		}
+	}
+	result.readers = nil
+	for _, reader := range note.readers {
+		if reader != userId {
+			result.readers = append(result.readers, reader)
+		}
+	}
+	result.receipts = nil
+	for _, receipt := range note.receipts {
+		if receipt.Reader != userId {
+			result.receipts = append(result.receipts, receipt)
+		}
+	}
+	return &result
+}
+
+func (note *Note) recipient(id uuid.UUID) *Recipient {
+	for i := range note.recipients {
+		if note.recipients[i].Id == id {
+			return &note.recipients[i]
+		}
+	}
+	return nil
+}
+
 func NewMysqlNotesdb(credentials *DbCredentials) (*MysqlNotesdb, error) {
 	dsn := credentials.User + ":" + credentials.Password + "@tcp(" + 
 		credentials.Host + ":" + credentials.Port + ")/geonote?parseTime=true"
@@ -56,31 +425,82 @@ func NewMysqlNotesdb(credentials *DbCredentials) (*MysqlNotesdb, error) {
 	return &MysqlNotesdb{conn: db}, nil
 }
 
+// InsertNote writes the note, its recipients and its readers in one
+// transaction.
 func (db MysqlNotesdb) InsertNote(note *Note) error {
-	insertSql := "INSERT INTO notes " + 
-		" (id, sender, recipient, note, latitude, longitude, timesent, isread, isdeleted) VALUES " +
-		" (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	if err := validateNote(note); err != nil {
+		return err
+	}
 
-	statement, err := db.conn.Prepare(insertSql)
+	tx, err := db.conn.Begin()
 	if err != nil {
-		log.Printf("Failed to prepare statement %v. Err: %v", insertSql, err)
+		log.Printf("Failed to begin transaction to insert note. Err: %v", err)
 		return err
 	}
-	defer statement.Close()
+	defer tx.Rollback()
 
-	_, err = statement.Exec(
+	var parentId interface{}
+	if note.IsReply() {
+		parentId = note.parentId.String()
+	}
+	var timeEdited interface{}
+	if note.Edited() {
+		timeEdited = note.timeEdited
+	}
+	insertSql := "INSERT INTO notes " + 
+		" (id, parent_id, thread_id, isdirect, sender, visibility, note, latitude, longitude, timesent, timeedited, " +
+		" isdeleted) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = tx.Exec(
+		insertSql,
 		note.id.String(),
+		parentId,
+		note.threadId.String(),
+		note.direct,
 		note.sender.String(),
-		note.recipient.String(),
+		note.visibility,
 		note.note,
 		note.latitude,
 		note.longitude,
 		note.timeSent,
-		note.read,
+		timeEdited,
 		note.deleted,
 	)
 	if err != nil {
-		log.Printf("Failed to insert note. Err:", err)
+		log.Printf("Failed to insert note. Err: %v", err)
+		return err
+	}
+
+	if len(note.recipients) > 0 {
+		var args []interface{}
+		for position, recipient := range note.recipients {
+			args = append(args, note.id.String(), recipient.Id.String(), position, recipient.Read, recipient.Deleted)
+			args = append(args, receiptArgs(note.ReceiptFor(recipient.Id))...)
+		}
+		insertSql = "INSERT INTO note_recipients " +
+			" (note_id, recipient, position, isread, isdeleted, readat, readlatitude, readlongitude) VALUES " +
+			" (?, ?, ?, ?, ?, ?, ?, ?)" + strings.Repeat(", (?, ?, ?, ?, ?, ?, ?, ?)", len(note.recipients) - 1)
+		if _, err = tx.Exec(insertSql, args...); err != nil {
+			log.Printf("Failed to insert recipients of note %v. Err: %v", note.id, err)
+			return err
+		}
+	}
+
+	if len(note.readers) > 0 {
+		var args []interface{}
+		for _, reader := range note.readers {
+			args = append(args, note.id.String(), reader.String())
+			args = append(args, receiptArgs(note.ReceiptFor(reader))...)
+		}
+		insertSql = "INSERT INTO note_reads (note_id, reader, readat, readlatitude, readlongitude) VALUES " +
+			" (?, ?, ?, ?, ?)" + strings.Repeat(", (?, ?, ?, ?, ?)", len(note.readers) - 1)
+		if _, err = tx.Exec(insertSql, args...); err != nil {
+			log.Printf("Failed to insert readers of note %v. Err: %v", note.id, err)
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Failed to commit note %v. Err: %v", note.id, err)
 		return err
 	}
 
@@ -88,93 +508,222 @@ func (db MysqlNotesdb) InsertNote(note *Note) error {
 }
 
 func (db MysqlNotesdb) PurgeNote(id uuid.UUID) error {
-	deleteSql := "DELETE FROM notes where id = ?"
-	statement, err := db.conn.Prepare(deleteSql)
+	tx, err := db.conn.Begin()
 	if err != nil {
-		log.Printf("Failed to prepare statement %v. Err: %v", deleteSql, err)
+		log.Printf("Failed to begin transaction to purge note %v. Err: %v", id, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.Exec("DELETE FROM note_recipients WHERE note_id = ?", id.String()); err != nil {
+		log.Printf("Failed to delete recipients of note %v. Err: %v", id, err)
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM note_reads WHERE note_id = ?", id.String()); err != nil {
+		log.Printf("Failed to delete readers of note %v. Err: %v", id, err)
+		return err
+	}
+	if _, err = tx.Exec("DELETE FROM note_versions WHERE note_id = ?", id.String()); err != nil {
+		log.Printf("Failed to delete versions of note %v. Err: %v", id, err)
 		return err
 	}
-	defer statement.Close()
 
-	result, err := statement.Exec(id.String())
+	result, err := tx.Exec("DELETE FROM notes where id = ?", id.String())
 	if err != nil {
 		log.Printf("Delete statement failed with err %v", err)
 		return err
 	}
+	if err = requireOneRow(result, "Note delete stmt did not delete one row."); err != nil {
+		return err
+	}
 
-	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected != 1 {
-		if err != nil  {
-			log.Printf("Error getting rows affected: %v", err)
-			return err
-		}
-		if rowsAffected != 1 {
-			message := "Note delete stmt did not delete one row. Actual: " + string(rowsAffected)
-			log.Print(message)
-			return errors.New(message)
-		}
+	if err = tx.Commit(); err != nil {
+		log.Printf("Failed to commit purge of note %v. Err: %v", id, err)
+		return err
 	}
 
 	return nil
 }
 
-func (db MysqlNotesdb) MarkNoteRead(id uuid.UUID) error {
-	updateSql := "UPDATE notes SET isread = 1 where id = ?"
-	statement, err := db.conn.Prepare(updateSql)
+// MarkNoteRead marks the note read by one of its recipients, keeping
+// receipt unless it's nil.
+func (db MysqlNotesdb) MarkNoteRead(id uuid.UUID, recipientId uuid.UUID, receipt *Receipt) error {
+	args := append(receiptArgs(receipt), id.String(), recipientId.String())
+	return db.updateOne(
+		"UPDATE note_recipients SET isread = 1, readat = ?, readlatitude = ?, readlongitude = ? " +
+			"WHERE note_id = ? AND recipient = ?",
+		"Mark as read failed to update exactly one row.",
+		args...)
+}
+
+// MarkNoteDeleted deletes the note for every recipient, as its sender
+// does.
+func (db MysqlNotesdb) MarkNoteDeleted(id uuid.UUID) error {
+	return db.updateOne(
+		"UPDATE notes SET isdeleted = 1 where id = ?",
+		"Mark as deleted failed to update exactly one row.",
+		id.String())
+}
+
+// MarkNoteDeletedFor deletes the note for one of its recipients, leaving
+// it as it was for the rest.
+func (db MysqlNotesdb) MarkNoteDeletedFor(id uuid.UUID, recipientId uuid.UUID) error {
+	return db.updateOne(
+		"UPDATE note_recipients SET isdeleted = 1 WHERE note_id = ? AND recipient = ?",
+		"Mark as deleted failed to update exactly one row.",
+		id.String(), recipientId.String())
+}
+
+// RemoveRecipient takes a recipient off the note altogether, for when
+// their account is erased but the note's other recipients should keep it.
+func (db MysqlNotesdb) RemoveRecipient(id uuid.UUID, recipientId uuid.UUID) error {
+	return db.updateOne(
+		"DELETE FROM note_recipients WHERE note_id = ? AND recipient = ?",
+		"Remove recipient failed to delete exactly one row.",
+		id.String(), recipientId.String())
+}
+
+// AddReader records that a user has read a shared note, keeping receipt
+// unless it's nil. Adding the same reader again does nothing.
+func (db MysqlNotesdb) AddReader(id uuid.UUID, readerId uuid.UUID, receipt *Receipt) error {
+	args := append([]interface{}{id.String(), readerId.String()}, receiptArgs(receipt)...)
+	_, err := db.conn.Exec("INSERT IGNORE INTO note_reads (note_id, reader, readat, readlatitude, readlongitude) " +
+		"VALUES (?, ?, ?, ?, ?)", args...)
 	if err != nil {
-		log.Printf("Failed to prepare statement to mark note with id %v as read. Err: %v", id, err)
-			return err
+		log.Printf("Failed to add reader %v to note %v. Err: %v", readerId, id, err)
+		return err
 	}
-	defer statement.Close()
+	return nil
+}
 
-	result, err := statement.Exec(id.String())
+// RemoveReader takes a reader off a shared note, for when their account
+// is erased.
+func (db MysqlNotesdb) RemoveReader(id uuid.UUID, readerId uuid.UUID) error {
+	return db.updateOne(
+		"DELETE FROM note_reads WHERE note_id = ? AND reader = ?",
+		"Remove reader failed to delete exactly one row.",
+		id.String(), readerId.String())
+}
+
+// EditNote replaces the note's text, keeping the old text as a version.
+// The note's recipients and readers are locked while it's checked, so
+// that no one can read it between the check and the edit; if anyone has
+// read it already, it returns ErrNoteRead.
+func (db MysqlNotesdb) EditNote(id uuid.UUID, text string, timeEdited time.Time) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction to edit note %v. Err: %v", id, err)
+		return err
+	}
+	defer tx.Rollback()
+
+	var oldText string
+	var timeSent time.Time
+	var lastEdited sql.NullTime
+	err = tx.QueryRow("SELECT note, timesent, timeedited FROM notes WHERE id = ? FOR UPDATE", id.String()).
+		Scan(&oldText, &timeSent, &lastEdited)
 	if err != nil {
-		log.Printf("Update statement for note id %v failed with err: %v", id, err)
+		log.Printf("Failed to fetch note %v to edit. Err: %v", id, err)
 		return err
 	}
 
-	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected != 1 {
+	var read int
+	err = tx.QueryRow("SELECT COUNT(*) FROM note_recipients WHERE note_id = ? AND isread = 1 FOR UPDATE",
+		id.String()).Scan(&read)
+	if err != nil {
+		log.Printf("Failed to check recipients of note %v. Err: %v", id, err)
+		return err
+	}
+	if read == 0 {
+		err = tx.QueryRow("SELECT COUNT(*) FROM note_reads WHERE note_id = ? FOR UPDATE", id.String()).Scan(&read)
 		if err != nil {
-			log.Printf("Error getting rows affected: %v", err)
+			log.Printf("Failed to check readers of note %v. Err: %v", id, err)
 			return err
 		}
-		if rowsAffected != 1 {
-			message := "Mark as read failed to update exactly one row. Actual: " + string(rowsAffected)
-			log.Print(message)
-			return errors.New(message)
-		}
+	}
+	if read > 0 {
+		return ErrNoteRead
+	}
+
+	writtenAt := timeSent
+	if lastEdited.Valid {
+		writtenAt = lastEdited.Time
+	}
+	_, err = tx.Exec("INSERT INTO note_versions (note_id, note, writtenat, replacedat) VALUES (?, ?, ?, ?)",
+		id.String(), oldText, writtenAt, timeEdited)
+	if err != nil {
+		log.Printf("Failed to keep old version of note %v. Err: %v", id, err)
+		return err
 	}
 
+	result, err := tx.Exec("UPDATE notes SET note = ?, timeedited = ? WHERE id = ?", text, timeEdited, id.String())
+	if err != nil {
+		log.Printf("Failed to edit note %v. Err: %v", id, err)
+		return err
+	}
+	if err = requireOneRow(result, "Edit failed to update exactly one row."); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		log.Printf("Failed to commit edit of note %v. Err: %v", id, err)
+		return err
+	}
 	return nil
 }
 
-func (db MysqlNotesdb) MarkNoteDeleted(id uuid.UUID) error {
-	updateSql := "UPDATE notes SET isdeleted = 1 where id = ?"
+// GetNoteVersions returns the note's earlier texts, oldest first. It's
+// empty for a note that's never been edited.
+func (db MysqlNotesdb) GetNoteVersions(id uuid.UUID) ([]*Version, error) {
+	rows, err := db.conn.Query("SELECT note, writtenat, replacedat FROM note_versions " +
+		"WHERE note_id = ? ORDER BY id", id.String())
+	if err != nil {
+		log.Printf("Failed to query versions of note %v. Err: %v", id, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []*Version
+	for rows.Next() {
+		var version Version
+		if err = rows.Scan(&version.Text, &version.WrittenAt, &version.ReplacedAt); err != nil {
+			log.Printf("Failed to scan note version. Err: %v", err)
+			return nil, err
+		}
+		versions = append(versions, &version)
+	}
+
+	return versions, rows.Err()
+}
+
+func (db MysqlNotesdb) updateOne(updateSql string, message string, args ...interface{}) error {
 	statement, err := db.conn.Prepare(updateSql)
 	if err != nil {
-		log.Printf("Failed to prepare statement to mark note with id %v as deleted. Err: %v", id, err)
-			return err
+		log.Printf("Failed to prepare statement %v. Err: %v", updateSql, err)
+		return err
 	}
 	defer statement.Close()
 
-	result, err := statement.Exec(id.String())
+	result, err := statement.Exec(args...)
 	if err != nil {
-		log.Printf("Update statement for note id %v failed with err: %v", id, err)
+		log.Printf("Update statement %v failed for %v with err: %v", updateSql, args, err)
 		return err
 	}
 
-	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected != 1 {
-		if err != nil {
-			log.Printf("Error getting rows affected: %v", err)
-			return err
-		}
-		if rowsAffected != 1 {
-			message := "Mark as deleted failed to update exactly one row. Actual: " + 
-				strconv.FormatInt(rowsAffected, 10)
-			log.Print(message)
-			return errors.New(message)
-		}
-	}
+	return requireOneRow(result, message)
+}
 
+func requireOneRow(result sql.Result, message string) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error getting rows affected: %v", err)
+		return err
+	}
+	if rowsAffected != 1 {
+		message += " Actual: " + strconv.FormatInt(rowsAffected, 10)
+		log.Print(message)
+		return errors.New(message)
+	}
 	return nil
 }
 
@@ -183,11 +732,11 @@ func (db MysqlNotesdb) GetNotesBySender(
 	count int,
 	offset int) ([]*Note, error) {
 	selectSql := "SELECT " +
-		"id, sender, recipient, note, latitude, longitude, " +
-		"timesent, isread, isdeleted " +
+		"id, parent_id, thread_id, isdirect, sender, visibility, note, latitude, longitude, " +
+		"timesent, timeedited, isdeleted " +
 		"FROM notes " +
 		"WHERE sender = ? " +
-		"ORDER BY timesent DESC " +
+		"ORDER BY timesent DESC, id DESC " +
 		"LIMIT ? OFFSET ?"
 	statement, err := db.conn.Prepare(selectSql)
 	if err != nil {
@@ -199,6 +748,10 @@ func (db MysqlNotesdb) GetNotesBySender(
 
 	var notes []*Note
 	rows, err := statement.Query(senderId.String(), count, offset)
+	if err != nil {
+		log.Printf("Failed to query notes from sender %v. Err: %v", senderId, err)
+		return nil, err
+	}
 	defer rows.Close()
 	for rows.Next() {
 		note, err := noteFromRow(rows)
@@ -208,19 +761,36 @@ func (db MysqlNotesdb) GetNotesBySender(
 		notes = append(notes, note)
 	}
 
-	return notes, nil
+	return notes, db.loadDetails(notes)
 }
 
+// GetNotesByRecipient returns a page of the notes addressed to
+// recipientId, alone or among others, newest first, leaving out any sent
+// by excludeSenders.
 func (db MysqlNotesdb) GetNotesByRecipient(
 	recipientId uuid.UUID,
+	excludeSenders []uuid.UUID,
 	count int,
 	offset int) ([]*Note, error) {
+	args := []interface{}{recipientId.String()}
+	excludeSql := ""
+	if len(excludeSenders) > 0 {
+		excludeSql = "AND notes.sender NOT IN (?" + strings.Repeat(", ?", len(excludeSenders) - 1) + ") "
+		for _, sender := range excludeSenders {
+			args = append(args, sender.String())
+		}
+	}
+	args = append(args, count, offset)
+
 	selectSql := "SELECT " +
-		"id, sender, recipient, note, latitude, longitude, " +
-		"timesent, isread, isdeleted " +
+		"notes.id, notes.parent_id, notes.thread_id, notes.isdirect, notes.sender, notes.visibility, " +
+		"notes.note, notes.latitude, notes.longitude, " +
+		"notes.timesent, notes.timeedited, notes.isdeleted " +
 		"FROM notes " +
-		"WHERE recipient = ? " +
-		"ORDER BY timesent DESC " +
+		"JOIN note_recipients ON note_recipients.note_id = notes.id " +
+		"WHERE note_recipients.recipient = ? " +
+		excludeSql +
+		"ORDER BY notes.timesent DESC, notes.id DESC " +
 		"LIMIT ? OFFSET ?"
 	statement, err := db.conn.Prepare(selectSql)
 	if err != nil {
@@ -231,7 +801,11 @@ func (db MysqlNotesdb) GetNotesByRecipient(
 	defer statement.Close()
 
 	var notes []*Note
-	rows, err := statement.Query(recipientId.String(), count, offset)
+	rows, err := statement.Query(args...)
+	if err != nil {
+		log.Printf("Failed to query notes for recipient %v. Err: %v", recipientId, err)
+		return nil, err
+	}
 	defer rows.Close()
 	for rows.Next() {
 		note, err := noteFromRow(rows)
@@ -241,7 +815,92 @@ func (db MysqlNotesdb) GetNotesByRecipient(
 		notes = append(notes, note)
 	}
 
-	return notes, nil
+	return notes, db.loadDetails(notes)
+}
+
+// CountNotesByRecipient counts the notes addressed to recipientId, alone
+// or among others, for each sender, leaving out any sent by excludeSenders
+// and any deleted for the recipient. Senders with the most unread notes
+// come first. It's answered from note_recipients' recipient index without
+// loading any notes, so it's cheap enough to show a badge with.
+func (db MysqlNotesdb) CountNotesByRecipient(
+	recipientId uuid.UUID,
+	excludeSenders []uuid.UUID) ([]*SenderCount, error) {
+	args := []interface{}{recipientId.String()}
+	excludeSql := ""
+	if len(excludeSenders) > 0 {
+		excludeSql = "AND notes.sender NOT IN (?" + strings.Repeat(", ?", len(excludeSenders) - 1) + ") "
+		for _, sender := range excludeSenders {
+			args = append(args, sender.String())
+		}
+	}
+
+	selectSql := "SELECT " +
+		"notes.sender, SUM(NOT note_recipients.isread) AS unread, COUNT(*) AS total " +
+		"FROM note_recipients " +
+		"JOIN notes ON notes.id = note_recipients.note_id " +
+		"WHERE note_recipients.recipient = ? " +
+		"AND NOT note_recipients.isdeleted AND NOT notes.isdeleted " +
+		excludeSql +
+		"GROUP BY notes.sender " +
+		"ORDER BY unread DESC, total DESC, notes.sender"
+	rows, err := db.conn.Query(selectSql, args...)
+	if err != nil {
+		log.Printf("Failed to count notes for recipient %v. Err: %v", recipientId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []*SenderCount
+	for rows.Next() {
+		var sender string
+		var count SenderCount
+		if err = rows.Scan(&sender, &count.Unread, &count.Total); err != nil {
+			log.Printf("Failed to scan note count. Err: %v", err)
+			return nil, err
+		}
+		if count.Sender, err = uuid.FromString(sender); err != nil {
+			log.Printf("Failed to parse sender %v. Err: %v", sender, err)
+			return nil, err
+		}
+		counts = append(counts, &count)
+	}
+
+	return counts, rows.Err()
+}
+
+// GetNotesReadBy returns a page of the shared notes readerId has read,
+// newest first.
+func (db MysqlNotesdb) GetNotesReadBy(readerId uuid.UUID, count int, offset int) ([]*Note, error) {
+	selectSql := "SELECT " +
+		"notes.id, notes.parent_id, notes.thread_id, notes.isdirect, notes.sender, notes.visibility, " +
+		"notes.note, notes.latitude, notes.longitude, " +
+		"notes.timesent, notes.timeedited, notes.isdeleted " +
+		"FROM notes " +
+		"JOIN note_reads ON note_reads.note_id = notes.id " +
+		"WHERE note_reads.reader = ? " +
+		"ORDER BY notes.timesent DESC, notes.id DESC " +
+		"LIMIT ? OFFSET ?"
+	rows, err := db.conn.Query(selectSql, readerId.String(), count, offset)
+	if err != nil {
+		log.Printf("Failed to query notes read by %v. Err: %v", readerId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*Note
+	for rows.Next() {
+		note, err := noteFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, db.loadDetails(notes)
 }
 
 func (db MysqlNotesdb) GetNotesByIds(ids []uuid.UUID) ([]*Note, error) {
@@ -258,12 +917,86 @@ func (db MysqlNotesdb) GetNotesByIds(ids []uuid.UUID) ([]*Note, error) {
 	return notes, nil
 }
 
+// GetNotesAfterId returns up to count notes whose ids sort after afterId,
+// ordered by id. Pass uuid.Nil to start from the beginning of the table.
+// Paging on the primary key rather than OFFSET keeps every batch equally
+// cheap, which matters when walking the whole table.
+func (db MysqlNotesdb) GetNotesAfterId(afterId uuid.UUID, count int) ([]*Note, error) {
+	selectSql := "SELECT " +
+		"id, parent_id, thread_id, isdirect, sender, visibility, note, latitude, longitude, " +
+		"timesent, timeedited, isdeleted " +
+		"FROM notes " +
+		"WHERE id > ? " +
+		"ORDER BY id " +
+		"LIMIT ?"
+	statement, err := db.conn.Prepare(selectSql)
+	if err != nil {
+		log.Printf("Failed to prepare statement to select notes after id %v. Err: %v",
+			afterId, err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(afterId.String(), count)
+	if err != nil {
+		log.Printf("Failed to query notes after id %v. Err: %v", afterId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*Note
+	for rows.Next() {
+		note, err := noteFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, db.loadDetails(notes)
+}
+
+// GetThread returns a page of the notes in a conversation, oldest first,
+// starting with the note that began it.
+func (db MysqlNotesdb) GetThread(threadId uuid.UUID, count int, offset int) ([]*Note, error) {
+	selectSql := "SELECT " +
+		"id, parent_id, thread_id, isdirect, sender, visibility, note, latitude, longitude, " +
+		"timesent, timeedited, isdeleted " +
+		"FROM notes " +
+		"WHERE thread_id = ? " +
+		"ORDER BY timesent, id " +
+		"LIMIT ? OFFSET ?"
+	rows, err := db.conn.Query(selectSql, threadId.String(), count, offset)
+	if err != nil {
+		log.Printf("Failed to query thread %v. Err: %v", threadId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []*Note
+	for rows.Next() {
+		note, err := noteFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return notes, db.loadDetails(notes)
+}
+
 func (db MysqlNotesdb) GetNoteById(id uuid.UUID) (*Note, error) {
 	var note *Note
 
 	selectSql := "SELECT " +
-		"id, sender, recipient, note, latitude, longitude, " +
-		"timesent, isread, isdeleted " +
+		"id, parent_id, thread_id, isdirect, sender, visibility, note, latitude, longitude, " +
+		"timesent, timeedited, isdeleted " +
 		"FROM notes " +
 		"WHERE id = ?"
 
@@ -287,23 +1020,175 @@ func (db MysqlNotesdb) GetNoteById(id uuid.UUID) (*Note, error) {
 		if err != nil {
 			panic(err.Error())
 		}
+		if err = db.loadDetails([]*Note{note}); err != nil {
+			return nil, err
+		}
 	}
 
 	return note, nil
 }
 
+// loadDetails fills in the recipients and readers of notes.
+func (db MysqlNotesdb) loadDetails(notes []*Note) error {
+	if err := db.loadRecipients(notes); err != nil {
+		return err
+	}
+	return db.loadReaders(notes)
+}
+
+// loadRecipients fills in the recipients of notes with one query.
+func (db MysqlNotesdb) loadRecipients(notes []*Note) error {
+	if len(notes) == 0 {
+		return nil
+	}
+
+	byId := make(map[uuid.UUID]*Note)
+	args := make([]interface{}, len(notes))
+	for i, note := range notes {
+		byId[note.id] = note
+		args[i] = note.id.String()
+	}
+
+	selectSql := "SELECT note_id, recipient, isread, isdeleted, readat, readlatitude, readlongitude " +
+		"FROM note_recipients " +
+		"WHERE note_id IN (?" + strings.Repeat(", ?", len(notes) - 1) + ") " +
+		"ORDER BY note_id, position"
+	rows, err := db.conn.Query(selectSql, args...)
+	if err != nil {
+		log.Printf("Failed to query note recipients. Err: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var noteId uuid.UUID
+		var recipient Recipient
+		var readAt sql.NullTime
+		var latitude, longitude sql.NullFloat64
+		err = rows.Scan(&noteId, &recipient.Id, &recipient.Read, &recipient.Deleted, &readAt, &latitude, &longitude)
+		if err != nil {
+			log.Printf("Failed to scan note recipient. Err: %v", err)
+			return err
+		}
+		if note, ok := byId[noteId]; ok {
+			note.recipients = append(note.recipients, recipient)
+			note.addReceipt(recipient.Id, readAt, latitude, longitude)
+		}
+	}
+
+	return rows.Err()
+}
+
+// loadReaders fills in the readers of whichever of notes are shared with
+// one query.
+func (db MysqlNotesdb) loadReaders(notes []*Note) error {
+	byId := make(map[uuid.UUID]*Note)
+	var args []interface{}
+	for _, note := range notes {
+		if note.Shared() {
+			byId[note.id] = note
+			args = append(args, note.id.String())
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+
+	selectSql := "SELECT note_id, reader, readat, readlatitude, readlongitude FROM note_reads " +
+		"WHERE note_id IN (?" + strings.Repeat(", ?", len(args) - 1) + ")"
+	rows, err := db.conn.Query(selectSql, args...)
+	if err != nil {
+		log.Printf("Failed to query note readers. Err: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var noteId, reader uuid.UUID
+		var readAt sql.NullTime
+		var latitude, longitude sql.NullFloat64
+		if err = rows.Scan(&noteId, &reader, &readAt, &latitude, &longitude); err != nil {
+			log.Printf("Failed to scan note reader. Err: %v", err)
+			return err
+		}
+		if note, ok := byId[noteId]; ok {
+			note.readers = append(note.readers, reader)
+			note.addReceipt(reader, readAt, latitude, longitude)
+		}
+	}
+
+	return rows.Err()
+}
+
+// addReceipt adds reader's receipt from a row of note_recipients or
+// note_reads, if there is one.
+func (note *Note) addReceipt(
+	reader uuid.UUID,
+	readAt sql.NullTime,
+	latitude sql.NullFloat64,
+	longitude sql.NullFloat64) {
+	if !readAt.Valid {
+		return
+	}
+	note.receipts = append(note.receipts, Receipt{
+		Reader: reader,
+		ReadAt: readAt.Time,
+		Located: latitude.Valid && longitude.Valid,
+		Latitude: latitude.Float64,
+		Longitude: longitude.Float64,
+	})
+}
+
+// receiptArgs returns the readat, readlatitude and readlongitude column
+// values for receipt, which are NULL for a missing receipt or location.
+func receiptArgs(receipt *Receipt) []interface{} {
+	if receipt == nil {
+		return []interface{}{nil, nil, nil}
+	}
+	if !receipt.Located {
+		return []interface{}{receipt.ReadAt, nil, nil}
+	}
+	return []interface{}{receipt.ReadAt, receipt.Latitude, receipt.Longitude}
+}
+
+// validateNote checks that a private note has recipients and a shared one
+// has none, and that only replies, which are always private, are direct.
+func validateNote(note *Note) error {
+	if !ValidVisibility(note.visibility) {
+		return errors.New("Unknown note visibility: " + note.visibility)
+	}
+	if !note.Shared() && len(note.recipients) == 0 {
+		return errors.New("A note needs at least one recipient.")
+	}
+	if note.Shared() && len(note.recipients) > 0 {
+		return errors.New("Shared notes can't have recipients.")
+	}
+	if note.IsReply() && note.Shared() {
+		return errors.New("Replies can't be shared.")
+	}
+	if note.direct && !note.IsReply() {
+		return errors.New("Only replies can be direct.")
+	}
+	return nil
+}
+
 func noteFromRow(rows *sql.Rows) (*Note, error) {
 	var note Note
+	var parentId uuid.NullUUID
+	var timeEdited sql.NullTime
 	
 	err := rows.Scan(
 		&note.id, 
+		&parentId,
+		&note.threadId,
+		&note.direct,
 		&note.sender,
-		&note.recipient, 
+		&note.visibility,
 		&note.note, 
 		&note.latitude,
 		&note.longitude,
 		&note.timeSent,
-		&note.read,
+		&timeEdited,
 		&note.deleted,
 	)
 
@@ -311,6 +1196,8 @@ func noteFromRow(rows *sql.Rows) (*Note, error) {
 		log.Printf("Failed to scan row. err: %v", err)
 		return nil, err
 	}
+	note.parentId = parentId.UUID
+	note.timeEdited = timeEdited.Time
 
 	return &note, err
 }
@@ -54,7 +54,13 @@ func TestMarkNoteRead(t *testing.T) {
 	}
 	defer db.PurgeNote(note.id)
 
-	if err = db.MarkNoteRead(note.id); err != nil {
+	receipt := &Receipt{
+		ReadAt: time.Date(2009, time.November, 11, 9, 30, 0, 0, time.UTC),
+		Located: true,
+		Latitude: 42.3,
+		Longitude: 24.5,
+	}
+	if err = db.MarkNoteRead(note.id, recipient, receipt); err != nil {
 		t.Fatal()
 	}
 
@@ -63,9 +69,14 @@ func TestMarkNoteRead(t *testing.T) {
 		t.Fatal("Failed to fetch note with id:", note.id, ", err: ", err)
 	}
 
-	if !resultNotes[0].read {
+	if !resultNotes[0].ReadBy(recipient) || !resultNotes[0].Read() {
 		t.Fatal("Failed to actually mark note read.")
 	}
+	result := resultNotes[0].ReceiptFor(recipient)
+	if result == nil || result.Reader != recipient || !result.ReadAt.Equal(receipt.ReadAt) ||
+		!result.Located || result.Latitude != 42.3 || result.Longitude != 24.5 {
+		t.Fatal("Expected the read receipt to be kept, got: ", result)
+	}
 }
 
 func TestMarkNoteDeleted(t *testing.T) {
@@ -103,6 +114,211 @@ func TestMarkNoteDeleted(t *testing.T) {
 	}
 }
 
+func TestEditNote(t *testing.T) {
+	credentials, err := parseDbCredentials("testingCredentials.yaml")
+	if err != nil {
+		log.Print("Failed to parse db credentials. Err:", err)
+		t.Fatal()
+	}
+
+	db, err := NewMysqlNotesdb(credentials)
+	if err != nil {
+		t.Fatal()
+	}
+
+	recipient := uuid.NewV4()
+	note := getTestNote(uuid.NewV4(), recipient)
+	if err = db.InsertNote(note); err != nil {
+		t.Fatal()
+	}
+	defer db.PurgeNote(note.id)
+
+	firstEdit := note.timeSent.Add(time.Minute)
+	secondEdit := firstEdit.Add(time.Minute)
+	for _, edit := range []struct {
+		text string
+		at time.Time
+	}{{"Second draft", firstEdit}, {"Final draft", secondEdit}} {
+		if err = db.EditNote(note.id, edit.text, edit.at); err != nil {
+			t.Fatal("Failed to edit note. Err: ", err)
+		}
+	}
+
+	resultNotes, err := db.GetNotesByIds([]uuid.UUID{note.id})
+	if err != nil || len(resultNotes) != 1 {
+		t.Fatal("Failed to fetch note with id:", note.id, ", err: ", err)
+	}
+	if resultNotes[0].Text() != "Final draft" || !resultNotes[0].TimeEdited().Equal(secondEdit) {
+		t.Fatal("Expected the latest edit, got: ", resultNotes[0])
+	}
+
+	versions, err := db.GetNoteVersions(note.id)
+	if err != nil || len(versions) != 2 {
+		t.Fatal("Expected two earlier versions, got: ", versions, " ", err)
+	}
+	if versions[0].Text != note.note || !versions[0].WrittenAt.Equal(note.timeSent) ||
+		!versions[0].ReplacedAt.Equal(firstEdit) || versions[1].Text != "Second draft" ||
+		!versions[1].WrittenAt.Equal(firstEdit) || !versions[1].ReplacedAt.Equal(secondEdit) {
+		t.Fatal("Unexpected versions: ", versions[0], versions[1])
+	}
+
+	if err = db.MarkNoteRead(note.id, recipient, nil); err != nil {
+		t.Fatal()
+	}
+	if err = db.EditNote(note.id, "Too late", secondEdit.Add(time.Minute)); err != ErrNoteRead {
+		t.Fatal("Expected ErrNoteRead editing a read note, got: ", err)
+	}
+}
+
+func TestGroupNote(t *testing.T) {
+	credentials, err := parseDbCredentials("testingCredentials.yaml")
+	if err != nil {
+		log.Print("Failed to parse db credentials. Err:", err)
+		t.Fatal()
+	}
+
+	db, err := NewMysqlNotesdb(credentials)
+	if err != nil {
+		t.Fatal()
+	}
+
+	first := uuid.NewV4()
+	second := uuid.NewV4()
+	note := NewGroupNote(uuid.NewV4(), []uuid.UUID{first, second, first}, "For you both",
+		42.2, 24.4, time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC))
+	if err = db.InsertNote(note); err != nil {
+		t.Fatal("Failed to insert note. Err: ", err)
+	}
+	defer db.PurgeNote(note.id)
+
+	if err = db.MarkNoteRead(note.id, first, nil); err != nil {
+		t.Fatal("Failed to mark note read. Err: ", err)
+	}
+	if err = db.MarkNoteDeletedFor(note.id, second); err != nil {
+		t.Fatal("Failed to mark note deleted. Err: ", err)
+	}
+
+	for _, recipient := range []uuid.UUID{first, second} {
+		resultNotes, err := db.GetNotesByRecipient(recipient, nil, 10, 0)
+		if err != nil || len(resultNotes) != 1 {
+			t.Fatal("Failed to fetch note for recipient:", recipient, ", err: ", err)
+		}
+		result := resultNotes[0]
+		if len(result.recipients) != 2 || result.Recipient() != first {
+			t.Fatal("Expected both recipients in order, got: ", result.recipients)
+		}
+		if !result.ReadBy(first) || result.ReadBy(second) || result.Read() {
+			t.Fatal("Expected the note read by the first recipient only.")
+		}
+		if result.DeletedFor(first) || !result.DeletedFor(second) || result.Deleted() {
+			t.Fatal("Expected the note deleted for the second recipient only.")
+		}
+	}
+
+	if err = db.RemoveRecipient(note.id, second); err != nil {
+		t.Fatal("Failed to remove recipient. Err: ", err)
+	}
+	if resultNotes, _ := db.GetNotesByRecipient(second, nil, 10, 0); len(resultNotes) != 0 {
+		t.Fatal("Expected no notes for the removed recipient, got: ", resultNotes)
+	}
+}
+
+func TestSharedNote(t *testing.T) {
+	credentials, err := parseDbCredentials("testingCredentials.yaml")
+	if err != nil {
+		log.Print("Failed to parse db credentials. Err:", err)
+		t.Fatal()
+	}
+
+	db, err := NewMysqlNotesdb(credentials)
+	if err != nil {
+		t.Fatal()
+	}
+
+	reader := uuid.NewV4()
+	note := NewSharedNote(uuid.NewV4(), VISIBILITY_PUBLIC, "For anyone",
+		42.2, 24.4, time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC))
+	if err = db.InsertNote(note); err != nil {
+		t.Fatal("Failed to insert note. Err: ", err)
+	}
+	defer db.PurgeNote(note.id)
+
+	readAt := time.Date(2009, time.November, 11, 9, 30, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err = db.AddReader(note.id, reader, &Receipt{ReadAt: readAt.Add(time.Duration(i) * time.Hour)}); err != nil {
+			t.Fatal("Failed to add reader. Err: ", err)
+		}
+	}
+
+	resultNotes, err := db.GetNotesReadBy(reader, 10, 0)
+	if err != nil || len(resultNotes) != 1 {
+		t.Fatal("Failed to fetch notes read by reader, err: ", err)
+	}
+	result := resultNotes[0]
+	if result.Visibility() != VISIBILITY_PUBLIC || len(result.recipients) != 0 ||
+		len(result.readers) != 1 || !result.ReadBy(reader) || !result.Read() {
+		t.Fatal("Unexpected shared note: ", result)
+	}
+	// Reading it again doesn't move the receipt, which has no location.
+	if receipt := result.ReceiptFor(reader); receipt == nil || !receipt.ReadAt.Equal(readAt) || receipt.Located {
+		t.Fatal("Expected the first read's receipt, got: ", receipt)
+	}
+
+	if err = db.RemoveReader(note.id, reader); err != nil {
+		t.Fatal("Failed to remove reader. Err: ", err)
+	}
+	if resultNotes, _ = db.GetNotesReadBy(reader, 10, 0); len(resultNotes) != 0 {
+		t.Fatal("Expected no notes for the removed reader, got: ", resultNotes)
+	}
+}
+
+func TestThread(t *testing.T) {
+	credentials, err := parseDbCredentials("testingCredentials.yaml")
+	if err != nil {
+		log.Print("Failed to parse db credentials. Err:", err)
+		t.Fatal()
+	}
+
+	db, err := NewMysqlNotesdb(credentials)
+	if err != nil {
+		t.Fatal()
+	}
+
+	alice, bob := uuid.NewV4(), uuid.NewV4()
+	timeSent := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	root := NewNote(alice, bob, "Meet here?", 42.2, 24.4, timeSent)
+	anchored := NewReply(root, bob, root.Participants()[:1], "Sure", 1, 1, false, timeSent.Add(time.Minute))
+	direct := NewReply(anchored, alice, []uuid.UUID{bob}, "Great", 1, 1, true, timeSent.Add(2 * time.Minute))
+	for _, note := range []*Note{direct, root, anchored} {
+		if err = db.InsertNote(note); err != nil {
+			t.Fatal("Failed to insert note. Err: ", err)
+		}
+		defer db.PurgeNote(note.id)
+	}
+
+	if anchored.Latitude() != root.Latitude() || direct.Latitude() != 1 {
+		t.Fatal("Expected only the anchored reply at the root's spot, got: ", anchored, direct)
+	}
+	thread, err := db.GetThread(root.ThreadId(), 10, 0)
+	if err != nil {
+		t.Fatal("Failed to get thread. Err: ", err)
+	}
+	if len(thread) != 3 || !notesAreEqual(thread[0], root) || !notesAreEqual(thread[1], anchored) ||
+		!notesAreEqual(thread[2], direct) {
+		t.Fatal("Expected the thread in order, got: ", thread)
+	}
+	if thread[0].IsReply() || thread[2].ParentId() != anchored.Id() || !thread[2].Direct() {
+		t.Fatal("Unexpected thread links: ", thread)
+	}
+
+	shared := NewSharedNote(alice, VISIBILITY_PUBLIC, "For anyone", 1, 1, timeSent)
+	shared.parentId = root.id
+	if err = db.InsertNote(shared); err == nil {
+		defer db.PurgeNote(shared.id)
+		t.Fatal("Expected a shared reply to be refused.")
+	}
+}
+
 func TestGetNotesBySender(t *testing.T) {
 	credentials, err := parseDbCredentials("testingCredentials.yaml")
 	if err != nil {
@@ -223,7 +439,7 @@ func TestPaginatedGetNotesByRecipient(t *testing.T) {
 	offset := 0
 
 	for offset < numNotes {
-		resultNotes, err := db.GetNotesByRecipient(recipient, maxCount, offset)
+		resultNotes, err := db.GetNotesByRecipient(recipient, nil, maxCount, offset)
 		if err != nil {
 			t.Fatal()
 		}
@@ -273,7 +489,7 @@ func TestGetNotesByRecipient(t *testing.T) {
 
 	maxCount := 10
 	offset := 0
-	resultNotes, err := db.GetNotesByRecipient(recipient, maxCount, offset)
+	resultNotes, err := db.GetNotesByRecipient(recipient, nil, maxCount, offset)
 	if err != nil {
 		t.Fatal()
 	}
@@ -283,6 +499,60 @@ func TestGetNotesByRecipient(t *testing.T) {
 	}
 }
 
+func TestCountNotesByRecipient(t *testing.T) {
+	credentials, err := parseDbCredentials("testingCredentials.yaml")
+	if err != nil {
+		log.Print("Failed to parse db credentials. Err:", err)
+		t.Fatal()
+	}
+
+	db, err := NewMysqlNotesdb(credentials)
+	if err != nil {
+		t.Fatal()
+	}
+
+	recipient := uuid.NewV4()
+	busy := uuid.NewV4()
+	quiet := uuid.NewV4()
+	muted := uuid.NewV4()
+	notes := append(getTestNotes(3, busy, recipient), getTestNotes(2, quiet, recipient)...)
+	notes = append(notes, getTestNote(muted, recipient), getTestNote(busy, uuid.NewV4()))
+	for _, note := range notes {
+		if err = db.InsertNote(note); err != nil {
+			t.Fatal()
+		}
+	}
+	defer deleteNotes(db, notes)
+
+	// Of busy's three, one is read and one deleted; both of quiet's are
+	// read, so busy comes first.
+	if err = db.MarkNoteRead(notes[0].id, recipient, nil); err != nil {
+		t.Fatal()
+	}
+	if err = db.MarkNoteDeletedFor(notes[1].id, recipient); err != nil {
+		t.Fatal()
+	}
+	for _, note := range notes[3:5] {
+		if err = db.MarkNoteRead(note.id, recipient, nil); err != nil {
+			t.Fatal()
+		}
+	}
+
+	counts, err := db.CountNotesByRecipient(recipient, []uuid.UUID{muted})
+	if err != nil {
+		t.Fatal("Failed to count notes. Err: ", err)
+	}
+	expected := []SenderCount{{busy, 1, 2}, {quiet, 0, 2}}
+	if len(counts) != len(expected) {
+		t.Fatal("Expected counts for two senders, got: ", counts)
+	}
+	for i, count := range counts {
+		if *count != expected[i] {
+			t.Fatal("Expected ", expected[i], ", got: ", *count)
+		}
+	}
+}
+
 func TestGetNotesById(t *testing.T) {
 	credentials, err := parseDbCredentials("testingCredentials.yaml")
 	if err != nil {
@@ -320,6 +590,53 @@ func TestGetNotesById(t *testing.T) {
 	}
 }
 
+func TestGetNotesAfterId(t *testing.T) {
+	credentials, err := parseDbCredentials("testingCredentials.yaml")
+	if err != nil {
+		log.Print("Failed to parse db credentials. Err:", err)
+		t.Fatal()
+	}
+
+	db, err := NewMysqlNotesdb(credentials)
+	if err != nil {
+		t.Fatal()
+	}
+
+	numNotes := 5
+	notes := getTestNotes(numNotes, uuid.NewV4(), uuid.NewV4())
+	for _, note := range notes {
+		if err = db.InsertNote(note); err != nil {
+			t.Fatal()
+		}
+	}
+	defer deleteNotes(db, notes)
+
+	sort.Sort(ById(notes))
+	afterId := notes[1].id
+	resultNotes, err := db.GetNotesAfterId(afterId, numNotes)
+	if err != nil {
+		t.Fatal("Failed to get notes after id: ", afterId, ", err: ", err)
+	}
+
+	// Other tests may share the table, so only check that every returned
+	// note sorts after afterId and that ours all turned up in order.
+	var ours []*Note
+	for _, note := range resultNotes {
+		if note.id.String() <= afterId.String() {
+			t.Fatal("Got note ", note.id, " which does not sort after ", afterId)
+		}
+		for _, expected := range notes[2:] {
+			if note.id == expected.id {
+				ours = append(ours, note)
+			}
+		}
+	}
+
+	if !allNotesAreEqual(notes[2:], ours) {
+		t.Fatal("Did not get back the notes after id ", afterId)
+	}
+}
+
 func deleteNotes(db NotesdbConnection, notes []*Note) error {
 	for _, note := range notes {
 		if err := db.PurgeNote(note.id); err != nil {
@@ -359,12 +676,26 @@ func notesAreEqual(lhs *Note, rhs *Note) bool {
 		return false
 	}
 
+	if lhs.parentId != rhs.parentId || lhs.threadId != rhs.threadId || lhs.direct != rhs.direct {
+		return false
+	}
+
 	if lhs.sender != rhs.sender {
 		return false 
 	}
 
-	if lhs.recipient != rhs.recipient {
-		return false 
+	if lhs.visibility != rhs.visibility {
+		return false
+	}
+
+	if len(lhs.recipients) != len(rhs.recipients) {
+		return false
+	}
+
+	for idx, _ := range lhs.recipients {
+		if lhs.recipients[idx] != rhs.recipients[idx] {
+			return false
+		}
 	}
 
 	if lhs.note != rhs.note {
@@ -379,11 +710,7 @@ func notesAreEqual(lhs *Note, rhs *Note) bool {
 		return false 
 	}
 
-	if lhs.timeSent != rhs.timeSent {
-		return false 
-	}
-
-	if lhs.read != rhs.read {
+	if lhs.timeSent != rhs.timeSent || !lhs.timeEdited.Equal(rhs.timeEdited) {
 		return false 
 	}
 
@@ -426,13 +753,14 @@ func getTestNote(sender uuid.UUID, recipient uuid.UUID) *Note {
 
 	return &Note{
 		id: id,
+		threadId: id,
 		sender: sender,
-		recipient: recipient,
+		visibility: VISIBILITY_PRIVATE,
+		recipients: []Recipient{{Id: recipient}},
 		note: "This is a test note",
 		latitude: 42.2,
 		longitude: 24.4,
 		timeSent: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
-		read: false,
 		deleted: false,
 	}
 }
@@ -0,0 +1,280 @@
+package reconcile
+
+import (
+	"log"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/solrnotes"
+)
+
+const (
+	DEFAULT_BATCH_SIZE = 500
+
+	FIELD_SENDER = "sender"
+	FIELD_VISIBILITY = "visibility"
+	FIELD_RECIPIENTS = "recipients"
+	FIELD_LATITUDE = "latitude"
+	FIELD_LONGITUDE = "longitude"
+	FIELD_TIMESENT = "timeSent"
+	FIELD_TIMEEDITED = "timeEdited"
+	FIELD_DIRECT = "direct"
+	FIELD_READ = "read"
+	FIELD_DELETED = "deleted"
+)
+
+// MAX_ID is the largest possible uuid, used as the default end of a range.
+var MAX_ID = uuid.UUID{
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+}
+
+// Checker compares the notes table against the Solr index. The read and
+// deleted flags live in both stores and are updated by separate calls, so
+// they can drift; notesdb is treated as the source of truth.
+type Checker struct {
+	notes notesdb.NotesdbConnection
+	index solrnotes.SolrConnection
+	options Options
+}
+
+type Options struct {
+	// Only notes with ids in (AfterId, ThroughId] are compared. The zero
+	// values check everything.
+	AfterId uuid.UUID
+	ThroughId uuid.UUID
+
+	// BatchSize is how many notes are compared per round trip. Defaults
+	// to DEFAULT_BATCH_SIZE.
+	BatchSize int
+
+	// Repair re-adds missing and mismatched docs from notesdb and purges
+	// orphaned docs from Solr.
+	Repair bool
+}
+
+type Report struct {
+	Checked int
+
+	// Missing notes exist in notesdb but have no Solr doc.
+	Missing []uuid.UUID
+
+	// Orphaned docs exist in Solr but have no note in notesdb.
+	Orphaned []uuid.UUID
+
+	Mismatched []Mismatch
+
+	// Repaired counts docs re-added or purged when Options.Repair is set.
+	Repaired int
+}
+
+type Mismatch struct {
+	Id uuid.UUID
+	Fields []string
+}
+
+func NewChecker(
+	notes notesdb.NotesdbConnection,
+	index solrnotes.SolrConnection,
+	options Options) *Checker {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DEFAULT_BATCH_SIZE
+	}
+	if options.ThroughId == uuid.Nil {
+		options.ThroughId = MAX_ID
+	}
+	return &Checker{notes: notes, index: index, options: options}
+}
+
+func (r *Report) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Orphaned) == 0 && len(r.Mismatched) == 0
+}
+
+// Run walks both stores in id order. Each batch of notes covers an id
+// range ending at the last note fetched, and every Solr doc in that same
+// range is fetched to compare against it, so orphans between notes are
+// found as well as missing docs.
+func (c *Checker) Run() (*Report, error) {
+	report := &Report{}
+	afterId := c.options.AfterId
+
+	for {
+		notes, err := c.notes.GetNotesAfterId(afterId, c.options.BatchSize)
+		if err != nil {
+			log.Printf("Failed to fetch notes after id %v. Err: %v", afterId, err)
+			return report, err
+		}
+
+		throughId := c.options.ThroughId
+		finished := len(notes) < c.options.BatchSize
+		if !finished && idLess(notes[len(notes) - 1].Id(), throughId) {
+			throughId = notes[len(notes) - 1].Id()
+		} else {
+			finished = true
+		}
+
+		inRange := make(map[uuid.UUID]*notesdb.Note)
+		for _, note := range notes {
+			if idLess(throughId, note.Id()) {
+				break
+			}
+			inRange[note.Id()] = note
+		}
+
+		docs, err := c.getDocs(afterId, throughId)
+		if err != nil {
+			return report, err
+		}
+
+		if err = c.compare(inRange, docs, report); err != nil {
+			return report, err
+		}
+
+		if finished {
+			break
+		}
+		afterId = throughId
+	}
+
+	return report, nil
+}
+
+func (c *Checker) getDocs(afterId uuid.UUID, throughId uuid.UUID) (map[uuid.UUID]*solrnotes.Document, error) {
+	docs := make(map[uuid.UUID]*solrnotes.Document)
+	for {
+		batch, err := c.index.GetDocsInIdRange(afterId, throughId, c.options.BatchSize)
+		if err != nil {
+			log.Printf("Failed to fetch docs in id range (%v, %v]. Err: %v", afterId, throughId, err)
+			return nil, err
+		}
+
+		for _, doc := range batch {
+			docs[doc.Id()] = doc
+		}
+
+		if len(batch) < c.options.BatchSize {
+			return docs, nil
+		}
+		afterId = batch[len(batch) - 1].Id()
+	}
+}
+
+func (c *Checker) compare(
+	notes map[uuid.UUID]*notesdb.Note,
+	docs map[uuid.UUID]*solrnotes.Document,
+	report *Report) error {
+	var toAdd []solrnotes.Document
+	var toPurge []uuid.UUID
+
+	for id, note := range notes {
+		report.Checked++
+
+		doc, ok := docs[id]
+		if !ok {
+			report.Missing = append(report.Missing, id)
+			toAdd = append(toAdd, solrnotes.DocumentFromNote(note))
+			continue
+		}
+
+		if fields := differingFields(note, doc); len(fields) > 0 {
+			report.Mismatched = append(report.Mismatched, Mismatch{Id: id, Fields: fields})
+			toAdd = append(toAdd, solrnotes.DocumentFromNote(note))
+		}
+	}
+
+	for id, _ := range docs {
+		if _, ok := notes[id]; !ok {
+			report.Orphaned = append(report.Orphaned, id)
+			toPurge = append(toPurge, id)
+		}
+	}
+
+	if !c.options.Repair {
+		return nil
+	}
+
+	if len(toAdd) > 0 {
+		if err := c.index.AddDocs(toAdd); err != nil {
+			log.Printf("Failed to repair %v docs. Err: %v", len(toAdd), err)
+			return err
+		}
+		report.Repaired += len(toAdd)
+	}
+
+	if len(toPurge) > 0 {
+		if err := c.index.PurgeDocs(toPurge); err != nil {
+			log.Printf("Failed to purge %v orphaned docs. Err: %v", len(toPurge), err)
+			return err
+		}
+		report.Repaired += len(toPurge)
+	}
+
+	return nil
+}
+
+func differingFields(note *notesdb.Note, doc *solrnotes.Document) []string {
+	var fields []string
+	if note.Sender() != doc.Sender() {
+		fields = append(fields, FIELD_SENDER)
+	}
+	if note.Visibility() != doc.Visibility() {
+		fields = append(fields, FIELD_VISIBILITY)
+	}
+	if !sameRecipients(note, doc) {
+		fields = append(fields, FIELD_RECIPIENTS)
+	}
+	if note.Latitude() != doc.Latitude() {
+		fields = append(fields, FIELD_LATITUDE)
+	}
+	if note.Longitude() != doc.Longitude() {
+		fields = append(fields, FIELD_LONGITUDE)
+	}
+	if !note.TimeSent().Equal(doc.TimeSent()) {
+		fields = append(fields, FIELD_TIMESENT)
+	}
+	if !note.TimeEdited().Equal(doc.TimeEdited()) {
+		fields = append(fields, FIELD_TIMEEDITED)
+	}
+	if note.Direct() != doc.Direct() {
+		fields = append(fields, FIELD_DIRECT)
+	}
+	read, deleted := true, note.Deleted() == doc.Deleted()
+	for _, recipient := range note.RecipientIds() {
+		read = read && note.ReadBy(recipient) == doc.ReadBy(recipient)
+		deleted = deleted && note.DeletedFor(recipient) == doc.DeletedFor(recipient)
+	}
+	if note.Shared() {
+		readers := note.Readers()
+		read = len(readers) == len(doc.Readers())
+		for _, reader := range readers {
+			read = read && doc.ReadBy(reader)
+		}
+	}
+	if !read {
+		fields = append(fields, FIELD_READ)
+	}
+	if !deleted {
+		fields = append(fields, FIELD_DELETED)
+	}
+	return fields
+}
+
+func sameRecipients(note *notesdb.Note, doc *solrnotes.Document) bool {
+	noteRecipients := note.RecipientIds()
+	docRecipients := doc.Recipients()
+	if len(noteRecipients) != len(docRecipients) {
+		return false
+	}
+	for i := range noteRecipients {
+		if noteRecipients[i] != docRecipients[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// idLess orders ids the same way MySQL and Solr order their string forms.
+func idLess(lhs uuid.UUID, rhs uuid.UUID) bool {
+	return lhs.String() < rhs.String()
+}
@@ -0,0 +1,202 @@
+package reconcile
+
+import (
+	"testing"
+	"time"
+	"sort"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/solrnotes"
+)
+
+func TestConsistentStores(t *testing.T) {
+	db, index, notes := getTestStores(t, 12)
+
+	report, err := NewChecker(db, index, Options{BatchSize: 5}).Run()
+	if err != nil {
+		t.Fatal("Check failed. Err: ", err)
+	}
+
+	if !report.Consistent() || report.Checked != len(notes) {
+		t.Fatal("Expected consistent stores, got: ", report)
+	}
+}
+
+func TestFindsDrift(t *testing.T) {
+	db, index, notes := getTestStores(t, 12)
+
+	missing := notes[3].Id()
+	index.PurgeDocs([]uuid.UUID{missing})
+
+	readOnlyInDb := notes[7].Id()
+	db.MarkNoteRead(readOnlyInDb, notes[7].Recipient(), nil)
+
+	orphan := solrnotes.NewDocument(uuid.NewV4(), uuid.NewV4(), notesdb.VISIBILITY_PRIVATE,
+		[]uuid.UUID{uuid.NewV4()}, 1.0, 2.0, time.Now(), nil, nil, false)
+	index.AddDoc(orphan)
+
+	report, err := NewChecker(db, index, Options{BatchSize: 5}).Run()
+	if err != nil {
+		t.Fatal("Check failed. Err: ", err)
+	}
+
+	if len(report.Missing) != 1 || report.Missing[0] != missing {
+		t.Fatal("Expected ", missing, " to be missing, got: ", report.Missing)
+	}
+
+	if len(report.Orphaned) != 1 || report.Orphaned[0] != orphan.Id() {
+		t.Fatal("Expected ", orphan.Id(), " to be orphaned, got: ", report.Orphaned)
+	}
+
+	if len(report.Mismatched) != 1 ||
+		report.Mismatched[0].Id != readOnlyInDb ||
+		len(report.Mismatched[0].Fields) != 1 ||
+		report.Mismatched[0].Fields[0] != FIELD_READ {
+		t.Fatal("Expected read flag mismatch on ", readOnlyInDb, ", got: ", report.Mismatched)
+	}
+
+	if report.Repaired != 0 {
+		t.Fatal("Checker repaired docs without being asked to.")
+	}
+}
+
+func TestFindsGroupNoteDrift(t *testing.T) {
+	db, index, _ := getTestStores(t, 0)
+
+	first := uuid.NewV4()
+	second := uuid.NewV4()
+	note := notesdb.NewGroupNote(uuid.NewV4(), []uuid.UUID{first, second}, "For you both",
+		42.2, 24.4, time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC))
+	db.InsertNote(note)
+	index.AddDoc(solrnotes.DocumentFromNote(note))
+
+	// Only the second recipient's delete is missing from the index.
+	db.MarkNoteDeletedFor(note.Id(), second)
+
+	report, err := NewChecker(db, index, Options{BatchSize: 5}).Run()
+	if err != nil {
+		t.Fatal("Check failed. Err: ", err)
+	}
+	if len(report.Mismatched) != 1 ||
+		len(report.Mismatched[0].Fields) != 1 ||
+		report.Mismatched[0].Fields[0] != FIELD_DELETED {
+		t.Fatal("Expected deleted flag mismatch on ", note.Id(), ", got: ", report.Mismatched)
+	}
+}
+
+func TestFindsUnindexedEdit(t *testing.T) {
+	db, index, notes := getTestStores(t, 3)
+
+	// The note was edited but the doc wasn't reindexed.
+	edited := notes[1].Id()
+	db.EditNote(edited, "Changed my mind", notes[1].TimeSent().Add(time.Minute))
+
+	report, err := NewChecker(db, index, Options{BatchSize: 5}).Run()
+	if err != nil {
+		t.Fatal("Check failed. Err: ", err)
+	}
+	if len(report.Mismatched) != 1 ||
+		report.Mismatched[0].Id != edited ||
+		len(report.Mismatched[0].Fields) != 1 ||
+		report.Mismatched[0].Fields[0] != FIELD_TIMEEDITED {
+		t.Fatal("Expected time edited mismatch on ", edited, ", got: ", report.Mismatched)
+	}
+}
+
+func TestFindsSharedNoteDrift(t *testing.T) {
+	db, index, _ := getTestStores(t, 0)
+
+	note := notesdb.NewSharedNote(uuid.NewV4(), notesdb.VISIBILITY_PUBLIC, "For anyone",
+		42.2, 24.4, time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC))
+	db.InsertNote(note)
+	index.AddDoc(solrnotes.DocumentFromNote(note))
+
+	// A reader was added to the note but not to the doc.
+	db.AddReader(note.Id(), uuid.NewV4(), nil)
+
+	report, err := NewChecker(db, index, Options{BatchSize: 5}).Run()
+	if err != nil {
+		t.Fatal("Check failed. Err: ", err)
+	}
+	if len(report.Mismatched) != 1 ||
+		len(report.Mismatched[0].Fields) != 1 ||
+		report.Mismatched[0].Fields[0] != FIELD_READ {
+		t.Fatal("Expected read flag mismatch on ", note.Id(), ", got: ", report.Mismatched)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	db, index, notes := getTestStores(t, 12)
+
+	index.PurgeDocs([]uuid.UUID{notes[0].Id()})
+	db.MarkNoteDeleted(notes[11].Id())
+	index.AddDoc(solrnotes.NewDocument(uuid.NewV4(), uuid.NewV4(), notesdb.VISIBILITY_PRIVATE,
+		[]uuid.UUID{uuid.NewV4()}, 1.0, 2.0, time.Now(), nil, nil, false))
+
+	report, err := NewChecker(db, index, Options{BatchSize: 5, Repair: true}).Run()
+	if err != nil {
+		t.Fatal("Repair failed. Err: ", err)
+	}
+	if report.Repaired != 3 {
+		t.Fatal("Expected 3 repairs, got ", report.Repaired)
+	}
+
+	report, err = NewChecker(db, index, Options{BatchSize: 5}).Run()
+	if err != nil {
+		t.Fatal("Check failed. Err: ", err)
+	}
+	if !report.Consistent() {
+		t.Fatal("Stores still inconsistent after repair: ", report)
+	}
+}
+
+func TestIdRange(t *testing.T) {
+	db, index, notes := getTestStores(t, 12)
+
+	// Drift outside the range shouldn't be reported.
+	index.PurgeDocs([]uuid.UUID{notes[0].Id(), notes[11].Id()})
+
+	options := Options{AfterId: notes[2].Id(), ThroughId: notes[8].Id(), BatchSize: 4}
+	report, err := NewChecker(db, index, options).Run()
+	if err != nil {
+		t.Fatal("Check failed. Err: ", err)
+	}
+
+	if !report.Consistent() || report.Checked != 6 {
+		t.Fatal("Expected 6 consistent notes in range, got: ", report)
+	}
+}
+
+// getTestStores returns stores that agree with each other, along with the
+// notes in them sorted by id.
+func getTestStores(t *testing.T, numNotes int) (
+	*notesdb.MemoryNotesdb, *solrnotes.MemorySolr, []*notesdb.Note) {
+	db := notesdb.NewMemoryNotesdb()
+	index := solrnotes.NewMemorySolr()
+
+	var notes []*notesdb.Note
+	for i := 0; i < numNotes; i++ {
+		note := notesdb.NewNote(
+			uuid.NewV4(),
+			uuid.NewV4(),
+			"This is a test note",
+			42.2,
+			24.4,
+			time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+		)
+		if err := db.InsertNote(note); err != nil {
+			t.Fatal("Failed to insert note. Err: ", err)
+		}
+		if err := index.AddDoc(solrnotes.DocumentFromNote(note)); err != nil {
+			t.Fatal("Failed to add doc. Err: ", err)
+		}
+		notes = append(notes, note)
+	}
+
+	sort.Slice(notes, func(i, j int) bool {
+		return notes[i].Id().String() < notes[j].Id().String()
+	})
+	return db, index, notes
+}
@@ -0,0 +1,217 @@
+package reindex
+
+import (
+	"time"
+	"log"
+	"os"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/solrnotes"
+)
+
+const (
+	DEFAULT_BATCH_SIZE = 500
+)
+
+// Reindexer rebuilds a Solr core from the notes table, which is the
+// source of truth. Point target at a freshly created core, run it, and
+// optionally have it move an alias over to the new core once it's full.
+type Reindexer struct {
+	notes notesdb.NotesdbConnection
+	target solrnotes.SolrConnection
+	options Options
+}
+
+type Options struct {
+	// BatchSize is how many notes are read and indexed per round trip.
+	// Defaults to DEFAULT_BATCH_SIZE.
+	BatchSize int
+
+	// CheckpointFile, if set, records the last note indexed after every
+	// batch. A later run with the same file picks up where the previous
+	// one stopped. The file is removed once a run completes.
+	CheckpointFile string
+
+	// Alias, if set, is pointed at the target core after the last batch.
+	Alias string
+
+	// Progress, if set, is called after every batch and once at the end.
+	Progress func(Progress)
+}
+
+type Progress struct {
+	Indexed int
+	LastId uuid.UUID
+	Elapsed time.Duration
+	Done bool
+}
+
+type checkpoint struct {
+	LastId string
+	Indexed int
+}
+
+func NewReindexer(
+	notes notesdb.NotesdbConnection,
+	target solrnotes.SolrConnection,
+	options Options) *Reindexer {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DEFAULT_BATCH_SIZE
+	}
+	return &Reindexer{notes: notes, target: target, options: options}
+}
+
+// Run walks the notes table in id order, indexing each batch into the
+// target core. The checkpoint is only advanced after a batch has been
+// committed to Solr, so a resumed run never skips notes; at worst it
+// re-adds one batch, which is harmless since adds overwrite by id.
+func (r *Reindexer) Run() (Progress, error) {
+	start := time.Now()
+
+	progress, err := r.loadCheckpoint()
+	if err != nil {
+		return progress, err
+	}
+	if progress.Indexed > 0 {
+		log.Printf("Resuming reindex after id %v with %v notes already indexed.",
+			progress.LastId, progress.Indexed)
+	}
+
+	for {
+		notes, err := r.notes.GetNotesAfterId(progress.LastId, r.options.BatchSize)
+		if err != nil {
+			log.Printf("Failed to fetch notes after id %v. Err: %v", progress.LastId, err)
+			return progress, err
+		}
+
+		if len(notes) == 0 {
+			break
+		}
+
+		docs := make([]solrnotes.Document, len(notes))
+		for i, note := range notes {
+			docs[i] = solrnotes.DocumentFromNote(note)
+		}
+
+		if err = r.target.AddDocs(docs); err != nil {
+			log.Printf("Failed to index batch after id %v. Err: %v", progress.LastId, err)
+			return progress, err
+		}
+
+		progress.LastId = notes[len(notes) - 1].Id()
+		progress.Indexed += len(notes)
+		progress.Elapsed = time.Since(start)
+
+		if err = r.saveCheckpoint(progress); err != nil {
+			return progress, err
+		}
+		r.report(progress)
+
+		if len(notes) < r.options.BatchSize {
+			break
+		}
+	}
+
+	if r.options.Alias != "" {
+		if err = r.target.PointAlias(r.options.Alias); err != nil {
+			log.Printf("Reindexed %v notes but failed to point alias %v. Err: %v",
+				progress.Indexed, r.options.Alias, err)
+			return progress, err
+		}
+	}
+
+	if err = r.clearCheckpoint(); err != nil {
+		return progress, err
+	}
+
+	progress.Elapsed = time.Since(start)
+	progress.Done = true
+	r.report(progress)
+
+	return progress, nil
+}
+
+func (r *Reindexer) report(progress Progress) {
+	if r.options.Progress != nil {
+		r.options.Progress(progress)
+	}
+}
+
+func (r *Reindexer) loadCheckpoint() (Progress, error) {
+	progress := Progress{LastId: uuid.Nil}
+	if r.options.CheckpointFile == "" {
+		return progress, nil
+	}
+
+	data, err := ioutil.ReadFile(r.options.CheckpointFile)
+	if os.IsNotExist(err) {
+		return progress, nil
+	}
+	if err != nil {
+		log.Printf("Failed to read checkpoint file %v. Err: %v", r.options.CheckpointFile, err)
+		return progress, err
+	}
+
+	var saved checkpoint
+	if err = json.Unmarshal(data, &saved); err != nil {
+		log.Printf("Failed to parse checkpoint file %v. Err: %v", r.options.CheckpointFile, err)
+		return progress, err
+	}
+
+	progress.LastId, err = uuid.FromString(saved.LastId)
+	if err != nil {
+		log.Printf("Bad last id in checkpoint file %v. Err: %v", r.options.CheckpointFile, err)
+		return progress, err
+	}
+	progress.Indexed = saved.Indexed
+
+	return progress, nil
+}
+
+// saveCheckpoint writes to a temporary file and renames it into place so
+// that a crash mid-write can't leave a truncated checkpoint behind.
+func (r *Reindexer) saveCheckpoint(progress Progress) error {
+	if r.options.CheckpointFile == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(checkpoint{
+		LastId: progress.LastId.String(),
+		Indexed: progress.Indexed,
+	})
+	if err != nil {
+		return err
+	}
+
+	tmpFile := r.options.CheckpointFile + ".tmp"
+	if err = ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		log.Printf("Failed to write checkpoint file %v. Err: %v", tmpFile, err)
+		return err
+	}
+
+	if err = os.Rename(tmpFile, r.options.CheckpointFile); err != nil {
+		log.Printf("Failed to move checkpoint into place at %v. Err: %v",
+			r.options.CheckpointFile, err)
+		return err
+	}
+
+	return nil
+}
+
+func (r *Reindexer) clearCheckpoint() error {
+	if r.options.CheckpointFile == "" {
+		return nil
+	}
+
+	err := os.Remove(r.options.CheckpointFile)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove checkpoint file %v. Err: %v", r.options.CheckpointFile, err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package reindex
+
+import (
+	"testing"
+	"time"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
+	"github.com/dbenny42/geonote/solrnotes"
+)
+
+func TestReindexAll(t *testing.T) {
+	db, notes := getTestNotesdb(t, 7)
+	target := newFlakySolr()
+
+	var reports []Progress
+	reindexer := NewReindexer(db, target, Options{
+		BatchSize: 3,
+		Alias: "geonotes",
+		Progress: func(p Progress) { reports = append(reports, p) },
+	})
+
+	progress, err := reindexer.Run()
+	if err != nil {
+		t.Fatal("Reindex failed. Err: ", err)
+	}
+
+	if progress.Indexed != 7 || !progress.Done {
+		t.Fatal("Unexpected final progress: ", progress)
+	}
+
+	for _, note := range notes {
+		doc, err := target.GetDoc(note.Id())
+		if err != nil {
+			t.Fatal("Note ", note.Id(), " was never indexed.")
+		}
+		if doc.Sender() != note.Sender() || doc.Latitude() != note.Latitude() {
+			t.Fatal("Doc for note ", note.Id(), " does not match the note.")
+		}
+	}
+
+	if target.Alias() != "geonotes" {
+		t.Fatal("Alias was not pointed at the target.")
+	}
+
+	// Three batches plus the final report.
+	if len(reports) != 4 || !reports[3].Done {
+		t.Fatal("Unexpected progress reports: ", reports)
+	}
+}
+
+func TestReindexResumes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	checkpointFile := filepath.Join(dir, "checkpoint")
+
+	db, notes := getTestNotesdb(t, 10)
+	target := newFlakySolr()
+	target.failAfter = 2
+
+	options := Options{BatchSize: 3, CheckpointFile: checkpointFile}
+	progress, err := NewReindexer(db, target, options).Run()
+	if err == nil {
+		t.Fatal("Expected the first run to fail.")
+	}
+	if progress.Indexed != 6 {
+		t.Fatal("Expected 6 notes indexed before failure, got ", progress.Indexed)
+	}
+
+	target.failAfter = -1
+	target.batches = 0
+	progress, err = NewReindexer(db, target, options).Run()
+	if err != nil {
+		t.Fatal("Resumed reindex failed. Err: ", err)
+	}
+
+	if progress.Indexed != 10 {
+		t.Fatal("Resumed run did not finish indexing. Progress: ", progress)
+	}
+
+	for _, note := range notes {
+		if _, err := target.GetDoc(note.Id()); err != nil {
+			t.Fatal("Note ", note.Id(), " was never indexed.")
+		}
+	}
+
+	// Only the remaining four notes should have been sent the second time.
+	if target.batches != 2 {
+		t.Fatal("Resumed run re-sent earlier batches. Batches: ", target.batches)
+	}
+
+	if _, err = os.Stat(checkpointFile); !os.IsNotExist(err) {
+		t.Fatal("Checkpoint file was not removed after completion.")
+	}
+}
+
+func getTestNotesdb(t *testing.T, numNotes int) (*notesdb.MemoryNotesdb, []*notesdb.Note) {
+	db := notesdb.NewMemoryNotesdb()
+	var notes []*notesdb.Note
+	for i := 0; i < numNotes; i++ {
+		note := notesdb.NewNote(
+			uuid.NewV4(),
+			uuid.NewV4(),
+			"This is a test note",
+			42.2,
+			24.4,
+			time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+		)
+		if err := db.InsertNote(note); err != nil {
+			t.Fatal("Failed to insert note. Err: ", err)
+		}
+		notes = append(notes, note)
+	}
+	return db, notes
+}
+
+// flakySolr counts the batches it's sent and can be told to start
+// failing after a given number of them.
+type flakySolr struct {
+	*solrnotes.MemorySolr
+	batches int
+	failAfter int
+}
+
+func newFlakySolr() *flakySolr {
+	return &flakySolr{MemorySolr: solrnotes.NewMemorySolr(), failAfter: -1}
+}
+
+func (sc *flakySolr) AddDocs(docs []solrnotes.Document) error {
+	if sc.failAfter >= 0 && sc.batches >= sc.failAfter {
+		return errors.New("solr is down")
+	}
+	sc.batches++
+	return sc.MemorySolr.AddDocs(docs)
+}
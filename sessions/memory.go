@@ -0,0 +1,63 @@
+package sessions
+
+import (
+	"sync"
+
+	"github.com/satori/go.uuid"
+)
+
+// MemorySessions is an in-memory SessionConnection for tests in packages
+// that sit on top of sessions and shouldn't need a live MySQL.
+type MemorySessions struct {
+	mutex sync.Mutex
+	tokens map[string]RefreshToken
+}
+
+func NewMemorySessions() *MemorySessions {
+	return &MemorySessions{tokens: make(map[string]RefreshToken)}
+}
+
+func (db *MemorySessions) InsertRefreshToken(token *RefreshToken) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.tokens[string(token.Hash)] = *token
+	return nil
+}
+
+func (db *MemorySessions) GetRefreshToken(hash []byte) (*RefreshToken, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	token, ok := db.tokens[string(hash)]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+func (db *MemorySessions) RevokeRefreshToken(hash []byte) (bool, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	token, ok := db.tokens[string(hash)]
+	if !ok || token.Revoked {
+		return false, nil
+	}
+	token.Revoked = true
+	db.tokens[string(hash)] = token
+	return true, nil
+}
+
+func (db *MemorySessions) RevokeUserRefreshTokens(userId uuid.UUID) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for hash, token := range db.tokens {
+		if token.UserId == userId {
+			token.Revoked = true
+			db.tokens[hash] = token
+		}
+	}
+	return nil
+}
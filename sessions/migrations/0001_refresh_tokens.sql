@@ -0,0 +1,15 @@
+-- Refresh tokens issued at login. Only the SHA-256 hash of each token is
+-- kept, so a copy of this table can't be used to resume anyone's session.
+--
+-- Rows are revoked rather than deleted so that reuse of a revoked token
+-- can be spotted; expired rows can be cleared out at leisure.
+
+CREATE TABLE refresh_tokens (
+	hash BINARY(32) NOT NULL,
+	user_id CHAR(36) NOT NULL,
+	issued_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	revoked BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (hash),
+	KEY refresh_tokens_user_id (user_id)
+);
@@ -0,0 +1,387 @@
+package sessions
+
+import (
+	"errors"
+	"log"
+	"time"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	DEFAULT_ACCESS_TTL = 15 * time.Minute
+	DEFAULT_REFRESH_TTL = 30 * 24 * time.Hour
+
+	// MIN_KEY_LEN is the shortest signing key NewManager accepts, in bytes.
+	MIN_KEY_LEN = 32
+	REFRESH_TOKEN_LEN = 32
+
+	// An access token's payload is the user's id followed by the expiry
+	// as big-endian unix seconds.
+	ACCESS_PAYLOAD_LEN = 16 + 8
+)
+
+var (
+	ErrInvalidToken = errors.New("Invalid token.")
+	ErrExpiredToken = errors.New("Token has expired.")
+	ErrRevokedToken = errors.New("Token has been revoked.")
+)
+
+// SessionConnection stores refresh tokens. Tokens are looked up by the
+// SHA-256 hash of the token string; the token itself is never stored.
+type SessionConnection interface {
+	InsertRefreshToken(token *RefreshToken) error
+	GetRefreshToken(hash []byte) (*RefreshToken, error)
+	RevokeRefreshToken(hash []byte) (bool, error)
+	RevokeUserRefreshTokens(userId uuid.UUID) error
+}
+
+// RefreshToken is a row in the refresh_tokens table.
+type RefreshToken struct {
+	Hash []byte
+	UserId uuid.UUID
+	IssuedAt time.Time
+	ExpiresAt time.Time
+	Revoked bool
+}
+
+// Tokens is what a client gets on login or refresh. The access token is
+// sent as a bearer token on every call; the refresh token is only sent
+// to get a new pair once the access token expires.
+type Tokens struct {
+	UserId uuid.UUID
+	AccessToken string
+	AccessExpiresAt time.Time
+	RefreshToken string
+	RefreshExpiresAt time.Time
+}
+
+type Options struct {
+	AccessTtl time.Duration
+	RefreshTtl time.Duration
+}
+
+// Manager issues and checks session tokens.
+//
+// Access tokens are HMAC-signed and carry the user's id and expiry, so
+// Authenticate never touches the store. The flip side is that revoking a
+// session only stops it being refreshed: an access token already handed
+// out stays good until it expires, which is why AccessTtl is short.
+type Manager struct {
+	store SessionConnection
+	key []byte
+	accessTtl time.Duration
+	refreshTtl time.Duration
+	now func() time.Time
+}
+
+func NewManager(store SessionConnection, key []byte, options Options) (*Manager, error) {
+	if len(key) < MIN_KEY_LEN {
+		return nil, errors.New("Session signing key must be at least 32 bytes.")
+	}
+	if options.AccessTtl == 0 {
+		options.AccessTtl = DEFAULT_ACCESS_TTL
+	}
+	if options.RefreshTtl == 0 {
+		options.RefreshTtl = DEFAULT_REFRESH_TTL
+	}
+
+	return &Manager{
+		store: store,
+		key: key,
+		accessTtl: options.AccessTtl,
+		refreshTtl: options.RefreshTtl,
+		now: time.Now,
+	}, nil
+}
+
+// Issue starts a new session for a user whose credentials have just been
+// checked.
+func (m *Manager) Issue(userId uuid.UUID) (*Tokens, error) {
+	now := m.now().UTC().Truncate(time.Second)
+
+	refreshToken, hash, err := newRefreshToken()
+	if err != nil {
+		log.Printf("Failed to generate refresh token. Err: %v", err)
+		return nil, err
+	}
+
+	err = m.store.InsertRefreshToken(&RefreshToken{
+		Hash: hash,
+		UserId: userId,
+		IssuedAt: now,
+		ExpiresAt: now.Add(m.refreshTtl),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accessExpiresAt := now.Add(m.accessTtl)
+	return &Tokens{
+		UserId: userId,
+		AccessToken: m.signAccessToken(userId, accessExpiresAt),
+		AccessExpiresAt: accessExpiresAt,
+		RefreshToken: refreshToken,
+		RefreshExpiresAt: now.Add(m.refreshTtl),
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new pair, revoking the old
+// refresh token. A refresh token is only good once, so seeing a revoked
+// one again means it was copied; every session for that user is revoked
+// rather than guess which holder is the real one.
+//
+// Two refreshes racing with the same token can both read it as live, so
+// only the one whose revoke actually flips it goes on to get a new pair;
+// the other is treated as reuse.
+func (m *Manager) Refresh(refreshToken string) (*Tokens, error) {
+	hash := hashRefreshToken(refreshToken)
+	token, err := m.store.GetRefreshToken(hash)
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, ErrInvalidToken
+	}
+
+	if token.Revoked {
+		return nil, m.revokeReused(token.UserId)
+	}
+	if !m.now().Before(token.ExpiresAt) {
+		return nil, ErrExpiredToken
+	}
+
+	revoked, err := m.store.RevokeRefreshToken(hash)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, m.revokeReused(token.UserId)
+	}
+	return m.Issue(token.UserId)
+}
+
+func (m *Manager) revokeReused(userId uuid.UUID) error {
+	log.Printf("Revoked refresh token reused for user %v; revoking all sessions.", userId)
+	if err := m.store.RevokeUserRefreshTokens(userId); err != nil {
+		return err
+	}
+	return ErrRevokedToken
+}
+
+// Revoke ends the session a refresh token belongs to, i.e. logs out one
+// device.
+func (m *Manager) Revoke(refreshToken string) error {
+	hash := hashRefreshToken(refreshToken)
+	token, err := m.store.GetRefreshToken(hash)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return ErrInvalidToken
+	}
+	_, err = m.store.RevokeRefreshToken(hash)
+	return err
+}
+
+// RevokeAll ends every session a user has, i.e. logs out everywhere.
+func (m *Manager) RevokeAll(userId uuid.UUID) error {
+	return m.store.RevokeUserRefreshTokens(userId)
+}
+
+// Authenticate checks an access token's signature and expiry and returns
+// the user it was issued to.
+func (m *Manager) Authenticate(accessToken string) (uuid.UUID, error) {
+	parts := strings.Split(accessToken, ".")
+	if len(parts) != 2 {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil || len(payload) != ACCESS_PAYLOAD_LEN {
+		return uuid.Nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, m.sign(payload)) {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	userId, err := uuid.FromBytes(payload[:16])
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	expiresAt := time.Unix(int64(binary.BigEndian.Uint64(payload[16:])), 0)
+	if !m.now().Before(expiresAt) {
+		return uuid.Nil, ErrExpiredToken
+	}
+
+	return userId, nil
+}
+
+func (m *Manager) signAccessToken(userId uuid.UUID, expiresAt time.Time) string {
+	payload := make([]byte, ACCESS_PAYLOAD_LEN)
+	copy(payload, userId.Bytes())
+	binary.BigEndian.PutUint64(payload[16:], uint64(expiresAt.Unix()))
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(m.sign(payload))
+}
+
+func (m *Manager) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func newRefreshToken() (string, []byte, error) {
+	raw := make([]byte, REFRESH_TOKEN_LEN)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+type MysqlSessions struct {
+	conn *sql.DB
+}
+
+type DbCredentials struct {
+	User string
+	Password string
+	Host string
+	Port string
+}
+
+func NewMysqlSessions(credentials *DbCredentials) (*MysqlSessions, error) {
+	dsn := credentials.User + ":" + credentials.Password + "@tcp(" +
+		credentials.Host + ":" + credentials.Port + ")/geonote?parseTime=true"
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Print("Failed to open db:", err)
+		return nil, err
+	}
+
+	return &MysqlSessions{conn: db}, nil
+}
+
+func (db MysqlSessions) InsertRefreshToken(token *RefreshToken) error {
+	insertSql := "INSERT INTO refresh_tokens " +
+		" (hash, user_id, issued_at, expires_at, revoked) VALUES " +
+		" (?, ?, ?, ?, ?) "
+
+	statement, err := db.conn.Prepare(insertSql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", insertSql, err)
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(
+		token.Hash,
+		token.UserId.String(),
+		token.IssuedAt,
+		token.ExpiresAt,
+		token.Revoked,
+	)
+	if err != nil {
+		log.Printf("Failed to insert refresh token for user %v. Err: %v", token.UserId, err)
+		return err
+	}
+
+	return nil
+}
+
+// GetRefreshToken returns the token with the given hash, or nil if there
+// is none.
+func (db MysqlSessions) GetRefreshToken(hash []byte) (*RefreshToken, error) {
+	sql := "SELECT hash, user_id, issued_at, expires_at, revoked FROM refresh_tokens WHERE hash = ?"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(hash)
+	if err != nil {
+		log.Printf("Failed to query for refresh token. Err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	var token RefreshToken
+	err = rows.Scan(
+		&token.Hash,
+		&token.UserId,
+		&token.IssuedAt,
+		&token.ExpiresAt,
+		&token.Revoked,
+	)
+	if err != nil {
+		log.Printf("Failed to scan row while fetching refresh token. Err: %v", err)
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RevokeRefreshToken reports whether this call is the one that revoked
+// the token. Revoking an already revoked token is not an error, but
+// returns false.
+func (db MysqlSessions) RevokeRefreshToken(hash []byte) (bool, error) {
+	sql := "UPDATE refresh_tokens SET revoked = TRUE WHERE hash = ? AND revoked = FALSE"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(hash)
+	if err != nil {
+		log.Printf("Failed to revoke refresh token. Err: %v", err)
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Failed to count revoked refresh tokens. Err: %v", err)
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (db MysqlSessions) RevokeUserRefreshTokens(userId uuid.UUID) error {
+	sql := "UPDATE refresh_tokens SET revoked = TRUE WHERE user_id = ? AND revoked = FALSE"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return err
+	}
+	defer statement.Close()
+
+	if _, err = statement.Exec(userId.String()); err != nil {
+		log.Printf("Failed to revoke refresh tokens for user %v. Err: %v", userId, err)
+		return err
+	}
+
+	return nil
+}
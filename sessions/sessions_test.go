@@ -0,0 +1,213 @@
+package sessions
+
+import (
+	"testing"
+	"strings"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+func TestIssueAndAuthenticate(t *testing.T) {
+	m, _ := getTestManager(t)
+	userId := uuid.NewV4()
+
+	tokens, err := m.Issue(userId)
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+
+	authenticated, err := m.Authenticate(tokens.AccessToken)
+	if err != nil {
+		t.Fatal("Failed to authenticate a fresh access token. Err: ", err)
+	}
+	if authenticated != userId {
+		t.Fatal("Authenticated as ", authenticated, " but tokens were issued to ", userId)
+	}
+}
+
+func TestAuthenticateRejectsBadTokens(t *testing.T) {
+	m, clock := getTestManager(t)
+	tokens, err := m.Issue(uuid.NewV4())
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+
+	other, err := NewManager(NewMemorySessions(), []byte(strings.Repeat("k", MIN_KEY_LEN)), Options{})
+	if err != nil {
+		t.Fatal("Failed to make manager. Err: ", err)
+	}
+	foreign, err := other.Issue(uuid.NewV4())
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+
+	parts := strings.Split(tokens.AccessToken, ".")
+	for _, token := range []string{
+		"",
+		"garbage",
+		parts[0],
+		parts[0] + "." + parts[0],
+		foreign.AccessToken,
+		tokens.RefreshToken,
+	} {
+		if _, err = m.Authenticate(token); err != ErrInvalidToken {
+			t.Fatal("Expected ErrInvalidToken for ", token, ", got: ", err)
+		}
+	}
+
+	*clock = clock.Add(DEFAULT_ACCESS_TTL)
+	if _, err = m.Authenticate(tokens.AccessToken); err != ErrExpiredToken {
+		t.Fatal("Expected ErrExpiredToken, got: ", err)
+	}
+}
+
+func TestRefreshRotates(t *testing.T) {
+	m, clock := getTestManager(t)
+	userId := uuid.NewV4()
+
+	tokens, err := m.Issue(userId)
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+
+	*clock = clock.Add(DEFAULT_ACCESS_TTL)
+	refreshed, err := m.Refresh(tokens.RefreshToken)
+	if err != nil {
+		t.Fatal("Failed to refresh. Err: ", err)
+	}
+	if refreshed.RefreshToken == tokens.RefreshToken {
+		t.Fatal("Refresh did not issue a new refresh token.")
+	}
+	if authenticated, err := m.Authenticate(refreshed.AccessToken); err != nil || authenticated != userId {
+		t.Fatal("Refreshed access token did not authenticate. Err: ", err)
+	}
+
+	// Reusing the old refresh token looks like theft, so it also kills the
+	// new one.
+	if _, err = m.Refresh(tokens.RefreshToken); err != ErrRevokedToken {
+		t.Fatal("Expected ErrRevokedToken on reuse, got: ", err)
+	}
+	if _, err = m.Refresh(refreshed.RefreshToken); err != ErrRevokedToken {
+		t.Fatal("Expected reuse to revoke the rotated token, got: ", err)
+	}
+}
+
+func TestRefreshRaceCountsAsReuse(t *testing.T) {
+	store := &racingSessions{MemorySessions: NewMemorySessions()}
+	m, err := NewManager(store, []byte(strings.Repeat("s", MIN_KEY_LEN)), Options{})
+	if err != nil {
+		t.Fatal("Failed to make manager. Err: ", err)
+	}
+	userId := uuid.NewV4()
+
+	tokens, err := m.Issue(userId)
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+	other, err := m.Issue(userId)
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+
+	// Another refresh revokes the token after this one has read it as
+	// live, so this one must not mint a second pair.
+	store.race = true
+	if _, err = m.Refresh(tokens.RefreshToken); err != ErrRevokedToken {
+		t.Fatal("Expected ErrRevokedToken when losing a refresh race, got: ", err)
+	}
+	store.race = false
+	if _, err = m.Refresh(other.RefreshToken); err != ErrRevokedToken {
+		t.Fatal("Expected losing a refresh race to revoke the user's other sessions, got: ", err)
+	}
+}
+
+func TestRefreshExpired(t *testing.T) {
+	m, clock := getTestManager(t)
+	tokens, err := m.Issue(uuid.NewV4())
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+
+	*clock = clock.Add(DEFAULT_REFRESH_TTL)
+	if _, err = m.Refresh(tokens.RefreshToken); err != ErrExpiredToken {
+		t.Fatal("Expected ErrExpiredToken, got: ", err)
+	}
+
+	if _, err = m.Refresh("not-a-token"); err != ErrInvalidToken {
+		t.Fatal("Expected ErrInvalidToken, got: ", err)
+	}
+}
+
+func TestRevokeAndRevokeAll(t *testing.T) {
+	m, _ := getTestManager(t)
+	userId := uuid.NewV4()
+
+	phone, err := m.Issue(userId)
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+	someoneElse, err := m.Issue(uuid.NewV4())
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+
+	if err = m.Revoke(phone.RefreshToken); err != nil {
+		t.Fatal("Failed to revoke. Err: ", err)
+	}
+	if _, err = m.Refresh(phone.RefreshToken); err != ErrRevokedToken {
+		t.Fatal("Expected ErrRevokedToken after revoke, got: ", err)
+	}
+
+	tablet, err := m.Issue(userId)
+	if err != nil {
+		t.Fatal("Failed to issue tokens. Err: ", err)
+	}
+	if err = m.RevokeAll(userId); err != nil {
+		t.Fatal("Failed to revoke all. Err: ", err)
+	}
+	if _, err = m.Refresh(tablet.RefreshToken); err != ErrRevokedToken {
+		t.Fatal("Expected ErrRevokedToken after revoke all, got: ", err)
+	}
+	if _, err = m.Refresh(someoneElse.RefreshToken); err != nil {
+		t.Fatal("Revoke all revoked another user's session. Err: ", err)
+	}
+
+	if err = m.Revoke("not-a-token"); err != ErrInvalidToken {
+		t.Fatal("Expected ErrInvalidToken, got: ", err)
+	}
+}
+
+func TestNewManagerRejectsShortKey(t *testing.T) {
+	if _, err := NewManager(NewMemorySessions(), []byte("short"), Options{}); err == nil {
+		t.Fatal("Accepted a short signing key.")
+	}
+}
+
+// getTestManager returns a manager over a memory store whose clock only
+// moves when the test moves it.
+func getTestManager(t *testing.T) (*Manager, *time.Time) {
+	m, err := NewManager(NewMemorySessions(), []byte(strings.Repeat("s", MIN_KEY_LEN)), Options{})
+	if err != nil {
+		t.Fatal("Failed to make manager. Err: ", err)
+	}
+
+	clock := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return clock }
+	return m, &clock
+}
+
+// racingSessions revokes a token straight after handing it out, as a
+// concurrent refresh with the same token would.
+type racingSessions struct {
+	*MemorySessions
+	race bool
+}
+
+func (db *racingSessions) GetRefreshToken(hash []byte) (*RefreshToken, error) {
+	token, err := db.MemorySessions.GetRefreshToken(hash)
+	if err == nil && token != nil && db.race {
+		_, err = db.MemorySessions.RevokeRefreshToken(hash)
+	}
+	return token, err
+}
@@ -0,0 +1,204 @@
+package solrnotes
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
+)
+
+// EARTH_RADIUS_KM matches the radius Solr's geofilt uses.
+const EARTH_RADIUS_KM = 6371.0087714
+
+// MemorySolr is an in-memory SolrConnection for tests in packages that sit
+// on top of solrnotes and shouldn't need a live Solr.
+type MemorySolr struct {
+	mutex sync.Mutex
+	docs map[uuid.UUID]Document
+	alias string
+}
+
+func NewMemorySolr() *MemorySolr {
+	return &MemorySolr{docs: make(map[uuid.UUID]Document)}
+}
+
+func (sc *MemorySolr) AddDoc(doc Document) error {
+	return sc.AddDocs([]Document{doc})
+}
+
+func (sc *MemorySolr) AddDocs(docs []Document) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	for _, doc := range docs {
+		sc.docs[doc.id] = doc
+	}
+	return nil
+}
+
+func (sc *MemorySolr) FindDocsNearby(
+	recipient uuid.UUID,
+	contactIds []uuid.UUID,
+	excludeSenders []uuid.UUID,
+	latitude float64,
+	longitude float64,
+	radiusKm float64,
+	maxRows int) ([]*Document, error) {
+	nearby := nearbyMatcher(recipient, contactIds, excludeSenders, latitude, longitude, radiusKm)
+	return limit(sc.matching(nearby), maxRows), nil
+}
+
+func (sc *MemorySolr) CountUnreadNearby(
+	recipient uuid.UUID,
+	contactIds []uuid.UUID,
+	excludeSenders []uuid.UUID,
+	latitude float64,
+	longitude float64,
+	radiusKm float64) (int, error) {
+	nearby := nearbyMatcher(recipient, contactIds, excludeSenders, latitude, longitude, radiusKm)
+	docs := sc.matching(func(doc *Document) bool {
+		return nearby(doc) && !doc.ReadBy(recipient) &&
+			(doc.HasRecipient(recipient) || doc.sender != recipient)
+	})
+	return len(docs), nil
+}
+
+func (sc *MemorySolr) GetDoc(id uuid.UUID) (*Document, error) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	doc, ok := sc.docs[id]
+	if !ok {
+		return nil, errors.New("Could not find any document for id: " + id.String())
+	}
+	return &doc, nil
+}
+
+func (sc *MemorySolr) GetDocsInIdRange(
+	afterId uuid.UUID,
+	throughId uuid.UUID,
+	maxRows int) ([]*Document, error) {
+	docs := sc.matching(func(doc *Document) bool {
+		return doc.id.String() > afterId.String() && doc.id.String() <= throughId.String()
+	})
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].id.String() < docs[j].id.String()
+	})
+	return limit(docs, maxRows), nil
+}
+
+func (sc *MemorySolr) PurgeDocs(ids []uuid.UUID) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	for _, id := range ids {
+		delete(sc.docs, id)
+	}
+	return nil
+}
+
+func (sc *MemorySolr) MarkDocDeleted(id uuid.UUID) error {
+	return sc.update(id, func(doc *Document) { doc.deleted = true })
+}
+
+func (sc *MemorySolr) MarkDocDeletedFor(id uuid.UUID, recipient uuid.UUID) error {
+	return sc.update(id, func(doc *Document) { doc.deletedFor = addId(doc.deletedFor, recipient) })
+}
+
+func (sc *MemorySolr) MarkDocRead(id uuid.UUID, user uuid.UUID) error {
+	return sc.update(id, func(doc *Document) { doc.readBy = addId(doc.readBy, user) })
+}
+
+func (sc *MemorySolr) PointAlias(alias string) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	sc.alias = alias
+	return nil
+}
+
+// Alias returns the alias last passed to PointAlias.
+func (sc *MemorySolr) Alias() string {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	return sc.alias
+}
+
+func (sc *MemorySolr) update(id uuid.UUID, apply func(doc *Document)) error {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	doc, ok := sc.docs[id]
+	if !ok {
+		return errors.New("Could not find any document for id: " + id.String())
+	}
+	apply(&doc)
+	sc.docs[id] = doc
+	return nil
+}
+
+// matching returns copies of every doc for which keep returns true.
+func (sc *MemorySolr) matching(keep func(doc *Document) bool) []*Document {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	var docs []*Document
+	for _, doc := range sc.docs {
+		doc := doc
+		if keep(&doc) {
+			docs = append(docs, &doc)
+		}
+	}
+	return docs
+}
+
+// nearbyMatcher matches what SolrNoteConnection's nearbyFilters do.
+func nearbyMatcher(
+	recipient uuid.UUID,
+	contactIds []uuid.UUID,
+	excludeSenders []uuid.UUID,
+	latitude float64,
+	longitude float64,
+	radiusKm float64) func(doc *Document) bool {
+	excluded := make(map[uuid.UUID]bool)
+	for _, sender := range excludeSenders {
+		excluded[sender] = true
+	}
+	contacts := map[uuid.UUID]bool{recipient: true}
+	for _, contact := range contactIds {
+		contacts[contact] = true
+	}
+	return func(doc *Document) bool {
+		visible := doc.HasRecipient(recipient) ||
+			doc.visibility == notesdb.VISIBILITY_PUBLIC ||
+			(doc.visibility == notesdb.VISIBILITY_CONTACTS && contacts[doc.sender])
+		return visible &&
+			!doc.DeletedFor(recipient) &&
+			!excluded[doc.sender] &&
+			(distanceKm(latitude, longitude, doc.latitude, doc.longitude) <= radiusKm ||
+				(doc.direct && doc.HasRecipient(recipient)))
+	}
+}
+
+func limit(docs []*Document, maxRows int) []*Document {
+	if maxRows < len(docs) {
+		return docs[:maxRows]
+	}
+	return docs
+}
+
+// distanceKm is the great-circle distance between two points, computed
+// with the haversine formula as Solr does.
+func distanceKm(lat1 float64, lon1 float64, lat2 float64, lon2 float64) float64 {
+	toRadians := math.Pi / 180
+	dLat := (lat2 - lat1) * toRadians
+	dLon := (lon2 - lon1) * toRadians
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRadians)*math.Cos(lat2*toRadians)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EARTH_RADIUS_KM * math.Asin(math.Sqrt(a))
+}
@@ -6,58 +6,259 @@ import (
 	"errors"
 	"strings"
 	"strconv"
+	"net/http"
+	"net/url"
 	
 	"github.com/rtt/Go-Solr"
 	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
 )
 
 type SolrConnection interface {
 	AddDoc(doc Document) error
+	AddDocs(docs []Document) error
 	FindDocsNearby(
 		recipient uuid.UUID,
+		contactIds []uuid.UUID,
+		excludeSenders []uuid.UUID,
 		latitude float64, 
 		longitude float64, 
 		radiusKm float64,
 		maxRows int) ([]*Document, error)
+	CountUnreadNearby(
+		recipient uuid.UUID,
+		contactIds []uuid.UUID,
+		excludeSenders []uuid.UUID,
+		latitude float64,
+		longitude float64,
+		radiusKm float64) (int, error)
 	GetDoc(id uuid.UUID) (*Document, error)
+	GetDocsInIdRange(afterId uuid.UUID, throughId uuid.UUID, maxRows int) ([]*Document, error)
 	PurgeDocs(ids []uuid.UUID) error
 	MarkDocDeleted(id uuid.UUID) error
-	MarkDocRead(id uuid.UUID) error
+	MarkDocDeletedFor(id uuid.UUID, recipient uuid.UUID) error
+	MarkDocRead(id uuid.UUID, user uuid.UUID) error
+	PointAlias(alias string) error
 }
 
 type SolrNoteConnection struct {
 	conn *solr.Connection
+	host string
+	port int
+	core string
 }
 
+// Document is a note as it's indexed. A note left for several users is
+// one document, with the recipients who have read it and the ones who
+// have deleted it for themselves held alongside them. A shared note has
+// no recipients, and readBy holds everyone who has read it.
 type Document struct {
 	id uuid.UUID
 	sender uuid.UUID
-	recipient uuid.UUID
+	visibility string
+	recipients []uuid.UUID
 	latitude float64
 	longitude float64
 	timeSent time.Time
-	read bool
+	timeEdited time.Time
+	direct bool
+	readBy []uuid.UUID
+	deletedFor []uuid.UUID
 	deleted bool
 }
 
 const  (
 	ID = "id"
 	SENDER = "sender_s"
-	RECIPIENT = "recipient_s"
 	LOCATION = "location_p"
 	TIMESENT = "timeSent_dt"
-	READ = "read_b"
 	DELETED = "deleted_b"
 
+	// TIMEEDITED is only set on documents for notes that have been
+	// edited.
+	TIMEEDITED = "timeEdited_dt"
+
+	// DIRECT is only set on documents for direct replies, which their
+	// recipients find nearby wherever they are.
+	DIRECT = "direct_b"
+
+	// VISIBILITY is missing from documents indexed before notes could be
+	// shared, which are all private.
+	VISIBILITY = "visibility_s"
+
+	// Multivalued fields. Documents indexed before notes could have several
+	// recipients have recipient_s and read_b instead, so the index has to
+	// be rebuilt with the reindex tool after upgrading.
+	RECIPIENTS = "recipient_ss"
+	READ_BY = "readBy_ss"
+	DELETED_FOR = "deletedFor_ss"
+
+	// VERSION set to 1 in an update makes Solr reject it unless the doc
+	// already exists.
+	VERSION = "_version_"
+
 	ISO8601_LAYOUT = time.RFC3339
+
+	DEFAULT_HOST = "localhost"
+	DEFAULT_PORT = 8983
+	DEFAULT_CORE = "geonotes"
 )
 
 func NewSolrNoteConnection() (*SolrNoteConnection, error) {
-	conn, err := solr.Init("localhost", 8983, "geonotes")
+	return NewSolrNoteConnectionToCore(DEFAULT_HOST, DEFAULT_PORT, DEFAULT_CORE)
+}
+
+// NewSolrNoteConnectionToCore connects to a specific core (or collection),
+// e.g. a freshly created one that is being rebuilt by a reindex.
+func NewSolrNoteConnectionToCore(host string, port int, core string) (*SolrNoteConnection, error) {
+	conn, err := solr.Init(host, port, core)
 	if err != nil {
 		return nil, err
 	}
-	return &SolrNoteConnection{conn}, nil
+	return &SolrNoteConnection{conn: conn, host: host, port: port, core: core}, nil
+}
+
+func NewDocument(
+	id uuid.UUID,
+	sender uuid.UUID,
+	visibility string,
+	recipients []uuid.UUID,
+	latitude float64,
+	longitude float64,
+	timeSent time.Time,
+	readBy []uuid.UUID,
+	deletedFor []uuid.UUID,
+	deleted bool) Document {
+	return Document{
+		id: id,
+		sender: sender,
+		visibility: visibility,
+		recipients: recipients,
+		latitude: latitude,
+		longitude: longitude,
+		timeSent: timeSent,
+		readBy: readBy,
+		deletedFor: deletedFor,
+		deleted: deleted,
+	}
+}
+
+func (doc *Document) Id() uuid.UUID {
+	return doc.id
+}
+
+func (doc *Document) Sender() uuid.UUID {
+	return doc.sender
+}
+
+func (doc *Document) Visibility() string {
+	return doc.visibility
+}
+
+// Recipient returns the document's first recipient, which is its only one
+// unless the note was left for several users.
+func (doc *Document) Recipient() uuid.UUID {
+	if len(doc.recipients) == 0 {
+		return uuid.Nil
+	}
+	return doc.recipients[0]
+}
+
+func (doc *Document) Recipients() []uuid.UUID {
+	return append([]uuid.UUID(nil), doc.recipients...)
+}
+
+func (doc *Document) HasRecipient(recipient uuid.UUID) bool {
+	return containsId(doc.recipients, recipient)
+}
+
+func (doc *Document) Latitude() float64 {
+	return doc.latitude
+}
+
+func (doc *Document) Longitude() float64 {
+	return doc.longitude
+}
+
+func (doc *Document) TimeSent() time.Time {
+	return doc.timeSent
+}
+
+// TimeEdited is when the note was last edited, or the zero time if it
+// never has been.
+func (doc *Document) TimeEdited() time.Time {
+	return doc.timeEdited
+}
+
+// Direct is whether the doc is a reply delivered straight to its
+// recipients rather than left at a spot.
+func (doc *Document) Direct() bool {
+	return doc.direct
+}
+
+// Read is whether every recipient has read the note, or for a shared
+// note, whether anyone has.
+func (doc *Document) Read() bool {
+	if doc.visibility != notesdb.VISIBILITY_PRIVATE {
+		return len(doc.readBy) > 0
+	}
+	for _, recipient := range doc.recipients {
+		if !doc.ReadBy(recipient) {
+			return false
+		}
+	}
+	return true
+}
+
+// Readers returns the recipients who have read the doc, or for a shared
+// doc, everyone who has.
+func (doc *Document) Readers() []uuid.UUID {
+	return append([]uuid.UUID(nil), doc.readBy...)
+}
+
+func (doc *Document) ReadBy(user uuid.UUID) bool {
+	return containsId(doc.readBy, user)
+}
+
+// Deleted is whether the sender deleted the note, for every recipient.
+func (doc *Document) Deleted() bool {
+	return doc.deleted
+}
+
+func (doc *Document) DeletedFor(recipient uuid.UUID) bool {
+	return doc.deleted || containsId(doc.deletedFor, recipient)
+}
+
+// DocumentFromNote builds the search document for a note stored in
+// notesdb. The note text itself is never indexed.
+func DocumentFromNote(note *notesdb.Note) Document {
+	readBy := note.Readers()
+	var deletedFor []uuid.UUID
+	for _, recipient := range note.Recipients() {
+		if recipient.Read {
+			readBy = append(readBy, recipient.Id)
+		}
+		if recipient.Deleted {
+			deletedFor = append(deletedFor, recipient.Id)
+		}
+	}
+
+	doc := NewDocument(
+		note.Id(),
+		note.Sender(),
+		note.Visibility(),
+		note.RecipientIds(),
+		note.Latitude(),
+		note.Longitude(),
+		note.TimeSent(),
+		readBy,
+		deletedFor,
+		note.Deleted(),
+	)
+	doc.timeEdited = note.TimeEdited()
+	doc.direct = note.Direct()
+	return doc
 }
 
 func (sc SolrNoteConnection) AddDoc(doc Document) error {
@@ -75,23 +276,79 @@ func (sc SolrNoteConnection) AddDoc(doc Document) error {
 	return nil
 }
 
+// AddDocs indexes many documents with a single update and commit, which
+// is much faster than calling AddDoc for each one when bulk loading.
+func (sc SolrNoteConnection) AddDocs(docs []Document) error {
+	if len(docs) == 0 {
+		return nil
+	}
+
+	adds := make([]interface{}, len(docs))
+	for i, _ := range docs {
+		adds[i] = getDocJson(&docs[i])
+	}
+	update := map[string]interface{}{
+		"add": adds,
+	}
+
+	commit := true
+	_, err := sc.conn.Update(update, commit)
+	if err != nil {
+		log.Printf("Failed to add %v docs to solr. Error: %#v", len(docs), err)
+		return err
+	}
+
+	return nil
+}
+
+// PointAlias creates alias, or moves it if it already exists, so that it
+// refers to this connection's collection. Once a rebuilt collection is
+// complete, pointing the alias clients query at it swaps it in atomically.
+// This uses the Collections API, so Solr must be running in cloud mode.
+func (sc SolrNoteConnection) PointAlias(alias string) error {
+	params := url.Values{}
+	params.Set("action", "CREATEALIAS")
+	params.Set("name", alias)
+	params.Set("collections", sc.core)
+	aliasUrl := "http://" + sc.host + ":" + strconv.Itoa(sc.port) +
+		"/solr/admin/collections?" + params.Encode()
+
+	response, err := http.Get(aliasUrl)
+	if err != nil {
+		log.Printf("Failed to point alias %v at %v. Err: %v", alias, sc.core, err)
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		message := "Pointing alias " + alias + " at " + sc.core + " failed with status: " +
+			response.Status
+		log.Print(message)
+		return errors.New(message)
+	}
+
+	return nil
+}
+
+// FindDocsNearby returns up to maxRows of the docs recipient can see that
+// aren't deleted for them and are within radiusKm of a point, or are
+// direct replies to them, leaving out any sent by excludeSenders.
+// Recipient can see the docs addressed to them, alone or among others,
+// every public doc, and the contacts docs of contactIds and their own.
 func (sc SolrNoteConnection) FindDocsNearby(
 	recipient uuid.UUID,
+	contactIds []uuid.UUID,
+	excludeSenders []uuid.UUID,
 	latitude float64, 
 	longitude float64, 
 	radiusKm float64,
 	maxRows int) ([]*Document, error) {
 
-	geofilter := formatGeofilter(latitude, longitude, radiusKm)
-	
+	filters := nearbyFilters(recipient, contactIds, excludeSenders, latitude, longitude, radiusKm)
 	q := solr.Query{
 		Params: solr.URLParamMap{
 			"q": []string{"*:*"},
-			"fq": []string{
-				RECIPIENT + ":" + recipient.String(),
-				"!" + DELETED + ":" + "true",
-				geofilter,
-			},
+			"fq": filters,
 		},
 		Rows: maxRows,
 	}
@@ -106,6 +363,42 @@ func (sc SolrNoteConnection) FindDocsNearby(
 	return docsFromResults(results), nil
 }
 
+// CountUnreadNearby counts the docs FindDocsNearby would find that
+// recipient hasn't read, other than shared docs of their own. Only Solr's
+// numFound comes back, so it costs the same however many there are.
+func (sc SolrNoteConnection) CountUnreadNearby(
+	recipient uuid.UUID,
+	contactIds []uuid.UUID,
+	excludeSenders []uuid.UUID,
+	latitude float64,
+	longitude float64,
+	radiusKm float64) (int, error) {
+
+	filters := nearbyFilters(recipient, contactIds, excludeSenders, latitude, longitude, radiusKm)
+	filters = append(filters,
+		"!" + READ_BY + ":\"" + recipient.String() + "\"",
+		RECIPIENTS + ":\"" + recipient.String() + "\" OR (*:* -" + SENDER + ":\"" + recipient.String() + "\")",
+	)
+	// Rows is left out of the request when it's zero, so ask for no rows
+	// in the params instead.
+	q := solr.Query{
+		Params: solr.URLParamMap{
+			"q": []string{"*:*"},
+			"fq": filters,
+			"rows": []string{"0"},
+		},
+	}
+
+	response, err := sc.conn.Select(&q)
+	if err != nil {
+		log.Printf("Failed to count unread docs near (%v, %v) for %v. Err: %v",
+			latitude, longitude, recipient, err)
+		return 0, err
+	}
+
+	return response.Results.NumFound, nil
+}
+
 func (sc SolrNoteConnection) GetDoc(id uuid.UUID) (*Document, error) {
 	q := solr.Query{
 		Params: solr.URLParamMap{
@@ -141,6 +434,33 @@ func (sc SolrNoteConnection) GetDoc(id uuid.UUID) (*Document, error) {
 	return docs[0], nil
 }
 
+// GetDocsInIdRange returns up to maxRows documents with ids in the range
+// (afterId, throughId], ordered by id, for walking the index in step with
+// notesdb.GetNotesAfterId.
+func (sc SolrNoteConnection) GetDocsInIdRange(
+	afterId uuid.UUID,
+	throughId uuid.UUID,
+	maxRows int) ([]*Document, error) {
+	q := solr.Query{
+		Params: solr.URLParamMap{
+			"q": []string{"*:*"},
+			"fq": []string{
+				ID + ":{\"" + afterId.String() + "\" TO \"" + throughId.String() + "\"]",
+			},
+			"sort": []string{ID + " asc"},
+		},
+		Rows: maxRows,
+	}
+
+	response, err := sc.conn.Select(&q)
+	if err != nil {
+		log.Printf("Failed to get docs in id range (%v, %v]. Err: %v", afterId, throughId, err)
+		return nil, err
+	}
+
+	return docsFromResults(response.Results), nil
+}
+
 func (sc SolrNoteConnection) PurgeDocs(ids []uuid.UUID) error {
 	deleteIds := make([]string, len(ids))
 	for i, id := range ids {
@@ -161,42 +481,46 @@ func (sc SolrNoteConnection) PurgeDocs(ids []uuid.UUID) error {
 	return nil
 }
 
+// MarkDocDeleted deletes the doc for every recipient, as its sender does.
 func (sc SolrNoteConnection) MarkDocDeleted(id uuid.UUID) error {
-	doc, err := sc.GetDoc(id)
-	if err != nil {
-		log.Println("Failed to get doc to mark deleted with id: ", id, " Err: ", err)
-	}
-
-	doc.deleted = true
-	update := getUpdateJson(doc)
-
-	commit := true
-	_, err = sc.conn.Update(update, commit)
+	return sc.updateField(id, DELETED, "set", true)
+}
 
-	if err != nil {
-		log.Printf("Failed to mark doc deleted in solr. Id: %v, Error: %#v", 
-			id.String(), err)
-		return err
-	}
+func (sc SolrNoteConnection) MarkDocDeletedFor(id uuid.UUID, recipient uuid.UUID) error {
+	return sc.updateField(id, DELETED_FOR, "add-distinct", recipient.String())
+}
 
-	return nil
+// MarkDocRead marks the doc read by one of its recipients, or adds a
+// reader to a shared doc.
+func (sc SolrNoteConnection) MarkDocRead(id uuid.UUID, user uuid.UUID) error {
+	return sc.updateField(id, READ_BY, "add-distinct", user.String())
 }
 
-func (sc SolrNoteConnection) MarkDocRead(id uuid.UUID) error {
-	doc, err := sc.GetDoc(id)
-	if err != nil {
-		log.Println("Failed to get doc to mark deleted with id: ", id, " Err: ", err)
+// updateField changes one field of the doc with an atomic update, so
+// recipients of a group note who read or delete it at the same time
+// don't overwrite each other's entries. It fails if the doc isn't
+// indexed, rather than indexing a doc with nothing but that field.
+func (sc SolrNoteConnection) updateField(
+	id uuid.UUID,
+	field string,
+	op string,
+	value interface{}) error {
+	update := map[string]interface{}{
+		"add": []interface{}{
+			map[string]interface{}{
+				ID: id.String(),
+				VERSION: 1,
+				field: map[string]interface{}{op: value},
+			},
+		},
 	}
 
-	doc.read = true
-	update := getUpdateJson(doc)
-
 	commit := true
-	_, err = sc.conn.Update(update, commit)
+	_, err := sc.conn.Update(update, commit)
 
 	if err != nil {
-		log.Printf("Failed to mark doc deleted in solr. Id: %v, Error: %#v", 
-			id.String(), err)
+		log.Printf("Failed to update doc in solr. Id: %v, Field: %v, Error: %#v",
+			id.String(), field, err)
 		return err
 	}
 
@@ -221,9 +545,26 @@ func docsFromResults(results *solr.DocumentCollection) []*Document {
 			continue
 		}		
 
-		docs[i].recipient, err = uuid.FromString(currDoc.Field(RECIPIENT).(string))
+		docs[i].visibility = notesdb.VISIBILITY_PRIVATE
+		if visibility, ok := currDoc.Field(VISIBILITY).(string); ok {
+			docs[i].visibility = visibility
+		}
+
+		docs[i].recipients, err = idsFromField(currDoc.Field(RECIPIENTS))
+		if err != nil {
+			log.Print("Failed to parse recipient ids. Src ids:", currDoc.Field(RECIPIENTS))
+			continue
+		}
+
+		docs[i].readBy, err = idsFromField(currDoc.Field(READ_BY))
+		if err != nil {
+			log.Print("Failed to parse reader ids. Src ids:", currDoc.Field(READ_BY))
+			continue
+		}
+
+		docs[i].deletedFor, err = idsFromField(currDoc.Field(DELETED_FOR))
 		if err != nil {
-			log.Print("Failed to parse recipient id. Src id:", currDoc.Field(RECIPIENT))
+			log.Print("Failed to parse deleted-for ids. Src ids:", currDoc.Field(DELETED_FOR))
 			continue
 		}
 
@@ -240,13 +581,71 @@ func docsFromResults(results *solr.DocumentCollection) []*Document {
 			continue
 		}
 
-		docs[i].read = currDoc.Field(READ).(bool)
+		if timeEdited, ok := currDoc.Field(TIMEEDITED).(string); ok {
+			docs[i].timeEdited, err = time.Parse(ISO8601_LAYOUT, timeEdited)
+			if err != nil {
+				log.Print("Failed to parse time edited: ", err)
+				continue
+			}
+		}
+
+		docs[i].direct, _ = currDoc.Field(DIRECT).(bool)
 		docs[i].deleted = currDoc.Field(DELETED).(bool)
 	}
 
 	return docPointers(docs)
 }
 
+// idsFromField parses a multivalued id field, which is missing altogether
+// when it has no values.
+func idsFromField(value interface{}) ([]uuid.UUID, error) {
+	if value == nil {
+		return nil, nil
+	}
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, errors.New("Expected a multivalued field.")
+	}
+
+	ids := make([]uuid.UUID, len(values))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.New("Expected a string id.")
+		}
+		id, err := uuid.FromString(s)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+func containsId(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+func addId(ids []uuid.UUID, id uuid.UUID) []uuid.UUID {
+	if containsId(ids, id) {
+		return ids
+	}
+	return append(append([]uuid.UUID(nil), ids...), id)
+}
+
+func idStrings(ids []uuid.UUID) []string {
+	results := make([]string, len(ids))
+	for i, id := range ids {
+		results[i] = id.String()
+	}
+	return results
+}
+
 func getCoordinateString(doc Document) string {
 	return formatCoordinateFloat(doc.latitude) + "," + formatCoordinateFloat(doc.longitude)
 }
@@ -274,6 +673,46 @@ func coordinatesFromString(s string) (float64, float64, error) {
 	return lat, lon, nil
 }
 
+// formatIdList formats ids as a query for any one of them, e.g.
+// ("a" OR "b").
+func formatIdList(ids []uuid.UUID) string {
+	quoted := make([]string, len(ids))
+	for i, id := range ids {
+		quoted[i] = "\"" + id.String() + "\""
+	}
+	return "(" + strings.Join(quoted, " OR ") + ")"
+}
+
+// nearbyFilters are the filter queries for the docs recipient can see
+// that aren't deleted for them and are within radiusKm of a point or are
+// direct replies to them, less any sent by excludeSenders. The geofilt is
+// nested with _query_ so it can be ORed with the direct clause.
+func nearbyFilters(
+	recipient uuid.UUID,
+	contactIds []uuid.UUID,
+	excludeSenders []uuid.UUID,
+	latitude float64,
+	longitude float64,
+	radiusKm float64) []string {
+	contactsSenders := append([]uuid.UUID{recipient}, contactIds...)
+	visible := "(" + RECIPIENTS + ":\"" + recipient.String() + "\"" +
+		" OR " + VISIBILITY + ":" + notesdb.VISIBILITY_PUBLIC +
+		" OR (" + VISIBILITY + ":" + notesdb.VISIBILITY_CONTACTS +
+		" AND " + SENDER + ":" + formatIdList(contactsSenders) + "))"
+
+	filters := []string{
+		visible,
+		"!" + DELETED + ":" + "true",
+		"!" + DELETED_FOR + ":\"" + recipient.String() + "\"",
+		"_query_:\"" + formatGeofilter(latitude, longitude, radiusKm) + "\"" +
+			" OR (" + DIRECT + ":true AND " + RECIPIENTS + ":\"" + recipient.String() + "\")",
+	}
+	if len(excludeSenders) > 0 {
+		filters = append(filters, "!" + SENDER + ":" + formatIdList(excludeSenders))
+	}
+	return filters
+}
+
 func formatGeofilter(lat float64, lon float64, radiusKm float64) string {
 	latStr := formatCoordinateFloat(lat)
 	lonStr := formatCoordinateFloat(lon)
@@ -294,15 +733,28 @@ func docPointers(docs []Document) []*Document {
 func getUpdateJson(doc *Document) map[string]interface{} {
 	return map[string]interface{}{
 		"add": []interface{}{
-			map[string]interface{}{
-				ID: doc.id.String(),
-				SENDER: doc.sender.String(),
-				RECIPIENT: doc.recipient.String(),
-				LOCATION: getCoordinateString(*doc),
-				TIMESENT: doc.timeSent.Format(ISO8601_LAYOUT),
-				READ: doc.read,
-				DELETED: doc.deleted,
-			},
+			getDocJson(doc),
 		},
 	}
 }
+
+func getDocJson(doc *Document) map[string]interface{} {
+	docJson := map[string]interface{}{
+		ID: doc.id.String(),
+		SENDER: doc.sender.String(),
+		VISIBILITY: doc.visibility,
+		RECIPIENTS: idStrings(doc.recipients),
+		LOCATION: getCoordinateString(*doc),
+		TIMESENT: doc.timeSent.Format(ISO8601_LAYOUT),
+		READ_BY: idStrings(doc.readBy),
+		DELETED_FOR: idStrings(doc.deletedFor),
+		DELETED: doc.deleted,
+	}
+	if !doc.timeEdited.IsZero() {
+		docJson[TIMEEDITED] = doc.timeEdited.Format(ISO8601_LAYOUT)
+	}
+	if doc.direct {
+		docJson[DIRECT] = true
+	}
+	return docJson
+}
@@ -4,8 +4,11 @@ import (
 	"testing"
 	"time"
 	"sort"
+	"sync"
 
 	"github.com/satori/go.uuid"
+
+	"github.com/dbenny42/geonote/notesdb"
 )
 
 func TestAddDoc(t *testing.T) {
@@ -31,6 +34,33 @@ func TestAddDoc(t *testing.T) {
 	}
 }
 
+func TestAddDocs(t *testing.T) {
+	conn, err := NewSolrNoteConnection()
+	if err != nil {
+		t.Fatal()
+	}
+
+	sender := uuid.NewV4()
+	recipient := uuid.NewV4()
+	docs := []Document{getTestDoc(sender, recipient), getTestDoc(sender, recipient)}
+	err = conn.AddDocs(docs)
+	if err != nil {
+		t.Fatal("Add docs failed.")
+	}
+	defer conn.PurgeDocs([]uuid.UUID{docs[0].id, docs[1].id})
+
+	for _, doc := range docs {
+		result, err := conn.GetDoc(doc.id)
+		if err != nil {
+			t.Fatal("Get doc failed.")
+		}
+
+		if !docsEqual(doc, *result) {
+			t.Fatal("Result doc did not match original doc.")
+		}
+	}
+}
+
 func TestFindDocsNearby(t *testing.T) {
 	conn, err := NewSolrNoteConnection()
 	if err != nil {
@@ -56,7 +86,7 @@ func TestFindDocsNearby(t *testing.T) {
 	searchLon := -73.944587
 	searchRadiusKm := .5
 	maxRows := 10
-	results, err := conn.FindDocsNearby(recipient, searchLat, searchLon, searchRadiusKm, maxRows)
+	results, err := conn.FindDocsNearby(recipient, nil, nil, searchLat, searchLon, searchRadiusKm, maxRows)
 	if err != nil {
 		t.Fatal("Error from FindDocsNearby: ", err)
 	}
@@ -93,7 +123,7 @@ func TestFindDocsIgnoresDeleted(t *testing.T) {
 	searchLon := -73.944587
 	searchRadiusKm := .5
 	maxRows := 10
-	results, err := conn.FindDocsNearby(recipient, searchLat, searchLon, searchRadiusKm, maxRows)
+	results, err := conn.FindDocsNearby(recipient, nil, nil, searchLat, searchLon, searchRadiusKm, maxRows)
 	if err != nil {
 		t.Fatal("Error from FindDocsNearby: ", err)
 	}
@@ -104,6 +134,185 @@ func TestFindDocsIgnoresDeleted(t *testing.T) {
 	}
 }
 
+func TestFindDocsForAnyRecipient(t *testing.T) {
+	conn, err := NewSolrNoteConnection()
+	if err != nil {
+		t.Fatalf("Failed to connect to solr. Err: %v", err)
+	}
+
+	sender := uuid.NewV4()
+	first := uuid.NewV4()
+	second := uuid.NewV4()
+	doc := getTestDocAtLocation(sender, first, 40.810260, -73.94694)
+	doc.recipients = append(doc.recipients, second)
+	if err = conn.AddDoc(doc); err != nil {
+		t.Fatalf("Failed to add doc. Err: %v", err)
+	}
+	defer conn.PurgeDocs([]uuid.UUID{doc.id})
+
+	if err = conn.MarkDocDeletedFor(doc.id, first); err != nil {
+		t.Fatal("Failed to mark doc deleted. Id:", doc.id, "Err:", err)
+	}
+
+	results, err := conn.FindDocsNearby(first, nil, nil, 40.809322, -73.944587, .5, 10)
+	if err != nil || len(results) != 0 {
+		t.Fatal("Expected nothing for the recipient who deleted it, got: ", results, " Err: ", err)
+	}
+
+	results, err = conn.FindDocsNearby(second, nil, nil, 40.809322, -73.944587, .5, 10)
+	if err != nil || len(results) != 1 || !results[0].DeletedFor(first) || results[0].DeletedFor(second) {
+		t.Fatal("Expected the doc for the other recipient, got: ", results, " Err: ", err)
+	}
+}
+
+func TestFindSharedDocs(t *testing.T) {
+	conn, err := NewSolrNoteConnection()
+	if err != nil {
+		t.Fatalf("Failed to connect to solr. Err: %v", err)
+	}
+
+	sender := uuid.NewV4()
+	contact := uuid.NewV4()
+	stranger := uuid.NewV4()
+	public := getTestDocAtLocation(sender, uuid.Nil, 40.810260, -73.94694)
+	public.visibility = notesdb.VISIBILITY_PUBLIC
+	public.recipients = nil
+	contacts := getTestDocAtLocation(sender, uuid.Nil, 40.808612, -73.944443)
+	contacts.visibility = notesdb.VISIBILITY_CONTACTS
+	contacts.recipients = nil
+	if err = conn.AddDocs([]Document{public, contacts}); err != nil {
+		t.Fatalf("Failed to add docs. Err: %v", err)
+	}
+	defer conn.PurgeDocs([]uuid.UUID{public.id, contacts.id})
+
+	if err = conn.MarkDocRead(public.id, stranger); err != nil {
+		t.Fatal("Failed to mark doc read. Id:", public.id, "Err:", err)
+	}
+
+	results, err := conn.FindDocsNearby(contact, []uuid.UUID{sender}, nil, 40.809322, -73.944587, .5, 10)
+	if err != nil || len(results) != 2 {
+		t.Fatal("Expected both docs for a contact, got: ", results, " Err: ", err)
+	}
+
+	results, err = conn.FindDocsNearby(stranger, nil, nil, 40.809322, -73.944587, .5, 10)
+	if err != nil || len(results) != 1 || results[0].id != public.id ||
+		!results[0].ReadBy(stranger) || results[0].ReadBy(contact) {
+		t.Fatal("Expected only the public doc, read by the stranger, got: ", results, " Err: ", err)
+	}
+}
+
+func TestFindDirectDocs(t *testing.T) {
+	conn, err := NewSolrNoteConnection()
+	if err != nil {
+		t.Fatal("Failed to connect to solr. Err: ", err)
+	}
+
+	sender := uuid.NewV4()
+	recipient := uuid.NewV4()
+	direct := getTestDocAtLocation(sender, recipient, 40.7, -74.0)
+	direct.direct = true
+	anchored := getTestDocAtLocation(sender, recipient, 40.7, -74.0)
+	if err = conn.AddDocs([]Document{direct, anchored}); err != nil {
+		t.Fatal("Failed to add docs. Err: ", err)
+	}
+	defer conn.PurgeDocs([]uuid.UUID{direct.id, anchored.id})
+
+	// Both docs are well outside the radius, but a direct one reaches its
+	// recipient wherever they are.
+	results, err := conn.FindDocsNearby(recipient, nil, nil, 40.809322, -73.944587, .5, 10)
+	if err != nil || len(results) != 1 || results[0].id != direct.id || !results[0].Direct() {
+		t.Fatal("Expected only the direct doc, got: ", results, " Err: ", err)
+	}
+
+	results, err = conn.FindDocsNearby(sender, nil, nil, 40.809322, -73.944587, .5, 10)
+	if err != nil || len(results) != 0 {
+		t.Fatal("Expected nothing for someone the direct doc isn't for, got: ", results, " Err: ", err)
+	}
+}
+
+func TestCountUnreadNearby(t *testing.T) {
+	conn, err := NewSolrNoteConnection()
+	if err != nil {
+		t.Fatalf("Failed to connect to solr. Err: %v", err)
+	}
+
+	sender := uuid.NewV4()
+	recipient := uuid.NewV4()
+	unread := getTestDocAtLocation(sender, recipient, 40.810260, -73.94694)
+	read := getTestDocAtLocation(sender, recipient, 40.808612, -73.944443)
+	far := getTestDocAtLocation(sender, recipient, 40.7, -74.0)
+	public := getTestDocAtLocation(sender, uuid.Nil, 40.810260, -73.94694)
+	public.visibility = notesdb.VISIBILITY_PUBLIC
+	public.recipients = nil
+	own := getTestDocAtLocation(recipient, uuid.Nil, 40.810260, -73.94694)
+	own.visibility = notesdb.VISIBILITY_PUBLIC
+	own.recipients = nil
+	docs := []Document{unread, read, far, public, own}
+	if err = conn.AddDocs(docs); err != nil {
+		t.Fatalf("Failed to add docs. Err: %v", err)
+	}
+	defer conn.PurgeDocs([]uuid.UUID{unread.id, read.id, far.id, public.id, own.id})
+
+	if err = conn.MarkDocRead(read.id, recipient); err != nil {
+		t.Fatal("Failed to mark doc read. Id:", read.id, "Err:", err)
+	}
+
+	count, err := conn.CountUnreadNearby(recipient, nil, nil, 40.809322, -73.944587, .5)
+	if err != nil || count != 2 {
+		t.Fatal("Expected the unread and public docs to be counted, got: ", count, " Err: ", err)
+	}
+
+	count, err = conn.CountUnreadNearby(recipient, nil, []uuid.UUID{sender}, 40.809322, -73.944587, .5)
+	if err != nil || count != 0 {
+		t.Fatal("Expected nothing from a muted sender, got: ", count, " Err: ", err)
+	}
+}
+
+func TestGetDocsInIdRange(t *testing.T) {
+	conn, err := NewSolrNoteConnection()
+	if err != nil {
+		t.Fatal("Failed to connect to solr. Err: ", err)
+	}
+
+	sender := uuid.NewV4()
+	recipient := uuid.NewV4()
+	docs := []*Document{}
+	for i := 0; i < 4; i++ {
+		doc := getTestDoc(sender, recipient)
+		docs = append(docs, &doc)
+	}
+	sort.Sort(ById(docs))
+
+	var ids []uuid.UUID
+	for _, doc := range docs {
+		if err = conn.AddDoc(*doc); err != nil {
+			t.Fatal("Failed to add doc. Err: ", err)
+		}
+		ids = append(ids, doc.id)
+	}
+	defer conn.PurgeDocs(ids)
+
+	maxRows := 10
+	results, err := conn.GetDocsInIdRange(docs[0].id, docs[2].id, maxRows)
+	if err != nil {
+		t.Fatal("Error from GetDocsInIdRange: ", err)
+	}
+
+	// Other tests may share the core, so pick out just our docs.
+	var ours []*Document
+	for _, result := range results {
+		for _, doc := range docs {
+			if result.id == doc.id {
+				ours = append(ours, result)
+			}
+		}
+	}
+
+	if !allDocsEqual(docs[1:3], ours) {
+		t.Fatal("Results are not what we expected.")
+	}
+}
+
 func TestMarkDocDeleted(t *testing.T) {
 	conn, err := NewSolrNoteConnection()
 	if err != nil {
@@ -151,7 +360,7 @@ func TestMarkDocRead(t *testing.T) {
 	}
 	defer conn.PurgeDocs([]uuid.UUID{doc.id})
 
-	err = conn.MarkDocRead(doc.id)
+	err = conn.MarkDocRead(doc.id, recipient)
 	if err != nil {
 		t.Fatal("Failed to mark doc deleted. Id:", doc.id, "Err:", err)
 	}
@@ -161,11 +370,67 @@ func TestMarkDocRead(t *testing.T) {
 		t.Fatal("Failed to find test doc with id:", doc.id, "Err:", err)
 	}
 
-	if !resultDoc.read {
+	if !resultDoc.ReadBy(recipient) || !resultDoc.Read() {
 		t.Fatal("Apparently we didn't actually delete theh doc in question.")
 	}
 }
 
+func TestMarkDocReadConcurrently(t *testing.T) {
+	conn, err := NewSolrNoteConnection()
+	if err != nil {
+		t.Fatal("Failed to connect to solr. Err: ", err)
+	}
+
+	sender := uuid.NewV4()
+	first := uuid.NewV4()
+	second := uuid.NewV4()
+	doc := getTestDoc(sender, first)
+	doc.recipients = append(doc.recipients, second)
+	if err = conn.AddDoc(doc); err != nil {
+		t.Fatal("Failed to add doc. Err: ", err)
+	}
+	defer conn.PurgeDocs([]uuid.UUID{doc.id})
+
+	// Each update adds its own entry, so none of them can drop another's.
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for _, update := range []func() error{
+		func() error { return conn.MarkDocRead(doc.id, first) },
+		func() error { return conn.MarkDocRead(doc.id, second) },
+		func() error { return conn.MarkDocDeletedFor(doc.id, first) },
+		func() error { return conn.MarkDocDeletedFor(doc.id, second) },
+	} {
+		wg.Add(1)
+		go func(update func() error) {
+			defer wg.Done()
+			errs <- update()
+		}(update)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal("Failed to update doc. Id: ", doc.id, " Err: ", err)
+		}
+	}
+
+	result, err := conn.GetDoc(doc.id)
+	if err != nil {
+		t.Fatal("Failed to find test doc with id: ", doc.id, " Err: ", err)
+	}
+	rest := *result
+	rest.readBy, rest.deletedFor = nil, nil
+	if !result.ReadBy(first) || !result.ReadBy(second) || len(result.readBy) != 2 ||
+		!result.DeletedFor(first) || !result.DeletedFor(second) || len(result.deletedFor) != 2 ||
+		!docsEqual(doc, rest) {
+		t.Fatal("Expected both recipients' reads and deletes on the intact doc, got: ", result)
+	}
+
+	if err = conn.MarkDocRead(uuid.NewV4(), first); err == nil {
+		t.Fatal("Expected an error marking a doc that isn't indexed.")
+	}
+}
+
 func getTestDoc(sender uuid.UUID, recipient uuid.UUID) Document {
 	lat := 42.4
 	lon := 69.9
@@ -181,11 +446,11 @@ func getTestDocAtLocation(
 	return Document{
 		id: uuid.NewV4(),
 		sender: sender,
-		recipient: recipient,
+		visibility: notesdb.VISIBILITY_PRIVATE,
+		recipients: []uuid.UUID{recipient},
 		latitude: lat,
 		longitude: lon,
 		timeSent: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
-		read: false,
 		deleted: false,
 	}
 }
@@ -214,7 +479,11 @@ func docsEqual(lhs Document, rhs Document) bool {
 		return false 
 	}
 
-	if lhs.recipient != rhs.recipient {
+	if lhs.visibility != rhs.visibility {
+		return false
+	}
+
+	if !idsEqual(lhs.recipients, rhs.recipients) {
 		return false 
 	}
 
@@ -230,17 +499,30 @@ func docsEqual(lhs Document, rhs Document) bool {
 		return false 
 	}
 
-	if lhs.read != rhs.read {
+	if !idsEqual(lhs.readBy, rhs.readBy) || !idsEqual(lhs.deletedFor, rhs.deletedFor) {
 		return false 
 	}
 
-	if lhs.deleted != rhs.deleted {
+	if lhs.direct != rhs.direct || lhs.deleted != rhs.deleted {
 		return false 
 	}
 
 	return true
 }
 
+func idsEqual(lhs []uuid.UUID, rhs []uuid.UUID) bool {
+	if len(lhs) != len(rhs) {
+		return false
+	}
+
+	for i, _ := range lhs {
+		if lhs[i] != rhs[i] {
+			return false
+		}
+	}
+	return true
+}
+
 type ById []*Document
 
 func (s ById) Len() int {
@@ -0,0 +1,78 @@
+package unlocks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// SUBSCRIBER_BUFFER is how many events a slow subscriber can fall
+	// behind by before further events for it are dropped.
+	SUBSCRIBER_BUFFER = 16
+)
+
+// Event records that a recipient unlocked, i.e. read, a note.
+type Event struct {
+	NoteId uuid.UUID
+	Sender uuid.UUID
+	Recipient uuid.UUID
+	UnlockedAt time.Time
+}
+
+// Hub fans unlock events out to whoever is watching the note's sender.
+// It's in-process only: subscribers see unlocks made through this
+// process, not through other instances.
+type Hub struct {
+	mutex sync.Mutex
+	subscribers map[uuid.UUID]map[chan Event]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[uuid.UUID]map[chan Event]bool)}
+}
+
+// Subscribe returns a channel of unlock events for notes sent by sender,
+// and a function that must be called to stop receiving them. The channel
+// is closed once cancel is called.
+func (h *Hub) Subscribe(sender uuid.UUID) (<-chan Event, func()) {
+	events := make(chan Event, SUBSCRIBER_BUFFER)
+
+	h.mutex.Lock()
+	if h.subscribers[sender] == nil {
+		h.subscribers[sender] = make(map[chan Event]bool)
+	}
+	h.subscribers[sender][events] = true
+	h.mutex.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			delete(h.subscribers[sender], events)
+			if len(h.subscribers[sender]) == 0 {
+				delete(h.subscribers, sender)
+			}
+			close(events)
+		})
+	}
+
+	return events, cancel
+}
+
+// Publish delivers event to everyone watching its sender. It never
+// blocks; subscribers whose buffers are full miss the event.
+func (h *Hub) Publish(event Event) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for events, _ := range h.subscribers[event.Sender] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package unlocks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+func TestPublishReachesSendersSubscribers(t *testing.T) {
+	hub := NewHub()
+	sender := uuid.NewV4()
+
+	events, cancel := hub.Subscribe(sender)
+	defer cancel()
+	otherEvents, cancelOther := hub.Subscribe(uuid.NewV4())
+	defer cancelOther()
+
+	event := Event{
+		NoteId: uuid.NewV4(),
+		Sender: sender,
+		Recipient: uuid.NewV4(),
+		UnlockedAt: time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC),
+	}
+	hub.Publish(event)
+
+	select {
+	case received := <-events:
+		if received != event {
+			t.Fatal("Received the wrong event: ", received)
+		}
+	default:
+		t.Fatal("Subscriber did not receive the event.")
+	}
+
+	select {
+	case received := <-otherEvents:
+		t.Fatal("Another sender's subscriber received an event: ", received)
+	default:
+	}
+}
+
+func TestCancelClosesChannel(t *testing.T) {
+	hub := NewHub()
+	sender := uuid.NewV4()
+
+	events, cancel := hub.Subscribe(sender)
+	cancel()
+	cancel()
+
+	if _, ok := <-events; ok {
+		t.Fatal("Channel was not closed by cancel.")
+	}
+
+	// Publishing after everyone has gone away mustn't block or panic.
+	hub.Publish(Event{Sender: sender})
+}
+
+func TestSlowSubscriberDoesNotBlock(t *testing.T) {
+	hub := NewHub()
+	sender := uuid.NewV4()
+
+	_, cancel := hub.Subscribe(sender)
+	defer cancel()
+
+	for i := 0; i < SUBSCRIBER_BUFFER * 2; i++ {
+		hub.Publish(Event{Sender: sender})
+	}
+}
@@ -0,0 +1,323 @@
+package userdb
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/satori/go.uuid"
+)
+
+// MemoryUserdb is an in-memory UserdbConnection for tests in packages that
+// sit on top of userdb and shouldn't need a live MySQL. Passwords are
+// hashed and rehashed exactly as MysqlUserdb does it, but with a cheap
+// hasher by default so tests stay fast.
+type MemoryUserdb struct {
+	mutex sync.Mutex
+	users map[string]UserEntry
+	resetTokens map[string]ResetToken
+	totp map[uuid.UUID]TotpEnrollment
+	recoveryCodes map[uuid.UUID]map[string]bool
+	profiles map[uuid.UUID]Profile
+	hasher PasswordHasher
+	policy CredentialPolicy
+}
+
+func NewMemoryUserdb() *MemoryUserdb {
+	return &MemoryUserdb{
+		users: make(map[string]UserEntry),
+		resetTokens: make(map[string]ResetToken),
+		totp: make(map[uuid.UUID]TotpEnrollment),
+		recoveryCodes: make(map[uuid.UUID]map[string]bool),
+		profiles: make(map[uuid.UUID]Profile),
+		hasher: TEST_PASSWORD_HASHER,
+		policy: DEFAULT_CREDENTIAL_POLICY,
+	}
+}
+
+// TEST_PASSWORD_HASHER is argon2id with the smallest sensible parameters.
+// It's only for tests.
+var TEST_PASSWORD_HASHER = PasswordHasher{
+	Algorithm: ALGORITHM_ARGON2ID,
+	BcryptCost: 4,
+	Argon2Time: 1,
+	Argon2Memory: 64,
+	Argon2Threads: 1,
+}
+
+func (db *MemoryUserdb) SetPasswordHasher(hasher PasswordHasher) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.hasher = hasher
+}
+
+func (db *MemoryUserdb) SetCredentialPolicy(policy CredentialPolicy) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.policy = policy
+}
+
+func (db *MemoryUserdb) RegisterUser(username string, password string) (uuid.UUID, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	userEntry, err := createUserEntry(username, password, db.policy, db.hasher)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if _, ok := db.users[userEntry.Name]; ok {
+		return uuid.Nil, ErrUsernameTaken
+	}
+	db.users[userEntry.Name] = *userEntry
+	return userEntry.Id, nil
+}
+
+func (db *MemoryUserdb) IsUsernameAvailable(username string) (bool, error) {
+	db.mutex.Lock()
+	policy := db.policy
+	db.mutex.Unlock()
+
+	return isUsernameAvailable(policy, username, db.GetUserByName)
+}
+
+func (db *MemoryUserdb) DeleteUser(username string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	username = NormalizeUsername(username)
+	userEntry, ok := db.users[username]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(db.profiles, userEntry.Id)
+	delete(db.totp, userEntry.Id)
+	delete(db.recoveryCodes, userEntry.Id)
+	for hash, token := range db.resetTokens {
+		if token.UserId == userEntry.Id {
+			delete(db.resetTokens, hash)
+		}
+	}
+	delete(db.users, username)
+	return nil
+}
+
+func (db *MemoryUserdb) CheckCredentials(username string, password string) (uuid.UUID, bool, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	username = NormalizeUsername(username)
+	userEntry, ok := db.users[username]
+	if !ok {
+		return uuid.Nil, false, nil
+	}
+
+	valid, err := db.hasher.Verify(password, userEntry.Salt, string(userEntry.Hash))
+	if err != nil || !valid {
+		return uuid.Nil, false, err
+	}
+
+	if db.hasher.NeedsRehash(userEntry.Salt, string(userEntry.Hash)) {
+		if hash, err := db.hasher.Hash(password); err == nil {
+			userEntry.Salt = ""
+			userEntry.Hash = []byte(hash)
+			db.users[username] = userEntry
+		}
+	}
+	return userEntry.Id, true, nil
+}
+
+func (db *MemoryUserdb) ChangePassword(username string, oldPassword string, newPassword string) (bool, error) {
+	id, valid, err := db.CheckCredentials(username, oldPassword)
+	if err != nil || !valid {
+		return false, err
+	}
+
+	if err = db.SetPassword(id, newPassword); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (db *MemoryUserdb) SetPassword(id uuid.UUID, password string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for username, userEntry := range db.users {
+		if userEntry.Id != id {
+			continue
+		}
+		if err := db.policy.CheckPassword(username, password); err != nil {
+			return err
+		}
+
+		hash, err := db.hasher.Hash(password)
+		if err != nil {
+			return err
+		}
+		userEntry.Salt = ""
+		userEntry.Hash = []byte(hash)
+		db.users[username] = userEntry
+		return nil
+	}
+	return errors.New("Set password result is incorrect; no user with id " + id.String())
+}
+
+func (db *MemoryUserdb) GetUserById(id uuid.UUID) (*UserEntry, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, userEntry := range db.users {
+		if userEntry.Id == id {
+			return &userEntry, nil
+		}
+	}
+	return nil, nil
+}
+
+func (db *MemoryUserdb) GetUserByName(username string) (*UserEntry, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	userEntry, ok := db.users[NormalizeUsername(username)]
+	if !ok {
+		return nil, nil
+	}
+	return &userEntry, nil
+}
+
+func (db *MemoryUserdb) InsertResetToken(token *ResetToken) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.resetTokens[string(token.Hash)] = *token
+	return nil
+}
+
+func (db *MemoryUserdb) ConsumeResetToken(hash []byte) (*ResetToken, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	token, ok := db.resetTokens[string(hash)]
+	if !ok || token.Used {
+		return nil, nil
+	}
+	token.Used = true
+	db.resetTokens[string(hash)] = token
+	return &token, nil
+}
+
+func (db *MemoryUserdb) PutTotpEnrollment(enrollment *TotpEnrollment) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.totp[enrollment.UserId] = *enrollment
+	return nil
+}
+
+func (db *MemoryUserdb) GetTotpEnrollment(userId uuid.UUID) (*TotpEnrollment, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	enrollment, ok := db.totp[userId]
+	if !ok {
+		return nil, nil
+	}
+	return &enrollment, nil
+}
+
+func (db *MemoryUserdb) DeleteTotpEnrollment(userId uuid.UUID) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	delete(db.totp, userId)
+	delete(db.recoveryCodes, userId)
+	return nil
+}
+
+func (db *MemoryUserdb) UseTotpStep(userId uuid.UUID, step int64) (bool, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	enrollment, ok := db.totp[userId]
+	if !ok || step <= enrollment.LastStep {
+		return false, nil
+	}
+	enrollment.LastStep = step
+	db.totp[userId] = enrollment
+	return true, nil
+}
+
+func (db *MemoryUserdb) SetRecoveryCodes(userId uuid.UUID, hashes [][]byte) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	codes := make(map[string]bool)
+	for _, hash := range hashes {
+		codes[string(hash)] = false
+	}
+	db.recoveryCodes[userId] = codes
+	return nil
+}
+
+func (db *MemoryUserdb) UseRecoveryCode(userId uuid.UUID, hash []byte) (bool, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	used, ok := db.recoveryCodes[userId][string(hash)]
+	if !ok || used {
+		return false, nil
+	}
+	db.recoveryCodes[userId][string(hash)] = true
+	return true, nil
+}
+
+func (db *MemoryUserdb) GetProfile(id uuid.UUID) (*Profile, error) {
+	profiles, err := db.GetProfiles([]uuid.UUID{id})
+	if err != nil || len(profiles) == 0 {
+		return nil, err
+	}
+	return profiles[0], nil
+}
+
+func (db *MemoryUserdb) GetProfiles(ids []uuid.UUID) ([]*Profile, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	wanted := make(map[uuid.UUID]bool)
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	var profiles []*Profile
+	for _, userEntry := range db.users {
+		if !wanted[userEntry.Id] {
+			continue
+		}
+		profile := db.profiles[userEntry.Id]
+		profile.UserId = userEntry.Id
+		profile.Username = userEntry.Name
+		profiles = append(profiles, &profile)
+	}
+	return profiles, nil
+}
+
+func (db *MemoryUserdb) UpdateProfile(profile *Profile) error {
+	if err := ValidateProfile(profile); err != nil {
+		return err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, userEntry := range db.users {
+		if userEntry.Id == profile.UserId {
+			profile.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+			db.profiles[profile.UserId] = *profile
+			return nil
+		}
+	}
+	return ErrNotFound
+}
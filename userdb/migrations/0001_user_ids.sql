@@ -0,0 +1,13 @@
+-- Give every user a stable id. Notes refer to users by this id as their
+-- sender and recipient, so it must never change once assigned.
+--
+-- Existing users get ids from MySQL's UUID(); new users get theirs from
+-- RegisterUser.
+
+ALTER TABLE users ADD COLUMN id CHAR(36) NULL FIRST;
+
+UPDATE users SET id = UUID() WHERE id IS NULL;
+
+ALTER TABLE users MODIFY COLUMN id CHAR(36) NOT NULL;
+
+ALTER TABLE users ADD UNIQUE KEY users_id (id);
@@ -0,0 +1,9 @@
+-- Password hashes are now self-describing strings from PasswordHasher,
+-- and argon2id ones are longer than bcrypt's 60 characters.
+--
+-- The salt column is kept for users who haven't logged in since; their
+-- hash is rewritten, and their salt cleared, at their next login.
+
+ALTER TABLE users MODIFY COLUMN hash VARCHAR(255) NOT NULL;
+
+ALTER TABLE users MODIFY COLUMN salt VARCHAR(32) NOT NULL DEFAULT '';
@@ -0,0 +1,12 @@
+-- Single-use tokens for resetting a forgotten password. Only the SHA-256
+-- hash of each token is kept. Used and expired rows can be cleared out at
+-- leisure.
+
+CREATE TABLE password_reset_tokens (
+	hash BINARY(32) NOT NULL,
+	user_id CHAR(36) NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (hash),
+	KEY password_reset_tokens_user_id (user_id)
+);
@@ -0,0 +1,12 @@
+-- Usernames are now stored and looked up case-folded and NFKC normalized;
+-- see NormalizeUsername. LOWER() gives the same result for ASCII names;
+-- any others should be checked by hand.
+--
+-- Names that differ only in case collide once lowered and make the
+-- UPDATE fail. Find them first with
+--
+--	SELECT LOWER(name) FROM users GROUP BY LOWER(name) HAVING COUNT(*) > 1;
+--
+-- and rename all but one of each by hand.
+
+UPDATE users SET name = LOWER(name) WHERE name <> LOWER(name);
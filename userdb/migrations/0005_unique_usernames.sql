@@ -0,0 +1,6 @@
+-- RegisterUser relies on a unique key violation to report a taken
+-- username, so two concurrent registrations can't both get the name.
+-- Run 0004 first, so names that differed only in case are already
+-- resolved.
+
+ALTER TABLE users ADD UNIQUE KEY users_name (name);
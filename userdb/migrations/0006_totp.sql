@@ -0,0 +1,19 @@
+-- Optional TOTP two-factor authentication. The secret has to be kept as
+-- is to check codes with; recovery codes are kept only as SHA-256 hashes.
+-- last_step is the time step of the last code accepted, so a code can't
+-- be replayed.
+
+CREATE TABLE totp_enrollments (
+	user_id CHAR(36) NOT NULL,
+	secret VARBINARY(64) NOT NULL,
+	confirmed BOOLEAN NOT NULL DEFAULT FALSE,
+	last_step BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id)
+);
+
+CREATE TABLE totp_recovery_codes (
+	user_id CHAR(36) NOT NULL,
+	hash BINARY(32) NOT NULL,
+	used BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (user_id, hash)
+);
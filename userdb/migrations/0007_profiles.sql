@@ -0,0 +1,13 @@
+-- A profile per user. Users without a row have an empty profile; see
+-- GetProfiles.
+
+CREATE TABLE profiles (
+	user_id CHAR(36) NOT NULL,
+	display_name VARCHAR(255) NOT NULL DEFAULT '',
+	avatar_ref VARCHAR(255) NOT NULL DEFAULT '',
+	bio TEXT NOT NULL,
+	time_zone VARCHAR(64) NOT NULL DEFAULT '',
+	unlock_radius_km DOUBLE NOT NULL DEFAULT 0,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (user_id)
+);
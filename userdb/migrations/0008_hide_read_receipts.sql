@@ -0,0 +1,4 @@
+-- Users can stop sending read receipts; see notesdb's 0004_read_receipts.
+
+ALTER TABLE profiles
+	ADD COLUMN hide_read_receipts BOOLEAN NOT NULL DEFAULT 0 AFTER unlock_radius_km;
@@ -0,0 +1,170 @@
+package userdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	ALGORITHM_ARGON2ID = "argon2id"
+	ALGORITHM_BCRYPT = "bcrypt"
+
+	ARGON2_SALT_LEN = 16
+	ARGON2_KEY_LEN = 32
+)
+
+// PasswordHasher turns passwords into self-describing hash strings: the
+// algorithm and its parameters are encoded alongside the hash, so hashes
+// made with older settings can still be checked after the settings
+// change.
+//
+// argon2id hashes are encoded as
+//
+//	$argon2id$v=19$m=<memory KiB>,t=<time>,p=<threads>$<salt>$<key>
+//
+// with unpadded base64 salt and key. bcrypt hashes use bcrypt's own
+// $2a$<cost>$ encoding.
+type PasswordHasher struct {
+	// Algorithm is what new hashes are made with.
+	Algorithm string
+
+	BcryptCost int
+
+	Argon2Time uint32
+	Argon2Memory uint32
+	Argon2Threads uint8
+}
+
+// DEFAULT_PASSWORD_HASHER follows the OWASP argon2id recommendation at
+// the time of writing. Raising any of these makes CheckCredentials
+// rehash each user's password at their next login.
+var DEFAULT_PASSWORD_HASHER = PasswordHasher{
+	Algorithm: ALGORITHM_ARGON2ID,
+	BcryptCost: 12,
+	Argon2Time: 3,
+	Argon2Memory: 64 * 1024,
+	Argon2Threads: 2,
+}
+
+var ErrMalformedHash = errors.New("Stored password hash is malformed.")
+
+type argon2Params struct {
+	time uint32
+	memory uint32
+	threads uint8
+}
+
+// Hash returns the encoded hash of password using h.Algorithm.
+func (h PasswordHasher) Hash(password string) (string, error) {
+	switch h.Algorithm {
+	case ALGORITHM_ARGON2ID:
+		salt := make([]byte, ARGON2_SALT_LEN)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		params := argon2Params{time: h.Argon2Time, memory: h.Argon2Memory, threads: h.Argon2Threads}
+		return encodeArgon2(params, salt, argon2Key(password, salt, params)), nil
+	case ALGORITHM_BCRYPT:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.BcryptCost)
+		return string(hash), err
+	}
+	return "", errors.New("Unknown password hashing algorithm: " + h.Algorithm)
+}
+
+// Verify reports whether password matches encoded. salt is only set for
+// users registered before hashes were self-describing; their bcrypt hash
+// is of the password with that salt appended.
+func (h PasswordHasher) Verify(password string, salt string, encoded string) (bool, error) {
+	if strings.HasPrefix(encoded, "$" + ALGORITHM_ARGON2ID + "$") {
+		params, hashSalt, key, err := decodeArgon2(encoded)
+		if err != nil {
+			return false, err
+		}
+		candidate := argon2Key(password, hashSalt, params)
+		return subtle.ConstantTimeCompare(candidate, key) == 1, nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password + salt))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, ErrMalformedHash
+	}
+	return true, nil
+}
+
+// NeedsRehash reports whether a hash that just verified should be
+// replaced: it uses another algorithm or weaker parameters than h, or
+// depends on a legacy salt.
+func (h PasswordHasher) NeedsRehash(salt string, encoded string) bool {
+	if salt != "" {
+		return true
+	}
+
+	switch h.Algorithm {
+	case ALGORITHM_ARGON2ID:
+		params, _, _, err := decodeArgon2(encoded)
+		if err != nil {
+			return true
+		}
+		return params.time != h.Argon2Time ||
+			params.memory != h.Argon2Memory ||
+			params.threads != h.Argon2Threads
+	case ALGORITHM_BCRYPT:
+		cost, err := bcrypt.Cost([]byte(encoded))
+		return err != nil || cost != h.BcryptCost
+	}
+	return false
+}
+
+func argon2Key(password string, salt []byte, params argon2Params) []byte {
+	return argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, ARGON2_KEY_LEN)
+}
+
+func encodeArgon2(params argon2Params, salt []byte, key []byte) string {
+	return fmt.Sprintf("$%v$v=%v$m=%v,t=%v,p=%v$%v$%v",
+		ALGORITHM_ARGON2ID,
+		argon2.Version,
+		params.memory,
+		params.time,
+		params.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+func decodeArgon2(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != ALGORITHM_ARGON2ID {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, ErrMalformedHash
+	}
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads)
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, ErrMalformedHash
+	}
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,146 @@
+package userdb
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// BCRYPT_MAX_PASSWORD_BYTES is as much of a password as bcrypt looks
+	// at. Longer passwords are refused rather than silently truncated,
+	// whichever algorithm is hashing today.
+	BCRYPT_MAX_PASSWORD_BYTES = 72
+)
+
+// CredentialPolicy is what RegisterUser, ChangePassword and SetPassword
+// require of usernames and passwords. Usernames are checked after
+// NormalizeUsername; passwords are checked as given.
+type CredentialPolicy struct {
+	MinUsernameLen int
+	MinPasswordLen int
+
+	// ReservedNames can't be registered. They're normalized before
+	// comparing, so "Admin" here also reserves "ADMIN".
+	ReservedNames []string
+
+	// BreachedPasswords are refused outright, e.g. a list of the most
+	// common passwords from public breaches. See LoadBreachedPasswords.
+	BreachedPasswords map[string]bool
+}
+
+// DEFAULT_CREDENTIAL_POLICY follows NIST SP 800-63B: a minimum length and
+// a breached password check, but no composition rules. It has no
+// breached passwords until some are loaded.
+var DEFAULT_CREDENTIAL_POLICY = CredentialPolicy{
+	MinUsernameLen: 3,
+	MinPasswordLen: 8,
+	ReservedNames: []string{
+		"admin", "administrator", "root", "system", "support", "help",
+		"geonote", "moderator", "security", "api", "null", "nobody",
+	},
+}
+
+// ValidationError says why a username or password was refused. Its
+// message is meant for the user.
+type ValidationError struct {
+	Field string
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + " " + e.Reason
+}
+
+// NormalizeUsername returns the form a username is stored and looked up
+// in: case-folded, then NFKC normalized, so that names which look the
+// same, like "Alice", "ALICE" and "ａｌｉｃｅ", are the same user.
+func NormalizeUsername(username string) string {
+	return norm.NFKC.String(cases.Fold().String(username))
+}
+
+// CheckUsername returns the normalized username, or a ValidationError if
+// it can't be registered.
+func (p CredentialPolicy) CheckUsername(username string) (string, error) {
+	if !utf8.ValidString(username) {
+		return "", &ValidationError{"username", "must be valid UTF-8."}
+	}
+
+	normalized := NormalizeUsername(username)
+	if utf8.RuneCountInString(normalized) < p.MinUsernameLen {
+		return "", &ValidationError{"username", "must be at least " +
+			strconv.Itoa(p.MinUsernameLen) + " characters."}
+	}
+	if len(normalized) > MAX_USERNAME_LEN {
+		return "", &ValidationError{"username", "must be at most " +
+			strconv.Itoa(MAX_USERNAME_LEN) + " bytes."}
+	}
+
+	for _, r := range normalized {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) && !strings.ContainsRune("._-", r) {
+			return "", &ValidationError{"username",
+				"may only contain letters, numbers, '.', '_' and '-'."}
+		}
+	}
+
+	for _, reserved := range p.ReservedNames {
+		if normalized == NormalizeUsername(reserved) {
+			return "", &ValidationError{"username", "is reserved."}
+		}
+	}
+
+	return normalized, nil
+}
+
+// CheckPassword returns a ValidationError if password can't be used by
+// the user with the given normalized username.
+func (p CredentialPolicy) CheckPassword(username string, password string) error {
+	if utf8.RuneCountInString(password) < p.MinPasswordLen {
+		return &ValidationError{"password", "must be at least " +
+			strconv.Itoa(p.MinPasswordLen) + " characters."}
+	}
+	if len(password) > BCRYPT_MAX_PASSWORD_BYTES {
+		return &ValidationError{"password", "must be at most " +
+			strconv.Itoa(BCRYPT_MAX_PASSWORD_BYTES) + " bytes."}
+	}
+	if NormalizeUsername(password) == username {
+		return &ValidationError{"password", "must not be the username."}
+	}
+	if p.BreachedPasswords[password] {
+		return &ValidationError{"password",
+			"appears in a list of breached passwords; choose another."}
+	}
+	return nil
+}
+
+// LoadBreachedPasswords reads a file of breached passwords, one per line,
+// for CredentialPolicy.BreachedPasswords.
+func LoadBreachedPasswords(filename string) (map[string]bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		log.Printf("Failed to open breached passwords file %v. Err: %v", filename, err)
+		return nil, err
+	}
+	defer file.Close()
+
+	passwords := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			passwords[line] = true
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		log.Printf("Failed to read breached passwords file %v. Err: %v", filename, err)
+		return nil, err
+	}
+
+	return passwords, nil
+}
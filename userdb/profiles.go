@@ -0,0 +1,220 @@
+package userdb
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+	"database/sql"
+
+	"github.com/satori/go.uuid"
+)
+
+const (
+	MAX_DISPLAY_NAME_LEN = 64
+	MAX_BIO_LEN = 500
+	MAX_AVATAR_REF_LEN = 255
+	MAX_UNLOCK_RADIUS_KM = 50
+)
+
+// Profile is what a user shows others about themselves, plus a few
+// preferences. Every user has one; until they set it, it's empty apart
+// from Username, which comes from the users table and can't be changed
+// through the profile.
+//
+// AvatarRef is an opaque reference to an image stored elsewhere, e.g. an
+// object key or URL. TimeZone is an IANA name like "America/New_York".
+// UnlockRadiusKm is the radius used when the user looks for nearby notes
+// without giving one; zero means the server's default. HideReadReceipts
+// stops senders learning when and where the user read their notes.
+type Profile struct {
+	UserId uuid.UUID
+	Username string
+	DisplayName string
+	AvatarRef string
+	Bio string
+	TimeZone string
+	UnlockRadiusKm float64
+	HideReadReceipts bool
+	UpdatedAt time.Time
+}
+
+// Name is what to show for the user: their display name, or their
+// username if they haven't set one.
+func (p *Profile) Name() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return p.Username
+}
+
+// ValidateProfile returns a *ValidationError for the first field of
+// profile that can't be stored.
+func ValidateProfile(profile *Profile) error {
+	if err := checkText("displayName", profile.DisplayName, MAX_DISPLAY_NAME_LEN, false); err != nil {
+		return err
+	}
+	if err := checkText("bio", profile.Bio, MAX_BIO_LEN, true); err != nil {
+		return err
+	}
+
+	if len(profile.AvatarRef) > MAX_AVATAR_REF_LEN {
+		return &ValidationError{"avatarRef", "must be at most " +
+			strconv.Itoa(MAX_AVATAR_REF_LEN) + " bytes."}
+	}
+	if strings.IndexFunc(profile.AvatarRef, unicode.IsSpace) >= 0 {
+		return &ValidationError{"avatarRef", "must not contain spaces."}
+	}
+
+	if profile.TimeZone != "" {
+		if _, err := time.LoadLocation(profile.TimeZone); err != nil || profile.TimeZone == "Local" {
+			return &ValidationError{"timeZone", "must be an IANA time zone name."}
+		}
+	}
+
+	if profile.UnlockRadiusKm < 0 || profile.UnlockRadiusKm > MAX_UNLOCK_RADIUS_KM {
+		return &ValidationError{"unlockRadiusKm", "must be between 0 and " +
+			strconv.Itoa(MAX_UNLOCK_RADIUS_KM) + "."}
+	}
+
+	return nil
+}
+
+// checkText refuses invalid UTF-8, control characters other than
+// newlines where multiline is allowed, and more than maxLen characters.
+func checkText(field string, text string, maxLen int, multiline bool) error {
+	if !utf8.ValidString(text) {
+		return &ValidationError{field, "must be valid UTF-8."}
+	}
+	if utf8.RuneCountInString(text) > maxLen {
+		return &ValidationError{field, "must be at most " + strconv.Itoa(maxLen) + " characters."}
+	}
+	for _, r := range text {
+		if unicode.IsControl(r) && !(multiline && r == '\n') {
+			return &ValidationError{field, "must not contain control characters."}
+		}
+	}
+	return nil
+}
+
+// GetProfile returns the user's profile, or nil if there's no such user.
+func (db MysqlUserdb) GetProfile(id uuid.UUID) (*Profile, error) {
+	profiles, err := db.GetProfiles([]uuid.UUID{id})
+	if err != nil || len(profiles) == 0 {
+		return nil, err
+	}
+	return profiles[0], nil
+}
+
+// GetProfiles returns the profiles of those of ids that are users, in no
+// particular order.
+func (db MysqlUserdb) GetProfiles(ids []uuid.UUID) ([]*Profile, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id.String()
+	}
+	sql := "SELECT users.id, users.name, profiles.display_name, profiles.avatar_ref, " +
+		" profiles.bio, profiles.time_zone, profiles.unlock_radius_km, profiles.hide_read_receipts, " +
+		" profiles.updated_at " +
+		" FROM users LEFT JOIN profiles ON profiles.user_id = users.id " +
+		" WHERE users.id IN (?" + strings.Repeat(", ?", len(ids) - 1) + ")"
+
+	rows, err := db.conn.Query(sql, args...)
+	if err != nil {
+		log.Printf("Failed to query profiles. Err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var profiles []*Profile
+	for rows.Next() {
+		profile, err := profileFromRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+
+	return profiles, rows.Err()
+}
+
+// UpdateProfile replaces the user's profile with profile, apart from
+// Username, and sets its UpdatedAt. It returns a *ValidationError if
+// ValidateProfile refuses it.
+func (db MysqlUserdb) UpdateProfile(profile *Profile) error {
+	if err := ValidateProfile(profile); err != nil {
+		return err
+	}
+	profile.UpdatedAt = time.Now().UTC().Truncate(time.Second)
+
+	sql := "INSERT INTO profiles " +
+		" (user_id, display_name, avatar_ref, bio, time_zone, unlock_radius_km, hide_read_receipts, " +
+		" updated_at) VALUES " +
+		" (?, ?, ?, ?, ?, ?, ?, ?) " +
+		" ON DUPLICATE KEY UPDATE display_name = VALUES(display_name), " +
+		" avatar_ref = VALUES(avatar_ref), bio = VALUES(bio), time_zone = VALUES(time_zone), " +
+		" unlock_radius_km = VALUES(unlock_radius_km), hide_read_receipts = VALUES(hide_read_receipts), " +
+		" updated_at = VALUES(updated_at)"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(
+		profile.UserId.String(),
+		profile.DisplayName,
+		profile.AvatarRef,
+		profile.Bio,
+		profile.TimeZone,
+		profile.UnlockRadiusKm,
+		profile.HideReadReceipts,
+		profile.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("Failed to update profile for user %v. Err: %v", profile.UserId, err)
+		return err
+	}
+
+	return nil
+}
+
+func profileFromRow(rows *sql.Rows) (*Profile, error) {
+	var profile Profile
+	var displayName, avatarRef, bio, timeZone sql.NullString
+	var unlockRadiusKm sql.NullFloat64
+	var hideReadReceipts sql.NullBool
+	var updatedAt sql.NullTime
+
+	err := rows.Scan(
+		&profile.UserId,
+		&profile.Username,
+		&displayName,
+		&avatarRef,
+		&bio,
+		&timeZone,
+		&unlockRadiusKm,
+		&hideReadReceipts,
+		&updatedAt,
+	)
+	if err != nil {
+		log.Printf("Failed to scan row while fetching profile. Err: %v", err)
+		return nil, err
+	}
+
+	profile.DisplayName = displayName.String
+	profile.AvatarRef = avatarRef.String
+	profile.Bio = bio.String
+	profile.TimeZone = timeZone.String
+	profile.UnlockRadiusKm = unlockRadiusKm.Float64
+	profile.HideReadReceipts = hideReadReceipts.Bool
+	profile.UpdatedAt = updatedAt.Time
+	return &profile, nil
+}
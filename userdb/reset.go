@@ -0,0 +1,232 @@
+package userdb
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+
+	"github.com/satori/go.uuid"
+)
+
+const (
+	DEFAULT_RESET_TTL = time.Hour
+	RESET_TOKEN_LEN = 32
+)
+
+var ErrInvalidResetToken = errors.New("Invalid or expired password reset token.")
+
+// ResetToken is a row in the password_reset_tokens table. Only the
+// SHA-256 hash of the token is stored.
+type ResetToken struct {
+	Hash []byte
+	UserId uuid.UUID
+	ExpiresAt time.Time
+	Used bool
+}
+
+type ResetTokenConnection interface {
+	InsertResetToken(token *ResetToken) error
+
+	// ConsumeResetToken marks the token with the given hash used and
+	// returns it, or returns nil if there's no such unused token. A token
+	// can only be consumed once, even by concurrent callers.
+	ConsumeResetToken(hash []byte) (*ResetToken, error)
+}
+
+// ResetSink delivers reset tokens to users, e.g. by email. It's given
+// the token itself, which is never stored.
+type ResetSink interface {
+	SendResetToken(user *UserEntry, token string, expiresAt time.Time) error
+}
+
+// LogResetSink writes reset tokens to the log. It's for development only;
+// anyone who can read the log can reset anyone's password.
+type LogResetSink struct{}
+
+func (LogResetSink) SendResetToken(user *UserEntry, token string, expiresAt time.Time) error {
+	log.Printf("Password reset token for %v, valid until %v: %v", user.Name, expiresAt, token)
+	return nil
+}
+
+// FileResetSink appends "<username> <token>" lines to a file, for tests
+// and for handing tokens to some other delivery process.
+type FileResetSink struct {
+	Filename string
+	mutex sync.Mutex
+}
+
+func (s *FileResetSink) SendResetToken(user *UserEntry, token string, expiresAt time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	file, err := os.OpenFile(s.Filename, os.O_APPEND | os.O_CREATE | os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("Failed to open reset token file %v. Err: %v", s.Filename, err)
+		return err
+	}
+
+	_, err = fmt.Fprintf(file, "%v %v\n", user.Name, token)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Resetter runs the forgotten password flow: RequestReset sends a user a
+// single-use token, and Reset trades it for a new password.
+type Resetter struct {
+	users UserdbConnection
+	tokens ResetTokenConnection
+	sink ResetSink
+	ttl time.Duration
+	policy CredentialPolicy
+	now func() time.Time
+}
+
+// NewResetter makes a Resetter whose tokens last ttl, or
+// DEFAULT_RESET_TTL if ttl is zero.
+func NewResetter(users UserdbConnection, tokens ResetTokenConnection, sink ResetSink, ttl time.Duration) *Resetter {
+	if ttl == 0 {
+		ttl = DEFAULT_RESET_TTL
+	}
+	return &Resetter{
+		users: users,
+		tokens: tokens,
+		sink: sink,
+		ttl: ttl,
+		policy: DEFAULT_CREDENTIAL_POLICY,
+		now: time.Now,
+	}
+}
+
+// SetCredentialPolicy sets the policy new passwords are checked against
+// before a token is used up. It should match the users store's policy.
+func (r *Resetter) SetCredentialPolicy(policy CredentialPolicy) {
+	r.policy = policy
+}
+
+// RequestReset sends username a reset token. It succeeds without sending
+// anything if there's no such user, so callers can't use it to find out
+// which usernames exist.
+func (r *Resetter) RequestReset(username string) error {
+	user, err := r.users.GetUserByName(username)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	raw := make([]byte, RESET_TOKEN_LEN)
+	if _, err = rand.Read(raw); err != nil {
+		log.Printf("Failed to generate reset token. Err: %v", err)
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	expiresAt := r.now().UTC().Truncate(time.Second).Add(r.ttl)
+
+	err = r.tokens.InsertResetToken(&ResetToken{
+		Hash: hashResetToken(token),
+		UserId: user.Id,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.sink.SendResetToken(user, token, expiresAt)
+}
+
+// Reset sets a new password for the user a reset token was sent to, and
+// returns their id. The token is used up even if it turns out to have
+// expired, but not if newPassword is refused by the policy; a
+// *ValidationError is returned then.
+func (r *Resetter) Reset(token string, newPassword string) (uuid.UUID, error) {
+	// Checking against the username has to wait until the token says
+	// whose it is.
+	if err := r.policy.CheckPassword("", newPassword); err != nil {
+		return uuid.Nil, err
+	}
+
+	resetToken, err := r.tokens.ConsumeResetToken(hashResetToken(token))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if resetToken == nil || !r.now().Before(resetToken.ExpiresAt) {
+		return uuid.Nil, ErrInvalidResetToken
+	}
+
+	if err = r.users.SetPassword(resetToken.UserId, newPassword); err != nil {
+		return uuid.Nil, err
+	}
+	return resetToken.UserId, nil
+}
+
+func hashResetToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
+}
+
+func (db MysqlUserdb) InsertResetToken(token *ResetToken) error {
+	insertSql := "INSERT INTO password_reset_tokens " +
+		" (hash, user_id, expires_at, used) VALUES " +
+		" (?, ?, ?, ?) "
+
+	statement, err := db.conn.Prepare(insertSql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", insertSql, err)
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(token.Hash, token.UserId.String(), token.ExpiresAt, token.Used)
+	if err != nil {
+		log.Printf("Failed to insert reset token for user %v. Err: %v", token.UserId, err)
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeResetToken relies on the UPDATE only matching an unused token,
+// so of two concurrent callers only one sees a row affected.
+func (db MysqlUserdb) ConsumeResetToken(hash []byte) (*ResetToken, error) {
+	sql := "UPDATE password_reset_tokens SET used = TRUE WHERE hash = ? AND used = FALSE"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(hash)
+	if err != nil {
+		log.Printf("Failed to consume reset token. Err: %v", err)
+		return nil, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error while fetching rows affected during consume. Err: %v", err)
+		return nil, err
+	}
+	if rowsAffected != 1 {
+		return nil, nil
+	}
+
+	token := ResetToken{Hash: hash, Used: true}
+	sql = "SELECT user_id, expires_at FROM password_reset_tokens WHERE hash = ?"
+	err = db.conn.QueryRow(sql, hash).Scan(&token.UserId, &token.ExpiresAt)
+	if err != nil {
+		log.Printf("Failed to fetch consumed reset token. Err: %v", err)
+		return nil, err
+	}
+
+	return &token, nil
+}
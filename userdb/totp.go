@@ -0,0 +1,449 @@
+package userdb
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+
+	"github.com/satori/go.uuid"
+)
+
+const (
+	TOTP_ISSUER = "GeoNote"
+	TOTP_SECRET_LEN = 20
+	TOTP_DIGITS = 6
+	TOTP_PERIOD = 30 * time.Second
+
+	// TOTP_SKEW_STEPS is how many periods either side of now a code is
+	// still accepted in, for phones whose clocks are a little off.
+	TOTP_SKEW_STEPS = 1
+
+	RECOVERY_CODE_COUNT = 10
+	RECOVERY_CODE_BYTES = 5
+)
+
+var (
+	ErrSecondFactorRequired = errors.New("A two-factor code is required.")
+	ErrInvalidTotpCode = errors.New("Invalid two-factor code.")
+	ErrTotpAlreadyEnrolled = errors.New("Two-factor authentication is already enabled.")
+	ErrTotpNotEnrolled = errors.New("Two-factor authentication is not enabled.")
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TotpEnrollment is a row in the totp_enrollments table. An enrollment
+// isn't used for logins until it's Confirmed with a code from the user's
+// authenticator app. LastStep is the time step of the last code accepted,
+// so that no code can be used twice.
+type TotpEnrollment struct {
+	UserId uuid.UUID
+	Secret []byte
+	Confirmed bool
+	LastStep int64
+}
+
+type TotpConnection interface {
+	// PutTotpEnrollment stores enrollment, replacing any the user already
+	// has.
+	PutTotpEnrollment(enrollment *TotpEnrollment) error
+
+	// GetTotpEnrollment returns nil if the user has no enrollment.
+	GetTotpEnrollment(userId uuid.UUID) (*TotpEnrollment, error)
+
+	// DeleteTotpEnrollment removes the user's enrollment and recovery
+	// codes.
+	DeleteTotpEnrollment(userId uuid.UUID) error
+
+	// UseTotpStep records step as the user's last used step if it's later
+	// than the current one, and reports whether it was. Of two concurrent
+	// callers with the same step only one succeeds.
+	UseTotpStep(userId uuid.UUID, step int64) (bool, error)
+
+	// SetRecoveryCodes replaces the user's recovery codes with the given
+	// SHA-256 hashes.
+	SetRecoveryCodes(userId uuid.UUID, hashes [][]byte) error
+
+	// UseRecoveryCode marks the unused recovery code with the given hash
+	// used, and reports whether there was one.
+	UseRecoveryCode(userId uuid.UUID, hash []byte) (bool, error)
+}
+
+// TotpSetup is what a user needs to add their account to an
+// authenticator app: the base32 secret, and the same as an otpauth URI
+// for a QR code.
+type TotpSetup struct {
+	Secret string
+	Uri string
+}
+
+// TwoFactor manages TOTP (RFC 6238) enrollment, and checks logins for
+// users who have it enabled. Users without it log in with their password
+// alone.
+type TwoFactor struct {
+	users UserdbConnection
+	store TotpConnection
+	now func() time.Time
+}
+
+func NewTwoFactor(users UserdbConnection, store TotpConnection) *TwoFactor {
+	return &TwoFactor{users: users, store: store, now: time.Now}
+}
+
+// CheckLogin is CheckCredentials plus a second factor. code is a TOTP
+// code or a recovery code, and is only looked at for users with
+// two-factor enabled. If such a user's password is right but code is
+// empty, it returns ErrSecondFactorRequired; a wrong code is reported
+// like a wrong password.
+func (f *TwoFactor) CheckLogin(username string, password string, code string) (uuid.UUID, bool, error) {
+	id, valid, err := f.users.CheckCredentials(username, password)
+	if err != nil || !valid {
+		return id, valid, err
+	}
+
+	enrolled, err := f.Enrolled(id)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	if !enrolled {
+		return id, true, nil
+	}
+	if code == "" {
+		return uuid.Nil, false, ErrSecondFactorRequired
+	}
+
+	if valid, err = f.Verify(id, code); err != nil || !valid {
+		return uuid.Nil, false, err
+	}
+	return id, true, nil
+}
+
+// Enrolled reports whether the user has confirmed two-factor enrollment.
+func (f *TwoFactor) Enrolled(userId uuid.UUID) (bool, error) {
+	enrollment, err := f.store.GetTotpEnrollment(userId)
+	if err != nil {
+		return false, err
+	}
+	return enrollment != nil && enrollment.Confirmed, nil
+}
+
+// Enroll starts two-factor enrollment with a new secret, replacing any
+// unconfirmed one. It takes effect once Confirm is called with a code
+// made from the secret.
+func (f *TwoFactor) Enroll(userId uuid.UUID) (*TotpSetup, error) {
+	user, err := f.users.GetUserById(userId)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrNotFound
+	}
+
+	enrolled, err := f.Enrolled(userId)
+	if err != nil {
+		return nil, err
+	}
+	if enrolled {
+		return nil, ErrTotpAlreadyEnrolled
+	}
+
+	secret := make([]byte, TOTP_SECRET_LEN)
+	if _, err = rand.Read(secret); err != nil {
+		log.Printf("Failed to generate TOTP secret. Err: %v", err)
+		return nil, err
+	}
+
+	err = f.store.PutTotpEnrollment(&TotpEnrollment{UserId: userId, Secret: secret})
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := totpEncoding.EncodeToString(secret)
+	return &TotpSetup{Secret: encoded, Uri: totpUri(user.Name, encoded)}, nil
+}
+
+// Confirm enables two-factor for the user if code is right for their
+// pending enrollment, and returns their recovery codes. They're only
+// ever returned here; only their hashes are kept.
+func (f *TwoFactor) Confirm(userId uuid.UUID, code string) ([]string, error) {
+	enrollment, err := f.store.GetTotpEnrollment(userId)
+	if err != nil {
+		return nil, err
+	}
+	if enrollment == nil {
+		return nil, ErrTotpNotEnrolled
+	}
+	if enrollment.Confirmed {
+		return nil, ErrTotpAlreadyEnrolled
+	}
+
+	step, ok := matchTotpCode(enrollment.Secret, code, f.now())
+	if !ok {
+		return nil, ErrInvalidTotpCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err = f.store.SetRecoveryCodes(userId, hashes); err != nil {
+		return nil, err
+	}
+
+	enrollment.Confirmed = true
+	enrollment.LastStep = step
+	if err = f.store.PutTotpEnrollment(enrollment); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns two-factor off, given a current TOTP or recovery code.
+func (f *TwoFactor) Disable(userId uuid.UUID, code string) error {
+	valid, err := f.Verify(userId, code)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidTotpCode
+	}
+
+	return f.store.DeleteTotpEnrollment(userId)
+}
+
+// Reset turns two-factor off without a code, for an operator helping a
+// user who has lost both their authenticator and recovery codes.
+func (f *TwoFactor) Reset(userId uuid.UUID) error {
+	return f.store.DeleteTotpEnrollment(userId)
+}
+
+// Verify reports whether code is a TOTP code or unused recovery code for
+// the user's confirmed enrollment. Either is used up by a successful
+// check.
+func (f *TwoFactor) Verify(userId uuid.UUID, code string) (bool, error) {
+	enrollment, err := f.store.GetTotpEnrollment(userId)
+	if err != nil {
+		return false, err
+	}
+	if enrollment == nil || !enrollment.Confirmed {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if step, ok := matchTotpCode(enrollment.Secret, code, f.now()); ok {
+		return f.store.UseTotpStep(userId, step)
+	}
+
+	return f.store.UseRecoveryCode(userId, hashRecoveryCode(code))
+}
+
+// TotpCode returns the code an authenticator app would show at the given
+// time for a base32 secret from Enroll, for tests and tools.
+func TotpCode(secret string, at time.Time) (string, error) {
+	raw, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpCode(raw, at.Unix() / int64(TOTP_PERIOD / time.Second)), nil
+}
+
+// matchTotpCode returns the time step within TOTP_SKEW_STEPS of now that
+// code is right for, if any.
+func matchTotpCode(secret []byte, code string, now time.Time) (int64, bool) {
+	if len(code) != TOTP_DIGITS {
+		return 0, false
+	}
+
+	current := now.Unix() / int64(TOTP_PERIOD / time.Second)
+	for step := current - TOTP_SKEW_STEPS; step <= current + TOTP_SKEW_STEPS; step++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(secret, step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode is the HOTP value (RFC 4226) of secret for the given step.
+func totpCode(secret []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum) - 1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset + 4]) & 0x7fffffff
+	return fmt.Sprintf("%06d", value % 1000000)
+}
+
+func totpUri(username string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", TOTP_ISSUER)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(TOTP_DIGITS))
+	query.Set("period", fmt.Sprint(int(TOTP_PERIOD / time.Second)))
+
+	label := url.PathEscape(TOTP_ISSUER + ":" + username)
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// newRecoveryCodes returns RECOVERY_CODE_COUNT codes like "abcd-efgh",
+// and their hashes.
+func newRecoveryCodes() ([]string, [][]byte, error) {
+	var codes []string
+	var hashes [][]byte
+	for i := 0; i < RECOVERY_CODE_COUNT; i++ {
+		raw := make([]byte, RECOVERY_CODE_BYTES)
+		if _, err := rand.Read(raw); err != nil {
+			log.Printf("Failed to generate recovery code. Err: %v", err)
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(totpEncoding.EncodeToString(raw))
+		codes = append(codes, encoded[:4] + "-" + encoded[4:])
+		hashes = append(hashes, hashRecoveryCode(encoded))
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case and dashes, since recovery codes are
+// typed in by hand.
+func hashRecoveryCode(code string) []byte {
+	code = strings.ToLower(strings.Replace(code, "-", "", -1))
+	hash := sha256.Sum256([]byte(code))
+	return hash[:]
+}
+
+func (db MysqlUserdb) PutTotpEnrollment(enrollment *TotpEnrollment) error {
+	sql := "REPLACE INTO totp_enrollments " +
+		" (user_id, secret, confirmed, last_step) VALUES " +
+		" (?, ?, ?, ?) "
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return err
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(enrollment.UserId.String(), enrollment.Secret,
+		enrollment.Confirmed, enrollment.LastStep)
+	if err != nil {
+		log.Printf("Failed to store TOTP enrollment for user %v. Err: %v", enrollment.UserId, err)
+		return err
+	}
+
+	return nil
+}
+
+func (db MysqlUserdb) GetTotpEnrollment(userId uuid.UUID) (*TotpEnrollment, error) {
+	sql := "SELECT secret, confirmed, last_step FROM totp_enrollments WHERE user_id = ?"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return nil, err
+	}
+	defer statement.Close()
+
+	rows, err := statement.Query(userId.String())
+	if err != nil {
+		log.Printf("Failed to query TOTP enrollment for user %v. Err: %v", userId, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	enrollment := TotpEnrollment{UserId: userId}
+	err = rows.Scan(&enrollment.Secret, &enrollment.Confirmed, &enrollment.LastStep)
+	if err != nil {
+		log.Printf("Failed to scan TOTP enrollment. Err: %v", err)
+		return nil, err
+	}
+
+	return &enrollment, nil
+}
+
+func (db MysqlUserdb) DeleteTotpEnrollment(userId uuid.UUID) error {
+	for _, sql := range []string{
+		"DELETE FROM totp_recovery_codes WHERE user_id = ?",
+		"DELETE FROM totp_enrollments WHERE user_id = ?",
+	} {
+		if _, err := db.conn.Exec(sql, userId.String()); err != nil {
+			log.Printf("Failed to delete TOTP enrollment for user %v. Err: %v", userId, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (db MysqlUserdb) UseTotpStep(userId uuid.UUID, step int64) (bool, error) {
+	sql := "UPDATE totp_enrollments SET last_step = ? WHERE user_id = ? AND last_step < ?"
+	return db.execOnce(sql, step, userId.String(), step)
+}
+
+func (db MysqlUserdb) SetRecoveryCodes(userId uuid.UUID, hashes [][]byte) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		log.Printf("Failed to begin transaction. Err: %v", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM totp_recovery_codes WHERE user_id = ?", userId.String())
+	if err != nil {
+		log.Printf("Failed to delete recovery codes for user %v. Err: %v", userId, err)
+		return err
+	}
+
+	for _, hash := range hashes {
+		_, err = tx.Exec("INSERT INTO totp_recovery_codes (user_id, hash, used) VALUES (?, ?, FALSE)",
+			userId.String(), hash)
+		if err != nil {
+			log.Printf("Failed to insert recovery code for user %v. Err: %v", userId, err)
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (db MysqlUserdb) UseRecoveryCode(userId uuid.UUID, hash []byte) (bool, error) {
+	sql := "UPDATE totp_recovery_codes SET used = TRUE WHERE user_id = ? AND hash = ? AND used = FALSE"
+	return db.execOnce(sql, userId.String(), hash)
+}
+
+// execOnce runs an UPDATE and reports whether it changed exactly one row.
+func (db MysqlUserdb) execOnce(sql string, args ...interface{}) (bool, error) {
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return false, err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(args...)
+	if err != nil {
+		log.Printf("Failed to execute %v. Err: %v", sql, err)
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error while fetching rows affected. Err: %v", err)
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
@@ -2,29 +2,52 @@ package userdb
 
 import (
 	"errors"
-	"fmt"
 	"log"
 	"database/sql"
-	"math/rand"
 
-	"golang.org/x/crypto/bcrypt"
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
+	"github.com/satori/go.uuid"
 )
 
 const (
 	MAX_USERNAME_LEN = 124
-	SALT_LEN = 32
-	HASH_LEN = 60
-	SALT_CHARS = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ_!@#$%^&*()"
+
+	// ER_DUP_ENTRY is MySQL's error number for a unique key violation.
+	ER_DUP_ENTRY = 1062
+)
+
+var (
+	ErrUsernameTaken = errors.New("Username is already taken.")
+	ErrNotFound = errors.New("No such user.")
 )
 
+// UserdbConnection looks users up by the normalized form of their name
+// (see NormalizeUsername), so any spelling that normalizes the same finds
+// the same user. RegisterUser, ChangePassword and SetPassword refuse
+// credentials the CredentialPolicy doesn't allow with a ValidationError.
 type UserdbConnection interface {
-	RegisterUser(username string, password string) error
+	RegisterUser(username string, password string) (uuid.UUID, error)
+	IsUsernameAvailable(username string) (bool, error)
 	DeleteUser(username string) error
-	CheckCredentials(username string, password string) (bool, error)
+	CheckCredentials(username string, password string) (uuid.UUID, bool, error)
+	ChangePassword(username string, oldPassword string, newPassword string) (bool, error)
+	SetPassword(id uuid.UUID, password string) error
+	GetUserById(id uuid.UUID) (*UserEntry, error)
+	GetUserByName(username string) (*UserEntry, error)
+	GetProfile(id uuid.UUID) (*Profile, error)
+	GetProfiles(ids []uuid.UUID) ([]*Profile, error)
+	UpdateProfile(profile *Profile) error
 }
 
+// UserEntry is a user's row in the users table. Id is generated when the
+// user registers and never changes; it's what notes use as sender and
+// recipient.
+//
+// Hash is an encoded hash from PasswordHasher. Salt is empty except for
+// users who haven't logged in since hashes became self-describing; see
+// PasswordHasher.Verify.
 type UserEntry struct {
+	Id uuid.UUID
 	Name string
 	Salt string
 	Hash []byte
@@ -32,6 +55,8 @@ type UserEntry struct {
 
 type MysqlUserdb struct {
 	conn *sql.DB
+	hasher PasswordHasher
+	policy CredentialPolicy
 }
 
 type DbCredentials struct {
@@ -50,40 +75,88 @@ func NewMysqlUserdb(credentials *DbCredentials) (*MysqlUserdb, error) {
 		return nil, err
 	}
 
-	return &MysqlUserdb{conn: db}, nil
+	return &MysqlUserdb{conn: db, hasher: DEFAULT_PASSWORD_HASHER, policy: DEFAULT_CREDENTIAL_POLICY}, nil
 }
 
-func (db MysqlUserdb) RegisterUser(username string, password string) error {
-	userEntry, err := createUserEntry(username, password)
+// SetPasswordHasher changes how new and rehashed passwords are hashed.
+// Existing hashes still verify whatever they were made with.
+func (db *MysqlUserdb) SetPasswordHasher(hasher PasswordHasher) {
+	db.hasher = hasher
+}
+
+// SetCredentialPolicy changes what new usernames and passwords must look
+// like. Existing users aren't checked against it.
+func (db *MysqlUserdb) SetCredentialPolicy(policy CredentialPolicy) {
+	db.policy = policy
+}
+
+func (db MysqlUserdb) CredentialPolicy() CredentialPolicy {
+	return db.policy
+}
+
+// RegisterUser stores a new user under the normalized username and
+// returns their generated id. It returns ErrUsernameTaken if the name is
+// already registered.
+func (db MysqlUserdb) RegisterUser(username string, password string) (uuid.UUID, error) {
+	userEntry, err := createUserEntry(username, password, db.policy, db.hasher)
 	if err != nil {
-		log.Printf("Failed to make user entry with name: %v", username)
+		log.Printf("Failed to make user entry with name: %v. Err: %v", username, err)
+		return uuid.Nil, err
 	}
 
 	insertSql := "INSERT INTO users " + 
-		" (name, salt, hash) VALUES " +
-		" (?, ?, ?) "
+		" (id, name, salt, hash) VALUES " +
+		" (?, ?, ?, ?) "
 
 	statement, err := db.conn.Prepare(insertSql)
 	if err != nil {
 		log.Printf("Failed to prepare statement %v. Err: %v", insertSql, err)
-		return err
+		return uuid.Nil, err
 	}
 	defer statement.Close()
 
 	_, err = statement.Exec(
+		userEntry.Id.String(),
 		userEntry.Name,
 		userEntry.Salt,
-		string(userEntry.Hash[:HASH_LEN]),
+		string(userEntry.Hash),
 	)
+	if mysqlErr, ok := err.(*mysql.MySQLError); ok && mysqlErr.Number == ER_DUP_ENTRY {
+		return uuid.Nil, ErrUsernameTaken
+	}
 	if err != nil {
-		log.Printf("Failed to register user. Err:", err)
-		return err
+		log.Printf("Failed to register user. Err: %v", err)
+		return uuid.Nil, err
 	}
 
-	return nil
+	return userEntry.Id, nil
+}
+
+// IsUsernameAvailable reports whether username could be registered now.
+// A name the policy refuses isn't available, and the *ValidationError
+// saying why is returned with it.
+func (db MysqlUserdb) IsUsernameAvailable(username string) (bool, error) {
+	return isUsernameAvailable(db.policy, username, db.GetUserByName)
 }
 
+// DeleteUser deletes the user with the given name along with their
+// profile, two-factor enrollment and password reset tokens, or returns
+// ErrNotFound if there's no such user.
 func (db MysqlUserdb) DeleteUser(username string) error {
+	for _, table := range []string{
+		"profiles",
+		"totp_recovery_codes",
+		"totp_enrollments",
+		"password_reset_tokens",
+	} {
+		_, err := db.conn.Exec("DELETE FROM " + table + " WHERE user_id = " +
+			" (SELECT id FROM users WHERE name = ?)", NormalizeUsername(username))
+		if err != nil {
+			log.Printf("Failed to delete %v of user: %v. Err: %v", table, username, err)
+			return err
+		}
+	}
+
 	sql := "DELETE from users WHERE name = ?"
 	statement, err := db.conn.Prepare(sql)
 	if err != nil {
@@ -92,7 +165,7 @@ func (db MysqlUserdb) DeleteUser(username string) error {
 	}
 	defer statement.Close()
 
-	result, err := statement.Exec(username)
+	result, err := statement.Exec(NormalizeUsername(username))
 	if err != nil {
 		log.Printf("Failed to delete user: %v", username)
 		return err
@@ -103,35 +176,125 @@ func (db MysqlUserdb) DeleteUser(username string) error {
 		log.Printf("Error while fetching rows affected during delete. Err: %v", err)
 		return err
 	}
-	
-	if rowsAffected != 1 {
-		msg := fmt.Sprintf("Delete user result is incorrect; actually affected %v entries while deleting %v.", rowsAffected, username)
-		log.Printf(msg)
-		return errors.New(msg)
+	if rowsAffected == 0 {
+		return ErrNotFound
 	}
 
 	return nil
 }
 
-func (db MysqlUserdb) CheckCredentials(username string, password string) (bool, error) {
+// CheckCredentials reports whether the password is correct for username,
+// and if so returns the user's id. On a bad login the id is uuid.Nil.
+//
+// A correct password whose hash is outdated is rehashed with the current
+// PasswordHasher. Failing to store the new hash doesn't fail the login.
+func (db MysqlUserdb) CheckCredentials(username string, password string) (uuid.UUID, bool, error) {
 	userEntry, err := getUserEntry(db, username)
 	if err != nil {
+		return uuid.Nil, false, err
+	}
+
+	if userEntry == nil {
+		return uuid.Nil, false, nil
+	}
+
+	valid, err := db.hasher.Verify(password, userEntry.Salt, string(userEntry.Hash))
+	if err != nil {
+		log.Printf("Failed to verify password for user %v. Err: %v", userEntry.Id, err)
+		return uuid.Nil, false, err
+	}
+	if !valid {
+		return uuid.Nil, false, nil
+	}
+
+	// The password may predate the policy, so it isn't checked again.
+	if db.hasher.NeedsRehash(userEntry.Salt, string(userEntry.Hash)) {
+		if err = db.storePassword(userEntry.Id, password); err != nil {
+			log.Printf("Failed to rehash password for user %v. Err: %v", userEntry.Id, err)
+		}
+	}
+	return userEntry.Id, true, nil
+}
+
+// GetUserById returns the user with the given id, or nil if there's no
+// such user.
+func (db MysqlUserdb) GetUserById(id uuid.UUID) (*UserEntry, error) {
+	return queryUserEntry(db, "id", id.String())
+}
+
+// GetUserByName returns the user with the given name, or nil if there's
+// no such user.
+func (db MysqlUserdb) GetUserByName(username string) (*UserEntry, error) {
+	return getUserEntry(db, username)
+}
+
+// ChangePassword sets a new password for username, but only if
+// oldPassword is their current one. It reports whether oldPassword was
+// correct; an unknown username is reported the same way as a wrong
+// password.
+func (db MysqlUserdb) ChangePassword(username string, oldPassword string, newPassword string) (bool, error) {
+	id, valid, err := db.CheckCredentials(username, oldPassword)
+	if err != nil || !valid {
 		return false, err
 	}
 
+	if err = db.SetPassword(id, newPassword); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetPassword replaces a user's password hash with one of password made
+// by the current hasher, dropping any legacy salt. It doesn't check the
+// old password; see ChangePassword and Resetter for that.
+func (db MysqlUserdb) SetPassword(id uuid.UUID, password string) error {
+	userEntry, err := db.GetUserById(id)
+	if err != nil {
+		return err
+	}
 	if userEntry == nil {
-		return false, nil
+		return errors.New("Set password result is incorrect; no user with id " + id.String())
+	}
+	if err = db.policy.CheckPassword(userEntry.Name, password); err != nil {
+		return err
 	}
 
-	err = bcrypt.CompareHashAndPassword(userEntry.Hash, []byte(password + userEntry.Salt))
-	validLogin := (err == nil)
-	return validLogin, nil
+	return db.storePassword(id, password)
 }
 
-func getHash(password string, salt string) ([]byte, error) {
-	passSalt := saltPassword(password, salt)
-	hash, err := bcrypt.GenerateFromPassword([]byte(passSalt), bcrypt.DefaultCost)
-	return hash, err
+// storePassword hashes and stores password without checking it against
+// the policy.
+func (db MysqlUserdb) storePassword(id uuid.UUID, password string) error {
+	hash, err := db.hasher.Hash(password)
+	if err != nil {
+		log.Printf("Failed to hash password. Err: %v", err)
+		return err
+	}
+
+	sql := "UPDATE users SET salt = '', hash = ? WHERE id = ?"
+	statement, err := db.conn.Prepare(sql)
+	if err != nil {
+		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
+		return err
+	}
+	defer statement.Close()
+
+	result, err := statement.Exec(hash, id.String())
+	if err != nil {
+		log.Printf("Failed to set password for user %v. Err: %v", id, err)
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		log.Printf("Error while fetching rows affected during set password. Err: %v", err)
+		return err
+	}
+	if rowsAffected != 1 {
+		return errors.New("Set password result is incorrect; no user with id " + id.String())
+	}
+
+	return nil
 }
 
 // getUserEntry returns a UserEntry object corresponding to the unique
@@ -140,7 +303,13 @@ func getHash(password string, salt string) ([]byte, error) {
 // for which you're searching, but *UserEntry will also be nil. Therefore,
 // callers of this function should check error & *UserEntry for nil.
 func getUserEntry(db MysqlUserdb, username string) (*UserEntry, error) {
-	sql := "SELECT name, salt, hash from users where name = ?"
+	return queryUserEntry(db, "name", NormalizeUsername(username))
+}
+
+// queryUserEntry looks up a single user by a unique column, with the same
+// nil, nil result as getUserEntry when there's no match.
+func queryUserEntry(db MysqlUserdb, column string, value string) (*UserEntry, error) {
+	sql := "SELECT id, name, salt, hash from users where " + column + " = ?"
 	statement, err := db.conn.Prepare(sql)
 	if err != nil {
 		log.Printf("Failed to prepare statement %v. Err: %v", sql, err)
@@ -148,12 +317,12 @@ func getUserEntry(db MysqlUserdb, username string) (*UserEntry, error) {
 	}
 	defer statement.Close()
 
-	rows, err := statement.Query(username)
-	defer rows.Close()
+	rows, err := statement.Query(value)
 	if err != nil {
-		log.Printf("Failed to query for username: %v. Err: %v", username, err)
+		log.Printf("Failed to query for %v: %v. Err: %v", column, value, err)
 		return nil, err
 	}
+	defer rows.Close()
 
 	if rows.Next() {
 		entry, err := userEntryFromRow(rows)
@@ -169,6 +338,7 @@ func getUserEntry(db MysqlUserdb, username string) (*UserEntry, error) {
 func userEntryFromRow(rows *sql.Rows) (*UserEntry, error) {
 	var entry UserEntry
 	err := rows.Scan(
+		&entry.Id,
 		&entry.Name,
 		&entry.Salt,
 		&entry.Hash,
@@ -182,32 +352,49 @@ func userEntryFromRow(rows *sql.Rows) (*UserEntry, error) {
 	return &entry, nil
 }
 
-func generateSalt() string {
-	salt := make([]byte, SALT_LEN)
+func isUsernameAvailable(
+	policy CredentialPolicy,
+	username string,
+	getUser func(string) (*UserEntry, error)) (bool, error) {
+	normalized, err := policy.CheckUsername(username)
+	if err != nil {
+		return false, err
+	}
 
-	for i := 0; i < SALT_LEN; i++ {
-		salt[i] = SALT_CHARS[rand.Intn(len(SALT_CHARS))]
+	user, err := getUser(normalized)
+	if err != nil {
+		return false, err
 	}
-	
-	saltStr := string(salt)
-	return saltStr
+	return user == nil, nil
 }
 
-func createUserEntry(username string, password string) (*UserEntry, error) {
+// createUserEntry checks the credentials against policy and makes the
+// row for a new user. New hashes carry their own salt, so the salt column
+// is left empty.
+func createUserEntry(
+	username string,
+	password string,
+	policy CredentialPolicy,
+	hasher PasswordHasher) (*UserEntry, error) {
 	var entry UserEntry
-	var err error
 
-	entry.Name = username
-	entry.Salt = generateSalt()
-	entry.Hash, err = getHash(password, entry.Salt)
+	normalized, err := policy.CheckUsername(username)
 	if err != nil {
-		log.Fatal("Failed to hash password. Dying.")
+		return nil, err
+	}
+	if err = policy.CheckPassword(normalized, password); err != nil {
 		return nil, err
 	}
 
-	return &entry, nil
-}
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		log.Printf("Failed to hash password. Err: %v", err)
+		return nil, err
+	}
 
-func saltPassword(password string, salt string) string {
-	return password + salt
+	entry.Id = uuid.NewV4()
+	entry.Name = normalized
+	entry.Hash = []byte(hash)
+
+	return &entry, nil
 }
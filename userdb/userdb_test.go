@@ -2,10 +2,16 @@ package userdb
 
 import (
 	"log"
+	"os"
 	"testing"
+	"strings"
+	"time"
 	"io/ioutil"
+	"path/filepath"
 
 	"github.com/go-yaml/yaml"	
+	"github.com/satori/go.uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestUserdb(t *testing.T) {
@@ -24,7 +30,7 @@ func TestUserdb(t *testing.T) {
 
 	// Run all subtests, now that common setup has occurred.
 	t.Run("RegisterAndDelete", func(t *testing.T) {
-		err = db.RegisterUser(username, password)
+		_, err = db.RegisterUser(username, password)
 		if err != nil {
 			t.Fatal("Failed to register new user.")
 		}
@@ -35,14 +41,35 @@ func TestUserdb(t *testing.T) {
 		}
 	})
 
+	t.Run("UsernameTaken", func(t *testing.T) {
+		_, err = db.RegisterUser(username, password)
+		if err != nil {
+			t.Fatal("Failed to register new user.")
+		}
+		defer db.DeleteUser(username)
+
+		if _, err = db.RegisterUser(username, password); err != ErrUsernameTaken {
+			t.Fatal("Expected ErrUsernameTaken, got: ", err)
+		}
+		if available, err := db.IsUsernameAvailable(username); err != nil || available {
+			t.Fatal("Expected the username to be taken. Err: ", err)
+		}
+	})
+
+	t.Run("DeleteMissingUser", func(t *testing.T) {
+		if err := db.DeleteUser("nosuchuser"); err != ErrNotFound {
+			t.Fatal("Expected ErrNotFound, got: ", err)
+		}
+	})
+
 	t.Run("CheckCredentialsBadPassword", func(t *testing.T) {
-		err = db.RegisterUser(username, password)
+		_, err = db.RegisterUser(username, password)
 		if err != nil {
 			t.Fatal("Failed to register new user.")
 		}
 		defer db.DeleteUser(username)
 
-		validLogin, err := db.CheckCredentials(username, "badpassword")
+		_, validLogin, err := db.CheckCredentials(username, "badpassword")
 		if err != nil {
 			t.Fatal("Error while checking bad credentials.")
 		}
@@ -53,13 +80,13 @@ func TestUserdb(t *testing.T) {
 	})
 
 	t.Run("CheckCredentialsBadUsername", func(t *testing.T) {
-		err = db.RegisterUser(username, password)
+		_, err = db.RegisterUser(username, password)
 		if err != nil {
 			t.Fatal("Failed to register new user.")
 		}
 		defer db.DeleteUser(username)
 
-		validLogin, err := db.CheckCredentials("badusername", password)
+		_, validLogin, err := db.CheckCredentials("badusername", password)
 		if err != nil {
 			t.Fatal("Error while checking bad credentials.")
 		}
@@ -70,13 +97,13 @@ func TestUserdb(t *testing.T) {
 	})
 
 	t.Run("CheckCredentialsSucceeds", func(t *testing.T) {
-		err = db.RegisterUser(username, password)
+		id, err := db.RegisterUser(username, password)
 		if err != nil {
 			t.Fatal("Failed to register new user.")
 		}
 		defer db.DeleteUser(username)
 
-		validLogin, err := db.CheckCredentials(username, password)
+		loginId, validLogin, err := db.CheckCredentials(username, password)
 		if err != nil {
 			t.Fatal("Error while checking bad credentials.")
 		}
@@ -84,7 +111,506 @@ func TestUserdb(t *testing.T) {
 		if !validLogin {
 			t.Fatal("Userdb did not accept a correct login.")
 		}
+
+		if loginId != id {
+			t.Fatal("Login returned id ", loginId, " but user was registered as ", id)
+		}
+	})
+
+	t.Run("GetUserByIdAndName", func(t *testing.T) {
+		id, err := db.RegisterUser(username, password)
+		if err != nil {
+			t.Fatal("Failed to register new user.")
+		}
+		defer db.DeleteUser(username)
+
+		if id == uuid.Nil {
+			t.Fatal("Registered user was not given an id.")
+		}
+
+		byId, err := db.GetUserById(id)
+		if err != nil || byId == nil || byId.Name != username {
+			t.Fatal("Failed to look up user by id. Err: ", err)
+		}
+
+		byName, err := db.GetUserByName(username)
+		if err != nil || byName == nil || byName.Id != id {
+			t.Fatal("Failed to look up user by name. Err: ", err)
+		}
+
+		missing, err := db.GetUserById(uuid.NewV4())
+		if err != nil || missing != nil {
+			t.Fatal("Expected no user for an unknown id. Err: ", err)
+		}
 	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		_, err := db.RegisterUser(username, password)
+		if err != nil {
+			t.Fatal("Failed to register new user.")
+		}
+		defer db.DeleteUser(username)
+
+		changed, err := db.ChangePassword(username, "badpassword", "newpassword")
+		if err != nil || changed {
+			t.Fatal("Changed password without the right old password. Err: ", err)
+		}
+
+		changed, err = db.ChangePassword(username, password, "newpassword")
+		if err != nil || !changed {
+			t.Fatal("Failed to change password. Err: ", err)
+		}
+
+		if _, validLogin, _ := db.CheckCredentials(username, "newpassword"); !validLogin {
+			t.Fatal("New password was not accepted.")
+		}
+		if _, validLogin, _ := db.CheckCredentials(username, password); validLogin {
+			t.Fatal("Old password still accepted.")
+		}
+	})
+
+	t.Run("ConsumeResetToken", func(t *testing.T) {
+		id, err := db.RegisterUser(username, password)
+		if err != nil {
+			t.Fatal("Failed to register new user.")
+		}
+		defer db.DeleteUser(username)
+
+		hash := hashResetToken(uuid.NewV4().String())
+		err = db.InsertResetToken(&ResetToken{Hash: hash, UserId: id, ExpiresAt: time.Now().Add(time.Hour)})
+		if err != nil {
+			t.Fatal("Failed to insert reset token. Err: ", err)
+		}
+
+		token, err := db.ConsumeResetToken(hash)
+		if err != nil || token == nil || token.UserId != id {
+			t.Fatal("Failed to consume reset token. Err: ", err)
+		}
+
+		token, err = db.ConsumeResetToken(hash)
+		if err != nil || token != nil {
+			t.Fatal("Consumed a reset token twice. Err: ", err)
+		}
+	})
+}
+
+func TestPasswordHasher(t *testing.T) {
+	bcryptHasher := TEST_PASSWORD_HASHER
+	bcryptHasher.Algorithm = ALGORITHM_BCRYPT
+
+	for _, hasher := range []PasswordHasher{TEST_PASSWORD_HASHER, bcryptHasher} {
+		hash, err := hasher.Hash("password")
+		if err != nil {
+			t.Fatal("Failed to hash with ", hasher.Algorithm, ". Err: ", err)
+		}
+
+		other, err := hasher.Hash("password")
+		if err != nil || other == hash {
+			t.Fatal(hasher.Algorithm, " hashes of the same password should differ. Err: ", err)
+		}
+
+		if valid, err := hasher.Verify("password", "", hash); err != nil || !valid {
+			t.Fatal(hasher.Algorithm, " did not verify the right password. Err: ", err)
+		}
+		if valid, err := hasher.Verify("wrong", "", hash); err != nil || valid {
+			t.Fatal(hasher.Algorithm, " verified the wrong password. Err: ", err)
+		}
+		if hasher.NeedsRehash("", hash) {
+			t.Fatal(hasher.Algorithm, " wants to rehash a hash it just made.")
+		}
+	}
+
+	if _, err := TEST_PASSWORD_HASHER.Verify("password", "", "$argon2id$v=19$m=x$$"); err != ErrMalformedHash {
+		t.Fatal("Expected ErrMalformedHash, got: ", err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	hash, err := TEST_PASSWORD_HASHER.Hash("password")
+	if err != nil {
+		t.Fatal("Failed to hash. Err: ", err)
+	}
+
+	stronger := TEST_PASSWORD_HASHER
+	stronger.Argon2Time++
+	if !stronger.NeedsRehash("", hash) {
+		t.Fatal("Expected a rehash when argon2 parameters change.")
+	}
+
+	bcryptHasher := TEST_PASSWORD_HASHER
+	bcryptHasher.Algorithm = ALGORITHM_BCRYPT
+	if !bcryptHasher.NeedsRehash("", hash) {
+		t.Fatal("Expected a rehash when the algorithm changes.")
+	}
+
+	if !TEST_PASSWORD_HASHER.NeedsRehash("legacysalt", hash) {
+		t.Fatal("Expected a rehash for a hash with a legacy salt.")
+	}
+}
+
+func TestRehashOnLogin(t *testing.T) {
+	db := NewMemoryUserdb()
+
+	// A user from before hashes were self-describing: bcrypt of the
+	// password with a separate salt appended.
+	legacyHash, err := bcrypt.GenerateFromPassword([]byte("password" + "legacysalt"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal("Failed to hash. Err: ", err)
+	}
+	id := uuid.NewV4()
+	db.users["myusername"] = UserEntry{Id: id, Name: "myusername", Salt: "legacysalt", Hash: legacyHash}
+
+	if _, valid, _ := db.CheckCredentials("myusername", "wrong"); valid {
+		t.Fatal("Accepted a wrong password for a legacy user.")
+	}
+	if db.users["myusername"].Salt == "" {
+		t.Fatal("Rehashed after a failed login.")
+	}
+
+	loginId, valid, err := db.CheckCredentials("myusername", "password")
+	if err != nil || !valid || loginId != id {
+		t.Fatal("Failed to log in a legacy user. Err: ", err)
+	}
+
+	entry := db.users["myusername"]
+	if entry.Salt != "" || !strings.HasPrefix(string(entry.Hash), "$argon2id$") {
+		t.Fatal("Legacy hash was not replaced: ", entry.Salt, " ", string(entry.Hash))
+	}
+
+	if _, valid, _ = db.CheckCredentials("myusername", "password"); !valid {
+		t.Fatal("Failed to log in with the rehashed password.")
+	}
+}
+
+func TestCredentialPolicy(t *testing.T) {
+	policy := DEFAULT_CREDENTIAL_POLICY
+	policy.BreachedPasswords = map[string]bool{"password1": true}
+
+	normalized, err := policy.CheckUsername("ＭｙＵｓｅｒ.Name")
+	if err != nil || normalized != "myuser.name" {
+		t.Fatal("Expected myuser.name, got: ", normalized, " ", err)
+	}
+	if NormalizeUsername("STRASSE") != NormalizeUsername("straße") {
+		t.Fatal("Usernames were not case-folded.")
+	}
+
+	badUsernames := []string{"", "ab", "my user", "me@example.com", "Admin", strings.Repeat("a", MAX_USERNAME_LEN + 1)}
+	for _, username := range badUsernames {
+		if _, err := policy.CheckUsername(username); err == nil {
+			t.Fatal("Accepted username ", username)
+		} else if _, ok := err.(*ValidationError); !ok {
+			t.Fatal("Expected a ValidationError, got: ", err)
+		}
+	}
+
+	badPasswords := []string{"", "short", "password1", "myuser.name", strings.Repeat("é", 37)}
+	for _, password := range badPasswords {
+		if err := policy.CheckPassword("myuser.name", password); err == nil {
+			t.Fatal("Accepted password ", password)
+		}
+	}
+	if err := policy.CheckPassword("myuser.name", "correct horse battery staple"); err != nil {
+		t.Fatal("Refused a good password. Err: ", err)
+	}
+}
+
+func TestMemoryNormalizesUsernames(t *testing.T) {
+	db := NewMemoryUserdb()
+	id, err := db.RegisterUser("MyUsername", "password")
+	if err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+
+	if _, err = db.RegisterUser("myusername", "password"); err == nil {
+		t.Fatal("Registered the same username with different case.")
+	}
+	if loginId, valid, _ := db.CheckCredentials("MYUSERNAME", "password"); !valid || loginId != id {
+		t.Fatal("Failed to log in with a differently cased username.")
+	}
+	if user, _ := db.GetUserByName("myUsername"); user == nil || user.Name != "myusername" {
+		t.Fatal("Expected the normalized name to be stored, got: ", user)
+	}
+
+	if _, err = db.RegisterUser("other", "short"); err == nil {
+		t.Fatal("Registered a user with a too short password.")
+	}
+	if err = db.SetPassword(id, "short"); err == nil {
+		t.Fatal("Set a too short password.")
+	}
+}
+
+func TestMemoryUsernameTaken(t *testing.T) {
+	db := NewMemoryUserdb()
+	if available, err := db.IsUsernameAvailable("myusername"); err != nil || !available {
+		t.Fatal("Expected myusername to be available. Err: ", err)
+	}
+	if _, err := db.RegisterUser("myusername", "password"); err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+
+	if _, err := db.RegisterUser("MyUsername", "password"); err != ErrUsernameTaken {
+		t.Fatal("Expected ErrUsernameTaken, got: ", err)
+	}
+	if available, err := db.IsUsernameAvailable("MYUSERNAME"); err != nil || available {
+		t.Fatal("Expected myusername to be taken. Err: ", err)
+	}
+	if _, err := db.IsUsernameAvailable("root"); err == nil {
+		t.Fatal("Expected a reserved name to be refused with a reason.")
+	}
+
+	if err := db.DeleteUser("myusername"); err != nil {
+		t.Fatal("Failed to delete user. Err: ", err)
+	}
+	if err := db.DeleteUser("myusername"); err != ErrNotFound {
+		t.Fatal("Expected ErrNotFound, got: ", err)
+	}
+}
+
+func TestMemoryProfiles(t *testing.T) {
+	db := NewMemoryUserdb()
+	id, err := db.RegisterUser("myusername", "password")
+	if err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+
+	profile, err := db.GetProfile(id)
+	if err != nil || profile == nil {
+		t.Fatal("Expected an empty profile for a new user. Err: ", err)
+	}
+	if profile.Name() != "myusername" {
+		t.Fatal("Expected the username as the name, got: ", profile.Name())
+	}
+
+	profile.DisplayName = "My Name"
+	profile.TimeZone = "Europe/Paris"
+	profile.UnlockRadiusKm = 2.5
+	profile.HideReadReceipts = true
+	if err = db.UpdateProfile(profile); err != nil {
+		t.Fatal("Failed to update profile. Err: ", err)
+	}
+	profiles, err := db.GetProfiles([]uuid.UUID{id, uuid.NewV4()})
+	if err != nil || len(profiles) != 1 {
+		t.Fatal("Expected one profile. Err: ", err)
+	}
+	if profiles[0].Name() != "My Name" || profiles[0].UnlockRadiusKm != 2.5 || !profiles[0].HideReadReceipts ||
+		profiles[0].UpdatedAt.IsZero() {
+		t.Fatal("Profile wasn't updated: ", profiles[0])
+	}
+
+	for _, bad := range []Profile{
+		{UserId: id, DisplayName: "two\nlines"},
+		{UserId: id, Bio: strings.Repeat("x", MAX_BIO_LEN + 1)},
+		{UserId: id, AvatarRef: "has space"},
+		{UserId: id, TimeZone: "Mars/Olympus_Mons"},
+		{UserId: id, UnlockRadiusKm: -1},
+	} {
+		if _, ok := db.UpdateProfile(&bad).(*ValidationError); !ok {
+			t.Fatal("Expected a ValidationError for: ", bad)
+		}
+	}
+	if err = db.UpdateProfile(&Profile{UserId: uuid.NewV4()}); err != ErrNotFound {
+		t.Fatal("Expected ErrNotFound, got: ", err)
+	}
+
+	if err = db.DeleteUser("myusername"); err != nil {
+		t.Fatal("Failed to delete user. Err: ", err)
+	}
+	if profile, _ = db.GetProfile(id); profile != nil {
+		t.Fatal("Expected no profile for a deleted user, got: ", profile)
+	}
+}
+
+func TestTotpCode(t *testing.T) {
+	// The SHA-1 test vectors from RFC 6238, cut to six digits.
+	secret := []byte("12345678901234567890")
+	vectors := map[int64]string{
+		59: "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, expected := range vectors {
+		if code := totpCode(secret, unix / 30); code != expected {
+			t.Error("At ", unix, " expected ", expected, ", got ", code)
+		}
+	}
+}
+
+func TestTwoFactor(t *testing.T) {
+	db := NewMemoryUserdb()
+	id, err := db.RegisterUser("myusername", "password")
+	if err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+
+	f := NewTwoFactor(db, db)
+	clock := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	f.now = func() time.Time { return clock }
+
+	setup, err := f.Enroll(id)
+	if err != nil {
+		t.Fatal("Failed to enroll. Err: ", err)
+	}
+	if !strings.HasPrefix(setup.Uri, "otpauth://totp/GeoNote:myusername?") ||
+		!strings.Contains(setup.Uri, "secret=" + setup.Secret) {
+		t.Fatal("Unexpected otpauth URI: ", setup.Uri)
+	}
+	codeAt := func(at time.Time) string {
+		code, err := TotpCode(setup.Secret, at)
+		if err != nil {
+			t.Fatal("Failed to make code. Err: ", err)
+		}
+		return code
+	}
+
+	// An unconfirmed enrollment doesn't affect logins.
+	if _, valid, err := f.CheckLogin("myusername", "password", ""); err != nil || !valid {
+		t.Fatal("Unconfirmed enrollment required a code. Err: ", err)
+	}
+
+	if _, err = f.Confirm(id, "000000"); err != ErrInvalidTotpCode {
+		t.Fatal("Expected ErrInvalidTotpCode, got: ", err)
+	}
+	recoveryCodes, err := f.Confirm(id, codeAt(clock))
+	if err != nil || len(recoveryCodes) != RECOVERY_CODE_COUNT {
+		t.Fatal("Failed to confirm. Err: ", err)
+	}
+	if _, err = f.Enroll(id); err != ErrTotpAlreadyEnrolled {
+		t.Fatal("Expected ErrTotpAlreadyEnrolled, got: ", err)
+	}
+
+	if _, _, err = f.CheckLogin("myusername", "password", ""); err != ErrSecondFactorRequired {
+		t.Fatal("Expected ErrSecondFactorRequired, got: ", err)
+	}
+	if _, valid, _ := f.CheckLogin("myusername", "wrong", codeAt(clock)); valid {
+		t.Fatal("Accepted a code with a wrong password.")
+	}
+
+	// The code used to confirm can't be replayed, but the next one, even
+	// from a phone a period behind, works once.
+	if _, valid, _ := f.CheckLogin("myusername", "password", codeAt(clock)); valid {
+		t.Fatal("Accepted a replayed code.")
+	}
+	clock = clock.Add(2 * TOTP_PERIOD)
+	skewed := codeAt(clock.Add(-TOTP_PERIOD))
+	if loginId, valid, err := f.CheckLogin("myusername", "password", skewed); err != nil || !valid || loginId != id {
+		t.Fatal("Failed to log in with a skewed code. Err: ", err)
+	}
+	if _, valid, _ := f.CheckLogin("myusername", "password", skewed); valid {
+		t.Fatal("Accepted a replayed code.")
+	}
+	if _, valid, _ := f.CheckLogin("myusername", "password", codeAt(clock.Add(5 * TOTP_PERIOD))); valid {
+		t.Fatal("Accepted a code from too far in the future.")
+	}
+
+	recoveryCode := strings.ToUpper(recoveryCodes[0])
+	if _, valid, err := f.CheckLogin("myusername", "password", recoveryCode); err != nil || !valid {
+		t.Fatal("Failed to log in with a recovery code. Err: ", err)
+	}
+	if _, valid, _ := f.CheckLogin("myusername", "password", recoveryCode); valid {
+		t.Fatal("Accepted a used recovery code.")
+	}
+
+	if err = f.Disable(id, recoveryCode); err != ErrInvalidTotpCode {
+		t.Fatal("Expected ErrInvalidTotpCode for a used recovery code, got: ", err)
+	}
+	if err = f.Disable(id, recoveryCodes[1]); err != nil {
+		t.Fatal("Failed to disable. Err: ", err)
+	}
+	if _, valid, err := f.CheckLogin("myusername", "password", ""); err != nil || !valid {
+		t.Fatal("Still required a code after disabling. Err: ", err)
+	}
+}
+
+func TestMemoryChangePassword(t *testing.T) {
+	db := NewMemoryUserdb()
+	if _, err := db.RegisterUser("myusername", "password"); err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+
+	if changed, _ := db.ChangePassword("myusername", "wrong", "newpassword"); changed {
+		t.Fatal("Changed password without the right old password.")
+	}
+	if changed, err := db.ChangePassword("myusername", "password", "newpassword"); err != nil || !changed {
+		t.Fatal("Failed to change password. Err: ", err)
+	}
+	if _, valid, _ := db.CheckCredentials("myusername", "newpassword"); !valid {
+		t.Fatal("New password was not accepted.")
+	}
+}
+
+func TestResetFlow(t *testing.T) {
+	db := NewMemoryUserdb()
+	id, err := db.RegisterUser("myusername", "password")
+	if err != nil {
+		t.Fatal("Failed to register user. Err: ", err)
+	}
+
+	dir, err := ioutil.TempDir("", "geonote-reset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	sink := &FileResetSink{Filename: filepath.Join(dir, "tokens")}
+
+	r := NewResetter(db, db, sink, 0)
+	clock := time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return clock }
+
+	if err = r.RequestReset("nosuchuser"); err != nil {
+		t.Fatal("Reset for an unknown user should quietly succeed. Err: ", err)
+	}
+	if err = r.RequestReset("myusername"); err != nil {
+		t.Fatal("Failed to request reset. Err: ", err)
+	}
+	token := readResetToken(t, sink.Filename, "myusername")
+
+	if _, err = r.Reset("not-a-token", "newpassword"); err != ErrInvalidResetToken {
+		t.Fatal("Expected ErrInvalidResetToken, got: ", err)
+	}
+
+	resetId, err := r.Reset(token, "newpassword")
+	if err != nil || resetId != id {
+		t.Fatal("Failed to reset password. Err: ", err)
+	}
+	if _, valid, _ := db.CheckCredentials("myusername", "newpassword"); !valid {
+		t.Fatal("Reset password was not accepted.")
+	}
+
+	if _, err = r.Reset(token, "anotherpassword"); err != ErrInvalidResetToken {
+		t.Fatal("Reset token was accepted twice. Err: ", err)
+	}
+
+	// Tokens expire, and trying an expired one uses it up.
+	os.Remove(sink.Filename)
+	if err = r.RequestReset("myusername"); err != nil {
+		t.Fatal("Failed to request reset. Err: ", err)
+	}
+	token = readResetToken(t, sink.Filename, "myusername")
+	clock = clock.Add(DEFAULT_RESET_TTL)
+	if _, err = r.Reset(token, "anotherpassword"); err != ErrInvalidResetToken {
+		t.Fatal("Expired reset token was accepted. Err: ", err)
+	}
+}
+
+// readResetToken returns the token FileResetSink last wrote for username.
+func readResetToken(t *testing.T, filename string, username string) string {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal("Failed to read reset tokens. Err: ", err)
+	}
+
+	var token string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == username {
+			token = fields[1]
+		}
+	}
+	if token == "" {
+		t.Fatal("No reset token was sent to ", username)
+	}
+	return token
 }
 
 func parseDbCredentials(filename string) (*DbCredentials, error) {